@@ -0,0 +1,115 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// MaintenanceWindowSpec defines when the cutovers of the MigrationPlans referencing the MaintenanceWindow may start
+type MaintenanceWindowSpec struct {
+	// TimeZone is the IANA time zone the schedules and blackout dates are in, such as Europe/Paris. Defaults to UTC
+	// +kubebuilder:default:=UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Windows are the recurring windows cutovers may start in
+	// +kubebuilder:validation:MinItems=1
+	Windows []MaintenanceWindowSchedule `json:"windows"`
+
+	// Blackouts are the dates no window opens on, such as holidays or change freezes
+	// +optional
+	Blackouts []MaintenanceWindowBlackout `json:"blackouts,omitempty"`
+}
+
+// MaintenanceWindowSchedule is a recurring window
+type MaintenanceWindowSchedule struct {
+	// Schedule is when the window opens, either a cron expression with 5 fields such as "0 22 * * 5"
+	// or an RRULE such as "FREQ=WEEKLY;BYDAY=FR;BYHOUR=22". RRULEs support FREQ=DAILY, WEEKLY or MONTHLY
+	// with BYMONTH, BYMONTHDAY, BYDAY, BYHOUR and BYMINUTE
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window stays open, such as 4h
+	Duration metav1.Duration `json:"duration"`
+}
+
+// MaintenanceWindowBlackout is a range of dates no window opens on
+type MaintenanceWindowBlackout struct {
+	// Start is the first date of the blackout, as YYYY-MM-DD
+	// +kubebuilder:validation:Pattern=`^\d{4}-\d{2}-\d{2}$`
+	Start string `json:"start"`
+
+	// End is the last date of the blackout, as YYYY-MM-DD. Defaults to Start
+	// +kubebuilder:validation:Pattern=`^\d{4}-\d{2}-\d{2}$`
+	// +optional
+	End string `json:"end,omitempty"`
+
+	// Reason describes the blackout
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// MaintenanceWindowStatus defines the observed state of MaintenanceWindow
+type MaintenanceWindowStatus struct {
+	// Open is true while a window is open
+	Open bool `json:"open"`
+
+	// NextWindowStart is the start of the open window or of the next window
+	// +optional
+	NextWindowStart *metav1.Time `json:"nextWindowStart,omitempty"`
+
+	// NextWindowEnd is the end of the open window or of the next window
+	// +optional
+	NextWindowEnd *metav1.Time `json:"nextWindowEnd,omitempty"`
+
+	// Message reports invalid schedules and blackout dates, or that no window opens within a year
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="TimeZone",type="string",JSONPath=".spec.timeZone"
+// +kubebuilder:printcolumn:name="Open",type="boolean",JSONPath=".status.open"
+// +kubebuilder:printcolumn:name="NextStart",type="date",JSONPath=".status.nextWindowStart"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// MaintenanceWindow is the Schema for the maintenancewindows API. MigrationPlans referencing it only start
+// the cutover of their VMs inside one of its windows, when the estimated downtime fits in what is left of it
+type MaintenanceWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the windows and blackout dates
+	Spec MaintenanceWindowSpec `json:"spec,omitempty"`
+
+	// Status defines the observed state of MaintenanceWindow
+	Status MaintenanceWindowStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// MaintenanceWindowList contains a list of MaintenanceWindow
+type MaintenanceWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []MaintenanceWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&MaintenanceWindow{}, &MaintenanceWindowList{})
+}
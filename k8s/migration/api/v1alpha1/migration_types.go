@@ -25,7 +25,7 @@ import (
 // tracking the detailed progression through various stages including validation, data copying,
 // disk conversion, and cutover. Each phase provides visibility into the migration's progress,
 // enabling precise monitoring and troubleshooting of the migration workflow.
-// +kubebuilder:validation:Enum=Pending;Validating;AwaitingDataCopyStart;CopyingBlocks;CopyingChangedBlocks;ConvertingDisk;AwaitingCutOverStartTime;AwaitingAdminCutOver;Succeeded;Failed;Unknown
+// +kubebuilder:validation:Enum=Pending;WaitingForCapacity;Validating;AwaitingDataCopyStart;CopyingBlocks;CopyingChangedBlocks;ConvertingDisk;AwaitingCutOverStartTime;AwaitingAdminCutOver;Succeeded;RolledBack;Inspecting;Inspected;Failed;Unknown
 type VMMigrationPhase string
 
 // MigrationConditionType represents the type of condition for a migration, used to track
@@ -36,6 +36,8 @@ type MigrationConditionType string
 const (
 	// VMMigrationPhasePending indicates the migration is waiting to start
 	VMMigrationPhasePending VMMigrationPhase = "Pending"
+	// VMMigrationPhaseWaitingForCapacity indicates the target project does not have enough quota left for the VM yet
+	VMMigrationPhaseWaitingForCapacity VMMigrationPhase = "WaitingForCapacity"
 	// VMMigrationPhaseValidating indicates the migration prerequisites are being validated
 	VMMigrationPhaseValidating VMMigrationPhase = "Validating"
 	// VMMigrationPhaseAwaitingDataCopyStart indicates the migration is waiting to begin data copy
@@ -52,12 +54,26 @@ const (
 	VMMigrationPhaseAwaitingAdminCutOver VMMigrationPhase = "AwaitingAdminCutOver"
 	// VMMigrationPhaseSucceeded indicates the migration completed successfully
 	VMMigrationPhaseSucceeded VMMigrationPhase = "Succeeded"
+	// VMMigrationPhaseRolledBack indicates the migrated VM failed its health checks and the source VM was restored
+	VMMigrationPhaseRolledBack VMMigrationPhase = "RolledBack"
 	// VMMigrationPhaseFailed indicates the migration has failed
 	VMMigrationPhaseFailed VMMigrationPhase = "Failed"
+	// VMMigrationPhaseInspecting indicates the disks of the VM are being inspected by an inspect-only migration
+	VMMigrationPhaseInspecting VMMigrationPhase = "Inspecting"
+	// VMMigrationPhaseInspected indicates an inspect-only migration recorded the inspection of the VM
+	VMMigrationPhaseInspected VMMigrationPhase = "Inspected"
 	// VMMigrationPhaseUnknown indicates the migration state is unknown
 	VMMigrationPhaseUnknown VMMigrationPhase = "Unknown"
 )
 
+// MigrationProgressAnnotation is the annotation on the migration pod that holds the
+// JSON encoded MigrationProgress reported by v2v-helper
+const MigrationProgressAnnotation = "vjailbreak.k8s.pf9.io/migration-progress"
+
+// MetricsPortAnnotation is the annotation on the migration pod that holds the port
+// v2v-helper serves its Prometheus metrics on
+const MetricsPortAnnotation = "vjailbreak.k8s.pf9.io/metrics-port"
+
 // MigrationSpec defines the desired state of Migration
 type MigrationSpec struct {
 	// MigrationPlan is the name of the migration plan
@@ -78,6 +94,237 @@ type MigrationSpec struct {
 	DisconnectSourceNetwork bool `json:"disconnectSourceNetwork,omitempty"`
 }
 
+// DiskProgress is the copy progress of a single disk of the VM
+type DiskProgress struct {
+	// Index is the position of the disk in the VM
+	Index int `json:"index"`
+
+	// Name is the name of the source VMware disk
+	Name string `json:"name,omitempty"`
+
+	// BytesCopied is the number of bytes copied in the current copy pass
+	BytesCopied int64 `json:"bytesCopied"`
+
+	// TotalBytes is the number of bytes to copy in the current copy pass
+	TotalBytes int64 `json:"totalBytes"`
+}
+
+// DiskDelta is the changed blocks copy of a disk in one copy iteration
+type DiskDelta struct {
+	// Index is the position of the disk in the VM
+	Index int `json:"index"`
+
+	// Iteration is the changed blocks copy iteration
+	Iteration int `json:"iteration"`
+
+	// ChangedBytes is the number of changed bytes copied
+	ChangedBytes int64 `json:"changedBytes"`
+
+	// BytesPerSecond is the copy rate of the changed bytes, 0 when nothing changed
+	// +optional
+	BytesPerSecond int64 `json:"bytesPerSecond,omitempty"`
+}
+
+// MigrationProgress is the typed progress record of a migration as reported by v2v-helper
+type MigrationProgress struct {
+	// Phase is the phase v2v-helper is currently in
+	Phase VMMigrationPhase `json:"phase,omitempty"`
+
+	// Message describes the last step taken, or the error when the phase is Failed
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Disks is the copy progress of each disk
+	// +optional
+	Disks []DiskProgress `json:"disks,omitempty"`
+
+	// ThroughputBytesPerSecond is the combined copy rate of all disks in the current copy pass
+	// +optional
+	ThroughputBytesPerSecond int64 `json:"throughputBytesPerSecond,omitempty"`
+
+	// CBTIteration is the changed blocks copy iteration, 0 during the full copy
+	// +optional
+	CBTIteration int `json:"cbtIteration,omitempty"`
+
+	// ETASeconds is the estimated number of seconds left in the current copy pass
+	// +optional
+	ETASeconds int64 `json:"etaSeconds,omitempty"`
+
+	// FullCopyBytesSkipped is the number of bytes the full copy did not read from the source, because they
+	// are unallocated or zero
+	// +optional
+	FullCopyBytesSkipped int64 `json:"fullCopyBytesSkipped,omitempty"`
+
+	// WarmSyncs are the latest incremental syncs of a warm migration, oldest first
+	// +optional
+	WarmSyncs []WarmSync `json:"warmSyncs,omitempty"`
+
+	// DeltaHistory is the rolling history of the changed blocks copies of each disk, oldest first
+	// +optional
+	DeltaHistory []DiskDelta `json:"deltaHistory,omitempty"`
+
+	// EstimatedDowntimeSeconds is the expected downtime of a cutover started now: the final copy of the changed
+	// blocks, the conversion of the disks and the boot of the migrated VM. It is estimated from DeltaHistory
+	// +optional
+	EstimatedDowntimeSeconds int64 `json:"estimatedDowntimeSeconds,omitempty"`
+
+	// ForecastDowntimeSeconds is the expected duration of the final sync of a warm migration, which runs with
+	// the source VM powered off. It is estimated from the latest syncs
+	// +optional
+	ForecastDowntimeSeconds int64 `json:"forecastDowntimeSeconds,omitempty"`
+
+	// Verification is the result of the data verification, once it has run
+	// +optional
+	Verification *DataVerificationResult `json:"verification,omitempty"`
+
+	// WindowsFirstBoot is the result of the first boot script of a Windows guest, once the migrated VM reported it
+	// +optional
+	WindowsFirstBoot *WindowsFirstBootResult `json:"windowsFirstBoot,omitempty"`
+
+	// OSSupport is the result of the check of the guest OS against the supported OS matrix
+	// +optional
+	OSSupport *OSSupportResult `json:"osSupport,omitempty"`
+
+	// LastUpdateTime is the time v2v-helper last updated the record
+	// +optional
+	LastUpdateTime metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+// WarmSync is an incremental sync of the changed blocks of a warm migration
+type WarmSync struct {
+	// Iteration is the changed blocks copy iteration of the sync
+	Iteration int `json:"iteration"`
+
+	// StartTime is when the sync started
+	StartTime metav1.Time `json:"startTime"`
+
+	// DeltaBytes is the number of changed bytes copied by the sync
+	DeltaBytes int64 `json:"deltaBytes"`
+
+	// DurationMilliseconds is how long the sync took
+	DurationMilliseconds int64 `json:"durationMilliseconds"`
+}
+
+// DiskVerification is the data verification result of a single disk of the VM
+type DiskVerification struct {
+	// Index is the position of the disk in the VM
+	Index int `json:"index"`
+
+	// Name is the name of the source VMware disk
+	Name string `json:"name,omitempty"`
+
+	// BytesVerified is the number of bytes whose checksums were compared
+	BytesVerified int64 `json:"bytesVerified"`
+
+	// MismatchedBytes is the number of bytes whose checksums differed and that were copied again
+	MismatchedBytes int64 `json:"mismatchedBytes,omitempty"`
+
+	// Verified is true if the volume matches the source, after the mismatched data was copied again
+	Verified bool `json:"verified"`
+}
+
+// DataVerificationResult is the result of the comparison of the copied volumes with the source snapshot
+type DataVerificationResult struct {
+	// Mode is the verification mode that was used
+	Mode DataVerificationMode `json:"mode"`
+
+	// CompletionTime is the time the verification completed
+	// +optional
+	CompletionTime metav1.Time `json:"completionTime,omitempty"`
+
+	// Disks are the results of each disk
+	// +optional
+	Disks []DiskVerification `json:"disks,omitempty"`
+}
+
+// WindowsAdapterStatus is the outcome of the configuration of a NIC by the Windows first boot script
+type WindowsAdapterStatus string
+
+const (
+	// WindowsAdapterStatic indicates the static addresses of the VMware NIC were applied
+	WindowsAdapterStatic WindowsAdapterStatus = "Static"
+	// WindowsAdapterDHCP indicates the NIC was set to DHCP
+	WindowsAdapterDHCP WindowsAdapterStatus = "DHCP"
+	// WindowsAdapterNotFound indicates no network adapter of the guest has the MAC address
+	WindowsAdapterNotFound WindowsAdapterStatus = "NotFound"
+	// WindowsAdapterFailed indicates the configuration of the NIC failed
+	WindowsAdapterFailed WindowsAdapterStatus = "Failed"
+)
+
+// WindowsAdapterResult is the result of the configuration of a single NIC of a Windows guest
+type WindowsAdapterResult struct {
+	// MAC is the MAC address of the NIC
+	MAC string `json:"mac"`
+
+	// Status is the outcome of the configuration
+	Status WindowsAdapterStatus `json:"status"`
+
+	// Message is the error when the status is Failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// WindowsFirstBootResult is the result of the script injected into a Windows guest, which re-applies the
+// network settings of the VMware NICs to the virtio NICs on the first boot of the migrated VM
+type WindowsFirstBootResult struct {
+	// Reported is false if the migrated VM did not report the result before the timeout
+	Reported bool `json:"reported"`
+
+	// CompletionTime is the time the result was received
+	// +optional
+	CompletionTime metav1.Time `json:"completionTime,omitempty"`
+
+	// Adapters are the results of each NIC
+	// +optional
+	Adapters []WindowsAdapterResult `json:"adapters,omitempty"`
+
+	// RemovedGhostAdapters are the VMware network adapters left behind by the migration that were removed
+	// +optional
+	RemovedGhostAdapters []string `json:"removedGhostAdapters,omitempty"`
+
+	// VMwareToolsRemoved is true if VMware Tools were uninstalled
+	// +optional
+	VMwareToolsRemoved bool `json:"vmwareToolsRemoved,omitempty"`
+
+	// Errors are the steps of the script that failed
+	// +optional
+	Errors []string `json:"errors,omitempty"`
+}
+
+// OSSupportResult is the result of the check of the guest OS against the supported OS matrix
+type OSSupportResult struct {
+	// OSName is the name of the guest OS as detected in the guest
+	OSName string `json:"osName"`
+
+	// Version is the version of the guest OS, empty if it could not be detected
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Distro is the operating system of the matrix entry matching the guest
+	// +optional
+	Distro string `json:"distro,omitempty"`
+
+	// Supported is true if the guest OS and its version are in the matrix
+	Supported bool `json:"supported"`
+
+	// Forced is true if the guest OS is not supported and the migration went ahead because
+	// AllowUnsupportedOS is set on the plan
+	// +optional
+	Forced bool `json:"forced,omitempty"`
+
+	// Message explains why the guest OS is not supported
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Caveats are the known issues of the migration of the guest OS
+	// +optional
+	Caveats []string `json:"caveats,omitempty"`
+
+	// MatrixVersion is the version of the matrix the guest OS was checked against
+	// +optional
+	MatrixVersion int `json:"matrixVersion,omitempty"`
+}
+
 // MigrationStatus defines the observed state of Migration
 type MigrationStatus struct {
 	// Phase is the current phase of the migration
@@ -88,12 +335,36 @@ type MigrationStatus struct {
 
 	// AgentName is the name of the agent where migration is running
 	AgentName string `json:"agentName,omitempty"`
+
+	// QueuePosition is the position of the migration in the queue of its plan while no agent has room for it,
+	// 0 once it has been placed on an agent or when no agent can run it
+	// +optional
+	QueuePosition int `json:"queuePosition,omitempty"`
+
+	// Progress is the latest progress reported by the migration pod
+	// +optional
+	Progress *MigrationProgress `json:"progress,omitempty"`
+
+	// DataVerification is the result of the verification of the copied data, kept for audit
+	// +optional
+	DataVerification *DataVerificationResult `json:"dataVerification,omitempty"`
+
+	// WindowsFirstBoot is the result of the first boot script of a Windows guest
+	// +optional
+	WindowsFirstBoot *WindowsFirstBootResult `json:"windowsFirstBoot,omitempty"`
+
+	// OSSupport is the result of the check of the guest OS against the supported OS matrix
+	// +optional
+	OSSupport *OSSupportResult `json:"osSupport,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
 // +kubebuilder:printcolumn:name="Agent Name",type="string",JSONPath=".status.agentName"
+// +kubebuilder:printcolumn:name="Queue Position",type="integer",JSONPath=".status.queuePosition",priority=1
+// +kubebuilder:printcolumn:name="Forecast Downtime",type="integer",JSONPath=".status.progress.forecastDowntimeSeconds",priority=1
+// +kubebuilder:printcolumn:name="Estimated Downtime",type="integer",JSONPath=".status.progress.estimatedDowntimeSeconds",priority=1
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Migration is the Schema for the migrations API that represents a single virtual machine
@@ -24,7 +24,10 @@ import (
 // MigrationPlanStrategy defines the strategy for executing a migration plan including
 // scheduling options and migration type (hot or cold)
 type MigrationPlanStrategy struct {
-	// +kubebuilder:validation:Enum=hot;cold
+	// Type is hot to copy the live VM and power it off once its changes are copied, cold to power it off
+	// before the copy, or warm to keep syncing the changes of the live VM every WarmSyncInterval until
+	// the cutover start time or the admin cutover
+	// +kubebuilder:validation:Enum=hot;cold;warm
 	Type string `json:"type"`
 	// +kubebuilder:validation:Type=string
 	// +kubebuilder:validation:Format:=date-time
@@ -37,14 +40,63 @@ type MigrationPlanStrategy struct {
 	VMCutoverEnd metav1.Time `json:"vmCutoverEnd,omitempty"`
 	// +kubebuilder:default:=false
 	AdminInitiatedCutOver bool `json:"adminInitiatedCutOver,omitempty"`
+	// CutoverReadyThreshold sets the CutoverReady condition of the Migrations whose estimated downtime
+	// for a cutover started now is at most this long
+	// +optional
+	CutoverReadyThreshold *metav1.Duration `json:"cutoverReadyThreshold,omitempty"`
 	// +kubebuilder:default:=false
 	PerformHealthChecks bool `json:"performHealthChecks,omitempty"`
 	// +kubebuilder:default:="443"
 	HealthCheckPort string `json:"healthCheckPort,omitempty"`
 	// +kubebuilder:default:=false
 	DisconnectSourceNetwork bool `json:"disconnectSourceNetwork,omitempty"`
+	// DataVerification compares the copied volumes with the source snapshot after the final copy,
+	// before the disks are converted. Mismatched data is copied again.
+	// +kubebuilder:default:=None
+	DataVerification DataVerificationMode `json:"dataVerification,omitempty"`
+	// RollbackPolicy restores the source VM when the health checks of the migrated VM fail.
+	// It only applies when PerformHealthChecks is set. Post-migration actions only run once the migration
+	// succeeded, so they are never applied to a rolled back VM.
+	// +kubebuilder:default:=None
+	RollbackPolicy RollbackPolicy `json:"rollbackPolicy,omitempty"`
+	// WarmSyncInterval is the interval between the incremental syncs of a warm migration. Defaults to 30 minutes
+	// +optional
+	WarmSyncInterval *metav1.Duration `json:"warmSyncInterval,omitempty"`
+	// MaintenanceWindow is the name of a MaintenanceWindow in the namespace of the plan. Cutovers only start
+	// inside its windows, when the estimated downtime fits in what is left of the window. A copy still running
+	// when a window closes keeps syncing until the next window instead of cutting over late
+	// +optional
+	MaintenanceWindow string `json:"maintenanceWindow,omitempty"`
 }
 
+// RollbackPolicy selects what is done to the migrated VM when its health checks fail.
+// With ShutOffTarget or DeleteTarget the ports created for the migrated VM are deleted, the network
+// interfaces of the source VM are reconnected, the source VM is powered on and the Migration ends RolledBack.
+// +kubebuilder:validation:Enum=None;ShutOffTarget;DeleteTarget
+type RollbackPolicy string
+
+const (
+	// RollbackPolicyNone keeps the migrated VM running and only reports the failed health checks
+	RollbackPolicyNone RollbackPolicy = "None"
+	// RollbackPolicyShutOffTarget shuts off the migrated VM and keeps its volumes for investigation
+	RollbackPolicyShutOffTarget RollbackPolicy = "ShutOffTarget"
+	// RollbackPolicyDeleteTarget deletes the migrated VM and its volumes
+	RollbackPolicyDeleteTarget RollbackPolicy = "DeleteTarget"
+)
+
+// DataVerificationMode selects how much of the copied data is verified
+// +kubebuilder:validation:Enum=None;Sampled;Full
+type DataVerificationMode string
+
+const (
+	// DataVerificationNone skips the verification
+	DataVerificationNone DataVerificationMode = "None"
+	// DataVerificationSampled verifies an evenly spread sample of the allocated areas of each disk
+	DataVerificationSampled DataVerificationMode = "Sampled"
+	// DataVerificationFull verifies all allocated areas of each disk
+	DataVerificationFull DataVerificationMode = "Full"
+)
+
 // AdvancedOptions defines advanced configuration options for the migration process
 // including granular selection of volumes, networks, and ports
 type AdvancedOptions struct {
@@ -69,10 +121,91 @@ type PostMigrationAction struct {
 type MigrationPlanSpec struct {
 	// MigrationPlanSpecPerVM is the migration plan specification per virtual machine
 	MigrationPlanSpecPerVM `json:",inline"`
-	// VirtualMachines is a list of virtual machines to be migrated
-	VirtualMachines [][]string `json:"virtualMachines"`
+	// VirtualMachines is a list of virtual machines to be migrated. It is ignored when Waves are set
+	// +optional
+	VirtualMachines [][]string `json:"virtualMachines,omitempty"`
 	SecurityGroups  []string   `json:"securityGroups,omitempty"`
 	FallbackToDHCP  bool       `json:"fallbackToDHCP,omitempty"`
+	// DryRun runs the pre-flight checks for every VM in VirtualMachines against vCenter and OpenStack
+	// and reports the result in Status.PreflightReport, without creating Migrations, Jobs, ports or volumes.
+	// The plan starts migrating once DryRun is unset.
+	DryRun bool `json:"dryRun,omitempty"`
+	// InspectOnly inspects the disks of every VM in VirtualMachines from a snapshot and records the result in
+	// the status of its VMwareMachine, without creating anything in OpenStack or changing the source VM.
+	// The Migrations of the plan end in the Inspected phase
+	InspectOnly bool `json:"inspectOnly,omitempty"`
+	// BandwidthLimit limits the bandwidth each VM of the plan uses to copy its disks from VMware.
+	// It can be changed while the plan is running.
+	BandwidthLimit *BandwidthLimit `json:"bandwidthLimit,omitempty"`
+	// VMPriorities are the priorities of the VMs of the plan by name. VMs with a higher priority are placed on
+	// the agents first when there is not room for all of them, VMs not listed have priority 0. With waves, the
+	// priorities order the VMs of the waves running at the same time
+	VMPriorities map[string]int `json:"vmPriorities,omitempty"`
+	// Waves are named groups of VMs migrated in parallel, each once the waves it depends on pass its gate.
+	// Waves without dependencies start together
+	// +optional
+	Waves []MigrationWave `json:"waves,omitempty"`
+	// ApprovedWaves are the names of the waves requiring approval that may start
+	// +optional
+	ApprovedWaves []string `json:"approvedWaves,omitempty"`
+}
+
+// WaveGate is what the waves a wave depends on have to reach before the wave starts
+// +kubebuilder:validation:Enum=Succeeded;Healthy
+type WaveGate string
+
+const (
+	// WaveGateSucceeded waits for all VMs of the dependencies to be migrated
+	WaveGateSucceeded WaveGate = "Succeeded"
+	// WaveGateHealthy also waits for all of them to pass their health checks. It needs PerformHealthChecks
+	WaveGateHealthy WaveGate = "Healthy"
+)
+
+// MigrationWave is a named group of VMs migrated in parallel
+type MigrationWave struct {
+	// Name identifies the wave in DependsOn and ApprovedWaves
+	Name string `json:"name"`
+	// VirtualMachines are the VMs of the wave. A VM belongs to a single wave
+	// +kubebuilder:validation:MinItems=1
+	VirtualMachines []string `json:"virtualMachines"`
+	// DependsOn are the names of the waves that have to pass Gate before the wave starts
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// Gate is what the waves in DependsOn have to reach
+	// +kubebuilder:default:=Succeeded
+	// +optional
+	Gate WaveGate `json:"gate,omitempty"`
+	// RequireApproval holds the wave, once its dependencies passed its gate, until its name is in ApprovedWaves
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+}
+
+// BandwidthLimit is a copy bandwidth limit in MB/s (1 MB = 1024*1024 bytes), with optional time-of-day windows
+// in which a different limit applies. A limit of 0 means unlimited.
+type BandwidthLimit struct {
+	// MBps is the limit outside of all windows
+	// +kubebuilder:validation:Minimum=0
+	MBps int `json:"mbps,omitempty"`
+	// TimeZone is the IANA time zone the windows are in, such as Europe/Paris. Defaults to UTC
+	// +kubebuilder:default:=UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+	// Windows are the times of day with a different limit. The first window containing the current time applies.
+	Windows []BandwidthWindow `json:"windows,omitempty"`
+}
+
+// BandwidthWindow is a time of day range, in the time zone of its BandwidthLimit, with its own bandwidth limit.
+// A window whose end is before its start spans midnight.
+type BandwidthWindow struct {
+	// Start is the time the window starts at, as HH:MM
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+	// End is the time the window ends at, as HH:MM
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+	// MBps is the limit during the window, 0 means unlimited
+	// +kubebuilder:validation:Minimum=0
+	MBps int `json:"mbps"`
 }
 
 // MigrationPlanSpecPerVM defines the configuration that applies to each VM in the migration plan
@@ -88,6 +221,11 @@ type MigrationPlanSpecPerVM struct {
 	// +kubebuilder:default:="echo \"Add your startup script here!\""
 	FirstBootScript     string               `json:"firstBootScript,omitempty"`
 	PostMigrationAction *PostMigrationAction `json:"postMigrationAction,omitempty"`
+	// RemoveVMwareTools uninstalls VMware Tools from Windows guests on the first boot after the migration
+	RemoveVMwareTools bool `json:"removeVMwareTools,omitempty"`
+	// AllowUnsupportedOS migrates the guests whose OS is not in the supported OS matrix instead of failing them.
+	// The migration of such a guest is recorded with a warning in the status of its Migration
+	AllowUnsupportedOS bool `json:"allowUnsupportedOS,omitempty"`
 }
 
 // MigrationPlanStatus defines the observed state of MigrationPlan including
@@ -100,6 +238,100 @@ type MigrationPlanStatus struct {
 	MigrationMessage string `json:"migrationMessage"`
 	// Migration RetryCount is the number of times the migration has been retried
 	RetryCount int `json:"retryCount,omitempty"`
+	// PreflightReport is the result of the last dry run of the plan
+	PreflightReport *PreflightReport `json:"preflightReport,omitempty"`
+	// Waves are the states of the waves of the plan, in the order of Spec.Waves
+	// +optional
+	Waves []MigrationWaveStatus `json:"waves,omitempty"`
+}
+
+// WavePhase is the state of a wave
+// +kubebuilder:validation:Enum=Pending;AwaitingApproval;Running;Succeeded;Failed;Blocked
+type WavePhase string
+
+const (
+	// WavePhasePending means the wave waits for its dependencies to pass its gate
+	WavePhasePending WavePhase = "Pending"
+	// WavePhaseAwaitingApproval means the dependencies passed the gate and the wave waits to be in ApprovedWaves
+	WavePhaseAwaitingApproval WavePhase = "AwaitingApproval"
+	// WavePhaseRunning means the VMs of the wave are being migrated
+	WavePhaseRunning WavePhase = "Running"
+	// WavePhaseSucceeded means all VMs of the wave have been migrated
+	WavePhaseSucceeded WavePhase = "Succeeded"
+	// WavePhaseFailed means the migration of a VM of the wave failed or was rolled back
+	WavePhaseFailed WavePhase = "Failed"
+	// WavePhaseBlocked means a dependency failed or can no longer pass the gate, so the wave cannot start
+	WavePhaseBlocked WavePhase = "Blocked"
+)
+
+// MigrationWaveStatus is the state of a wave of a MigrationPlan
+type MigrationWaveStatus struct {
+	// Name is the name of the wave
+	Name string `json:"name"`
+	// Phase is the state of the wave
+	Phase WavePhase `json:"phase"`
+	// Message explains the phase
+	// +optional
+	Message string `json:"message,omitempty"`
+	// VMs is the number of VMs of the wave
+	VMs int `json:"vms"`
+	// SucceededVMs is the number of VMs of the wave migrated
+	SucceededVMs int `json:"succeededVMs"`
+	// HealthyVMs is the number of migrated VMs of the wave that passed their health checks
+	HealthyVMs int `json:"healthyVMs"`
+	// FailedVMs is the number of VMs of the wave whose migration failed or was rolled back
+	FailedVMs int `json:"failedVMs"`
+	// StartTime is the time the wave started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time all VMs of the wave were migrated
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+}
+
+// PreflightResult is the outcome of a pre-flight check
+// +kubebuilder:validation:Enum=Pass;Warn;Fail
+type PreflightResult string
+
+const (
+	// PreflightResultPass means the check found nothing that would stop the migration
+	PreflightResultPass PreflightResult = "Pass"
+	// PreflightResultWarn means the migration can go ahead but may not behave as configured
+	PreflightResultWarn PreflightResult = "Warn"
+	// PreflightResultFail means the migration would fail
+	PreflightResultFail PreflightResult = "Fail"
+)
+
+// PreflightCheck is the result of a single pre-flight check of a VM
+type PreflightCheck struct {
+	// Name is the name of the check
+	Name string `json:"name"`
+	// Result is the outcome of the check
+	Result PreflightResult `json:"result"`
+	// Message explains the result
+	Message string `json:"message,omitempty"`
+}
+
+// VMPreflightReport holds the pre-flight checks of a single VM
+type VMPreflightReport struct {
+	// VMName is the name of the VM in vCenter
+	VMName string `json:"vmName"`
+	// Result is the worst result of all checks of the VM
+	Result PreflightResult `json:"result"`
+	// Checks are the individual checks run for the VM
+	Checks []PreflightCheck `json:"checks,omitempty"`
+}
+
+// PreflightReport is the result of a dry run of a MigrationPlan
+type PreflightReport struct {
+	// ObservedGeneration is the generation of the MigrationPlan the report was created for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// CompletionTime is the time the dry run completed
+	CompletionTime metav1.Time `json:"completionTime,omitempty"`
+	// Result is the worst result of all VMs
+	Result PreflightResult `json:"result"`
+	// VMs are the per-VM reports, in the order of VirtualMachines
+	VMs []VMPreflightReport `json:"vms,omitempty"`
 }
 
 // +kubebuilder:object:root=true
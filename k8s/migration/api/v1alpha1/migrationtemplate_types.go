@@ -52,8 +52,41 @@ type MigrationTemplateSpec struct {
 	// UseFlavorless indicates if the migration should use flavorless VM creation for PCD.
 	// +optional
 	UseFlavorless bool `json:"useFlavorless,omitempty"`
+	// TargetBackend is how the disks of the VMs reach their Cinder volumes
+	// +kubebuilder:default=CinderAttach
+	// +optional
+	TargetBackend DiskTargetBackend `json:"targetBackend,omitempty"`
+	// CephRBD writes the copied data straight to the Ceph RBD images of the Cinder volumes, instead of
+	// through the volumes attached to the agent. Only used with the CinderAttach target backend
+	// +optional
+	CephRBD *CephRBDSpec `json:"cephRBD,omitempty"`
+}
+
+// CephRBDSpec defines how the agent connects to the Ceph cluster backing the Cinder volumes. The monitors,
+// pool and user come from the connection info of the volumes
+type CephRBDSpec struct {
+	// KeyringSecret is the name of the secret in the namespace of the migration plans whose keyring key holds
+	// the Ceph keyring of the Cinder user
+	KeyringSecret string `json:"keyringSecret"`
 }
 
+// DiskTargetBackend is how the disks of a VM are copied and converted before they become its Cinder volumes
+// +kubebuilder:validation:Enum=CinderAttach;GlanceImage;CinderImageUpload
+type DiskTargetBackend string
+
+const (
+	// DiskTargetBackendCinderAttach creates the volumes first and attaches them to the agent, which copies
+	// and converts the disks on them
+	DiskTargetBackendCinderAttach DiskTargetBackend = "CinderAttach"
+	// DiskTargetBackendGlanceImage copies and converts the disks to staging files on the agent, uploads them
+	// as Glance images and creates the volumes from the images, which are kept. The agent needs free disk
+	// space for the full size of the disks, and an interrupted migration starts over instead of resuming
+	DiskTargetBackendGlanceImage DiskTargetBackend = "GlanceImage"
+	// DiskTargetBackendCinderImageUpload is like DiskTargetBackendGlanceImage, uploading through Glance as
+	// well, but deletes the images once the volumes are created from them
+	DiskTargetBackendCinderImageUpload DiskTargetBackend = "CinderImageUpload"
+)
+
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 
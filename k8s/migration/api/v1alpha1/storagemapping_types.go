@@ -25,6 +25,10 @@ import (
 type StorageMappingSpec struct {
 	// Storages is a list of storage mappings between source (VMware) and target (OpenStack) environments
 	Storages []Storage `json:"storages"`
+	// ZeroedVolumeTypes are the target volume types whose new volumes read back as zeroes. The full copy
+	// skips the unallocated areas of the disks on these volume types, and zero fills them on the others
+	// +optional
+	ZeroedVolumeTypes []string `json:"zeroedVolumeTypes,omitempty"`
 }
 
 // Storage represents a mapping between source and target storage types
@@ -40,6 +40,28 @@ type VjailbreakNodeSpec struct {
 
 	// OpenstackImageID is the image of the VM
 	OpenstackImageID string `json:"openstackImageID"`
+
+	// MaxConcurrentMigrations is the number of migrations the node runs at the same time,
+	// 0 uses the default of 5
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxConcurrentMigrations int `json:"maxConcurrentMigrations,omitempty"`
+
+	// MaxVolumeAttachments is the number of Cinder volumes that can be attached to the node at the same time,
+	// 0 uses the default of 25
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxVolumeAttachments int `json:"maxVolumeAttachments,omitempty"`
+
+	// BandwidthMBps is the network bandwidth of the node in MB/s shared by the copies of its migrations,
+	// 0 means unlimited
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	BandwidthMBps int `json:"bandwidthMBps,omitempty"`
+
+	// Draining stops new migrations from being placed on the node, those running on it carry on
+	// +optional
+	Draining bool `json:"draining,omitempty"`
 }
 
 // VjailbreakNodeStatus defines the observed state of VjailbreakNode including
@@ -58,6 +80,10 @@ type VjailbreakNodeStatus struct {
 	// ActiveMigrations is the list of active migrations currently being processed on this node,
 	// containing references to MigrationPlan resources
 	ActiveMigrations []string `json:"activeMigrations,omitempty"`
+
+	// IdleSince is the time the last active migration of the node ended, unset while migrations are active
+	// +optional
+	IdleSince *metav1.Time `json:"idleSince,omitempty"`
 }
 
 // +kubebuilder:object:root=true
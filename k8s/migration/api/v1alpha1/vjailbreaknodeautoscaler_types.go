@@ -0,0 +1,107 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VjailbreakNodeAutoscalerSpec defines the policy used to add and remove worker VjailbreakNodes
+// as migrations queue up and finish
+type VjailbreakNodeAutoscalerSpec struct {
+	// MinNodes is the number of worker nodes the autoscaler always keeps
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinNodes int `json:"minNodes,omitempty"`
+
+	// MaxNodes is the largest number of worker nodes the autoscaler creates
+	// +kubebuilder:validation:Minimum=1
+	MaxNodes int `json:"maxNodes"`
+
+	// OpenstackCreds is the name of the OpenstackCreds the nodes are created with
+	OpenstackCreds string `json:"openstackCreds"`
+
+	// OpenstackFlavorID is the flavor of the VMs of the nodes. They boot the image of the master node
+	OpenstackFlavorID string `json:"openstackFlavorID"`
+
+	// ScaleUpThreshold is the number of queued migrations at which nodes are added
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default:=1
+	// +optional
+	ScaleUpThreshold int `json:"scaleUpThreshold,omitempty"`
+
+	// ScaleDownDelay is how long a node has to be idle, with no migrations queued, before it is drained and removed.
+	// Defaults to 15 minutes
+	// +optional
+	ScaleDownDelay *metav1.Duration `json:"scaleDownDelay,omitempty"`
+}
+
+// VjailbreakNodeAutoscalerStatus defines the observed state of VjailbreakNodeAutoscaler
+type VjailbreakNodeAutoscalerStatus struct {
+	// Nodes is the number of worker nodes created by the autoscaler, including those being provisioned or drained
+	Nodes int `json:"nodes"`
+
+	// QueuedMigrations is the number of migrations waiting for room on the agents
+	QueuedMigrations int `json:"queuedMigrations"`
+
+	// DrainingNodes are the nodes being drained before they are removed
+	// +optional
+	DrainingNodes []string `json:"drainingNodes,omitempty"`
+
+	// LastScaleTime is the last time the autoscaler added or removed a node
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+
+	// Message describes the last scaling decision
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Min",type="integer",JSONPath=".spec.minNodes"
+// +kubebuilder:printcolumn:name="Max",type="integer",JSONPath=".spec.maxNodes"
+// +kubebuilder:printcolumn:name="Nodes",type="integer",JSONPath=".status.nodes"
+// +kubebuilder:printcolumn:name="Queued",type="integer",JSONPath=".status.queuedMigrations"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// VjailbreakNodeAutoscaler is the Schema for the vjailbreaknodeautoscalers API. It adds worker VjailbreakNodes
+// while migrations are queued for lack of room on the agents, and drains and removes the nodes it added once
+// they have been idle for a while
+type VjailbreakNodeAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Spec defines the scaling policy
+	Spec VjailbreakNodeAutoscalerSpec `json:"spec,omitempty"`
+
+	// Status defines the observed state of VjailbreakNodeAutoscaler
+	Status VjailbreakNodeAutoscalerStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// VjailbreakNodeAutoscalerList contains a list of VjailbreakNodeAutoscaler
+type VjailbreakNodeAutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VjailbreakNodeAutoscaler `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VjailbreakNodeAutoscaler{}, &VjailbreakNodeAutoscalerList{})
+}
@@ -30,6 +30,8 @@ type VMInfo struct {
 	Datastores []string `json:"datastores,omitempty"`
 	// Disks is the list of disks for the virtual machine
 	Disks []string `json:"disks,omitempty"`
+	// DiskSizes is the capacity in bytes of each disk in Disks, in the same order
+	DiskSizes []int64 `json:"diskSizes,omitempty"`
 	// Networks is the list of networks for the virtual machine
 	Networks []string `json:"networks,omitempty"`
 	// IPAddress is the IP address of the virtual machine
@@ -38,6 +40,8 @@ type VMInfo struct {
 	VMState string `json:"vmState,omitempty"`
 	// OSFamily is the OS family of the virtual machine
 	OSFamily string `json:"osFamily,omitempty"`
+	// OSName is the full name of the guest OS as reported by VMware Tools, or as configured on the VM
+	OSName string `json:"osName,omitempty"`
 	// CPU is the number of CPUs in the virtual machine
 	CPU int `json:"cpu,omitempty"`
 	// Memory is the amount of memory in the virtual machine
@@ -70,6 +74,7 @@ type GuestNetwork struct {
 	IP           string   `json:"ip,omitempty"`
 	Origin       string   `json:"origin,omitempty"`       // DHCP or static
 	PrefixLength int32    `json:"prefixLength,omitempty"` // Subnet mask length
+	Gateway      string   `json:"gateway,omitempty"`      // Default gateway of the interface
 	DNS          []string `json:"dns,omitempty"`          // DNS servers
 	Device       string   `json:"device,omitempty"`       // e.g. eth0
 }
@@ -92,6 +97,86 @@ type VMwareMachineStatus struct {
 	// +kubebuilder:default=false
 	// +kubebuilder:validation:Required
 	Migrated bool `json:"migrated,omitempty"`
+
+	// GuestInspection is the result of the last inspection of the guest disks, made during the
+	// conversion of the VM or by an inspect-only MigrationPlan
+	GuestInspection *GuestInspection `json:"guestInspection,omitempty"`
+}
+
+// GuestFirmware is the firmware the guest boots with
+// +kubebuilder:validation:Enum=BIOS;UEFI
+type GuestFirmware string
+
+const (
+	// GuestFirmwareBIOS means the guest boots with a legacy BIOS
+	GuestFirmwareBIOS GuestFirmware = "BIOS"
+	// GuestFirmwareUEFI means the guest boots with UEFI
+	GuestFirmwareUEFI GuestFirmware = "UEFI"
+)
+
+// GuestTools is the VMware guest agent installed in the guest
+// +kubebuilder:validation:Enum=None;OpenVMTools;VMwareTools
+type GuestTools string
+
+const (
+	// GuestToolsNone means no VMware guest agent was found
+	GuestToolsNone GuestTools = "None"
+	// GuestToolsOpenVMTools means the open-vm-tools package of the distribution is installed
+	GuestToolsOpenVMTools GuestTools = "OpenVMTools"
+	// GuestToolsVMwareTools means VMware Tools is installed
+	GuestToolsVMwareTools GuestTools = "VMwareTools"
+)
+
+// GuestLVM is the LVM layout of the guest
+type GuestLVM struct {
+	// PhysicalVolumes are the LVM physical volumes
+	PhysicalVolumes []string `json:"physicalVolumes,omitempty"`
+	// VolumeGroups are the LVM volume groups
+	VolumeGroups []string `json:"volumeGroups,omitempty"`
+	// LogicalVolumes are the LVM logical volumes
+	LogicalVolumes []string `json:"logicalVolumes,omitempty"`
+}
+
+// GuestFilesystem is a filesystem mounted in the guest
+type GuestFilesystem struct {
+	// Device is the device of the filesystem, as seen by the inspection
+	Device string `json:"device"`
+	// MountPoint is the mount point of the filesystem in the guest
+	MountPoint string `json:"mountPoint"`
+	// SizeBytes is the size of the filesystem
+	SizeBytes int64 `json:"sizeBytes"`
+	// UsedBytes is the space used on the filesystem
+	UsedBytes int64 `json:"usedBytes"`
+}
+
+// GuestInspection is what the inspection of the guest disks found
+type GuestInspection struct {
+	// InspectionTime is the time the guest was inspected
+	InspectionTime metav1.Time `json:"inspectionTime,omitempty"`
+	// OSType is the type of the operating system, such as linux or windows
+	OSType string `json:"osType,omitempty"`
+	// Distro is the distribution of the operating system, such as rhel or ubuntu
+	Distro string `json:"distro,omitempty"`
+	// OSName is the product name of the operating system
+	OSName string `json:"osName,omitempty"`
+	// OSVersion is the major and minor version of the operating system
+	OSVersion string `json:"osVersion,omitempty"`
+	// Kernels are the versions of the kernels installed in a Linux guest
+	Kernels []string `json:"kernels,omitempty"`
+	// Firmware is the firmware the guest boots with
+	Firmware GuestFirmware `json:"firmware,omitempty"`
+	// Bootloader is the bootloader found in the guest, such as GRUB2 or Windows Boot Manager
+	Bootloader string `json:"bootloader,omitempty"`
+	// LVM is the LVM layout of the guest, nil if the guest does not use LVM
+	LVM *GuestLVM `json:"lvm,omitempty"`
+	// Filesystems are the filesystems mounted in the guest and their usage
+	Filesystems []GuestFilesystem `json:"filesystems,omitempty"`
+	// NetworkConfigFiles are the network configuration files found in the guest
+	NetworkConfigFiles []string `json:"networkConfigFiles,omitempty"`
+	// VMwareTools is the VMware guest agent installed in the guest
+	VMwareTools GuestTools `json:"vmwareTools,omitempty"`
+	// VirtioDrivers are the virtio drivers already present in the guest
+	VirtioDrivers []string `json:"virtioDrivers,omitempty"`
 }
 
 // +kubebuilder:object:root=true
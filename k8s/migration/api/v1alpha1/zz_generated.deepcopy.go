@@ -148,6 +148,41 @@ func (in *BMConfigStatus) DeepCopy() *BMConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BandwidthLimit) DeepCopyInto(out *BandwidthLimit) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]BandwidthWindow, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BandwidthLimit.
+func (in *BandwidthLimit) DeepCopy() *BandwidthLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(BandwidthLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BandwidthWindow) DeepCopyInto(out *BandwidthWindow) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BandwidthWindow.
+func (in *BandwidthWindow) DeepCopy() *BandwidthWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(BandwidthWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BootSource) DeepCopyInto(out *BootSource) {
 	*out = *in
@@ -163,6 +198,21 @@ func (in *BootSource) DeepCopy() *BootSource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CephRBDSpec) DeepCopyInto(out *CephRBDSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CephRBDSpec.
+func (in *CephRBDSpec) DeepCopy() *CephRBDSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CephRBDSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterMapping) DeepCopyInto(out *ClusterMapping) {
 	*out = *in
@@ -295,6 +345,72 @@ func (in *ClusterMigrationStatus) DeepCopy() *ClusterMigrationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataVerificationResult) DeepCopyInto(out *DataVerificationResult) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]DiskVerification, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataVerificationResult.
+func (in *DataVerificationResult) DeepCopy() *DataVerificationResult {
+	if in == nil {
+		return nil
+	}
+	out := new(DataVerificationResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskDelta) DeepCopyInto(out *DiskDelta) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskDelta.
+func (in *DiskDelta) DeepCopy() *DiskDelta {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskDelta)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskProgress) DeepCopyInto(out *DiskProgress) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskProgress.
+func (in *DiskProgress) DeepCopy() *DiskProgress {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskProgress)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiskVerification) DeepCopyInto(out *DiskVerification) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiskVerification.
+func (in *DiskVerification) DeepCopy() *DiskVerification {
+	if in == nil {
+		return nil
+	}
+	out := new(DiskVerification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ESXIMigration) DeepCopyInto(out *ESXIMigration) {
 	*out = *in
@@ -392,6 +508,92 @@ func (in *ESXIMigrationStatus) DeepCopy() *ESXIMigrationStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuestFilesystem) DeepCopyInto(out *GuestFilesystem) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuestFilesystem.
+func (in *GuestFilesystem) DeepCopy() *GuestFilesystem {
+	if in == nil {
+		return nil
+	}
+	out := new(GuestFilesystem)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuestInspection) DeepCopyInto(out *GuestInspection) {
+	*out = *in
+	in.InspectionTime.DeepCopyInto(&out.InspectionTime)
+	if in.Kernels != nil {
+		in, out := &in.Kernels, &out.Kernels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LVM != nil {
+		in, out := &in.LVM, &out.LVM
+		*out = new(GuestLVM)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Filesystems != nil {
+		in, out := &in.Filesystems, &out.Filesystems
+		*out = make([]GuestFilesystem, len(*in))
+		copy(*out, *in)
+	}
+	if in.NetworkConfigFiles != nil {
+		in, out := &in.NetworkConfigFiles, &out.NetworkConfigFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VirtioDrivers != nil {
+		in, out := &in.VirtioDrivers, &out.VirtioDrivers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuestInspection.
+func (in *GuestInspection) DeepCopy() *GuestInspection {
+	if in == nil {
+		return nil
+	}
+	out := new(GuestInspection)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GuestLVM) DeepCopyInto(out *GuestLVM) {
+	*out = *in
+	if in.PhysicalVolumes != nil {
+		in, out := &in.PhysicalVolumes, &out.PhysicalVolumes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VolumeGroups != nil {
+		in, out := &in.VolumeGroups, &out.VolumeGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LogicalVolumes != nil {
+		in, out := &in.LogicalVolumes, &out.LogicalVolumes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GuestLVM.
+func (in *GuestLVM) DeepCopy() *GuestLVM {
+	if in == nil {
+		return nil
+	}
+	out := new(GuestLVM)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GuestNetwork) DeepCopyInto(out *GuestNetwork) {
 	*out = *in
@@ -434,6 +636,144 @@ func (in *HostConfig) DeepCopy() *HostConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindow) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowBlackout) DeepCopyInto(out *MaintenanceWindowBlackout) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowBlackout.
+func (in *MaintenanceWindowBlackout) DeepCopy() *MaintenanceWindowBlackout {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowBlackout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowList) DeepCopyInto(out *MaintenanceWindowList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowList.
+func (in *MaintenanceWindowList) DeepCopy() *MaintenanceWindowList {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *MaintenanceWindowList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowSchedule) DeepCopyInto(out *MaintenanceWindowSchedule) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowSchedule.
+func (in *MaintenanceWindowSchedule) DeepCopy() *MaintenanceWindowSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowSpec) DeepCopyInto(out *MaintenanceWindowSpec) {
+	*out = *in
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]MaintenanceWindowSchedule, len(*in))
+		copy(*out, *in)
+	}
+	if in.Blackouts != nil {
+		in, out := &in.Blackouts, &out.Blackouts
+		*out = make([]MaintenanceWindowBlackout, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowSpec.
+func (in *MaintenanceWindowSpec) DeepCopy() *MaintenanceWindowSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowStatus) DeepCopyInto(out *MaintenanceWindowStatus) {
+	*out = *in
+	if in.NextWindowStart != nil {
+		in, out := &in.NextWindowStart, &out.NextWindowStart
+		*out = (*in).DeepCopy()
+	}
+	if in.NextWindowEnd != nil {
+		in, out := &in.NextWindowEnd, &out.NextWindowEnd
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowStatus.
+func (in *MaintenanceWindowStatus) DeepCopy() *MaintenanceWindowStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Migration) DeepCopyInto(out *Migration) {
 	*out = *in
@@ -499,7 +839,7 @@ func (in *MigrationPlan) DeepCopyInto(out *MigrationPlan) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlan.
@@ -572,6 +912,30 @@ func (in *MigrationPlanSpec) DeepCopyInto(out *MigrationPlanSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.BandwidthLimit != nil {
+		in, out := &in.BandwidthLimit, &out.BandwidthLimit
+		*out = new(BandwidthLimit)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VMPriorities != nil {
+		in, out := &in.VMPriorities, &out.VMPriorities
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Waves != nil {
+		in, out := &in.Waves, &out.Waves
+		*out = make([]MigrationWave, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ApprovedWaves != nil {
+		in, out := &in.ApprovedWaves, &out.ApprovedWaves
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanSpec.
@@ -609,32 +973,102 @@ func (in *MigrationPlanSpecPerVM) DeepCopy() *MigrationPlanSpecPerVM {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MigrationPlanStatus) DeepCopyInto(out *MigrationPlanStatus) {
 	*out = *in
+	if in.PreflightReport != nil {
+		in, out := &in.PreflightReport, &out.PreflightReport
+		*out = new(PreflightReport)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Waves != nil {
+		in, out := &in.Waves, &out.Waves
+		*out = make([]MigrationWaveStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanStatus.
+func (in *MigrationPlanStatus) DeepCopy() *MigrationPlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationPlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationPlanStrategy) DeepCopyInto(out *MigrationPlanStrategy) {
+	*out = *in
+	in.DataCopyStart.DeepCopyInto(&out.DataCopyStart)
+	in.VMCutoverStart.DeepCopyInto(&out.VMCutoverStart)
+	in.VMCutoverEnd.DeepCopyInto(&out.VMCutoverEnd)
+	if in.CutoverReadyThreshold != nil {
+		in, out := &in.CutoverReadyThreshold, &out.CutoverReadyThreshold
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.WarmSyncInterval != nil {
+		in, out := &in.WarmSyncInterval, &out.WarmSyncInterval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanStatus.
-func (in *MigrationPlanStatus) DeepCopy() *MigrationPlanStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanStrategy.
+func (in *MigrationPlanStrategy) DeepCopy() *MigrationPlanStrategy {
 	if in == nil {
 		return nil
 	}
-	out := new(MigrationPlanStatus)
+	out := new(MigrationPlanStrategy)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *MigrationPlanStrategy) DeepCopyInto(out *MigrationPlanStrategy) {
+func (in *MigrationProgress) DeepCopyInto(out *MigrationProgress) {
 	*out = *in
-	in.DataCopyStart.DeepCopyInto(&out.DataCopyStart)
-	in.VMCutoverStart.DeepCopyInto(&out.VMCutoverStart)
-	in.VMCutoverEnd.DeepCopyInto(&out.VMCutoverEnd)
+	if in.Disks != nil {
+		in, out := &in.Disks, &out.Disks
+		*out = make([]DiskProgress, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(DataVerificationResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WindowsFirstBoot != nil {
+		in, out := &in.WindowsFirstBoot, &out.WindowsFirstBoot
+		*out = new(WindowsFirstBootResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OSSupport != nil {
+		in, out := &in.OSSupport, &out.OSSupport
+		*out = new(OSSupportResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeltaHistory != nil {
+		in, out := &in.DeltaHistory, &out.DeltaHistory
+		*out = make([]DiskDelta, len(*in))
+		copy(*out, *in)
+	}
+	if in.WarmSyncs != nil {
+		in, out := &in.WarmSyncs, &out.WarmSyncs
+		*out = make([]WarmSync, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationPlanStrategy.
-func (in *MigrationPlanStrategy) DeepCopy() *MigrationPlanStrategy {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationProgress.
+func (in *MigrationProgress) DeepCopy() *MigrationProgress {
 	if in == nil {
 		return nil
 	}
-	out := new(MigrationPlanStrategy)
+	out := new(MigrationProgress)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -664,6 +1098,26 @@ func (in *MigrationStatus) DeepCopyInto(out *MigrationStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Progress != nil {
+		in, out := &in.Progress, &out.Progress
+		*out = new(MigrationProgress)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DataVerification != nil {
+		in, out := &in.DataVerification, &out.DataVerification
+		*out = new(DataVerificationResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WindowsFirstBoot != nil {
+		in, out := &in.WindowsFirstBoot, &out.WindowsFirstBoot
+		*out = new(WindowsFirstBootResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.OSSupport != nil {
+		in, out := &in.OSSupport, &out.OSSupport
+		*out = new(OSSupportResult)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationStatus.
@@ -681,7 +1135,7 @@ func (in *MigrationTemplate) DeepCopyInto(out *MigrationTemplate) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplate.
@@ -769,6 +1223,11 @@ func (in *MigrationTemplateSpec) DeepCopyInto(out *MigrationTemplateSpec) {
 	*out = *in
 	out.Source = in.Source
 	out.Destination = in.Destination
+	if in.CephRBD != nil {
+		in, out := &in.CephRBD, &out.CephRBD
+		*out = new(CephRBDSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationTemplateSpec.
@@ -781,6 +1240,54 @@ func (in *MigrationTemplateSpec) DeepCopy() *MigrationTemplateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationWave) DeepCopyInto(out *MigrationWave) {
+	*out = *in
+	if in.VirtualMachines != nil {
+		in, out := &in.VirtualMachines, &out.VirtualMachines
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationWave.
+func (in *MigrationWave) DeepCopy() *MigrationWave {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationWave)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigrationWaveStatus) DeepCopyInto(out *MigrationWaveStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigrationWaveStatus.
+func (in *MigrationWaveStatus) DeepCopy() *MigrationWaveStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MigrationWaveStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NIC) DeepCopyInto(out *NIC) {
 	*out = *in
@@ -905,6 +1412,26 @@ func (in *NetworkMappingStatus) DeepCopy() *NetworkMappingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OSSupportResult) DeepCopyInto(out *OSSupportResult) {
+	*out = *in
+	if in.Caveats != nil {
+		in, out := &in.Caveats, &out.Caveats
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OSSupportResult.
+func (in *OSSupportResult) DeepCopy() *OSSupportResult {
+	if in == nil {
+		return nil
+	}
+	out := new(OSSupportResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OpenStackCredsInfo) DeepCopyInto(out *OpenStackCredsInfo) {
 	*out = *in
@@ -1342,6 +1869,44 @@ func (in *PostMigrationAction) DeepCopy() *PostMigrationAction {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightCheck) DeepCopyInto(out *PreflightCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightCheck.
+func (in *PreflightCheck) DeepCopy() *PreflightCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreflightReport) DeepCopyInto(out *PreflightReport) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	if in.VMs != nil {
+		in, out := &in.VMs, &out.VMs
+		*out = make([]VMPreflightReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreflightReport.
+func (in *PreflightReport) DeepCopy() *PreflightReport {
+	if in == nil {
+		return nil
+	}
+	out := new(PreflightReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RDMDisk) DeepCopyInto(out *RDMDisk) {
 	*out = *in
@@ -1684,6 +2249,11 @@ func (in *StorageMappingSpec) DeepCopyInto(out *StorageMappingSpec) {
 		*out = make([]Storage, len(*in))
 		copy(*out, *in)
 	}
+	if in.ZeroedVolumeTypes != nil {
+		in, out := &in.ZeroedVolumeTypes, &out.ZeroedVolumeTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StorageMappingSpec.
@@ -1724,6 +2294,11 @@ func (in *VMInfo) DeepCopyInto(out *VMInfo) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.DiskSizes != nil {
+		in, out := &in.DiskSizes, &out.DiskSizes
+		*out = make([]int64, len(*in))
+		copy(*out, *in)
+	}
 	if in.Networks != nil {
 		in, out := &in.Networks, &out.Networks
 		*out = make([]string, len(*in))
@@ -1758,6 +2333,26 @@ func (in *VMInfo) DeepCopy() *VMInfo {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMPreflightReport) DeepCopyInto(out *VMPreflightReport) {
+	*out = *in
+	if in.Checks != nil {
+		in, out := &in.Checks, &out.Checks
+		*out = make([]PreflightCheck, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMPreflightReport.
+func (in *VMPreflightReport) DeepCopy() *VMPreflightReport {
+	if in == nil {
+		return nil
+	}
+	out := new(VMPreflightReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMSequenceInfo) DeepCopyInto(out *VMSequenceInfo) {
 	*out = *in
@@ -2067,7 +2662,7 @@ func (in *VMwareMachine) DeepCopyInto(out *VMwareMachine) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMwareMachine.
@@ -2139,6 +2734,11 @@ func (in *VMwareMachineSpec) DeepCopy() *VMwareMachineSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VMwareMachineStatus) DeepCopyInto(out *VMwareMachineStatus) {
 	*out = *in
+	if in.GuestInspection != nil {
+		in, out := &in.GuestInspection, &out.GuestInspection
+		*out = new(GuestInspection)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMwareMachineStatus.
@@ -2178,6 +2778,109 @@ func (in *VjailbreakNode) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VjailbreakNodeAutoscaler) DeepCopyInto(out *VjailbreakNodeAutoscaler) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeAutoscaler.
+func (in *VjailbreakNodeAutoscaler) DeepCopy() *VjailbreakNodeAutoscaler {
+	if in == nil {
+		return nil
+	}
+	out := new(VjailbreakNodeAutoscaler)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VjailbreakNodeAutoscaler) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VjailbreakNodeAutoscalerList) DeepCopyInto(out *VjailbreakNodeAutoscalerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]VjailbreakNodeAutoscaler, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeAutoscalerList.
+func (in *VjailbreakNodeAutoscalerList) DeepCopy() *VjailbreakNodeAutoscalerList {
+	if in == nil {
+		return nil
+	}
+	out := new(VjailbreakNodeAutoscalerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *VjailbreakNodeAutoscalerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VjailbreakNodeAutoscalerSpec) DeepCopyInto(out *VjailbreakNodeAutoscalerSpec) {
+	*out = *in
+	if in.ScaleDownDelay != nil {
+		in, out := &in.ScaleDownDelay, &out.ScaleDownDelay
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeAutoscalerSpec.
+func (in *VjailbreakNodeAutoscalerSpec) DeepCopy() *VjailbreakNodeAutoscalerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(VjailbreakNodeAutoscalerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VjailbreakNodeAutoscalerStatus) DeepCopyInto(out *VjailbreakNodeAutoscalerStatus) {
+	*out = *in
+	if in.DrainingNodes != nil {
+		in, out := &in.DrainingNodes, &out.DrainingNodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeAutoscalerStatus.
+func (in *VjailbreakNodeAutoscalerStatus) DeepCopy() *VjailbreakNodeAutoscalerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VjailbreakNodeAutoscalerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VjailbreakNodeList) DeepCopyInto(out *VjailbreakNodeList) {
 	*out = *in
@@ -2234,6 +2937,10 @@ func (in *VjailbreakNodeStatus) DeepCopyInto(out *VjailbreakNodeStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.IdleSince != nil {
+		in, out := &in.IdleSince, &out.IdleSince
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VjailbreakNodeStatus.
@@ -2245,3 +2952,65 @@ func (in *VjailbreakNodeStatus) DeepCopy() *VjailbreakNodeStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WarmSync) DeepCopyInto(out *WarmSync) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WarmSync.
+func (in *WarmSync) DeepCopy() *WarmSync {
+	if in == nil {
+		return nil
+	}
+	out := new(WarmSync)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WindowsAdapterResult) DeepCopyInto(out *WindowsAdapterResult) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WindowsAdapterResult.
+func (in *WindowsAdapterResult) DeepCopy() *WindowsAdapterResult {
+	if in == nil {
+		return nil
+	}
+	out := new(WindowsAdapterResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WindowsFirstBootResult) DeepCopyInto(out *WindowsFirstBootResult) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	if in.Adapters != nil {
+		in, out := &in.Adapters, &out.Adapters
+		*out = make([]WindowsAdapterResult, len(*in))
+		copy(*out, *in)
+	}
+	if in.RemovedGhostAdapters != nil {
+		in, out := &in.RemovedGhostAdapters, &out.RemovedGhostAdapters
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WindowsFirstBootResult.
+func (in *WindowsFirstBootResult) DeepCopy() *WindowsFirstBootResult {
+	if in == nil {
+		return nil
+	}
+	out := new(WindowsFirstBootResult)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -22,7 +22,10 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
+	// Embed the time zone database, the maintenance windows are in IANA time zones
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -37,10 +40,14 @@ import (
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	vjbmetrics "github.com/platform9/vjailbreak/k8s/migration/pkg/metrics"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/osmatrix"
 	utils "github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
 
 	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	"github.com/platform9/vjailbreak/k8s/migration/internal/controller"
+	webhookv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/internal/webhook/v1alpha1"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -124,11 +131,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	vjbmetrics.Register(mgr.GetClient())
+
 	if err = SetupControllers(mgr, local, maxConcurrentReconciles); err != nil {
 		setupLog.Error(err, "unable to set up controllers")
 		os.Exit(1)
 	}
 
+	if webhooksEnabled() {
+		if err = SetupWebhooks(mgr); err != nil {
+			setupLog.Error(err, "unable to set up webhooks")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controller.ESXIMigrationReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -197,6 +213,10 @@ func main() {
 		handleStartupError(err, "Problem creating master node entry")
 	}
 
+	if err = osmatrix.EnsureConfigMap(ctx, mgr.GetClient(), constants.NamespaceMigrationSystem); err != nil {
+		handleStartupError(err, "Problem creating supported OS matrix")
+	}
+
 	// Block forever
 	select {}
 }
@@ -289,6 +309,20 @@ func SetupControllers(mgr ctrl.Manager, local bool, maxConcurrentReconciles int)
 		setupLog.Error(err, "unable to create controller", "controller", "VjailbreakNode")
 		return err
 	}
+	if err := (&controller.VjailbreakNodeAutoscalerReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "VjailbreakNodeAutoscaler")
+		return err
+	}
+	if err := (&controller.MaintenanceWindowReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MaintenanceWindow")
+		return err
+	}
 	if err := (&controller.RollingMigrationPlanReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
@@ -300,3 +334,43 @@ func SetupControllers(mgr ctrl.Manager, local bool, maxConcurrentReconciles int)
 
 	return nil
 }
+
+// webhooksEnabled returns whether the admission webhooks are served, unless turned off with ENABLE_WEBHOOKS=false.
+// They need the serving certificate issued by cert-manager, the manager exits when it is not mounted
+func webhooksEnabled() bool {
+	if os.Getenv("ENABLE_WEBHOOKS") == "false" {
+		setupLog.Info("admission webhooks disabled by ENABLE_WEBHOOKS")
+		return false
+	}
+	certFile := filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs", "tls.crt")
+	if _, err := os.Stat(certFile); err != nil {
+		setupLog.Error(err, "admission webhooks enabled but the serving certificate is missing, set ENABLE_WEBHOOKS=false to run without them",
+			"path", certFile)
+		os.Exit(1)
+	}
+	return true
+}
+
+// SetupWebhooks registers the defaulting and validating admission webhooks with the manager
+func SetupWebhooks(mgr ctrl.Manager) error {
+	webhooks := []struct {
+		kind  string
+		setup func(ctrl.Manager) error
+	}{
+		{"MigrationPlan", webhookv1alpha1.SetupMigrationPlanWebhookWithManager},
+		{"MigrationTemplate", webhookv1alpha1.SetupMigrationTemplateWebhookWithManager},
+		{"NetworkMapping", webhookv1alpha1.SetupNetworkMappingWebhookWithManager},
+		{"StorageMapping", webhookv1alpha1.SetupStorageMappingWebhookWithManager},
+		{"RollingMigrationPlan", webhookv1alpha1.SetupRollingMigrationPlanWebhookWithManager},
+		{"BMConfig", webhookv1alpha1.SetupBMConfigWebhookWithManager},
+		{"OpenstackCreds", webhookv1alpha1.SetupOpenstackCredsWebhookWithManager},
+		{"VMwareCreds", webhookv1alpha1.SetupVMwareCredsWebhookWithManager},
+	}
+	for _, w := range webhooks {
+		if err := w.setup(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", w.kind)
+			return err
+		}
+	}
+	return nil
+}
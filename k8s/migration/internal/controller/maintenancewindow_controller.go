@@ -0,0 +1,102 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/maintenancewindow"
+)
+
+// MaintenanceWindowReconciler reconciles a MaintenanceWindow object
+type MaintenanceWindowReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=maintenancewindows,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=maintenancewindows/status,verbs=get;update;patch
+
+// Reconcile validates the windows and blackout dates of a MaintenanceWindow and reports the open or next window
+// in its status, until the next time a window opens or closes
+func (r *MaintenanceWindowReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctxlog := log.FromContext(ctx).WithName(constants.MaintenanceWindowControllerName)
+
+	window := &vjailbreakv1alpha1.MaintenanceWindow{}
+	if err := r.Get(ctx, req.NamespacedName, window); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "failed to get maintenance window")
+	}
+
+	oldStatus := window.Status.DeepCopy()
+	window.Status = vjailbreakv1alpha1.MaintenanceWindowStatus{}
+	result := ctrl.Result{}
+	now := time.Now()
+	calendar, err := maintenancewindow.Parse(&window.Spec)
+	if err != nil {
+		// The windows are only evaluated again once the spec is fixed
+		ctxlog.Info("Invalid maintenance window", "maintenancewindow", window.Name, "error", err.Error())
+		window.Status.Message = err.Error()
+	} else {
+		interval, open := calendar.Current(now)
+		found := open
+		if !open {
+			interval, found = calendar.Next(now, 0)
+		}
+		if !found {
+			window.Status.Message = "No window opens within a year"
+			result.RequeueAfter = constants.MaintenanceWindowRecheckInterval
+		} else {
+			window.Status.Open = open
+			window.Status.NextWindowStart = &metav1.Time{Time: interval.Start}
+			window.Status.NextWindowEnd = &metav1.Time{Time: interval.End}
+			transition := interval.Start
+			if open {
+				transition = interval.End
+			}
+			result.RequeueAfter = min(max(transition.Sub(now), time.Second), constants.MaintenanceWindowRecheckInterval)
+		}
+	}
+
+	if !reflect.DeepEqual(oldStatus, &window.Status) {
+		if err := r.Status().Update(ctx, window); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update maintenance window status")
+		}
+	}
+	return result, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MaintenanceWindowReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vjailbreakv1alpha1.MaintenanceWindow{}).
+		Complete(r)
+}
@@ -19,17 +19,17 @@ package controller
 import (
 	"context"
 	"fmt"
-	"slices"
 	"sort"
 	"strings"
 	"time"
 
-	openstackconst "github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -42,6 +42,7 @@ import (
 	"github.com/pkg/errors"
 	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	constants "github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	vjbmetrics "github.com/platform9/vjailbreak/k8s/migration/pkg/metrics"
 	"github.com/platform9/vjailbreak/k8s/migration/pkg/scope"
 	utils "github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
 )
@@ -80,12 +81,16 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	if err := r.Get(ctx, req.NamespacedName, migration); err != nil {
 		if apierrors.IsNotFound(err) {
 			// Object deleted successfully
+			migration.Name, migration.Namespace = req.Name, req.Namespace
+			vjbmetrics.ForgetObject(vjbmetrics.KindMigration, migration)
 			return ctrl.Result{}, nil
 		}
 		ctxlog.Error(err, fmt.Sprintf("Unexpected error reading Migration '%s' object", migration.Name))
 		return ctrl.Result{}, err
 	}
 
+	vjbmetrics.RecordPhase(vjbmetrics.KindMigration, migration, string(migration.Status.Phase))
+
 	migrationScope, err := scope.NewMigrationScope(scope.MigrationScopeParams{
 		Logger:    ctxlog,
 		Client:    r.Client,
@@ -168,8 +173,30 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	migration.Status.Conditions = utils.CreateDataCopyCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateMigratingCondition(migration, filteredEvents)
 	migration.Status.Conditions = utils.CreateFailedCondition(migration, filteredEvents)
+	migration.Status.Conditions = utils.CreateHealthCheckCondition(migration, filteredEvents)
 
 	migration.Status.AgentName = pod.Spec.NodeName
+	progress, err := utils.GetMigrationProgress(pod)
+	if err != nil {
+		ctxlog.Error(err, fmt.Sprintf("Failed to read progress of Pod '%s'", pod.Name))
+	} else if progress != nil {
+		migration.Status.Progress = progress
+		if progress.Verification != nil {
+			migration.Status.DataVerification = progress.Verification
+		}
+		if progress.WindowsFirstBoot != nil {
+			migration.Status.WindowsFirstBoot = progress.WindowsFirstBoot
+		}
+		if progress.OSSupport != nil {
+			migration.Status.OSSupport = progress.OSSupport
+		}
+	}
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migration.Spec.MigrationPlan, Namespace: migration.Namespace}, migrationplan); err != nil {
+		ctxlog.Error(err, fmt.Sprintf("Failed to get MigrationPlan '%s'", migration.Spec.MigrationPlan))
+	} else {
+		migration.Status.Conditions = utils.CreateCutoverReadyCondition(migration, migrationplan.Spec.MigrationStrategy.CutoverReadyThreshold)
+	}
 	err = r.SetupMigrationPhase(ctx, migrationScope)
 	if err != nil {
 		return ctrl.Result{}, errors.Wrap(err, "error setting migration phase")
@@ -180,7 +207,9 @@ func (r *MigrationReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	if string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseFailed) &&
-		string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseSucceeded) {
+		string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseSucceeded) &&
+		string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseRolledBack) &&
+		string(migration.Status.Phase) != string(vjailbreakv1alpha1.VMMigrationPhaseInspected) {
 		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 	}
 
@@ -252,6 +281,10 @@ func (r *MigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 							return true
 						}
 					}
+					// Disk progress alone is picked up by the periodic requeue, only phase changes trigger a reconcile
+					if reportedPhase(oldpod) != reportedPhase(newpod) {
+						return true
+					}
 					return oldpod.Status.Phase != newpod.Status.Phase
 				},
 			},
@@ -260,82 +293,58 @@ func (r *MigrationReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
-// SetupMigrationPhase sets up the migration phase based on current state
-//
-//nolint:gocyclo
-func (r *MigrationReconciler) SetupMigrationPhase(ctx context.Context, scope *scope.MigrationScope) error {
-	events, err := r.GetEventsSorted(ctx, scope)
-	if err != nil {
-		return err
+// reportedPhase returns the phase in the progress record of the pod, or an empty phase if it has none
+func reportedPhase(pod *corev1.Pod) vjailbreakv1alpha1.VMMigrationPhase {
+	progress, err := utils.GetMigrationProgress(pod)
+	if err != nil || progress == nil {
+		return ""
 	}
+	return progress.Phase
+}
 
+// SetupMigrationPhase sets up the migration phase from the progress reported by the migration pod. The migration
+// fails when its Job failed without the pod reporting a final phase
+func (r *MigrationReconciler) SetupMigrationPhase(ctx context.Context, scope *scope.MigrationScope) error {
 	// Get the pod to check startCutover label
 	pod, err := r.GetPod(ctx, scope)
 	if err != nil {
 		return err
 	}
 
-	IgnoredPhases := []vjailbreakv1alpha1.VMMigrationPhase{
-		vjailbreakv1alpha1.VMMigrationPhaseValidating,
-		vjailbreakv1alpha1.VMMigrationPhasePending}
-
-loop:
-	for i := range events.Items {
-		switch {
-		// In reverse order, because the events are sorted by timestamp latest to oldest
-		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageMigrationSucessful) &&
-			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseSucceeded]:
-			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseSucceeded
-			if err := r.markMigrationSuccessful(ctx, scope); err != nil {
-				return err
+	var reported vjailbreakv1alpha1.VMMigrationPhase
+	if scope.Migration.Status.Progress != nil {
+		reported = scope.Migration.Status.Progress.Phase
+	}
+	if !isFinalMigrationPhase(scope.Migration.Status.Phase) && !isFinalMigrationPhase(reported) {
+		if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "Job" {
+			job := &batchv1.Job{}
+			if err := r.Get(ctx, types.NamespacedName{Name: owner.Name, Namespace: pod.Namespace}, job); err != nil {
+				return errors.Wrapf(err, "failed to get job %s", owner.Name)
 			}
-			break loop
-		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageWaitingForAdminCutOver) &&
-			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver]:
-			// Only stay in AwaitingAdminCutOver if cutover hasn't been triggered yet
-			if pod.Labels["startCutover"] != "yes" {
-				scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver
-				break loop
+			if utils.MigrationJobFailed(job) {
+				reported = vjailbreakv1alpha1.VMMigrationPhaseFailed
 			}
-			// Admin cutover was triggered, reset to a lower phase so it can progress normally
-			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseCopying
-
-			// If startCutover is "yes", don't set phase here - let it progress to next phase
-			// by continuing to check other events
-			continue
-		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageWaitingForCutOverStart) &&
-			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime]:
-			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime
-			break loop
-		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageConvertingDisk) &&
-			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk]:
-			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk
-			break loop
-		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageCopyingChangedBlocksWithIteration) &&
-			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks]:
-			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks
-			break loop
-		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageCopyingDisk) &&
-			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseCopying]:
-			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseCopying
-			break loop
-		case strings.Contains(events.Items[i].Message, openstackconst.EventMessageWaitingForDataCopyStart) &&
-			constants.VMMigrationStatesEnum[scope.Migration.Status.Phase] <= constants.VMMigrationStatesEnum[vjailbreakv1alpha1.VMMigrationPhaseAwaitingDataCopyStart]:
-			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseAwaitingDataCopyStart
-			break loop
-		case strings.Contains(strings.TrimSpace(events.Items[i].Message), openstackconst.EventMessageMigrationFailed) ||
-			strings.Contains(strings.TrimSpace(events.Items[i].Message), openstackconst.EventMessageFailed):
-			scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseFailed
-			break loop
-		case slices.Contains(IgnoredPhases, scope.Migration.Status.Phase):
-			break loop
-		default:
-			continue
 		}
 	}
+
+	cutoverStarted := pod.Labels["startCutover"] == constants.StartCutOverYes
+	scope.Migration.Status.Phase = utils.NextMigrationPhase(scope.Migration.Status.Phase, reported, cutoverStarted)
+	if scope.Migration.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseSucceeded {
+		return r.markMigrationSuccessful(ctx, scope)
+	}
 	return nil
 }
 
+// isFinalMigrationPhase returns whether a migration in the phase has finished
+func isFinalMigrationPhase(phase vjailbreakv1alpha1.VMMigrationPhase) bool {
+	switch phase {
+	case vjailbreakv1alpha1.VMMigrationPhaseFailed, vjailbreakv1alpha1.VMMigrationPhaseSucceeded,
+		vjailbreakv1alpha1.VMMigrationPhaseRolledBack, vjailbreakv1alpha1.VMMigrationPhaseInspected:
+		return true
+	}
+	return false
+}
+
 // Extracted function to handle successful migration updates
 func (r *MigrationReconciler) markMigrationSuccessful(ctx context.Context, scope *scope.MigrationScope) error {
 	scope.Migration.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseSucceeded
@@ -408,5 +417,5 @@ func (r *MigrationReconciler) GetPod(ctx context.Context, scope *scope.Migration
 	if len(podList.Items) == 0 {
 		return nil, apierrors.NewNotFound(corev1.Resource("pods"), fmt.Sprintf("migration pod not found for vm %s", migration.Spec.VMName))
 	}
-	return &podList.Items[0], nil
-}
\ No newline at end of file
+	return utils.CurrentMigrationPod(podList.Items), nil
+}
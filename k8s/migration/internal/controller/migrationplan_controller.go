@@ -22,18 +22,24 @@ import (
 	"os"
 	"os/user"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	"github.com/pkg/errors"
 	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/maintenancewindow"
+	vjbmetrics "github.com/platform9/vjailbreak/k8s/migration/pkg/metrics"
 	"github.com/platform9/vjailbreak/k8s/migration/pkg/scope"
 	utils "github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
 	"github.com/platform9/vjailbreak/k8s/migration/pkg/verrors"
+	openstackconst "github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
 	"github.com/platform9/vjailbreak/v2v-helper/vcenter"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -69,6 +75,12 @@ type MigrationPlanReconciler struct {
 
 var migrationPlanFinalizer = "migrationplan.vjailbreak.pf9.io/finalizer"
 
+// waitingForCapacityMessage ends the plan message while one of its VMs is held for capacity
+const waitingForCapacityMessage = "is waiting for capacity in the target project"
+
+// waitingForApprovalMessage starts the plan message while none of its waves runs and some wait for approval
+const waitingForApprovalMessage = "Waiting for approval of wave(s)"
+
 // The default image. This is replaced by Go linker flags in the Dockerfile
 var v2vimage = "platform9/v2v-helper:v0.1"
 
@@ -82,6 +94,7 @@ var v2vimage = "platform9/v2v-helper:v0.1"
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationplans/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationplans/finalizers,verbs=update
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationtemplates,verbs=get;list;watch
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=maintenancewindows,verbs=get;list;watch
 
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationtemplates,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrationtemplates/status,verbs=get;update;patch
@@ -94,11 +107,14 @@ func (r *MigrationPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	if err := r.Get(ctx, req.NamespacedName, migrationplan); err != nil {
 		if apierrors.IsNotFound(err) {
+			migrationplan.Name, migrationplan.Namespace = req.Name, req.Namespace
+			vjbmetrics.ForgetObject(vjbmetrics.KindMigrationPlan, migrationplan)
 			return ctrl.Result{}, nil
 		}
 		r.ctxlog.Error(err, fmt.Sprintf("failed to read MigrationPlan '%s'", migrationplan.Name))
 		return ctrl.Result{}, errors.Wrapf(err, "failed to read MigrationPlan '%s'", migrationplan.Name)
 	}
+	vjbmetrics.RecordPhase(vjbmetrics.KindMigrationPlan, migrationplan, string(migrationplan.Status.MigrationStatus))
 
 	err := utils.ValidateMigrationPlan(migrationplan)
 	if err != nil {
@@ -136,6 +152,10 @@ func (r *MigrationPlanReconciler) reconcileNormal(ctx context.Context, scope *sc
 
 	controllerutil.AddFinalizer(migrationplan, migrationPlanFinalizer)
 
+	if migrationplan.Spec.DryRun {
+		return r.reconcileDryRun(ctx, migrationplan)
+	}
+
 	res, err := r.ReconcileMigrationPlanJob(ctx, migrationplan, scope)
 	if err != nil {
 		return res, errors.Wrap(err, "failed to reconcile migration plan job")
@@ -143,6 +163,42 @@ func (r *MigrationPlanReconciler) reconcileNormal(ctx context.Context, scope *sc
 	return res, nil
 }
 
+// reconcileDryRun runs the pre-flight checks of the plan once per generation and reports them in the status.
+// No Migrations, Jobs, ports or volumes are created while the plan is in dry-run mode.
+func (r *MigrationPlanReconciler) reconcileDryRun(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan) (ctrl.Result, error) {
+	if utils.PreflightReportUpToDate(migrationplan) {
+		return ctrl.Result{}, nil
+	}
+	r.ctxlog.Info("Running pre-flight checks", "migrationplan", migrationplan.Name)
+
+	migrationtemplate, vmwcreds, _, err := r.getMigrationTemplateAndCreds(ctx, migrationplan)
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to get migration template and credentials")
+	}
+	openstackcreds := &vjailbreakv1alpha1.OpenstackCreds{}
+	if ok, err := r.checkStatusSuccess(ctx, migrationtemplate.Namespace, migrationtemplate.Spec.Destination.OpenstackRef,
+		false, openstackcreds); !ok {
+		return ctrl.Result{}, errors.Wrapf(err, "failed to check openstackcreds status '%s'", migrationtemplate.Spec.Destination.OpenstackRef)
+	}
+
+	report, err := utils.RunMigrationPlanPreflight(ctx, r.Client, utils.PreflightParams{
+		MigrationPlan:     migrationplan,
+		MigrationTemplate: migrationtemplate,
+		VMwareCreds:       vmwcreds,
+		OpenstackCreds:    openstackcreds,
+		VDDKDirectory:     VDDKDirectory,
+	})
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to run pre-flight checks")
+	}
+	migrationplan.Status.PreflightReport = report
+	migrationplan.Status.MigrationMessage = utils.PreflightSummary(report)
+	if err := r.Status().Update(ctx, migrationplan); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+	}
+	return ctrl.Result{}, nil
+}
+
 //nolint:unparam //future use
 func (r *MigrationPlanReconciler) reconcileDelete(
 	ctx context.Context,
@@ -153,6 +209,11 @@ func (r *MigrationPlanReconciler) reconcileDelete(
 	// The object is being deleted
 	ctxlog.Info(fmt.Sprintf("MigrationPlan '%s' CR is being deleted", migrationplan.Name))
 
+	// The volumes kept to resume failed migrations can no longer be resumed once the plan is gone
+	if err := r.deleteCheckpointedVolumes(ctx, migrationplan); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to delete checkpointed volumes")
+	}
+
 	// Now that the finalizer has completed deletion tasks, we can remove it
 	// to allow deletion of the Migration object
 	controllerutil.RemoveFinalizer(migrationplan, migrationPlanFinalizer)
@@ -163,6 +224,41 @@ func (r *MigrationPlanReconciler) reconcileDelete(
 	return ctrl.Result{}, nil
 }
 
+// deleteCheckpointedVolumes deletes the volumes v2v-helper kept after failed migrations of the plan to resume them
+func (r *MigrationPlanReconciler) deleteCheckpointedVolumes(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan) error {
+	ctxlog := log.FromContext(ctx).WithName(constants.MigrationControllerName)
+	volumeIDs, err := utils.GetCheckpointedVolumes(ctx, r.Client, migrationplan)
+	if err != nil || len(volumeIDs) == 0 {
+		return err
+	}
+
+	migrationtemplate := &vjailbreakv1alpha1.MigrationTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migrationplan.Spec.MigrationTemplate, Namespace: migrationplan.Namespace},
+		migrationtemplate); err != nil {
+		// Without the template there is no OpenStack to delete the volumes from, they are left for the admin
+		ctxlog.Error(err, "Failed to get MigrationTemplate, not deleting checkpointed volumes", "volumes", volumeIDs)
+		return nil
+	}
+	openstackcreds := &vjailbreakv1alpha1.OpenstackCreds{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migrationtemplate.Spec.Destination.OpenstackRef, Namespace: migrationplan.Namespace},
+		openstackcreds); err != nil {
+		ctxlog.Error(err, "Failed to get OpenstackCreds, not deleting checkpointed volumes", "volumes", volumeIDs)
+		return nil
+	}
+	openstackClients, err := utils.GetOpenStackClients(ctx, r.Client, openstackcreds)
+	if err != nil {
+		return errors.Wrap(err, "failed to get openstack clients")
+	}
+	for _, volumeID := range volumeIDs {
+		err := volumes.Delete(openstackClients.BlockStorageClient, volumeID, volumes.DeleteOpts{}).ExtractErr()
+		if _, notFound := err.(gophercloud.ErrDefault404); err != nil && !notFound {
+			return errors.Wrapf(err, "failed to delete volume '%s'", volumeID)
+		}
+		ctxlog.Info(fmt.Sprintf("Deleted volume '%s' kept to resume a migration", volumeID))
+	}
+	return nil
+}
+
 func (r *MigrationPlanReconciler) getMigrationTemplateAndCreds(
 	ctx context.Context,
 	migrationplan *vjailbreakv1alpha1.MigrationPlan,
@@ -409,6 +505,16 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 		false, openstackcreds); !ok {
 		return ctrl.Result{}, errors.Wrapf(err, "failed to check openstackcreds status '%s'", migrationtemplate.Spec.Destination.OpenstackRef)
 	}
+	// The cutovers wait for the windows of the MaintenanceWindow, it has to be valid before the copies start
+	if name := migrationplan.Spec.MigrationStrategy.MaintenanceWindow; name != "" {
+		window := &vjailbreakv1alpha1.MaintenanceWindow{}
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: migrationplan.Namespace}, window); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to get MaintenanceWindow '%s'", name)
+		}
+		if _, err := maintenancewindow.Parse(&window.Spec); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "invalid MaintenanceWindow '%s'", name)
+		}
+	}
 
 	// Starting the Migrations
 	if migrationplan.Status.MigrationStatus == "" {
@@ -421,15 +527,13 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 	// vmMachinesArr is created to maintain order in which VM migration is triggered
 	vmMachinesArr := make([]*vjailbreakv1alpha1.VMwareMachine, 0)
 	vmMachinesMap := make(map[string]*vjailbreakv1alpha1.VMwareMachine, 0)
-	for _, parallelvms := range migrationplan.Spec.VirtualMachines {
-		for _, vm := range parallelvms {
-			vmMachine, err := GetVMwareMachineForVM(ctx, r, vm, migrationtemplate, vmwcreds)
-			if err != nil {
-				return ctrl.Result{}, errors.Wrapf(err, "failed to get VMwareMachine for VM %s", vm)
-			}
-			vmMachinesArr = append(vmMachinesArr, vmMachine)
-			vmMachinesMap[vm] = vmMachine
+	for _, vm := range utils.GetMigrationPlanVMs(migrationplan) {
+		vmMachine, err := GetVMwareMachineForVM(ctx, r, vm, migrationtemplate, vmwcreds)
+		if err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to get VMwareMachine for VM %s", vm)
 		}
+		vmMachinesArr = append(vmMachinesArr, vmMachine)
+		vmMachinesMap[vm] = vmMachine
 	}
 	// Migrate RDM disks if any
 	err := r.migrateRDMdisks(ctx, migrationplan, vmMachinesMap, openstackcreds)
@@ -446,56 +550,32 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 		return ctrl.Result{}, nil
 	}
 
-	for _, parallelvms := range migrationplan.Spec.VirtualMachines {
-		migrationobjs := &vjailbreakv1alpha1.MigrationList{}
-		err := r.TriggerMigration(ctx, migrationplan, migrationobjs, openstackcreds, vmwcreds, migrationtemplate, vmMachinesArr)
-		if err != nil {
-			if strings.Contains(err.Error(), "VDDK_MISSING") {
-				r.ctxlog.Info("Requeuing due to missing VDDK files.")
-				return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
-			}
-			return ctrl.Result{}, errors.Wrapf(err, "failed to trigger migration")
-		}
-		for i := 0; i < len(migrationobjs.Items); i++ {
-			switch migrationobjs.Items[i].Status.Phase {
-			case vjailbreakv1alpha1.VMMigrationPhaseFailed:
-				r.ctxlog.Info(fmt.Sprintf("Migration for VM '%s' failed", migrationobjs.Items[i].Spec.VMName))
-				if migrationplan.Spec.Retry {
-					r.ctxlog.Info(fmt.Sprintf("Retrying migration for VM '%s'", migrationobjs.Items[i].Spec.VMName))
-					// Delete the migration so that it can be recreated
-					err := r.Delete(ctx, &migrationobjs.Items[i])
-					if err != nil {
-						return ctrl.Result{}, errors.Wrap(err, "failed to delete migration")
-					}
-					migrationplan.Status.MigrationStatus = "Retrying"
-					migrationplan.Status.MigrationMessage = fmt.Sprintf("Retrying migration for VM '%s'", migrationobjs.Items[i].Spec.VMName)
-					migrationplan.Spec.Retry = false
-					err = r.Update(ctx, migrationplan)
-					if err != nil {
-						return ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
-					}
-					return ctrl.Result{}, nil
-				}
-				err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodFailed,
-					fmt.Sprintf("Migration for VM '%s' failed", migrationobjs.Items[i].Spec.VMName))
-				if err != nil {
-					return ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
-				}
-				return ctrl.Result{}, nil
-			case vjailbreakv1alpha1.VMMigrationPhaseSucceeded:
-				err := r.reconcilePostMigration(ctx, scope, migrationobjs.Items[i].Spec.VMName)
-				if err != nil {
-					r.ctxlog.Error(err, fmt.Sprintf("Post-migration actions failed for VM '%s'", migrationobjs.Items[i].Spec.VMName))
-					return ctrl.Result{}, errors.Wrap(err, "failed to reconcile post migration")
+	if len(migrationplan.Spec.Waves) > 0 {
+		if done, res, err := r.reconcileMigrationWaves(ctx, migrationplan, scope, openstackcreds, vmwcreds, migrationtemplate,
+			vmMachinesMap); !done || err != nil {
+			return res, err
+		}
+	} else {
+		for _, parallelvms := range migrationplan.Spec.VirtualMachines {
+			migrationobjs := &vjailbreakv1alpha1.MigrationList{}
+			err := r.TriggerMigration(ctx, migrationplan, migrationobjs, openstackcreds, vmwcreds, migrationtemplate, vmMachinesArr)
+			if err != nil {
+				if strings.Contains(err.Error(), "VDDK_MISSING") {
+					r.ctxlog.Info("Requeuing due to missing VDDK files.")
+					return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
 				}
-				continue
-			default:
-				r.ctxlog.Info(fmt.Sprintf("Waiting for all VMs in parallel batch %d to complete: %v", i+1, parallelvms))
-				return ctrl.Result{}, nil
+				return ctrl.Result{}, errors.Wrapf(err, "failed to trigger migration")
+			}
+			if done, res, err := r.checkMigrations(ctx, migrationplan, scope, migrationobjs, parallelvms); !done || err != nil {
+				return res, err
 			}
 		}
 	}
-	r.ctxlog.Info(fmt.Sprintf("All VMs in MigrationPlan '%s' have been successfully migrated", migrationplan.Name))
+	if migrationplan.Spec.InspectOnly {
+		r.ctxlog.Info(fmt.Sprintf("All VMs in MigrationPlan '%s' have been inspected", migrationplan.Name))
+	} else {
+		r.ctxlog.Info(fmt.Sprintf("All VMs in MigrationPlan '%s' have been successfully migrated", migrationplan.Name))
+	}
 	migrationplan.Status.MigrationStatus = corev1.PodSucceeded
 	err = r.Status().Update(ctx, migrationplan)
 	if err != nil {
@@ -505,6 +585,202 @@ func (r *MigrationPlanReconciler) ReconcileMigrationPlanJob(ctx context.Context,
 	return ctrl.Result{}, nil
 }
 
+// checkMigrations handles the outcome of the Migrations of a batch of VMs. It returns true once all of them
+// succeeded, otherwise the result to return from the reconcile
+func (r *MigrationPlanReconciler) checkMigrations(ctx context.Context,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	scope *scope.MigrationPlanScope,
+	migrationobjs *vjailbreakv1alpha1.MigrationList,
+	parallelvms []string) (bool, ctrl.Result, error) {
+	// VMs held for capacity are checked again periodically, quota may be freed outside of vJailbreak
+	waitingForCapacity := false
+	// VMs queued for lack of room on the agents are scheduled again once running migrations may have finished,
+	// and those no agent can run once the agents may have changed
+	queued := false
+	for i := range migrationobjs.Items {
+		if migrationobjs.Items[i].Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseWaitingForCapacity {
+			waitingForCapacity = true
+		}
+		if migrationobjs.Items[i].Status.QueuePosition > 0 || isMigrationUnschedulable(&migrationobjs.Items[i]) {
+			queued = true
+		}
+	}
+	if !waitingForCapacity && strings.HasSuffix(migrationplan.Status.MigrationMessage, waitingForCapacityMessage) {
+		if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodRunning, "Migration(s) in progress"); err != nil {
+			return false, ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+		}
+	}
+	for i := 0; i < len(migrationobjs.Items); i++ {
+		switch migrationobjs.Items[i].Status.Phase {
+		case vjailbreakv1alpha1.VMMigrationPhaseFailed:
+			r.ctxlog.Info(fmt.Sprintf("Migration for VM '%s' failed", migrationobjs.Items[i].Spec.VMName))
+			if migrationplan.Spec.Retry {
+				r.ctxlog.Info(fmt.Sprintf("Retrying migration for VM '%s'", migrationobjs.Items[i].Spec.VMName))
+				// Delete the migration so that it can be recreated
+				err := r.Delete(ctx, &migrationobjs.Items[i])
+				if err != nil {
+					return false, ctrl.Result{}, errors.Wrap(err, "failed to delete migration")
+				}
+				migrationplan.Status.MigrationStatus = "Retrying"
+				migrationplan.Status.MigrationMessage = fmt.Sprintf("Retrying migration for VM '%s'", migrationobjs.Items[i].Spec.VMName)
+				migrationplan.Spec.Retry = false
+				err = r.Update(ctx, migrationplan)
+				if err != nil {
+					return false, ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+				}
+				return false, ctrl.Result{}, nil
+			}
+			err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodFailed,
+				fmt.Sprintf("Migration for VM '%s' failed", migrationobjs.Items[i].Spec.VMName))
+			if err != nil {
+				return false, ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+			}
+			return false, ctrl.Result{}, nil
+		case vjailbreakv1alpha1.VMMigrationPhaseRolledBack:
+			// The source VM is running again, the migration is not retried
+			r.ctxlog.Info(fmt.Sprintf("Migration for VM '%s' was rolled back", migrationobjs.Items[i].Spec.VMName))
+			err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodFailed,
+				fmt.Sprintf("Migration for VM '%s' was rolled back after failed health checks", migrationobjs.Items[i].Spec.VMName))
+			if err != nil {
+				return false, ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+			}
+			return false, ctrl.Result{}, nil
+		case vjailbreakv1alpha1.VMMigrationPhaseSucceeded:
+			err := r.reconcilePostMigration(ctx, scope, migrationobjs.Items[i].Spec.VMName)
+			if err != nil {
+				r.ctxlog.Error(err, fmt.Sprintf("Post-migration actions failed for VM '%s'", migrationobjs.Items[i].Spec.VMName))
+				return false, ctrl.Result{}, errors.Wrap(err, "failed to reconcile post migration")
+			}
+			continue
+		case vjailbreakv1alpha1.VMMigrationPhaseInspected:
+			// Inspect-only migrations have no post-migration actions
+			continue
+		case vjailbreakv1alpha1.VMMigrationPhaseWaitingForCapacity:
+			message := fmt.Sprintf("Migration for VM '%s' %s", migrationobjs.Items[i].Spec.VMName, waitingForCapacityMessage)
+			if migrationplan.Status.MigrationMessage != message {
+				if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodRunning, message); err != nil {
+					return false, ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+				}
+			}
+			return false, ctrl.Result{RequeueAfter: constants.CapacityRecheckInterval}, nil
+		default:
+			r.ctxlog.Info(fmt.Sprintf("Waiting for all VMs in parallel batch %d to complete: %v", i+1, parallelvms))
+			if queued {
+				return false, ctrl.Result{RequeueAfter: constants.SchedulerRecheckInterval}, nil
+			}
+			if waitingForCapacity {
+				return false, ctrl.Result{RequeueAfter: constants.CapacityRecheckInterval}, nil
+			}
+			return false, ctrl.Result{}, nil
+		}
+	}
+	return true, ctrl.Result{}, nil
+}
+
+// reconcileMigrationWaves starts the waves of the plan whose dependencies passed their gate and records the state
+// of each wave in the status. It returns true once all waves succeeded, otherwise the result to return from the reconcile
+func (r *MigrationPlanReconciler) reconcileMigrationWaves(ctx context.Context,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	scope *scope.MigrationPlanScope,
+	openstackcreds *vjailbreakv1alpha1.OpenstackCreds,
+	vmwcreds *vjailbreakv1alpha1.VMwareCreds,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	vmMachinesMap map[string]*vjailbreakv1alpha1.VMwareMachine) (bool, ctrl.Result, error) {
+	migrations, err := r.getPlanMigrations(ctx, migrationplan)
+	if err != nil {
+		return false, ctrl.Result{}, err
+	}
+	waves := utils.EvaluateMigrationWaves(migrationplan, migrations, metav1.Now())
+	if !reflect.DeepEqual(waves, migrationplan.Status.Waves) {
+		migrationplan.Status.Waves = waves
+		if err := r.Status().Update(ctx, migrationplan); err != nil {
+			return false, ctrl.Result{}, errors.Wrap(err, "failed to update migration plan status")
+		}
+	}
+
+	runningVMs := []string{}
+	runningMachines := []*vjailbreakv1alpha1.VMwareMachine{}
+	awaiting, blocked := []string{}, []string{}
+	for i := range waves {
+		switch waves[i].Phase {
+		// Failed waves are checked with the running ones, so that their Migrations are retried or fail the plan
+		case vjailbreakv1alpha1.WavePhaseRunning, vjailbreakv1alpha1.WavePhaseFailed:
+			for _, vm := range migrationplan.Spec.Waves[i].VirtualMachines {
+				runningVMs = append(runningVMs, vm)
+				runningMachines = append(runningMachines, vmMachinesMap[vm])
+			}
+		case vjailbreakv1alpha1.WavePhaseAwaitingApproval:
+			awaiting = append(awaiting, waves[i].Name)
+		case vjailbreakv1alpha1.WavePhaseBlocked:
+			blocked = append(blocked, fmt.Sprintf("%s (%s)", waves[i].Name, waves[i].Message))
+		}
+	}
+
+	if len(runningMachines) > 0 {
+		r.ctxlog.Info("Migrating running waves", "migrationplan", migrationplan.Name, "vms", runningVMs)
+		if strings.HasPrefix(migrationplan.Status.MigrationMessage, waitingForApprovalMessage) {
+			if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodRunning, "Migration(s) in progress"); err != nil {
+				return false, ctrl.Result{}, err
+			}
+		}
+		migrationobjs := &vjailbreakv1alpha1.MigrationList{}
+		err := r.TriggerMigration(ctx, migrationplan, migrationobjs, openstackcreds, vmwcreds, migrationtemplate, runningMachines)
+		if err != nil {
+			if strings.Contains(err.Error(), "VDDK_MISSING") {
+				r.ctxlog.Info("Requeuing due to missing VDDK files.")
+				return false, ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+			}
+			return false, ctrl.Result{}, errors.Wrapf(err, "failed to trigger migration")
+		}
+		if done, res, err := r.checkMigrations(ctx, migrationplan, scope, migrationobjs, runningVMs); !done || err != nil {
+			return false, res, err
+		}
+		if slices.ContainsFunc(waves, func(wave vjailbreakv1alpha1.MigrationWaveStatus) bool {
+			return wave.Phase == vjailbreakv1alpha1.WavePhaseFailed
+		}) {
+			// A failed wave never completes the plan, it only moves on once its Migrations are retried
+			return false, ctrl.Result{}, nil
+		}
+		// The running waves are done, the waves depending on them are evaluated again
+		return false, ctrl.Result{Requeue: true}, nil
+	}
+	if len(awaiting) > 0 {
+		message := fmt.Sprintf("%s %s", waitingForApprovalMessage, strings.Join(awaiting, ", "))
+		if migrationplan.Status.MigrationMessage != message {
+			if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodRunning, message); err != nil {
+				return false, ctrl.Result{}, err
+			}
+		}
+		// Approving a wave changes the plan, which reconciles it again
+		return false, ctrl.Result{}, nil
+	}
+	if len(blocked) > 0 {
+		message := fmt.Sprintf("Wave(s) cannot start: %s", strings.Join(blocked, ", "))
+		if migrationplan.Status.MigrationMessage != message {
+			if err := r.UpdateMigrationPlanStatus(ctx, migrationplan, corev1.PodFailed, message); err != nil {
+				return false, ctrl.Result{}, err
+			}
+		}
+		return false, ctrl.Result{}, nil
+	}
+	return true, ctrl.Result{}, nil
+}
+
+// getPlanMigrations returns the Migrations of a plan by VM name
+func (r *MigrationPlanReconciler) getPlanMigrations(ctx context.Context,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan) (map[string]*vjailbreakv1alpha1.Migration, error) {
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := r.List(ctx, migrationList, client.InNamespace(migrationplan.Namespace),
+		client.MatchingLabels{"migrationplan": migrationplan.Name}); err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+	migrations := map[string]*vjailbreakv1alpha1.Migration{}
+	for i := range migrationList.Items {
+		migrations[migrationList.Items[i].Spec.VMName] = &migrationList.Items[i]
+	}
+	return migrations, nil
+}
+
 // handleRDMDiskMigrationError handles errors that occur during RDM disk migration
 func (r *MigrationPlanReconciler) handleRDMDiskMigrationError(ctx context.Context, migrationplan *vjailbreakv1alpha1.MigrationPlan, err error) (ctrl.Result, error) {
 	if err == verrors.ErrRDMDiskNotMigrated {
@@ -627,7 +903,9 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 	firstbootconfigMapName string,
 	vmwareSecretRef string,
 	openstackSecretRef string,
-	vmMachine *vjailbreakv1alpha1.VMwareMachine) error {
+	vmMachine *vjailbreakv1alpha1.VMwareMachine,
+	hostname string,
+	demand utils.MigrationDemand) error {
 	vmwarecreds, err := utils.GetVMwareCredsNameFromMigrationPlan(ctx, r.Client, migrationplan)
 	if err != nil {
 		return errors.Wrap(err, "failed to get vmware credentials")
@@ -677,16 +955,37 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 		r.ctxlog.Info(fmt.Sprintf("Creating new Job '%s' for VM '%s'", jobName, vm))
 		job = &batchv1.Job{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:      jobName,
-				Namespace: migrationplan.Namespace,
+				Name:        jobName,
+				Namespace:   migrationplan.Namespace,
+				Annotations: demand.Annotations(),
 			},
 			Spec: batchv1.JobSpec{
+				BackoffLimit: ptr.To(constants.MigrationJobBackoffLimit),
 				PodFailurePolicy: &batchv1.PodFailurePolicy{
 					Rules: []batchv1.PodFailurePolicyRule{
+						{
+							// Pods evicted or preempted are recreated, v2v-helper resumes the copy from its checkpoint
+							Action: batchv1.PodFailurePolicyActionIgnore,
+							OnPodConditions: []batchv1.PodFailurePolicyOnPodConditionsPattern{
+								{
+									Type:   corev1.DisruptionTarget,
+									Status: corev1.ConditionTrue,
+								},
+							},
+						},
+						{
+							// Pods stopped with a resumable checkpoint, such as deleted pods, are recreated up to the
+							// backoff limit
+							Action: batchv1.PodFailurePolicyActionCount,
+							OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+								Values:   []int32{openstackconst.ResumeExitCode},
+								Operator: batchv1.PodFailurePolicyOnExitCodesOpIn,
+							},
+						},
 						{
 							Action: batchv1.PodFailurePolicyActionFailJob,
 							OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
-								Values:   []int32{0},
+								Values:   []int32{0, openstackconst.ResumeExitCode},
 								Operator: batchv1.PodFailurePolicyOnExitCodesOpNotIn,
 							},
 						},
@@ -827,6 +1126,44 @@ func (r *MigrationPlanReconciler) CreateJob(ctx context.Context,
 				},
 			},
 		}
+		// The scheduler placed the migration on the agent with room for it
+		if hostname != "" {
+			job.Spec.Template.Spec.NodeSelector = map[string]string{corev1.LabelHostname: hostname}
+		}
+		// Backends other than CinderAttach copy the disks to staging files on the disk of the agent
+		if backend := migrationtemplate.Spec.TargetBackend; backend != "" && backend != vjailbreakv1alpha1.DiskTargetBackendCinderAttach {
+			podSpec := &job.Spec.Template.Spec
+			podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      "staging",
+				MountPath: "/home/fedora/staging",
+			})
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name: "staging",
+				VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{
+						Path: "/home/ubuntu/vjailbreak-staging",
+						Type: utils.NewHostPathType("DirectoryOrCreate"),
+					},
+				},
+			})
+		}
+		// Direct RBD writes map the images with the Ceph keyring of the Cinder user
+		if cephRBD := migrationtemplate.Spec.CephRBD; cephRBD != nil {
+			podSpec := &job.Spec.Template.Spec
+			podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, corev1.VolumeMount{
+				Name:      "ceph-keyring",
+				MountPath: "/etc/ceph",
+				ReadOnly:  true,
+			})
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name: "ceph-keyring",
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName: cephRBD.KeyringSecret,
+					},
+				},
+			})
+		}
 		if err := r.createResource(ctx, migrationobj, job); err != nil {
 			r.ctxlog.Error(err, fmt.Sprintf("Failed to create Job '%s'", jobName))
 			return errors.Wrap(err, fmt.Sprintf("failed to create job '%s'", jobName))
@@ -887,10 +1224,19 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 	} else {
 		virtiodrivers = migrationtemplate.Spec.VirtioWinDriver
 	}
+	warmSyncInterval := ""
+	if migrationplan.Spec.MigrationStrategy.WarmSyncInterval != nil {
+		warmSyncInterval = migrationplan.Spec.MigrationStrategy.WarmSyncInterval.Duration.String()
+	}
 	openstacknws, openstackvolumetypes, err := r.reconcileMapping(ctx, migrationtemplate, openstackcreds, vmwcreds, vm)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to reconcile mapping")
 	}
+	storagemap := &vjailbreakv1alpha1.StorageMapping{}
+	err = r.Get(ctx, types.NamespacedName{Name: migrationtemplate.Spec.StorageMapping, Namespace: migrationtemplate.Namespace}, storagemap)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to retrieve StorageMapping CR")
+	}
 
 	openstackports := []string{}
 	// If advanced options are set, replace the networks and/or volume types with the ones in the advanced options
@@ -932,6 +1278,9 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 				"DATACOPYSTART":              migrationplan.Spec.MigrationStrategy.DataCopyStart.Format(time.RFC3339),
 				"CUTOVERSTART":               migrationplan.Spec.MigrationStrategy.VMCutoverStart.Format(time.RFC3339),
 				"CUTOVEREND":                 migrationplan.Spec.MigrationStrategy.VMCutoverEnd.Format(time.RFC3339),
+				"WARM_SYNC_INTERVAL":         warmSyncInterval,
+				"MAINTENANCE_WINDOW":         migrationplan.Spec.MigrationStrategy.MaintenanceWindow,
+				"MIGRATION_PLAN_NAMESPACE":   migrationplan.Namespace,
 				"NEUTRON_NETWORK_NAMES":      strings.Join(openstacknws, ","),
 				"NEUTRON_PORT_IDS":           strings.Join(openstackports, ","),
 				"CINDER_VOLUME_TYPES":        strings.Join(openstackvolumetypes, ","),
@@ -942,6 +1291,14 @@ func (r *MigrationPlanReconciler) CreateMigrationConfigMap(ctx context.Context,
 				"SECURITY_GROUPS":            strings.Join(migrationplan.Spec.SecurityGroups, ","),
 				"RDM_DISK_NAMES":             strings.Join(vmMachine.Spec.VMInfo.RDMDisks, ","),
 				"FALLBACK_TO_DHCP":           strconv.FormatBool(migrationplan.Spec.FallbackToDHCP),
+				"DATA_VERIFICATION":          string(migrationplan.Spec.MigrationStrategy.DataVerification),
+				"ROLLBACK_POLICY":            string(migrationplan.Spec.MigrationStrategy.RollbackPolicy),
+				"REMOVE_VMWARE_TOOLS":        strconv.FormatBool(migrationplan.Spec.RemoveVMwareTools),
+				"ALLOW_UNSUPPORTED_OS":       strconv.FormatBool(migrationplan.Spec.AllowUnsupportedOS),
+				"INSPECT_ONLY":               strconv.FormatBool(migrationplan.Spec.InspectOnly),
+				"TARGET_BACKEND":             string(migrationtemplate.Spec.TargetBackend),
+				"RBD_DIRECT_WRITE":           strconv.FormatBool(migrationtemplate.Spec.CephRBD != nil),
+				"ZEROED_VOLUME_TYPES":        strings.Join(storagemap.Spec.ZeroedVolumeTypes, ","),
 			},
 		}
 		if utils.IsOpenstackPCD(*openstackcreds) {
@@ -1075,21 +1432,10 @@ func (r *MigrationPlanReconciler) reconcileNetwork(ctx context.Context,
 		return nil, errors.Wrap(err, "failed to retrieve NetworkMapping CR")
 	}
 
-	openstacknws := []string{}
-	// Process each VM network (including duplicates for multiple NICs)
 	// Map each NIC's network to the corresponding target network
-	for _, vmnw := range vmnws {
-		found := false
-		for _, nwm := range networkmap.Spec.Networks {
-			if vmnw == nwm.Source {
-				openstacknws = append(openstacknws, nwm.Target)
-				found = true
-				break // Use the first matching mapping
-			}
-		}
-		if !found {
-			return nil, errors.Errorf("VMware network %q not found in NetworkMapping", vmnw)
-		}
+	openstacknws, err := utils.MapVMNetworks(vmnws, networkmap.Spec.Networks)
+	if err != nil {
+		return nil, err
 	}
 
 	// Get unique networks for validation
@@ -1135,16 +1481,9 @@ func (r *MigrationPlanReconciler) reconcileStorage(ctx context.Context,
 		return nil, errors.Wrap(err, "failed to retrieve StorageMapping CR")
 	}
 
-	openstackvolumetypes := []string{}
-	for _, vmdatastore := range vmds {
-		for _, storagemaptype := range storagemap.Spec.Storages {
-			if vmdatastore == storagemaptype.Source {
-				openstackvolumetypes = append(openstackvolumetypes, storagemaptype.Target)
-			}
-		}
-	}
-	if len(openstackvolumetypes) != len(vmds) {
-		return nil, errors.Errorf("VMware Datastore(s) not found in StorageMapping vm(%d) openstack(%d)", len(vmds), len(openstackvolumetypes))
+	openstackvolumetypes, err := utils.MapVMDatastores(vmds, storagemap.Spec.Storages)
+	if err != nil {
+		return nil, err
 	}
 	if storagemap.Status.StoragemappingValidationStatus != string(corev1.PodSucceeded) {
 		err = utils.VerifyStorage(ctx, r.Client, openstackcreds, openstackvolumetypes)
@@ -1161,7 +1500,8 @@ func (r *MigrationPlanReconciler) reconcileStorage(ctx context.Context,
 	return openstackvolumetypes, nil
 }
 
-// TriggerMigration triggers a migration process
+// TriggerMigration creates the Migrations of the VMs and starts those that fit in the quota of the target project
+// on the agents with room for them. The others are queued and placed on a later reconcile
 func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 	migrationplan *vjailbreakv1alpha1.MigrationPlan,
 	migrationobjs *vjailbreakv1alpha1.MigrationList,
@@ -1170,16 +1510,17 @@ func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
 	parallelvms []*vjailbreakv1alpha1.VMwareMachine) error {
 	ctxlog := r.ctxlog.WithValues("migrationplan", migrationplan.Name)
-	var (
-		fbcm *corev1.ConfigMap
-	)
-
-	nodeList := &corev1.NodeList{}
-	err := r.List(ctx, nodeList)
+	migrationJobs, err := r.getMigrationJobs(ctx, migrationplan.Namespace)
 	if err != nil {
-		return errors.Wrap(err, "failed to list nodes")
-	}
-	counter := len(nodeList.Items)
+		return err
+	}
+	// capacity is only fetched from OpenStack once a VM whose Job has not been created yet is found
+	var capacity *utils.ProjectCapacity
+	// pending are the migrations whose Job is created once they are placed on an agent
+	pending := []utils.PendingMigration{}
+	migrations := map[string]*vjailbreakv1alpha1.Migration{}
+	vmMachines := map[string]*vjailbreakv1alpha1.VMwareMachine{}
+	ordered := []*vjailbreakv1alpha1.Migration{}
 	for _, vmMachineObj := range parallelvms {
 		if vmMachineObj == nil {
 			return errors.Wrapf(err, "VM '%s' not found in VMwareMachine", vmMachineObj.Name)
@@ -1223,43 +1564,384 @@ func (r *MigrationPlanReconciler) TriggerMigration(ctx context.Context,
 			}
 			return errors.Wrapf(err, "failed to create Migration for VM %s", vm)
 		}
-		migrationobjs.Items = append(migrationobjs.Items, *migrationobj)
-		_, err = r.CreateMigrationConfigMap(ctx, migrationplan, migrationtemplate, migrationobj, openstackcreds, vmwcreds, vm, vmMachineObj)
-		if err != nil {
-			return errors.Wrapf(err, "failed to create ConfigMap for VM %s", vm)
+		migrations[migrationobj.Name] = migrationobj
+		vmMachines[migrationobj.Name] = vmMachineObj
+		ordered = append(ordered, migrationobj)
+		if _, started := migrationJobs[migrationobj.Name]; started || !isMigrationNotStarted(migrationobj) {
+			if err := r.startMigration(ctx, migrationplan, migrationtemplate, migrationobj, openstackcreds, vmwcreds,
+				vmMachineObj, "", utils.MigrationDemand{}); err != nil {
+				return err
+			}
+			continue
+		}
+		// Inspect-only migrations create nothing in the target project
+		if !migrationplan.Spec.InspectOnly {
+			if capacity == nil {
+				capacity, err = r.getProjectCapacity(ctx, migrationplan.Namespace, openstackcreds, migrationJobs)
+				if err != nil {
+					return errors.Wrap(err, "failed to get the capacity left in the target project")
+				}
+			}
+			fits, err := r.reserveCapacity(ctx, capacity, migrationobj, vmwcreds, vmMachineObj)
+			if err != nil {
+				return errors.Wrapf(err, "failed to check the capacity needed by VM %s", vm)
+			}
+			if !fits {
+				ctxlog.Info("Not enough quota left in the target project, holding the migration", "vm", vm)
+				continue
+			}
 		}
-		fbcm, err = r.CreateFirstbootConfigMap(ctx, migrationplan, vm)
+		pending = append(pending, utils.PendingMigration{
+			Name:     migrationobj.Name,
+			Priority: migrationplan.Spec.VMPriorities[vm],
+			Demand:   utils.MigrationDemandForVM(&vmMachineObj.Spec.VMInfo, migrationtemplate, migrationplan),
+		})
+	}
+
+	if len(pending) > 0 {
+		agents, err := r.getAgentCapacities(ctx, migrationplan.Namespace, migrationJobs)
 		if err != nil {
-			return errors.Wrapf(err, "failed to create Firstboot ConfigMap for VM %s", vm)
+			return errors.Wrap(err, "failed to get the capacity left on the agents")
 		}
-		//nolint:gocritic // err is already declared above
-		if err = r.validateVDDKPresence(ctx, migrationobj, ctxlog); err != nil {
-			return err
+		schedule := utils.ScheduleMigrations(agents, pending)
+		for _, migration := range pending {
+			migrationobj := migrations[migration.Name]
+			agent, placed := schedule.Placed[migration.Name]
+			if !placed {
+				continue
+			}
+			ctxlog.Info("Placing migration on agent", "migration", migration.Name, "agent", agent.Name)
+			if err := r.setQueuePosition(ctx, migrationobj, 0, constants.ScheduledReasonPlaced, fmt.Sprintf("Placed on agent %s", agent.Name)); err != nil {
+				return err
+			}
+			if err := r.startMigration(ctx, migrationplan, migrationtemplate, migrationobj, openstackcreds, vmwcreds,
+				vmMachines[migration.Name], agent.Hostname, migration.Demand); err != nil {
+				return err
+			}
 		}
+		for i, name := range schedule.Queued {
+			ctxlog.Info("No agent has room for the migration, queueing it", "migration", name, "position", i+1)
+			message := "No agent has room for the migration: " + schedule.Reasons[name]
+			if err := r.setQueuePosition(ctx, migrations[name], i+1, constants.ScheduledReasonQueued, message); err != nil {
+				return err
+			}
+		}
+		for _, name := range schedule.Unschedulable {
+			ctxlog.Info("No agent can run the migration", "migration", name, "reason", schedule.Reasons[name])
+			message := "No agent can run the migration, even with nothing else running on it: " + schedule.Reasons[name]
+			if err := r.setQueuePosition(ctx, migrations[name], 0, constants.ScheduledReasonUnschedulable, message); err != nil {
+				return err
+			}
+		}
+	}
 
-		err = r.CreateJob(ctx,
-			migrationplan,
-			migrationtemplate,
-			migrationobj,
-			vm,
-			fbcm.Name,
-			vmwcreds.Spec.SecretRef.Name,
-			openstackcreds.Spec.SecretRef.Name,
-			vmMachineObj)
-		if err != nil {
-			return errors.Wrap(err, fmt.Sprintf("failed to create Job for VM %s", vm))
+	for _, migrationobj := range ordered {
+		migrationobjs.Items = append(migrationobjs.Items, *migrationobj)
+	}
+	return nil
+}
+
+// startMigration creates the ConfigMaps and the Job of a migration, placing the Job on the node hostname if set
+func (r *MigrationPlanReconciler) startMigration(ctx context.Context,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	migrationobj *vjailbreakv1alpha1.Migration,
+	openstackcreds *vjailbreakv1alpha1.OpenstackCreds,
+	vmwcreds *vjailbreakv1alpha1.VMwareCreds,
+	vmMachineObj *vjailbreakv1alpha1.VMwareMachine,
+	hostname string,
+	demand utils.MigrationDemand) error {
+	ctxlog := r.ctxlog.WithValues("migrationplan", migrationplan.Name)
+	vm := vmMachineObj.Spec.VMInfo.Name
+	_, err := r.CreateMigrationConfigMap(ctx, migrationplan, migrationtemplate, migrationobj, openstackcreds, vmwcreds, vm, vmMachineObj)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create ConfigMap for VM %s", vm)
+	}
+	fbcm, err := r.CreateFirstbootConfigMap(ctx, migrationplan, vm)
+	if err != nil {
+		return errors.Wrapf(err, "failed to create Firstboot ConfigMap for VM %s", vm)
+	}
+	if err = r.validateVDDKPresence(ctx, migrationobj, ctxlog); err != nil {
+		return err
+	}
+
+	err = r.CreateJob(ctx,
+		migrationplan,
+		migrationtemplate,
+		migrationobj,
+		vm,
+		fbcm.Name,
+		vmwcreds.Spec.SecretRef.Name,
+		openstackcreds.Spec.SecretRef.Name,
+		vmMachineObj,
+		hostname,
+		demand)
+	if err != nil {
+		return errors.Wrap(err, fmt.Sprintf("failed to create Job for VM %s", vm))
+	}
+	return nil
+}
+
+// getAgentCapacities returns the capacity of the ready agents that are not draining, less what the unfinished migration Jobs take on them.
+// Agents are matched to their Kubernetes node by IP.
+func (r *MigrationPlanReconciler) getAgentCapacities(ctx context.Context, namespace string,
+	migrationJobs map[string]*batchv1.Job) ([]*utils.AgentCapacity, error) {
+	vjNodes := &vjailbreakv1alpha1.VjailbreakNodeList{}
+	if err := r.List(ctx, vjNodes, client.InNamespace(constants.NamespaceMigrationSystem)); err != nil {
+		return nil, errors.Wrap(err, "failed to list vjailbreak nodes")
+	}
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	nodesByIP := map[string]*corev1.Node{}
+	for i := range nodeList.Items {
+		if ip := utils.GetNodeInternalIP(&nodeList.Items[i]); ip != "" {
+			nodesByIP[ip] = &nodeList.Items[i]
+		}
+	}
+	agents := []*utils.AgentCapacity{}
+	activeMigrations := map[*utils.AgentCapacity]int{}
+	// agentsByNode holds the agents by both the name and the hostname label of their node
+	agentsByNode := map[string]*utils.AgentCapacity{}
+	for i := range vjNodes.Items {
+		vjNode := &vjNodes.Items[i]
+		node, ok := nodesByIP[vjNode.Status.VMIP]
+		// Draining nodes take no new migrations
+		if vjNode.Status.Phase != constants.VjailbreakNodePhaseNodeReady || vjNode.Spec.Draining || !ok {
+			continue
+		}
+		hostname := node.Labels[corev1.LabelHostname]
+		if hostname == "" {
+			hostname = node.Name
 		}
-		counter--
+		agent := utils.NewAgentCapacity(vjNode, hostname)
+		agents = append(agents, agent)
+		activeMigrations[agent] = len(vjNode.Status.ActiveMigrations)
+		agentsByNode[node.Name] = agent
+		agentsByNode[hostname] = agent
+	}
 
-		if counter == 0 {
-			// Control the number of VMs in parallel
-			counter = len(nodeList.Items)
-			time.Sleep(constants.MigrationTriggerDelay)
+	migrationList := &vjailbreakv1alpha1.MigrationList{}
+	if err := r.List(ctx, migrationList, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrap(err, "failed to list migrations")
+	}
+	agentNames := map[string]string{}
+	for i := range migrationList.Items {
+		agentNames[migrationList.Items[i].Name] = migrationList.Items[i].Status.AgentName
+	}
+	for migrationName, job := range migrationJobs {
+		if isJobFinished(job) {
+			continue
 		}
+		// Jobs created before they were placed run where Kubernetes scheduled them
+		node := job.Spec.Template.Spec.NodeSelector[corev1.LabelHostname]
+		if node == "" {
+			node = agentNames[migrationName]
+		}
+		if agent, ok := agentsByNode[node]; ok {
+			agent.Take(utils.MigrationDemandFromAnnotations(job.Annotations))
+		}
+	}
+	// Migrations of other namespaces only show up in the status of the node
+	for _, agent := range agents {
+		agent.Migrations = max(agent.Migrations, activeMigrations[agent])
+	}
+	return agents, nil
+}
+
+// setQueuePosition records the position of a migration in the queue of its plan, 0 once it is placed on an agent
+// or when no agent can run it, and the Scheduled condition explaining it
+func (r *MigrationPlanReconciler) setQueuePosition(ctx context.Context, migrationobj *vjailbreakv1alpha1.Migration,
+	position int, reason, message string) error {
+	oldStatus := migrationobj.Status.DeepCopy()
+	status := corev1.ConditionFalse
+	if reason == constants.ScheduledReasonPlaced {
+		status = corev1.ConditionTrue
+	}
+	idx := utils.GetConditonIndex(migrationobj.Status.Conditions, constants.MigrationConditionTypeScheduled,
+		constants.ScheduledReasonPlaced, constants.ScheduledReasonQueued, constants.ScheduledReasonUnschedulable)
+	timestamp := metav1.Now()
+	if idx != -1 && migrationobj.Status.Conditions[idx].Status == status {
+		timestamp = migrationobj.Status.Conditions[idx].LastTransitionTime
+	}
+	condition := utils.GeneratePodCondition(constants.MigrationConditionTypeScheduled, status, reason, message, timestamp)
+	if idx == -1 {
+		migrationobj.Status.Conditions = append(migrationobj.Status.Conditions, *condition)
+	} else {
+		migrationobj.Status.Conditions[idx] = *condition
+	}
+	migrationobj.Status.QueuePosition = position
+
+	if reflect.DeepEqual(oldStatus, &migrationobj.Status) {
+		return nil
+	}
+	if err := r.Status().Update(ctx, migrationobj); err != nil {
+		return errors.Wrapf(err, "failed to update queue position of migration '%s'", migrationobj.Name)
 	}
 	return nil
 }
 
+// getMigrationJobs returns the v2v-helper Jobs in the namespace by the name of the Migration they belong to
+func (r *MigrationPlanReconciler) getMigrationJobs(ctx context.Context, namespace string) (map[string]*batchv1.Job, error) {
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs, client.InNamespace(namespace)); err != nil {
+		return nil, errors.Wrap(err, "failed to list jobs")
+	}
+	migrationJobs := make(map[string]*batchv1.Job, len(jobs.Items))
+	for i := range jobs.Items {
+		owner := metav1.GetControllerOf(&jobs.Items[i])
+		if owner != nil && owner.Kind == "Migration" {
+			migrationJobs[owner.Name] = &jobs.Items[i]
+		}
+	}
+	return migrationJobs, nil
+}
+
+// isMigrationUnschedulable returns true if the migration has not started because no agent can run it
+func isMigrationUnschedulable(migrationobj *vjailbreakv1alpha1.Migration) bool {
+	return isMigrationNotStarted(migrationobj) && utils.GetConditonIndex(migrationobj.Status.Conditions,
+		constants.MigrationConditionTypeScheduled, constants.ScheduledReasonUnschedulable) != -1
+}
+
+// isMigrationNotStarted returns true if the migration has not progressed past waiting for its Job
+func isMigrationNotStarted(migrationobj *vjailbreakv1alpha1.Migration) bool {
+	switch migrationobj.Status.Phase {
+	case "", vjailbreakv1alpha1.VMMigrationPhasePending, vjailbreakv1alpha1.VMMigrationPhaseWaitingForCapacity:
+		return true
+	default:
+		return false
+	}
+}
+
+// isJobFinished returns true if the Job has completed or failed
+func isJobFinished(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// getProjectCapacity returns the capacity left in the project of the OpenStack credentials. Running migrations
+// create their VM and ports only at the end, so what they need is taken off the usage reported by OpenStack.
+func (r *MigrationPlanReconciler) getProjectCapacity(ctx context.Context, namespace string,
+	openstackcreds *vjailbreakv1alpha1.OpenstackCreds, migrationJobs map[string]*batchv1.Job) (*utils.ProjectCapacity, error) {
+	capacity, err := utils.GetProjectCapacity(ctx, r.Client, openstackcreds)
+	if err != nil {
+		return nil, err
+	}
+	// openstackRefs caches the OpenStack credentials used by each plan
+	openstackRefs := map[string]string{}
+	for migrationName, job := range migrationJobs {
+		if isJobFinished(job) {
+			continue
+		}
+		migration := &vjailbreakv1alpha1.Migration{}
+		if err := r.Get(ctx, types.NamespacedName{Name: migrationName, Namespace: namespace}, migration); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get Migration '%s'", migrationName)
+		}
+		openstackRef, ok := openstackRefs[migration.Spec.MigrationPlan]
+		if !ok {
+			openstackRef, err = r.getPlanOpenstackRef(ctx, namespace, migration.Spec.MigrationPlan)
+			if err != nil {
+				return nil, err
+			}
+			openstackRefs[migration.Spec.MigrationPlan] = openstackRef
+		}
+		if openstackRef != openstackcreds.Name {
+			continue
+		}
+		vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+		vmMachineName := job.Spec.Template.Labels[constants.VMNameLabel]
+		if err := r.Get(ctx, types.NamespacedName{Name: vmMachineName, Namespace: namespace}, vmMachine); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to get VMwareMachine '%s'", vmMachineName)
+		}
+		capacity.Take(utils.ResourceRequestForVM(&vmMachine.Spec.VMInfo, nil).ComputeOnly())
+	}
+	return capacity, nil
+}
+
+// getPlanOpenstackRef returns the name of the OpenStack credentials a MigrationPlan migrates to,
+// or an empty string if the plan or its template no longer exist
+func (r *MigrationPlanReconciler) getPlanOpenstackRef(ctx context.Context, namespace, planName string) (string, error) {
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{}
+	if err := r.Get(ctx, types.NamespacedName{Name: planName, Namespace: namespace}, migrationplan); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to get MigrationPlan '%s'", planName)
+	}
+	migrationtemplate := &vjailbreakv1alpha1.MigrationTemplate{}
+	if err := r.Get(ctx, types.NamespacedName{Name: migrationplan.Spec.MigrationTemplate, Namespace: namespace}, migrationtemplate); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", errors.Wrapf(err, "failed to get MigrationTemplate '%s'", migrationplan.Spec.MigrationTemplate)
+	}
+	return migrationtemplate.Spec.Destination.OpenstackRef, nil
+}
+
+// reserveCapacity takes what the VM needs off the capacity left in the target project. A VM that does not fit
+// is held in the WaitingForCapacity phase, with a condition listing the missing quota, and false is returned.
+func (r *MigrationPlanReconciler) reserveCapacity(ctx context.Context, capacity *utils.ProjectCapacity,
+	migrationobj *vjailbreakv1alpha1.Migration, vmwcreds *vjailbreakv1alpha1.VMwareCreds,
+	vmMachine *vjailbreakv1alpha1.VMwareMachine) (bool, error) {
+	request, err := utils.GetVMResourceRequest(ctx, r.Client, vmwcreds, vmMachine)
+	if err != nil {
+		return false, err
+	}
+	shortfalls := capacity.Reserve(request)
+
+	oldStatus := migrationobj.Status.DeepCopy()
+	var previous *corev1.PodCondition
+	conditions := []corev1.PodCondition{}
+	for i, c := range migrationobj.Status.Conditions {
+		if c.Type == constants.MigrationConditionTypeCapacity {
+			previous = &migrationobj.Status.Conditions[i]
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+	if len(shortfalls) == 0 {
+		if migrationobj.Status.Phase == vjailbreakv1alpha1.VMMigrationPhaseWaitingForCapacity {
+			migrationobj.Status.Phase = vjailbreakv1alpha1.VMMigrationPhasePending
+		}
+	} else {
+		condition := corev1.PodCondition{
+			Type:               constants.MigrationConditionTypeCapacity,
+			Status:             corev1.ConditionFalse,
+			Reason:             "InsufficientQuota",
+			Message:            "Not enough quota left in the target project: " + strings.Join(shortfalls, ", "),
+			LastTransitionTime: metav1.Now(),
+		}
+		if previous != nil && previous.Message == condition.Message {
+			condition.LastTransitionTime = previous.LastTransitionTime
+		}
+		conditions = append(conditions, condition)
+		migrationobj.Status.Phase = vjailbreakv1alpha1.VMMigrationPhaseWaitingForCapacity
+		// The migration leaves the queue of the agents until there is quota for it
+		migrationobj.Status.QueuePosition = 0
+	}
+	if previous != nil || len(shortfalls) > 0 {
+		migrationobj.Status.Conditions = conditions
+	}
+
+	if !reflect.DeepEqual(oldStatus, &migrationobj.Status) {
+		if err := r.Status().Update(ctx, migrationobj); err != nil {
+			return false, errors.Wrap(err, "failed to update migration status after checking capacity")
+		}
+	}
+	return len(shortfalls) == 0, nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *MigrationPlanReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
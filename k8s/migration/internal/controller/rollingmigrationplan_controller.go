@@ -35,6 +35,7 @@ import (
 	"github.com/pkg/errors"
 	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	constants "github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	vjbmetrics "github.com/platform9/vjailbreak/k8s/migration/pkg/metrics"
 	scope "github.com/platform9/vjailbreak/k8s/migration/pkg/scope"
 	utils "github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
 )
@@ -59,10 +60,13 @@ func (r *RollingMigrationPlanReconciler) Reconcile(ctx context.Context, req ctrl
 	rollingmigrationplan := &vjailbreakv1alpha1.RollingMigrationPlan{}
 	if err := r.Get(ctx, req.NamespacedName, rollingmigrationplan); err != nil {
 		if apierrors.IsNotFound(err) {
+			rollingmigrationplan.Name, rollingmigrationplan.Namespace = req.Name, req.Namespace
+			vjbmetrics.ForgetObject(vjbmetrics.KindRollingMigrationPlan, rollingmigrationplan)
 			return ctrl.Result{}, nil
 		}
 		return ctrl.Result{}, err
 	}
+	vjbmetrics.RecordPhase(vjbmetrics.KindRollingMigrationPlan, rollingmigrationplan, string(rollingmigrationplan.Status.Phase))
 	scope, err := scope.NewRollingMigrationPlanScope(scope.RollingMigrationPlanScopeParams{
 		Logger:               ctxlog,
 		Client:               r.Client,
@@ -432,7 +436,7 @@ func (r *RollingMigrationPlanReconciler) UpdateRollingMigrationPlanStatus(ctx co
 					return errors.Wrap(err, "failed to get VMMigration")
 				}
 				switch migration.Status.Phase {
-				case vjailbreakv1alpha1.VMMigrationPhaseFailed:
+				case vjailbreakv1alpha1.VMMigrationPhaseFailed, vjailbreakv1alpha1.VMMigrationPhaseRolledBack:
 					scope.RollingMigrationPlan.Status.FailedVMs = append(scope.RollingMigrationPlan.Status.FailedVMs, vm)
 				case vjailbreakv1alpha1.VMMigrationPhaseSucceeded:
 					scope.RollingMigrationPlan.Status.MigratedVMs = append(scope.RollingMigrationPlan.Status.MigratedVMs, vm)
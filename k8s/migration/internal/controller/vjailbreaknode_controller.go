@@ -23,6 +23,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -236,7 +237,7 @@ func (r *VjailbreakNodeReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(r)
 }
 
-// updateActiveMigrations efficiently updates just the ActiveMigrations field
+// updateActiveMigrations efficiently updates just the ActiveMigrations field, and the time the node became idle
 func (r *VjailbreakNodeReconciler) updateActiveMigrations(ctx context.Context,
 	scope *scope.VjailbreakNodeScope) (ctrl.Result, error) {
 	vjNode := scope.VjailbreakNode
@@ -249,6 +250,12 @@ func (r *VjailbreakNodeReconciler) updateActiveMigrations(ctx context.Context,
 	// Create a patch to update only the ActiveMigrations field
 	patch := client.MergeFrom(vjNode.DeepCopy())
 	vjNode.Status.ActiveMigrations = activeMigrations
+	if len(activeMigrations) > 0 {
+		vjNode.Status.IdleSince = nil
+	} else if vjNode.Status.IdleSince == nil {
+		now := metav1.Now()
+		vjNode.Status.IdleSince = &now
+	}
 
 	err = r.Client.Status().Patch(ctx, vjNode, patch)
 	if err != nil {
@@ -0,0 +1,269 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+)
+
+// VjailbreakNodeAutoscalerReconciler reconciles a VjailbreakNodeAutoscaler object
+type VjailbreakNodeAutoscalerReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodeautoscalers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodeautoscalers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=vjailbreaknodes,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=vjailbreak.k8s.pf9.io,resources=migrations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=nodes,verbs=get;list;watch
+
+// Reconcile adds and removes the worker VjailbreakNodes of an autoscaler following the queued migrations
+func (r *VjailbreakNodeAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctxlog := log.FromContext(ctx).WithName(constants.VjailbreakNodeAutoscalerControllerName)
+
+	autoscaler := &vjailbreakv1alpha1.VjailbreakNodeAutoscaler{}
+	if err := r.Get(ctx, req.NamespacedName, autoscaler); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		return ctrl.Result{}, errors.Wrap(err, "failed to get vjailbreak node autoscaler")
+	}
+	// The nodes of a deleted autoscaler are left as they are, they may still be running migrations
+	if !autoscaler.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	vjNodes := &vjailbreakv1alpha1.VjailbreakNodeList{}
+	if err := r.List(ctx, vjNodes, client.InNamespace(constants.NamespaceMigrationSystem),
+		client.MatchingLabels{constants.AutoscalerLabel: autoscaler.Name}); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "failed to list vjailbreak nodes")
+	}
+	queued, err := r.countQueuedMigrations(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	placed, err := r.getPlacedJobs(ctx, vjNodes.Items)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	nodes := []utils.AutoscaledNode{}
+	byName := map[string]*vjailbreakv1alpha1.VjailbreakNode{}
+	for i := range vjNodes.Items {
+		vjNode := &vjNodes.Items[i]
+		// Nodes being deleted are on their way out already
+		if !vjNode.DeletionTimestamp.IsZero() {
+			continue
+		}
+		node := utils.AutoscaledNode{
+			Name:     vjNode.Name,
+			Ready:    vjNode.Status.Phase == constants.VjailbreakNodePhaseNodeReady,
+			Draining: vjNode.Spec.Draining,
+			Busy:     len(vjNode.Status.ActiveMigrations) > 0 || placed[vjNode.Name],
+		}
+		if vjNode.Status.IdleSince != nil {
+			node.IdleSince = &vjNode.Status.IdleSince.Time
+		}
+		nodes = append(nodes, node)
+		byName[vjNode.Name] = vjNode
+	}
+
+	decision := utils.PlanAutoscale(&autoscaler.Spec, nodes, queued, time.Now())
+	messages := []string{}
+	for _, name := range decision.Undrain {
+		if err := r.setDraining(ctx, byName[name], false); err != nil {
+			return ctrl.Result{}, err
+		}
+		messages = append(messages, fmt.Sprintf("placing migrations on %s again", name))
+	}
+	for i := 0; i < decision.Create; i++ {
+		vjNode, err := r.createNode(ctx, autoscaler)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		ctxlog.Info("Added node", "autoscaler", autoscaler.Name, "node", vjNode.Name, "queued", queued)
+		messages = append(messages, fmt.Sprintf("added %s", vjNode.Name))
+	}
+	for _, name := range decision.Drain {
+		if err := r.setDraining(ctx, byName[name], true); err != nil {
+			return ctrl.Result{}, err
+		}
+		ctxlog.Info("Draining idle node", "autoscaler", autoscaler.Name, "node", name)
+		messages = append(messages, fmt.Sprintf("draining %s", name))
+	}
+	deleted := []string{}
+	for _, name := range decision.Delete {
+		// The node is checked again right before it is deleted, a migration may have been placed on it since it was listed
+		vjNode := byName[name]
+		if err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: vjNode.Namespace}, vjNode); err != nil {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to get vjailbreak node '%s'", name)
+		}
+		if len(vjNode.Status.ActiveMigrations) > 0 || !vjNode.Spec.Draining {
+			continue
+		}
+		stillPlaced, err := r.getPlacedJobs(ctx, []vjailbreakv1alpha1.VjailbreakNode{*vjNode})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if stillPlaced[name] {
+			continue
+		}
+		if err := r.Delete(ctx, vjNode); err != nil && !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, errors.Wrapf(err, "failed to delete vjailbreak node '%s'", name)
+		}
+		ctxlog.Info("Removed drained node", "autoscaler", autoscaler.Name, "node", name)
+		deleted = append(deleted, name)
+		messages = append(messages, fmt.Sprintf("removed %s", name))
+	}
+
+	oldStatus := autoscaler.Status.DeepCopy()
+	autoscaler.Status.Nodes = len(nodes) + decision.Create - len(deleted)
+	autoscaler.Status.QueuedMigrations = queued
+	autoscaler.Status.DrainingNodes = nil
+	for _, node := range nodes {
+		if (node.Draining && !slices.Contains(decision.Undrain, node.Name) && !slices.Contains(deleted, node.Name)) ||
+			slices.Contains(decision.Drain, node.Name) {
+			autoscaler.Status.DrainingNodes = append(autoscaler.Status.DrainingNodes, node.Name)
+		}
+	}
+	if len(messages) > 0 {
+		now := metav1.Now()
+		autoscaler.Status.LastScaleTime = &now
+		autoscaler.Status.Message = fmt.Sprintf("%d migration(s) queued: %s", queued, strings.Join(messages, ", "))
+	}
+	if !reflect.DeepEqual(oldStatus, &autoscaler.Status) {
+		if err := r.Status().Update(ctx, autoscaler); err != nil {
+			return ctrl.Result{}, errors.Wrap(err, "failed to update vjailbreak node autoscaler status")
+		}
+	}
+	return ctrl.Result{RequeueAfter: constants.AutoscalerRecheckInterval}, nil
+}
+
+// countQueuedMigrations returns the number of migrations waiting for room on the agents
+func (r *VjailbreakNodeAutoscalerReconciler) countQueuedMigrations(ctx context.Context) (int, error) {
+	migrations := &vjailbreakv1alpha1.MigrationList{}
+	if err := r.List(ctx, migrations); err != nil {
+		return 0, errors.Wrap(err, "failed to list migrations")
+	}
+	queued := 0
+	for i := range migrations.Items {
+		if migrations.Items[i].Status.QueuePosition > 0 {
+			queued++
+		}
+	}
+	return queued, nil
+}
+
+// getPlacedJobs returns the VjailbreakNodes unfinished migration Jobs have been placed on. Their migrations only show
+// up in the active migrations of the node once their pod runs. Jobs are placed on the hostname of the Kubernetes node,
+// which is matched to its VjailbreakNode by IP
+func (r *VjailbreakNodeAutoscalerReconciler) getPlacedJobs(ctx context.Context,
+	vjNodes []vjailbreakv1alpha1.VjailbreakNode) (map[string]bool, error) {
+	nodeList := &corev1.NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		return nil, errors.Wrap(err, "failed to list nodes")
+	}
+	agentNames := utils.AgentNamesByNode(vjNodes, nodeList.Items)
+	jobs := &batchv1.JobList{}
+	if err := r.List(ctx, jobs); err != nil {
+		return nil, errors.Wrap(err, "failed to list jobs")
+	}
+	placed := map[string]bool{}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if owner := metav1.GetControllerOf(job); owner == nil || owner.Kind != "Migration" || isJobFinished(job) {
+			continue
+		}
+		if name, ok := agentNames[job.Spec.Template.Spec.NodeSelector[corev1.LabelHostname]]; ok {
+			placed[name] = true
+		}
+	}
+	return placed, nil
+}
+
+// createNode adds a worker node to the autoscaler, booting the image of the master node
+func (r *VjailbreakNodeAutoscalerReconciler) createNode(ctx context.Context,
+	autoscaler *vjailbreakv1alpha1.VjailbreakNodeAutoscaler) (*vjailbreakv1alpha1.VjailbreakNode, error) {
+	masterNode := &vjailbreakv1alpha1.VjailbreakNode{}
+	if err := r.Get(ctx, types.NamespacedName{Name: constants.VjailbreakMasterNodeName, Namespace: constants.NamespaceMigrationSystem},
+		masterNode); err != nil {
+		return nil, errors.Wrap(err, "failed to get master vjailbreak node")
+	}
+	if masterNode.Spec.OpenstackImageID == "" {
+		return nil, errors.New("the image of the master node is not known yet")
+	}
+	vjNode := &vjailbreakv1alpha1.VjailbreakNode{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: constants.AgentNamePrefix,
+			Namespace:    constants.NamespaceMigrationSystem,
+			Labels:       map[string]string{constants.AutoscalerLabel: autoscaler.Name},
+		},
+		Spec: vjailbreakv1alpha1.VjailbreakNodeSpec{
+			NodeRole: constants.NodeRoleWorker,
+			OpenstackCreds: corev1.ObjectReference{
+				Kind:      "openstackcreds",
+				Name:      autoscaler.Spec.OpenstackCreds,
+				Namespace: constants.NamespaceMigrationSystem,
+			},
+			OpenstackFlavorID: autoscaler.Spec.OpenstackFlavorID,
+			OpenstackImageID:  masterNode.Spec.OpenstackImageID,
+		},
+	}
+	if err := r.Create(ctx, vjNode); err != nil {
+		return nil, errors.Wrap(err, "failed to create vjailbreak node")
+	}
+	return vjNode, nil
+}
+
+// setDraining starts or stops draining a node
+func (r *VjailbreakNodeAutoscalerReconciler) setDraining(ctx context.Context, vjNode *vjailbreakv1alpha1.VjailbreakNode, draining bool) error {
+	patch := client.MergeFrom(vjNode.DeepCopy())
+	vjNode.Spec.Draining = draining
+	if err := r.Patch(ctx, vjNode, patch); err != nil {
+		return errors.Wrapf(err, "failed to update draining of vjailbreak node '%s'", vjNode.Name)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *VjailbreakNodeAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vjailbreakv1alpha1.VjailbreakNodeAutoscaler{}).
+		Complete(r)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _ = ginkgo.Describe("VjailbreakNodeAutoscaler Controller", func() {
+	ginkgo.Context("When reconciling a resource", func() {
+		const resourceName = "test-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default", // TODO(user):Modify as needed
+		}
+		vjailbreaknodeautoscaler := &vjailbreakv1alpha1.VjailbreakNodeAutoscaler{}
+
+		ginkgo.BeforeEach(func() {
+			ginkgo.By("creating the custom resource for the Kind VjailbreakNodeAutoscaler")
+			err := k8sClient.Get(ctx, typeNamespacedName, vjailbreaknodeautoscaler)
+			if err != nil && errors.IsNotFound(err) {
+				resource := &vjailbreakv1alpha1.VjailbreakNodeAutoscaler{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      resourceName,
+						Namespace: "default",
+					},
+					Spec: vjailbreakv1alpha1.VjailbreakNodeAutoscalerSpec{
+						MaxNodes:          1,
+						OpenstackCreds:    "test-openstackcreds",
+						OpenstackFlavorID: "test-flavor",
+					},
+				}
+				gomega.Expect(k8sClient.Create(ctx, resource)).To(gomega.Succeed())
+			}
+		})
+
+		ginkgo.AfterEach(func() {
+			// TODO(user): Cleanup logic after each test, like removing the resource instance.
+			resource := &vjailbreakv1alpha1.VjailbreakNodeAutoscaler{}
+			err := k8sClient.Get(ctx, typeNamespacedName, resource)
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+
+			ginkgo.By("Cleanup the specific resource instance VjailbreakNodeAutoscaler")
+			gomega.Expect(k8sClient.Delete(ctx, resource)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("should successfully reconcile the resource", func() {
+			ginkgo.By("Reconciling the created resource")
+			controllerReconciler := &VjailbreakNodeAutoscalerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
+				NamespacedName: typeNamespacedName,
+			})
+			gomega.Expect(err).NotTo(gomega.HaveOccurred())
+			// TODO(user): Add more specific assertions depending on your controller's reconciliation logic.
+			// Example: If you expect a certain status condition after reconciliation, verify it here.
+		})
+	})
+})
@@ -0,0 +1,119 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// defaultBootSourceRelease is the Ubuntu release provisioned on the hosts when not set
+const defaultBootSourceRelease = "jammy"
+
+// SetupBMConfigWebhookWithManager registers the webhooks for BMConfig in the manager
+func SetupBMConfigWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vjailbreakv1alpha1.BMConfig{}).
+		WithDefaulter(&BMConfigCustomDefaulter{}).
+		WithValidator(&BMConfigCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vjailbreak-k8s-pf9-io-v1alpha1-bmconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=bmconfigs,verbs=create;update,versions=v1alpha1,name=mbmconfig-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// BMConfigCustomDefaulter sets the defaults of BMConfigs
+type BMConfigCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &BMConfigCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *BMConfigCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	bmConfig, ok := obj.(*vjailbreakv1alpha1.BMConfig)
+	if !ok {
+		return fmt.Errorf("expected a BMConfig object but got %T", obj)
+	}
+	if bmConfig.Spec.ProviderType == "" {
+		bmConfig.Spec.ProviderType = vjailbreakv1alpha1.MAASProvider
+	}
+	if bmConfig.Spec.BootSource.Release == "" {
+		bmConfig.Spec.BootSource.Release = defaultBootSourceRelease
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-vjailbreak-k8s-pf9-io-v1alpha1-bmconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=bmconfigs,verbs=create;update,versions=v1alpha1,name=vbmconfig-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// BMConfigCustomValidator rejects the BMConfigs the BM provider cannot be reached with
+type BMConfigCustomValidator struct{}
+
+var _ admission.CustomValidator = &BMConfigCustomValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *BMConfigCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	bmConfig, ok := obj.(*vjailbreakv1alpha1.BMConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a BMConfig object but got %T", obj)
+	}
+	return nil, v.validate(bmConfig)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates leaving the spec as is are always allowed
+func (v *BMConfigCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldBMConfig, ok := oldObj.(*vjailbreakv1alpha1.BMConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a BMConfig object but got %T", oldObj)
+	}
+	bmConfig, ok := newObj.(*vjailbreakv1alpha1.BMConfig)
+	if !ok {
+		return nil, fmt.Errorf("expected a BMConfig object but got %T", newObj)
+	}
+	if !bmConfig.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldBMConfig.Spec, bmConfig.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(bmConfig)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *BMConfigCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *BMConfigCustomValidator) validate(bmConfig *vjailbreakv1alpha1.BMConfig) error {
+	specPath := field.NewPath("spec")
+	allErrs := field.ErrorList{}
+	if bmConfig.Spec.ProviderType != vjailbreakv1alpha1.MAASProvider {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("providerType"), bmConfig.Spec.ProviderType,
+			[]vjailbreakv1alpha1.BMCProviderName{vjailbreakv1alpha1.MAASProvider}))
+	}
+	if bmConfig.Spec.APIUrl == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("apiUrl"), "the API URL of the provider is required"))
+	} else if u, err := url.Parse(bmConfig.Spec.APIUrl); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("apiUrl"), bmConfig.Spec.APIUrl, "must be an http or https URL"))
+	}
+	if bmConfig.Spec.APIKey == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("apiKey"), "the API key of the provider is required"))
+	}
+	return invalid("BMConfig", bmConfig.Name, allErrs)
+}
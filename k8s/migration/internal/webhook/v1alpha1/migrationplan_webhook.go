@@ -0,0 +1,252 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/maintenancewindow"
+	utils "github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+)
+
+// SetupMigrationPlanWebhookWithManager registers the webhooks for MigrationPlan in the manager
+func SetupMigrationPlanWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vjailbreakv1alpha1.MigrationPlan{}).
+		WithDefaulter(&MigrationPlanCustomDefaulter{}).
+		WithValidator(&MigrationPlanCustomValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vjailbreak-k8s-pf9-io-v1alpha1-migrationplan,mutating=true,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=migrationplans,verbs=create;update,versions=v1alpha1,name=mmigrationplan-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// MigrationPlanCustomDefaulter sets the defaults of the MigrationPlans created without the CRD defaults,
+// e.g. by the UI or by older clients
+type MigrationPlanCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &MigrationPlanCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *MigrationPlanCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	migrationplan, ok := obj.(*vjailbreakv1alpha1.MigrationPlan)
+	if !ok {
+		return fmt.Errorf("expected a MigrationPlan object but got %T", obj)
+	}
+	defaultMigrationPlanSpecPerVM(&migrationplan.Spec.MigrationPlanSpecPerVM)
+	for i := range migrationplan.Spec.Waves {
+		if migrationplan.Spec.Waves[i].Gate == "" {
+			migrationplan.Spec.Waves[i].Gate = vjailbreakv1alpha1.WaveGateSucceeded
+		}
+	}
+	return nil
+}
+
+// defaultMigrationPlanSpecPerVM sets the defaults of the migration settings shared by MigrationPlans and
+// RollingMigrationPlans
+func defaultMigrationPlanSpecPerVM(spec *vjailbreakv1alpha1.MigrationPlanSpecPerVM) {
+	strategy := &spec.MigrationStrategy
+	if strategy.HealthCheckPort == "" {
+		strategy.HealthCheckPort = "443"
+	}
+	if strategy.DataVerification == "" {
+		strategy.DataVerification = vjailbreakv1alpha1.DataVerificationNone
+	}
+	if strategy.RollbackPolicy == "" {
+		strategy.RollbackPolicy = vjailbreakv1alpha1.RollbackPolicyNone
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-vjailbreak-k8s-pf9-io-v1alpha1-migrationplan,mutating=false,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=migrationplans,verbs=create;update,versions=v1alpha1,name=vmigrationplan-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// MigrationPlanCustomValidator rejects the MigrationPlans the MigrationPlan controller would fail
+type MigrationPlanCustomValidator struct {
+	// Client reads the objects the plans refer to
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &MigrationPlanCustomValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *MigrationPlanCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	migrationplan, ok := obj.(*vjailbreakv1alpha1.MigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationPlan object but got %T", obj)
+	}
+	return v.validate(ctx, migrationplan, nil)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates leaving the spec as is, e.g. of the finalizers,
+// are always allowed
+func (v *MigrationPlanCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldPlan, ok := oldObj.(*vjailbreakv1alpha1.MigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationPlan object but got %T", oldObj)
+	}
+	migrationplan, ok := newObj.(*vjailbreakv1alpha1.MigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationPlan object but got %T", newObj)
+	}
+	if !migrationplan.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldPlan.Spec, migrationplan.Spec) {
+		return nil, nil
+	}
+	return v.validate(ctx, migrationplan, oldPlan)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *MigrationPlanCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks a MigrationPlan, and the objects it refers to when it is created or they changed
+func (v *MigrationPlanCustomValidator) validate(ctx context.Context, migrationplan, oldPlan *vjailbreakv1alpha1.MigrationPlan) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	spec := &migrationplan.Spec
+	var oldSpec *vjailbreakv1alpha1.MigrationPlanSpecPerVM
+	if oldPlan != nil {
+		oldSpec = &oldPlan.Spec.MigrationPlanSpecPerVM
+	}
+	warnings, allErrs := validateMigrationPlanSpecPerVM(ctx, v.Client, specPath, &spec.MigrationPlanSpecPerVM, oldSpec, migrationplan.Namespace)
+
+	if len(spec.Waves) > 0 {
+		if len(spec.VirtualMachines) > 0 {
+			warnings = append(warnings, "spec.virtualMachines is ignored as the plan has waves")
+		}
+		if err := utils.ValidateMigrationWaves(spec); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("waves"), field.OmitValueType{}, err.Error()))
+		}
+		for i, name := range spec.ApprovedWaves {
+			if !slices.ContainsFunc(spec.Waves, func(wave vjailbreakv1alpha1.MigrationWave) bool { return wave.Name == name }) {
+				allErrs = append(allErrs, field.NotFound(specPath.Child("approvedWaves").Index(i), name))
+			}
+		}
+	} else {
+		allErrs = append(allErrs, validateVirtualMachines(specPath.Child("virtualMachines"), spec.VirtualMachines)...)
+	}
+
+	if spec.BandwidthLimit != nil && spec.BandwidthLimit.TimeZone != "" {
+		if _, err := time.LoadLocation(spec.BandwidthLimit.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("bandwidthLimit", "timeZone"), spec.BandwidthLimit.TimeZone,
+				"unknown time zone"))
+		}
+	}
+
+	if !reflect.DeepEqual(spec.AdvancedOptions, vjailbreakv1alpha1.AdvancedOptions{}) && len(utils.GetMigrationPlanVMs(migrationplan)) > 1 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("advancedOptions"), field.OmitValueType{},
+			"advanced options can only be set on a plan with a single VM"))
+	}
+	return warnings, invalid("MigrationPlan", migrationplan.Name, allErrs)
+}
+
+// validateVirtualMachines checks the parallel groups of VMs of a plan without waves hold each VM once
+func validateVirtualMachines(path *field.Path, virtualMachines [][]string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	seen := map[string]bool{}
+	for i, parallelvms := range virtualMachines {
+		for j, vm := range parallelvms {
+			switch {
+			case vm == "":
+				allErrs = append(allErrs, field.Required(path.Index(i).Index(j), "VM name must not be empty"))
+			case seen[vm]:
+				allErrs = append(allErrs, field.Duplicate(path.Index(i).Index(j), vm))
+			}
+			seen[vm] = true
+		}
+	}
+	if len(seen) == 0 {
+		allErrs = append(allErrs, field.Required(path, "a plan needs VMs, in virtualMachines or in waves"))
+	}
+	return allErrs
+}
+
+// validateMigrationPlanSpecPerVM checks the migration settings shared by MigrationPlans and RollingMigrationPlans.
+// The MigrationTemplate and the MaintenanceWindow are only looked up when the spec is created or they changed,
+// so that the plans can still be updated once they are deleted
+func validateMigrationPlanSpecPerVM(ctx context.Context, reader client.Reader, path *field.Path,
+	spec, oldSpec *vjailbreakv1alpha1.MigrationPlanSpecPerVM, namespace string) (admission.Warnings, field.ErrorList) {
+	warnings := admission.Warnings{}
+	allErrs := field.ErrorList{}
+
+	templatePath := path.Child("migrationTemplate")
+	switch {
+	case spec.MigrationTemplate == "":
+		allErrs = append(allErrs, field.Required(templatePath, "the MigrationTemplate of the plan is required"))
+	case oldSpec == nil || oldSpec.MigrationTemplate != spec.MigrationTemplate:
+		if err := validateReference(ctx, reader, templatePath, &vjailbreakv1alpha1.MigrationTemplate{},
+			"MigrationTemplate", spec.MigrationTemplate, namespace); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	strategy := &spec.MigrationStrategy
+	strategyPath := path.Child("migrationStrategy")
+	if strategy.Type == "cold" && strategy.AdminInitiatedCutOver {
+		allErrs = append(allErrs, field.Invalid(strategyPath.Child("adminInitiatedCutOver"), true,
+			"an admin initiated cutover is not supported by cold migrations, which power the VM off before copying it"))
+	}
+	if !strategy.VMCutoverEnd.IsZero() {
+		if strategy.VMCutoverStart.After(strategy.VMCutoverEnd.Time) {
+			allErrs = append(allErrs, field.Invalid(strategyPath.Child("vmCutoverEnd"), strategy.VMCutoverEnd.Format(time.RFC3339),
+				"must not be before vmCutoverStart"))
+		}
+		if strategy.DataCopyStart.After(strategy.VMCutoverEnd.Time) {
+			allErrs = append(allErrs, field.Invalid(strategyPath.Child("vmCutoverEnd"), strategy.VMCutoverEnd.Format(time.RFC3339),
+				"must not be before dataCopyStart"))
+		}
+	}
+	if strategy.WarmSyncInterval != nil {
+		if strategy.WarmSyncInterval.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(strategyPath.Child("warmSyncInterval"), strategy.WarmSyncInterval.Duration.String(),
+				"must be positive"))
+		} else if strategy.Type != "warm" {
+			warnings = append(warnings, fmt.Sprintf("%s is only used by warm migrations", strategyPath.Child("warmSyncInterval")))
+		}
+	}
+	if strategy.CutoverReadyThreshold != nil && strategy.CutoverReadyThreshold.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(strategyPath.Child("cutoverReadyThreshold"), strategy.CutoverReadyThreshold.Duration.String(),
+			"must not be negative"))
+	}
+	if port, err := strconv.Atoi(strategy.HealthCheckPort); strategy.HealthCheckPort != "" && (err != nil || port < 1 || port > 65535) {
+		allErrs = append(allErrs, field.Invalid(strategyPath.Child("healthCheckPort"), strategy.HealthCheckPort,
+			"must be a port number between 1 and 65535"))
+	}
+	if strategy.RollbackPolicy != "" && strategy.RollbackPolicy != vjailbreakv1alpha1.RollbackPolicyNone && !strategy.PerformHealthChecks {
+		warnings = append(warnings, fmt.Sprintf("%s is only applied when performHealthChecks is set", strategyPath.Child("rollbackPolicy")))
+	}
+	if strategy.MaintenanceWindow != "" && (oldSpec == nil || oldSpec.MigrationStrategy.MaintenanceWindow != strategy.MaintenanceWindow) {
+		windowPath := strategyPath.Child("maintenanceWindow")
+		window := &vjailbreakv1alpha1.MaintenanceWindow{}
+		if err := validateReference(ctx, reader, windowPath, window, "MaintenanceWindow", strategy.MaintenanceWindow, namespace); err != nil {
+			allErrs = append(allErrs, err)
+		} else if _, err := maintenancewindow.Parse(&window.Spec); err != nil {
+			allErrs = append(allErrs, field.Invalid(windowPath, strategy.MaintenanceWindow,
+				fmt.Sprintf("MaintenanceWindow '%s' is invalid: %v", strategy.MaintenanceWindow, err)))
+		}
+	}
+	return warnings, allErrs
+}
@@ -0,0 +1,184 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	webhookv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/internal/webhook/v1alpha1"
+)
+
+func TestMigrationPlanDefaulter(t *testing.T) {
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{
+		ObjectMeta: objectMeta("plan"),
+		Spec:       vjailbreakv1alpha1.MigrationPlanSpec{Waves: []vjailbreakv1alpha1.MigrationWave{{Name: "db", VirtualMachines: []string{"vm1"}}}},
+	}
+	if err := (&webhookv1alpha1.MigrationPlanCustomDefaulter{}).Default(context.Background(), migrationplan); err != nil {
+		t.Fatal(err)
+	}
+	strategy := migrationplan.Spec.MigrationStrategy
+	if strategy.HealthCheckPort != "443" || strategy.DataVerification != vjailbreakv1alpha1.DataVerificationNone ||
+		strategy.RollbackPolicy != vjailbreakv1alpha1.RollbackPolicyNone {
+		t.Errorf("unexpected strategy defaults %+v", strategy)
+	}
+	if migrationplan.Spec.Waves[0].Gate != vjailbreakv1alpha1.WaveGateSucceeded {
+		t.Errorf("expected the wave gate to default to Succeeded, got %q", migrationplan.Spec.Waves[0].Gate)
+	}
+}
+
+func TestMigrationPlanValidator(t *testing.T) {
+	now := time.Now()
+	validPlan := func() *vjailbreakv1alpha1.MigrationPlan {
+		return &vjailbreakv1alpha1.MigrationPlan{
+			ObjectMeta: objectMeta("plan"),
+			Spec: vjailbreakv1alpha1.MigrationPlanSpec{
+				MigrationPlanSpecPerVM: vjailbreakv1alpha1.MigrationPlanSpecPerVM{
+					MigrationTemplate: "template",
+					MigrationStrategy: vjailbreakv1alpha1.MigrationPlanStrategy{Type: "hot", HealthCheckPort: "443"},
+				},
+				VirtualMachines: [][]string{{"vm1", "vm2"}},
+			},
+		}
+	}
+	window := &vjailbreakv1alpha1.MaintenanceWindow{
+		ObjectMeta: objectMeta("weekend"),
+		Spec: vjailbreakv1alpha1.MaintenanceWindowSpec{Windows: []vjailbreakv1alpha1.MaintenanceWindowSchedule{
+			{Schedule: "0 22 * * SAT", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+		}},
+	}
+	brokenWindow := &vjailbreakv1alpha1.MaintenanceWindow{
+		ObjectMeta: objectMeta("broken"),
+		Spec: vjailbreakv1alpha1.MaintenanceWindowSpec{Windows: []vjailbreakv1alpha1.MaintenanceWindowSchedule{
+			{Schedule: "0 25 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+		}},
+	}
+	validator := &webhookv1alpha1.MigrationPlanCustomValidator{
+		Client: newReader(t, &vjailbreakv1alpha1.MigrationTemplate{ObjectMeta: objectMeta("template")}, window, brokenWindow),
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*vjailbreakv1alpha1.MigrationPlan)
+		expected string
+	}{
+		{name: "valid", modify: func(*vjailbreakv1alpha1.MigrationPlan) {}},
+		{
+			name:     "missing template",
+			modify:   func(p *vjailbreakv1alpha1.MigrationPlan) { p.Spec.MigrationTemplate = "other" },
+			expected: "spec.migrationTemplate: Invalid value: \"other\": MigrationTemplate 'other' does not exist in namespace migration-system",
+		},
+		{
+			name:     "no template",
+			modify:   func(p *vjailbreakv1alpha1.MigrationPlan) { p.Spec.MigrationTemplate = "" },
+			expected: "spec.migrationTemplate: Required value",
+		},
+		{
+			name: "cold with admin cutover",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) {
+				p.Spec.MigrationStrategy.Type = "cold"
+				p.Spec.MigrationStrategy.AdminInitiatedCutOver = true
+			},
+			expected: "spec.migrationStrategy.adminInitiatedCutOver",
+		},
+		{
+			name: "cutover end before start",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) {
+				p.Spec.MigrationStrategy.VMCutoverStart = metav1.NewTime(now.Add(time.Hour))
+				p.Spec.MigrationStrategy.VMCutoverEnd = metav1.NewTime(now)
+			},
+			expected: "must not be before vmCutoverStart",
+		},
+		{
+			name:     "health check port",
+			modify:   func(p *vjailbreakv1alpha1.MigrationPlan) { p.Spec.MigrationStrategy.HealthCheckPort = "https" },
+			expected: "spec.migrationStrategy.healthCheckPort",
+		},
+		{
+			name:   "maintenance window",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) { p.Spec.MigrationStrategy.MaintenanceWindow = "weekend" },
+		},
+		{
+			name:     "invalid maintenance window",
+			modify:   func(p *vjailbreakv1alpha1.MigrationPlan) { p.Spec.MigrationStrategy.MaintenanceWindow = "broken" },
+			expected: "MaintenanceWindow 'broken' is invalid",
+		},
+		{
+			name: "bandwidth limit time zone",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) {
+				p.Spec.BandwidthLimit = &vjailbreakv1alpha1.BandwidthLimit{TimeZone: "Europe/Paris"}
+			},
+		},
+		{
+			name: "unknown bandwidth limit time zone",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) {
+				p.Spec.BandwidthLimit = &vjailbreakv1alpha1.BandwidthLimit{TimeZone: "Europe/Atlantis"}
+			},
+			expected: "spec.bandwidthLimit.timeZone: Invalid value: \"Europe/Atlantis\": unknown time zone",
+		},
+		{
+			name:     "no VMs",
+			modify:   func(p *vjailbreakv1alpha1.MigrationPlan) { p.Spec.VirtualMachines = nil },
+			expected: "spec.virtualMachines: Required value",
+		},
+		{
+			name: "duplicate VM",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) {
+				p.Spec.VirtualMachines = append(p.Spec.VirtualMachines, []string{"vm1"})
+			},
+			expected: "spec.virtualMachines[1][0]: Duplicate value: \"vm1\"",
+		},
+		{
+			name: "advanced options with several VMs",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) {
+				p.Spec.AdvancedOptions.GranularNetworks = []string{"private"}
+			},
+			expected: "spec.advancedOptions",
+		},
+		{
+			name: "waves depending on each other",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) {
+				p.Spec.Waves = []vjailbreakv1alpha1.MigrationWave{
+					{Name: "app", VirtualMachines: []string{"vm1"}, DependsOn: []string{"db"}},
+					{Name: "db", VirtualMachines: []string{"vm2"}, DependsOn: []string{"app"}},
+				}
+			},
+			expected: "waves depend on each other",
+		},
+		{
+			name: "unknown approved wave",
+			modify: func(p *vjailbreakv1alpha1.MigrationPlan) {
+				p.Spec.Waves = []vjailbreakv1alpha1.MigrationWave{{Name: "db", VirtualMachines: []string{"vm1"}}}
+				p.Spec.ApprovedWaves = []string{"app"}
+			},
+			expected: "spec.approvedWaves[0]: Not found: \"app\"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrationplan := validPlan()
+			tt.modify(migrationplan)
+			_, err := validator.ValidateCreate(context.Background(), migrationplan)
+			checkError(t, err, tt.expected)
+		})
+	}
+
+	// A plan whose template was deleted can still be updated as long as the template is not changed
+	orphan := validPlan()
+	orphan.Spec.MigrationTemplate = "deleted"
+	updated := orphan.DeepCopy()
+	updated.Spec.Retry = true
+	_, err := validator.ValidateUpdate(context.Background(), orphan, updated)
+	checkError(t, err, "")
+
+	// Settings ignored by the strategy are warned about
+	warm := validPlan()
+	warm.Spec.MigrationStrategy.WarmSyncInterval = &metav1.Duration{Duration: time.Minute}
+	warnings, err := validator.ValidateCreate(context.Background(), warm)
+	checkError(t, err, "")
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning about warmSyncInterval, got %v", warnings)
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// SetupMigrationTemplateWebhookWithManager registers the webhooks for MigrationTemplate in the manager
+func SetupMigrationTemplateWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vjailbreakv1alpha1.MigrationTemplate{}).
+		WithDefaulter(&MigrationTemplateCustomDefaulter{}).
+		WithValidator(&MigrationTemplateCustomValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vjailbreak-k8s-pf9-io-v1alpha1-migrationtemplate,mutating=true,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=migrationtemplates,verbs=create;update,versions=v1alpha1,name=mmigrationtemplate-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// MigrationTemplateCustomDefaulter sets the defaults of MigrationTemplates
+type MigrationTemplateCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &MigrationTemplateCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *MigrationTemplateCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	migrationtemplate, ok := obj.(*vjailbreakv1alpha1.MigrationTemplate)
+	if !ok {
+		return fmt.Errorf("expected a MigrationTemplate object but got %T", obj)
+	}
+	if migrationtemplate.Spec.TargetBackend == "" {
+		migrationtemplate.Spec.TargetBackend = vjailbreakv1alpha1.DiskTargetBackendCinderAttach
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-vjailbreak-k8s-pf9-io-v1alpha1-migrationtemplate,mutating=false,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=migrationtemplates,verbs=create;update,versions=v1alpha1,name=vmigrationtemplate-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// MigrationTemplateCustomValidator rejects the MigrationTemplates referring to missing credentials or mappings
+type MigrationTemplateCustomValidator struct {
+	// Client reads the objects the templates refer to
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &MigrationTemplateCustomValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *MigrationTemplateCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	migrationtemplate, ok := obj.(*vjailbreakv1alpha1.MigrationTemplate)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationTemplate object but got %T", obj)
+	}
+	return nil, v.validate(ctx, migrationtemplate, nil)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates leaving the spec as is are always allowed
+func (v *MigrationTemplateCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldTemplate, ok := oldObj.(*vjailbreakv1alpha1.MigrationTemplate)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationTemplate object but got %T", oldObj)
+	}
+	migrationtemplate, ok := newObj.(*vjailbreakv1alpha1.MigrationTemplate)
+	if !ok {
+		return nil, fmt.Errorf("expected a MigrationTemplate object but got %T", newObj)
+	}
+	if !migrationtemplate.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldTemplate.Spec, migrationtemplate.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(ctx, migrationtemplate, oldTemplate)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *MigrationTemplateCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks a MigrationTemplate. The credentials and mappings it refers to are only looked up when the
+// template is created or they changed. The mappings may be empty, the UI creates them after the template
+func (v *MigrationTemplateCustomValidator) validate(ctx context.Context, migrationtemplate, oldTemplate *vjailbreakv1alpha1.MigrationTemplate) error {
+	specPath := field.NewPath("spec")
+	spec := &migrationtemplate.Spec
+	var oldSpec *vjailbreakv1alpha1.MigrationTemplateSpec
+	if oldTemplate != nil {
+		oldSpec = &oldTemplate.Spec
+	}
+	allErrs := field.ErrorList{}
+
+	references := []struct {
+		path     *field.Path
+		obj      client.Object
+		kind     string
+		name     string
+		oldName  func(*vjailbreakv1alpha1.MigrationTemplateSpec) string
+		required bool
+	}{
+		{
+			path: specPath.Child("source", "vmwareRef"), obj: &vjailbreakv1alpha1.VMwareCreds{}, kind: "VMwareCreds",
+			name:     spec.Source.VMwareRef,
+			oldName:  func(s *vjailbreakv1alpha1.MigrationTemplateSpec) string { return s.Source.VMwareRef },
+			required: true,
+		},
+		{
+			path: specPath.Child("destination", "openstackRef"), obj: &vjailbreakv1alpha1.OpenstackCreds{}, kind: "OpenstackCreds",
+			name:     spec.Destination.OpenstackRef,
+			oldName:  func(s *vjailbreakv1alpha1.MigrationTemplateSpec) string { return s.Destination.OpenstackRef },
+			required: true,
+		},
+		{
+			path: specPath.Child("networkMapping"), obj: &vjailbreakv1alpha1.NetworkMapping{}, kind: "NetworkMapping",
+			name:    spec.NetworkMapping,
+			oldName: func(s *vjailbreakv1alpha1.MigrationTemplateSpec) string { return s.NetworkMapping },
+		},
+		{
+			path: specPath.Child("storageMapping"), obj: &vjailbreakv1alpha1.StorageMapping{}, kind: "StorageMapping",
+			name:    spec.StorageMapping,
+			oldName: func(s *vjailbreakv1alpha1.MigrationTemplateSpec) string { return s.StorageMapping },
+		},
+	}
+	for _, reference := range references {
+		switch {
+		case reference.name == "":
+			if reference.required {
+				allErrs = append(allErrs, field.Required(reference.path, fmt.Sprintf("the %s of the template is required", reference.kind)))
+			}
+		case oldSpec == nil || reference.oldName(oldSpec) != reference.name:
+			if err := validateReference(ctx, v.Client, reference.path, reference.obj, reference.kind, reference.name,
+				migrationtemplate.Namespace); err != nil {
+				allErrs = append(allErrs, err)
+			}
+		}
+	}
+
+	if spec.CephRBD != nil {
+		cephPath := specPath.Child("cephRBD")
+		if spec.TargetBackend != "" && spec.TargetBackend != vjailbreakv1alpha1.DiskTargetBackendCinderAttach {
+			allErrs = append(allErrs, field.Invalid(cephPath, field.OmitValueType{},
+				fmt.Sprintf("is only used with the %s target backend, not %s", vjailbreakv1alpha1.DiskTargetBackendCinderAttach, spec.TargetBackend)))
+		}
+		if spec.CephRBD.KeyringSecret == "" {
+			allErrs = append(allErrs, field.Required(cephPath.Child("keyringSecret"), "the secret holding the Ceph keyring is required"))
+		}
+	}
+	return invalid("MigrationTemplate", migrationtemplate.Name, allErrs)
+}
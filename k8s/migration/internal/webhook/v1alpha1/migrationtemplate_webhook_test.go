@@ -0,0 +1,82 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	webhookv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/internal/webhook/v1alpha1"
+)
+
+func TestMigrationTemplateWebhook(t *testing.T) {
+	ctx := context.Background()
+	validTemplate := func() *vjailbreakv1alpha1.MigrationTemplate {
+		return &vjailbreakv1alpha1.MigrationTemplate{
+			ObjectMeta: objectMeta("template"),
+			Spec: vjailbreakv1alpha1.MigrationTemplateSpec{
+				Source:      vjailbreakv1alpha1.MigrationTemplateSource{VMwareRef: "vcenter"},
+				Destination: vjailbreakv1alpha1.MigrationTemplateDestination{OpenstackRef: "openstack"},
+			},
+		}
+	}
+	validator := &webhookv1alpha1.MigrationTemplateCustomValidator{Client: newReader(t,
+		&vjailbreakv1alpha1.VMwareCreds{ObjectMeta: objectMeta("vcenter")},
+		&vjailbreakv1alpha1.OpenstackCreds{ObjectMeta: objectMeta("openstack")},
+		&vjailbreakv1alpha1.NetworkMapping{ObjectMeta: objectMeta("networks")},
+	)}
+
+	template := validTemplate()
+	if err := (&webhookv1alpha1.MigrationTemplateCustomDefaulter{}).Default(ctx, template); err != nil {
+		t.Fatal(err)
+	}
+	if template.Spec.TargetBackend != vjailbreakv1alpha1.DiskTargetBackendCinderAttach {
+		t.Errorf("expected the target backend to default to CinderAttach, got %q", template.Spec.TargetBackend)
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*vjailbreakv1alpha1.MigrationTemplate)
+		expected string
+	}{
+		// The UI creates the mappings after the template
+		{name: "without mappings", modify: func(*vjailbreakv1alpha1.MigrationTemplate) {}},
+		{
+			name:   "with mappings",
+			modify: func(m *vjailbreakv1alpha1.MigrationTemplate) { m.Spec.NetworkMapping = "networks" },
+		},
+		{
+			name:     "missing storage mapping",
+			modify:   func(m *vjailbreakv1alpha1.MigrationTemplate) { m.Spec.StorageMapping = "storages" },
+			expected: "spec.storageMapping: Invalid value: \"storages\": StorageMapping 'storages' does not exist",
+		},
+		{
+			name:     "no VMware credentials",
+			modify:   func(m *vjailbreakv1alpha1.MigrationTemplate) { m.Spec.Source.VMwareRef = "" },
+			expected: "spec.source.vmwareRef: Required value",
+		},
+		{
+			name: "Ceph RBD with a Glance image backend",
+			modify: func(m *vjailbreakv1alpha1.MigrationTemplate) {
+				m.Spec.TargetBackend = vjailbreakv1alpha1.DiskTargetBackendGlanceImage
+				m.Spec.CephRBD = &vjailbreakv1alpha1.CephRBDSpec{}
+			},
+			expected: "spec.cephRBD.keyringSecret: Required value",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template := validTemplate()
+			tt.modify(template)
+			_, err := validator.ValidateCreate(ctx, template)
+			checkError(t, err, tt.expected)
+		})
+	}
+
+	// Patching the mappings into the template only looks the mappings up
+	template = validTemplate()
+	template.Spec.Source.VMwareRef = "deleted"
+	patched := template.DeepCopy()
+	patched.Spec.NetworkMapping = "networks"
+	_, err := validator.ValidateUpdate(ctx, template, patched)
+	checkError(t, err, "")
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// SetupNetworkMappingWebhookWithManager registers the webhooks for NetworkMapping in the manager
+func SetupNetworkMappingWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vjailbreakv1alpha1.NetworkMapping{}).
+		WithDefaulter(&NetworkMappingCustomDefaulter{}).
+		WithValidator(&NetworkMappingCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vjailbreak-k8s-pf9-io-v1alpha1-networkmapping,mutating=true,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=networkmappings,verbs=create;update,versions=v1alpha1,name=mnetworkmapping-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// NetworkMappingCustomDefaulter removes the networks mapped more than once to the same target
+type NetworkMappingCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &NetworkMappingCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *NetworkMappingCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	networkmapping, ok := obj.(*vjailbreakv1alpha1.NetworkMapping)
+	if !ok {
+		return fmt.Errorf("expected a NetworkMapping object but got %T", obj)
+	}
+	networkmapping.Spec.Networks = removeDuplicates(networkmapping.Spec.Networks)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-vjailbreak-k8s-pf9-io-v1alpha1-networkmapping,mutating=false,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=networkmappings,verbs=create;update,versions=v1alpha1,name=vnetworkmapping-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// NetworkMappingCustomValidator rejects the NetworkMappings with incomplete entries or mapping a network twice
+type NetworkMappingCustomValidator struct{}
+
+var _ admission.CustomValidator = &NetworkMappingCustomValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *NetworkMappingCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	networkmapping, ok := obj.(*vjailbreakv1alpha1.NetworkMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a NetworkMapping object but got %T", obj)
+	}
+	return nil, v.validate(networkmapping)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates leaving the spec as is are always allowed
+func (v *NetworkMappingCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldNetworkMapping, ok := oldObj.(*vjailbreakv1alpha1.NetworkMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a NetworkMapping object but got %T", oldObj)
+	}
+	networkmapping, ok := newObj.(*vjailbreakv1alpha1.NetworkMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a NetworkMapping object but got %T", newObj)
+	}
+	if !networkmapping.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldNetworkMapping.Spec, networkmapping.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(networkmapping)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *NetworkMappingCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *NetworkMappingCustomValidator) validate(networkmapping *vjailbreakv1alpha1.NetworkMapping) error {
+	networks := networkmapping.Spec.Networks
+	allErrs := validateMappings(field.NewPath("spec", "networks"), len(networks), func(i int) (string, string) {
+		return networks[i].Source, networks[i].Target
+	})
+	return invalid("NetworkMapping", networkmapping.Name, allErrs)
+}
+
+// removeDuplicates returns the entries without those equal to an earlier entry
+func removeDuplicates[T comparable](entries []T) []T {
+	seen := map[T]bool{}
+	result := entries[:0]
+	for _, entry := range entries {
+		if !seen[entry] {
+			seen[entry] = true
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// validateMappings checks the count entries of a mapping, whose source and target are returned by entry, have a
+// source and a target and map each source once. A source mapped twice would make the target depend on the order
+// the controllers look the entries up in
+func validateMappings(path *field.Path, count int, entry func(i int) (string, string)) field.ErrorList {
+	allErrs := field.ErrorList{}
+	targets := map[string]string{}
+	for i := range count {
+		source, target := entry(i)
+		if source == "" {
+			allErrs = append(allErrs, field.Required(path.Index(i).Child("source"), "the source of the mapping is required"))
+		}
+		if target == "" {
+			allErrs = append(allErrs, field.Required(path.Index(i).Child("target"), "the target of the mapping is required"))
+		}
+		if other, ok := targets[source]; ok && source != "" {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("source"), source,
+				fmt.Sprintf("is already mapped to '%s', a source can only be mapped once", other)))
+			continue
+		}
+		targets[source] = target
+	}
+	return allErrs
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// SetupOpenstackCredsWebhookWithManager registers the webhooks for OpenstackCreds in the manager
+func SetupOpenstackCredsWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vjailbreakv1alpha1.OpenstackCreds{}).
+		WithDefaulter(&OpenstackCredsCustomDefaulter{}).
+		WithValidator(&OpenstackCredsCustomValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vjailbreak-k8s-pf9-io-v1alpha1-openstackcreds,mutating=true,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=openstackcreds,verbs=create;update,versions=v1alpha1,name=mopenstackcreds-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// OpenstackCredsCustomDefaulter sets the defaults of OpenstackCreds
+type OpenstackCredsCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &OpenstackCredsCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *OpenstackCredsCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	openstackcreds, ok := obj.(*vjailbreakv1alpha1.OpenstackCreds)
+	if !ok {
+		return fmt.Errorf("expected an OpenstackCreds object but got %T", obj)
+	}
+	defaultCredsSecretRef(&openstackcreds.Spec.SecretRef)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-vjailbreak-k8s-pf9-io-v1alpha1-openstackcreds,mutating=false,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=openstackcreds,verbs=create;update,versions=v1alpha1,name=vopenstackcreds-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// OpenstackCredsCustomValidator rejects the OpenstackCreds whose secret cannot be read. The flavors and hosts
+// the controller records in the spec are not checked
+type OpenstackCredsCustomValidator struct {
+	// Client reads the secrets of the credentials
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &OpenstackCredsCustomValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *OpenstackCredsCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	openstackcreds, ok := obj.(*vjailbreakv1alpha1.OpenstackCreds)
+	if !ok {
+		return nil, fmt.Errorf("expected an OpenstackCreds object but got %T", obj)
+	}
+	allErrs := validateCredsSecretRef(ctx, v.Client, field.NewPath("spec", "secretRef"), &openstackcreds.Spec.SecretRef, nil)
+	return nil, invalid("OpenstackCreds", openstackcreds.Name, allErrs)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates leaving the secret reference as is are always allowed
+func (v *OpenstackCredsCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCreds, ok := oldObj.(*vjailbreakv1alpha1.OpenstackCreds)
+	if !ok {
+		return nil, fmt.Errorf("expected an OpenstackCreds object but got %T", oldObj)
+	}
+	openstackcreds, ok := newObj.(*vjailbreakv1alpha1.OpenstackCreds)
+	if !ok {
+		return nil, fmt.Errorf("expected an OpenstackCreds object but got %T", newObj)
+	}
+	if !openstackcreds.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldCreds.Spec.SecretRef, openstackcreds.Spec.SecretRef) {
+		return nil, nil
+	}
+	allErrs := validateCredsSecretRef(ctx, v.Client, field.NewPath("spec", "secretRef"), &openstackcreds.Spec.SecretRef,
+		&oldCreds.Spec.SecretRef)
+	return nil, invalid("OpenstackCreds", openstackcreds.Name, allErrs)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *OpenstackCredsCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
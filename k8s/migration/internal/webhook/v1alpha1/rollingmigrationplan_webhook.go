@@ -0,0 +1,178 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+)
+
+// defaultVMMigrationBatchSize is the number of VMs of a cluster migrated in parallel when not set
+const defaultVMMigrationBatchSize = 10
+
+// SetupRollingMigrationPlanWebhookWithManager registers the webhooks for RollingMigrationPlan in the manager
+func SetupRollingMigrationPlanWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vjailbreakv1alpha1.RollingMigrationPlan{}).
+		WithDefaulter(&RollingMigrationPlanCustomDefaulter{}).
+		WithValidator(&RollingMigrationPlanCustomValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vjailbreak-k8s-pf9-io-v1alpha1-rollingmigrationplan,mutating=true,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=rollingmigrationplans,verbs=create;update,versions=v1alpha1,name=mrollingmigrationplan-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RollingMigrationPlanCustomDefaulter sets the defaults of RollingMigrationPlans
+type RollingMigrationPlanCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &RollingMigrationPlanCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *RollingMigrationPlanCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	rollingmigrationplan, ok := obj.(*vjailbreakv1alpha1.RollingMigrationPlan)
+	if !ok {
+		return fmt.Errorf("expected a RollingMigrationPlan object but got %T", obj)
+	}
+	defaultMigrationPlanSpecPerVM(&rollingmigrationplan.Spec.MigrationPlanSpecPerVM)
+	for i := range rollingmigrationplan.Spec.ClusterSequence {
+		if rollingmigrationplan.Spec.ClusterSequence[i].VMMigrationBatchSize == 0 {
+			rollingmigrationplan.Spec.ClusterSequence[i].VMMigrationBatchSize = defaultVMMigrationBatchSize
+		}
+	}
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-vjailbreak-k8s-pf9-io-v1alpha1-rollingmigrationplan,mutating=false,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=rollingmigrationplans,verbs=create;update,versions=v1alpha1,name=vrollingmigrationplan-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// RollingMigrationPlanCustomValidator rejects the RollingMigrationPlans the RollingMigrationPlan controller would fail
+type RollingMigrationPlanCustomValidator struct {
+	// Client reads the objects the plans refer to
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &RollingMigrationPlanCustomValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *RollingMigrationPlanCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	rollingmigrationplan, ok := obj.(*vjailbreakv1alpha1.RollingMigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a RollingMigrationPlan object but got %T", obj)
+	}
+	return v.validate(ctx, rollingmigrationplan, nil)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates leaving the spec as is are always allowed
+func (v *RollingMigrationPlanCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldPlan, ok := oldObj.(*vjailbreakv1alpha1.RollingMigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a RollingMigrationPlan object but got %T", oldObj)
+	}
+	rollingmigrationplan, ok := newObj.(*vjailbreakv1alpha1.RollingMigrationPlan)
+	if !ok {
+		return nil, fmt.Errorf("expected a RollingMigrationPlan object but got %T", newObj)
+	}
+	if !rollingmigrationplan.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldPlan.Spec, rollingmigrationplan.Spec) {
+		return nil, nil
+	}
+	return v.validate(ctx, rollingmigrationplan, oldPlan)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *RollingMigrationPlanCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate checks a RollingMigrationPlan. Like the controllers, it looks its BMConfig up in the migration system
+// namespace, and only when the plan is created or its BMConfig changed
+func (v *RollingMigrationPlanCustomValidator) validate(ctx context.Context,
+	rollingmigrationplan, oldPlan *vjailbreakv1alpha1.RollingMigrationPlan) (admission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	spec := &rollingmigrationplan.Spec
+	var oldSpec *vjailbreakv1alpha1.MigrationPlanSpecPerVM
+	if oldPlan != nil {
+		oldSpec = &oldPlan.Spec.MigrationPlanSpecPerVM
+	}
+	warnings, allErrs := validateMigrationPlanSpecPerVM(ctx, v.Client, specPath, &spec.MigrationPlanSpecPerVM, oldSpec,
+		rollingmigrationplan.Namespace)
+
+	bmConfigPath := specPath.Child("bmConfigRef", "name")
+	switch {
+	case spec.BMConfigRef.Name == "":
+		allErrs = append(allErrs, field.Required(bmConfigPath, "the BMConfig of the plan is required"))
+	case oldPlan == nil || oldPlan.Spec.BMConfigRef.Name != spec.BMConfigRef.Name:
+		if err := validateReference(ctx, v.Client, bmConfigPath, &vjailbreakv1alpha1.BMConfig{}, "BMConfig",
+			spec.BMConfigRef.Name, constants.NamespaceMigrationSystem); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+
+	sequencePath := specPath.Child("clusterSequence")
+	if len(spec.ClusterSequence) == 0 {
+		allErrs = append(allErrs, field.Required(sequencePath, "the plan needs at least one cluster to migrate"))
+	}
+	clusters := map[string]bool{}
+	vms := map[string]bool{}
+	for i, cluster := range spec.ClusterSequence {
+		clusterPath := sequencePath.Index(i)
+		switch {
+		case cluster.ClusterName == "":
+			allErrs = append(allErrs, field.Required(clusterPath.Child("clusterName"), "cluster name must not be empty"))
+		case clusters[cluster.ClusterName]:
+			allErrs = append(allErrs, field.Duplicate(clusterPath.Child("clusterName"), cluster.ClusterName))
+		}
+		clusters[cluster.ClusterName] = true
+		if cluster.VMMigrationBatchSize < 1 {
+			allErrs = append(allErrs, field.Invalid(clusterPath.Child("vmMigrationBatchSize"), cluster.VMMigrationBatchSize,
+				"must be at least 1"))
+		}
+		for j, vm := range cluster.VMSequence {
+			vmPath := clusterPath.Child("vmSequence").Index(j).Child("vmName")
+			switch {
+			case vm.VMName == "":
+				allErrs = append(allErrs, field.Required(vmPath, "VM name must not be empty"))
+			case vms[vm.VMName]:
+				allErrs = append(allErrs, field.Duplicate(vmPath, vm.VMName))
+			}
+			vms[vm.VMName] = true
+		}
+	}
+
+	mappingPath := specPath.Child("clusterMapping")
+	mapped := map[string]bool{}
+	for i, mapping := range spec.ClusterMapping {
+		if mapping.PCDClusterName == "" {
+			allErrs = append(allErrs, field.Required(mappingPath.Index(i).Child("pcdClusterName"), "PCD cluster name must not be empty"))
+		}
+		vmwarePath := mappingPath.Index(i).Child("vmwareClusterName")
+		switch {
+		case mapping.VMwareClusterName == "":
+			allErrs = append(allErrs, field.Required(vmwarePath, "vCenter cluster name must not be empty"))
+		case mapped[mapping.VMwareClusterName]:
+			allErrs = append(allErrs, field.Duplicate(vmwarePath, mapping.VMwareClusterName))
+		}
+		mapped[mapping.VMwareClusterName] = true
+	}
+	return warnings, invalid("RollingMigrationPlan", rollingmigrationplan.Name, allErrs)
+}
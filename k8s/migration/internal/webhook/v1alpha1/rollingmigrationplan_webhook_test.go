@@ -0,0 +1,98 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	webhookv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/internal/webhook/v1alpha1"
+)
+
+func TestRollingMigrationPlanWebhook(t *testing.T) {
+	ctx := context.Background()
+	validPlan := func() *vjailbreakv1alpha1.RollingMigrationPlan {
+		return &vjailbreakv1alpha1.RollingMigrationPlan{
+			ObjectMeta: objectMeta("rolling"),
+			Spec: vjailbreakv1alpha1.RollingMigrationPlanSpec{
+				BMConfigRef: corev1.LocalObjectReference{Name: "maas"},
+				ClusterSequence: []vjailbreakv1alpha1.ClusterMigrationInfo{{
+					ClusterName: "cluster1",
+					VMSequence:  []vjailbreakv1alpha1.VMSequenceInfo{{VMName: "vm1"}, {VMName: "vm2"}},
+				}},
+				ClusterMapping: []vjailbreakv1alpha1.ClusterMapping{{VMwareClusterName: "cluster1", PCDClusterName: "pcd1"}},
+				MigrationPlanSpecPerVM: vjailbreakv1alpha1.MigrationPlanSpecPerVM{
+					MigrationTemplate: "template",
+					MigrationStrategy: vjailbreakv1alpha1.MigrationPlanStrategy{Type: "hot"},
+				},
+			},
+		}
+	}
+	defaulter := &webhookv1alpha1.RollingMigrationPlanCustomDefaulter{}
+	validator := &webhookv1alpha1.RollingMigrationPlanCustomValidator{Client: newReader(t,
+		&vjailbreakv1alpha1.BMConfig{ObjectMeta: objectMeta("maas")},
+		&vjailbreakv1alpha1.MigrationTemplate{ObjectMeta: objectMeta("template")},
+	)}
+
+	tests := []struct {
+		name     string
+		modify   func(*vjailbreakv1alpha1.RollingMigrationPlan)
+		expected string
+	}{
+		{name: "valid", modify: func(*vjailbreakv1alpha1.RollingMigrationPlan) {}},
+		{
+			name:     "missing BMConfig",
+			modify:   func(p *vjailbreakv1alpha1.RollingMigrationPlan) { p.Spec.BMConfigRef.Name = "ironic" },
+			expected: "spec.bmConfigRef.name: Invalid value: \"ironic\": BMConfig 'ironic' does not exist in namespace migration-system",
+		},
+		{
+			name:     "no BMConfig",
+			modify:   func(p *vjailbreakv1alpha1.RollingMigrationPlan) { p.Spec.BMConfigRef.Name = "" },
+			expected: "spec.bmConfigRef.name: Required value",
+		},
+		{
+			name: "VM in two clusters",
+			modify: func(p *vjailbreakv1alpha1.RollingMigrationPlan) {
+				p.Spec.ClusterSequence = append(p.Spec.ClusterSequence, vjailbreakv1alpha1.ClusterMigrationInfo{
+					ClusterName: "cluster2", VMSequence: []vjailbreakv1alpha1.VMSequenceInfo{{VMName: "vm2"}},
+				})
+			},
+			expected: "spec.clusterSequence[1].vmSequence[0].vmName: Duplicate value: \"vm2\"",
+		},
+		{
+			name: "cluster mapped twice",
+			modify: func(p *vjailbreakv1alpha1.RollingMigrationPlan) {
+				p.Spec.ClusterMapping = append(p.Spec.ClusterMapping, vjailbreakv1alpha1.ClusterMapping{VMwareClusterName: "cluster1", PCDClusterName: "pcd2"})
+			},
+			expected: "spec.clusterMapping[1].vmwareClusterName: Duplicate value",
+		},
+		{
+			name: "cold with admin cutover",
+			modify: func(p *vjailbreakv1alpha1.RollingMigrationPlan) {
+				p.Spec.MigrationStrategy.Type = "cold"
+				p.Spec.MigrationStrategy.AdminInitiatedCutOver = true
+			},
+			expected: "spec.migrationStrategy.adminInitiatedCutOver",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rollingmigrationplan := validPlan()
+			tt.modify(rollingmigrationplan)
+			if err := defaulter.Default(ctx, rollingmigrationplan); err != nil {
+				t.Fatal(err)
+			}
+			_, err := validator.ValidateCreate(ctx, rollingmigrationplan)
+			checkError(t, err, tt.expected)
+		})
+	}
+
+	rollingmigrationplan := validPlan()
+	if err := defaulter.Default(ctx, rollingmigrationplan); err != nil {
+		t.Fatal(err)
+	}
+	if size := rollingmigrationplan.Spec.ClusterSequence[0].VMMigrationBatchSize; size != 10 {
+		t.Errorf("expected the batch size to default to 10, got %d", size)
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// SetupStorageMappingWebhookWithManager registers the webhooks for StorageMapping in the manager
+func SetupStorageMappingWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vjailbreakv1alpha1.StorageMapping{}).
+		WithDefaulter(&StorageMappingCustomDefaulter{}).
+		WithValidator(&StorageMappingCustomValidator{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vjailbreak-k8s-pf9-io-v1alpha1-storagemapping,mutating=true,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=storagemappings,verbs=create;update,versions=v1alpha1,name=mstoragemapping-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// StorageMappingCustomDefaulter removes the datastores mapped more than once to the same target and the volume
+// types listed more than once
+type StorageMappingCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &StorageMappingCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *StorageMappingCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	storagemapping, ok := obj.(*vjailbreakv1alpha1.StorageMapping)
+	if !ok {
+		return fmt.Errorf("expected a StorageMapping object but got %T", obj)
+	}
+	storagemapping.Spec.Storages = removeDuplicates(storagemapping.Spec.Storages)
+	storagemapping.Spec.ZeroedVolumeTypes = removeDuplicates(storagemapping.Spec.ZeroedVolumeTypes)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-vjailbreak-k8s-pf9-io-v1alpha1-storagemapping,mutating=false,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=storagemappings,verbs=create;update,versions=v1alpha1,name=vstoragemapping-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// StorageMappingCustomValidator rejects the StorageMappings with incomplete entries or mapping a datastore twice
+type StorageMappingCustomValidator struct{}
+
+var _ admission.CustomValidator = &StorageMappingCustomValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *StorageMappingCustomValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	storagemapping, ok := obj.(*vjailbreakv1alpha1.StorageMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a StorageMapping object but got %T", obj)
+	}
+	return nil, v.validate(storagemapping)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates leaving the spec as is are always allowed
+func (v *StorageMappingCustomValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldStorageMapping, ok := oldObj.(*vjailbreakv1alpha1.StorageMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a StorageMapping object but got %T", oldObj)
+	}
+	storagemapping, ok := newObj.(*vjailbreakv1alpha1.StorageMapping)
+	if !ok {
+		return nil, fmt.Errorf("expected a StorageMapping object but got %T", newObj)
+	}
+	if !storagemapping.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldStorageMapping.Spec, storagemapping.Spec) {
+		return nil, nil
+	}
+	return nil, v.validate(storagemapping)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *StorageMappingCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *StorageMappingCustomValidator) validate(storagemapping *vjailbreakv1alpha1.StorageMapping) error {
+	storages := storagemapping.Spec.Storages
+	allErrs := validateMappings(field.NewPath("spec", "storages"), len(storages), func(i int) (string, string) {
+		return storages[i].Source, storages[i].Target
+	})
+	for i, volumeType := range storagemapping.Spec.ZeroedVolumeTypes {
+		if volumeType == "" {
+			allErrs = append(allErrs, field.Required(field.NewPath("spec", "zeroedVolumeTypes").Index(i), "volume type must not be empty"))
+		}
+	}
+	return invalid("StorageMapping", storagemapping.Name, allErrs)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// SetupVMwareCredsWebhookWithManager registers the webhooks for VMwareCreds in the manager
+func SetupVMwareCredsWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(&vjailbreakv1alpha1.VMwareCreds{}).
+		WithDefaulter(&VMwareCredsCustomDefaulter{}).
+		WithValidator(&VMwareCredsCustomValidator{Client: mgr.GetAPIReader()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-vjailbreak-k8s-pf9-io-v1alpha1-vmwarecreds,mutating=true,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=vmwarecreds,verbs=create;update,versions=v1alpha1,name=mvmwarecreds-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// VMwareCredsCustomDefaulter sets the defaults of VMwareCreds
+type VMwareCredsCustomDefaulter struct{}
+
+var _ admission.CustomDefaulter = &VMwareCredsCustomDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *VMwareCredsCustomDefaulter) Default(_ context.Context, obj runtime.Object) error {
+	vmwcreds, ok := obj.(*vjailbreakv1alpha1.VMwareCreds)
+	if !ok {
+		return fmt.Errorf("expected a VMwareCreds object but got %T", obj)
+	}
+	defaultCredsSecretRef(&vmwcreds.Spec.SecretRef)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-vjailbreak-k8s-pf9-io-v1alpha1-vmwarecreds,mutating=false,failurePolicy=fail,sideEffects=None,groups=vjailbreak.k8s.pf9.io,resources=vmwarecreds,verbs=create;update,versions=v1alpha1,name=vvmwarecreds-v1alpha1.kb.io,admissionReviewVersions=v1
+
+// VMwareCredsCustomValidator rejects the VMwareCreds whose secret cannot be read
+type VMwareCredsCustomValidator struct {
+	// Client reads the secrets of the credentials
+	Client client.Reader
+}
+
+var _ admission.CustomValidator = &VMwareCredsCustomValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *VMwareCredsCustomValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	vmwcreds, ok := obj.(*vjailbreakv1alpha1.VMwareCreds)
+	if !ok {
+		return nil, fmt.Errorf("expected a VMwareCreds object but got %T", obj)
+	}
+	allErrs := validateCredsSecretRef(ctx, v.Client, field.NewPath("spec", "secretRef"), &vmwcreds.Spec.SecretRef, nil)
+	return nil, invalid("VMwareCreds", vmwcreds.Name, allErrs)
+}
+
+// ValidateUpdate implements admission.CustomValidator. Updates leaving the spec as is are always allowed
+func (v *VMwareCredsCustomValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldCreds, ok := oldObj.(*vjailbreakv1alpha1.VMwareCreds)
+	if !ok {
+		return nil, fmt.Errorf("expected a VMwareCreds object but got %T", oldObj)
+	}
+	vmwcreds, ok := newObj.(*vjailbreakv1alpha1.VMwareCreds)
+	if !ok {
+		return nil, fmt.Errorf("expected a VMwareCreds object but got %T", newObj)
+	}
+	if !vmwcreds.DeletionTimestamp.IsZero() || equality.Semantic.DeepEqual(oldCreds.Spec, vmwcreds.Spec) {
+		return nil, nil
+	}
+	allErrs := validateCredsSecretRef(ctx, v.Client, field.NewPath("spec", "secretRef"), &vmwcreds.Spec.SecretRef,
+		&oldCreds.Spec.SecretRef)
+	return nil, invalid("VMwareCreds", vmwcreds.Name, allErrs)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *VMwareCredsCustomValidator) ValidateDelete(_ context.Context, _ runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
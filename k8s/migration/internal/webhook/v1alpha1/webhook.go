@@ -0,0 +1,82 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 holds the defaulting and validating admission webhooks of the vjailbreak v1alpha1 API.
+// They reject the specs the controllers would only fail on while reconciling, at the time they are applied
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+)
+
+// invalid returns the error rejecting an object of the given kind with the given field errors, nil without errors
+func invalid(kind, name string, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: vjailbreakv1alpha1.GroupVersion.Group, Kind: kind}, name, allErrs)
+}
+
+// validateReference returns an error when the object of the given kind a field refers to does not exist
+func validateReference(ctx context.Context, reader client.Reader, path *field.Path,
+	obj client.Object, kind, name, namespace string) *field.Error {
+	if err := reader.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return field.Invalid(path, name, fmt.Sprintf("%s '%s' does not exist in namespace %s", kind, name, namespace))
+		}
+		return field.InternalError(path, fmt.Errorf("failed to get %s '%s': %w", kind, name, err))
+	}
+	return nil
+}
+
+// defaultCredsSecretRef sets the namespace of the secret of credentials to the namespace the controllers read it from
+func defaultCredsSecretRef(secretRef *corev1.ObjectReference) {
+	if secretRef.Namespace == "" {
+		secretRef.Namespace = constants.NamespaceMigrationSystem
+	}
+}
+
+// validateCredsSecretRef checks the secret holding credentials is in the migration system namespace, and that it
+// exists when the credentials are created or it changed
+func validateCredsSecretRef(ctx context.Context, reader client.Reader, path *field.Path,
+	secretRef, oldSecretRef *corev1.ObjectReference) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if secretRef.Namespace != "" && secretRef.Namespace != constants.NamespaceMigrationSystem {
+		allErrs = append(allErrs, field.Invalid(path.Child("namespace"), secretRef.Namespace,
+			fmt.Sprintf("the secret is read from namespace %s", constants.NamespaceMigrationSystem)))
+	}
+	switch {
+	case secretRef.Name == "":
+		allErrs = append(allErrs, field.Required(path.Child("name"), "the secret holding the credentials is required"))
+	case oldSecretRef == nil || oldSecretRef.Name != secretRef.Name:
+		if err := validateReference(ctx, reader, path.Child("name"), &corev1.Secret{}, "Secret", secretRef.Name,
+			constants.NamespaceMigrationSystem); err != nil {
+			allErrs = append(allErrs, err)
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,153 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	webhookv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/internal/webhook/v1alpha1"
+)
+
+const namespace = "migration-system"
+
+func objectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{Name: name, Namespace: namespace}
+}
+
+func newReader(t *testing.T, objs ...client.Object) client.Reader {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := vjailbreakv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+// checkError fails the test unless err is nil when expected is empty, or mentions expected otherwise
+func checkError(t *testing.T, err error, expected string) {
+	t.Helper()
+	switch {
+	case expected == "" && err != nil:
+		t.Errorf("expected no error, got %v", err)
+	case expected != "" && (err == nil || !strings.Contains(err.Error(), expected)):
+		t.Errorf("expected an error mentioning %q, got %v", expected, err)
+	}
+}
+
+func TestNetworkMappingWebhook(t *testing.T) {
+	ctx := context.Background()
+	mapping := &vjailbreakv1alpha1.NetworkMapping{
+		ObjectMeta: objectMeta("networks"),
+		Spec: vjailbreakv1alpha1.NetworkMappingSpec{Networks: []vjailbreakv1alpha1.Network{
+			{Source: "VM Network", Target: "private"},
+			{Source: "VM Network", Target: "private"},
+			{Source: "DMZ", Target: "public"},
+		}},
+	}
+	if err := (&webhookv1alpha1.NetworkMappingCustomDefaulter{}).Default(ctx, mapping); err != nil {
+		t.Fatal(err)
+	}
+	if len(mapping.Spec.Networks) != 2 {
+		t.Errorf("expected the repeated entry to be removed, got %v", mapping.Spec.Networks)
+	}
+	validator := &webhookv1alpha1.NetworkMappingCustomValidator{}
+	_, err := validator.ValidateCreate(ctx, mapping)
+	checkError(t, err, "")
+
+	conflicting := mapping.DeepCopy()
+	conflicting.Spec.Networks = append(conflicting.Spec.Networks, vjailbreakv1alpha1.Network{Source: "DMZ", Target: "private"}, vjailbreakv1alpha1.Network{Source: "Backup"})
+	_, err = validator.ValidateCreate(ctx, conflicting)
+	checkError(t, err, "spec.networks[2].source: Invalid value: \"DMZ\": is already mapped to 'public'")
+	checkError(t, err, "spec.networks[3].target: Required value")
+
+	// Updates leaving the spec as is, e.g. removing a finalizer, are allowed
+	_, err = validator.ValidateUpdate(ctx, conflicting, conflicting.DeepCopy())
+	checkError(t, err, "")
+}
+
+func TestStorageMappingWebhook(t *testing.T) {
+	ctx := context.Background()
+	mapping := &vjailbreakv1alpha1.StorageMapping{
+		ObjectMeta: objectMeta("storages"),
+		Spec: vjailbreakv1alpha1.StorageMappingSpec{
+			Storages:          []vjailbreakv1alpha1.Storage{{Source: "datastore1", Target: "ceph"}, {Source: "datastore1", Target: "lvm"}},
+			ZeroedVolumeTypes: []string{"ceph", "ceph"},
+		},
+	}
+	if err := (&webhookv1alpha1.StorageMappingCustomDefaulter{}).Default(ctx, mapping); err != nil {
+		t.Fatal(err)
+	}
+	if len(mapping.Spec.ZeroedVolumeTypes) != 1 {
+		t.Errorf("expected the repeated volume type to be removed, got %v", mapping.Spec.ZeroedVolumeTypes)
+	}
+	_, err := (&webhookv1alpha1.StorageMappingCustomValidator{}).ValidateCreate(ctx, mapping)
+	checkError(t, err, "spec.storages[1].source")
+}
+
+func TestCredsWebhooks(t *testing.T) {
+	ctx := context.Background()
+	reader := newReader(t, &corev1.Secret{ObjectMeta: objectMeta("vcenter-secret")})
+
+	vmwcreds := &vjailbreakv1alpha1.VMwareCreds{
+		ObjectMeta: objectMeta("vcenter"),
+		Spec:       vjailbreakv1alpha1.VMwareCredsSpec{SecretRef: corev1.ObjectReference{Name: "vcenter-secret"}},
+	}
+	if err := (&webhookv1alpha1.VMwareCredsCustomDefaulter{}).Default(ctx, vmwcreds); err != nil {
+		t.Fatal(err)
+	}
+	if vmwcreds.Spec.SecretRef.Namespace != namespace {
+		t.Errorf("expected the secret namespace to default to %s, got %q", namespace, vmwcreds.Spec.SecretRef.Namespace)
+	}
+	vmwValidator := &webhookv1alpha1.VMwareCredsCustomValidator{Client: reader}
+	_, err := vmwValidator.ValidateCreate(ctx, vmwcreds)
+	checkError(t, err, "")
+
+	openstackcreds := &vjailbreakv1alpha1.OpenstackCreds{
+		ObjectMeta: objectMeta("openstack"),
+		Spec:       vjailbreakv1alpha1.OpenstackCredsSpec{SecretRef: corev1.ObjectReference{Name: "openstack-secret", Namespace: "default"}},
+	}
+	osValidator := &webhookv1alpha1.OpenstackCredsCustomValidator{Client: reader}
+	_, err = osValidator.ValidateCreate(ctx, openstackcreds)
+	checkError(t, err, "Secret 'openstack-secret' does not exist")
+	checkError(t, err, "spec.secretRef.namespace")
+
+	// The flavors recorded by the controller do not trigger the secret lookup again
+	updated := openstackcreds.DeepCopy()
+	updated.Spec.ProjectName = "service"
+	_, err = osValidator.ValidateUpdate(ctx, openstackcreds, updated)
+	checkError(t, err, "")
+}
+
+func TestBMConfigWebhook(t *testing.T) {
+	ctx := context.Background()
+	bmConfig := &vjailbreakv1alpha1.BMConfig{
+		ObjectMeta: objectMeta("maas"),
+		Spec:       vjailbreakv1alpha1.BMConfigSpec{APIUrl: "http://maas.example.com:5240/MAAS", APIKey: "consumer:token:secret"},
+	}
+	if err := (&webhookv1alpha1.BMConfigCustomDefaulter{}).Default(ctx, bmConfig); err != nil {
+		t.Fatal(err)
+	}
+	if bmConfig.Spec.ProviderType != vjailbreakv1alpha1.MAASProvider || bmConfig.Spec.BootSource.Release != "jammy" {
+		t.Errorf("expected the MAAS provider and the jammy release, got %+v", bmConfig.Spec)
+	}
+	validator := &webhookv1alpha1.BMConfigCustomValidator{}
+	_, err := validator.ValidateCreate(ctx, bmConfig)
+	checkError(t, err, "")
+
+	bmConfig.Spec.APIUrl = "maas.example.com"
+	bmConfig.Spec.APIKey = ""
+	_, err = validator.ValidateCreate(ctx, bmConfig)
+	checkError(t, err, "spec.apiUrl: Invalid value")
+	checkError(t, err, "spec.apiKey: Required value")
+}
@@ -24,9 +24,19 @@ const (
 	// MaxJobNameLength defines the maximum length of a job name
 	MaxJobNameLength = 46 // 63 - 11 (prefix v2v-helper-) - 1 (hyphen) - 5 (hash)
 
+	// MigrationJobBackoffLimit is the number of times the job of a migration starts a new pod to resume
+	// the migration after its pod stopped with a resumable checkpoint
+	MigrationJobBackoffLimit = int32(5)
+
 	// VjailbreakNodeControllerName is the name of the vjailbreak node controller
 	VjailbreakNodeControllerName = "vjailbreaknode-controller"
 
+	// VjailbreakNodeAutoscalerControllerName is the name of the vjailbreak node autoscaler controller
+	VjailbreakNodeAutoscalerControllerName = "vjailbreaknodeautoscaler-controller"
+
+	// MaintenanceWindowControllerName is the name of the maintenance window controller
+	MaintenanceWindowControllerName = "maintenancewindow-controller"
+
 	// OpenstackCredsControllerName is the name of the openstack credentials controller
 	OpenstackCredsControllerName = "openstackcreds-controller" //nolint:gosec // not a password string
 
@@ -96,6 +106,25 @@ const (
 	// NodeRoleMaster is the role of the master node
 	NodeRoleMaster = "master"
 
+	// NodeRoleWorker is the role of the worker nodes
+	NodeRoleWorker = "worker"
+
+	// AgentNamePrefix prefixes the names of the worker nodes
+	AgentNamePrefix = "vjailbreak-agent-"
+
+	// AutoscalerLabel holds on the VjailbreakNodes created by an autoscaler the name of the autoscaler
+	AutoscalerLabel = "vjailbreak.k8s.pf9.io/autoscaler"
+
+	// DefaultScaleDownDelay is how long a node added by an autoscaler has to be idle before it is removed
+	// when the autoscaler does not set it
+	DefaultScaleDownDelay = 15 * time.Minute
+
+	// AutoscalerRecheckInterval is how often the autoscalers check the queued migrations and their nodes
+	AutoscalerRecheckInterval = 30 * time.Second
+
+	// MaintenanceWindowRecheckInterval is the longest a maintenance window waits before its status is refreshed
+	MaintenanceWindowRecheckInterval = time.Hour
+
 	// InternalIPAnnotation is the annotation for internal IP
 	InternalIPAnnotation = "k3s.io/internal-ip"
 
@@ -150,8 +179,25 @@ const (
 	// ENVFileLocation is the location of the env file
 	ENVFileLocation = "/etc/pf9/k3s.env"
 
-	// MigrationTriggerDelay is the delay for migration trigger
-	MigrationTriggerDelay = 5 * time.Second
+	// SchedulerRecheckInterval is how often migrations queued for lack of room on the agents are scheduled again
+	SchedulerRecheckInterval = 30 * time.Second
+
+	// DefaultMaxConcurrentMigrationsPerNode is the number of migrations a node runs at the same time
+	// when its VjailbreakNode does not set it
+	DefaultMaxConcurrentMigrationsPerNode = 5
+
+	// DefaultMaxVolumeAttachmentsPerNode is the number of Cinder volumes that can be attached to a node at the same
+	// time when its VjailbreakNode does not set it
+	DefaultMaxVolumeAttachmentsPerNode = 25
+
+	// VolumeAttachmentsAnnotation records on a migration Job the number of volumes it attaches to its node
+	VolumeAttachmentsAnnotation = "vjailbreak.k8s.pf9.io/volume-attachments"
+
+	// BandwidthMBpsAnnotation records on a migration Job the copy bandwidth limit of its migration, 0 if unlimited
+	BandwidthMBpsAnnotation = "vjailbreak.k8s.pf9.io/bandwidth-mbps"
+
+	// CapacityRecheckInterval is how often the quota of the target project is checked again for VMs waiting for capacity
+	CapacityRecheckInterval = time.Minute
 
 	// MigrationReason is the reason for migration
 	MigrationReason = "Migration"
@@ -241,19 +287,57 @@ runcmd:
 	MigrationConditionTypeValidated corev1.PodConditionType = "Validated"
 	MigrationConditionTypeFailed    corev1.PodConditionType = "Failed"
 
+	// MigrationConditionTypeCapacity represents the condition type for the quota check of the target project
+	MigrationConditionTypeCapacity corev1.PodConditionType = "CapacityCheck"
+
+	// MigrationConditionTypeScheduled represents the condition type for the placement of the migration on an agent
+	MigrationConditionTypeScheduled corev1.PodConditionType = "Scheduled"
+
+	// ScheduledReasonPlaced is the reason of a true Scheduled condition
+	ScheduledReasonPlaced = "PlacedOnAgent"
+
+	// ScheduledReasonQueued is the reason of a false Scheduled condition
+	ScheduledReasonQueued = "WaitingForAgent"
+
+	// ScheduledReasonUnschedulable is the reason of a false Scheduled condition when no agent can run the migration
+	ScheduledReasonUnschedulable = "NoAgentCanRun"
+
+	// MigrationConditionTypeCutoverReady represents the condition type for the estimated downtime of a cutover
+	// being within the threshold of the migration plan
+	MigrationConditionTypeCutoverReady corev1.PodConditionType = "CutoverReady"
+
+	// CutoverReadyReasonWithinThreshold is the reason of a true CutoverReady condition
+	CutoverReadyReasonWithinThreshold = "DowntimeWithinThreshold"
+
+	// CutoverReadyReasonAboveThreshold is the reason of a false CutoverReady condition
+	CutoverReadyReasonAboveThreshold = "DowntimeAboveThreshold"
+
+	// MigrationConditionTypeHealthCheck represents the condition type for the health checks of the migrated VM
+	MigrationConditionTypeHealthCheck corev1.PodConditionType = "HealthCheck"
+
+	// HealthCheckReasonPassed is the reason of a true HealthCheck condition
+	HealthCheckReasonPassed = "HealthChecksPassed"
+
+	// HealthCheckReasonFailed is the reason of a false HealthCheck condition
+	HealthCheckReasonFailed = "HealthChecksFailed"
+
 	// VMMigrationStatesEnum is a map of migration phase to state
 	VMMigrationStatesEnum = map[vjailbreakv1alpha1.VMMigrationPhase]int{
 		vjailbreakv1alpha1.VMMigrationPhasePending:                  0,
-		vjailbreakv1alpha1.VMMigrationPhaseValidating:               1,
-		vjailbreakv1alpha1.VMMigrationPhaseFailed:                   2,
-		vjailbreakv1alpha1.VMMigrationPhaseAwaitingDataCopyStart:    3,
-		vjailbreakv1alpha1.VMMigrationPhaseCopying:                  4,
-		vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks:     5,
-		vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk:           6,
-		vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime: 7,
-		vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver:     8,
-		vjailbreakv1alpha1.VMMigrationPhaseSucceeded:                9,
-		vjailbreakv1alpha1.VMMigrationPhaseUnknown:                  10,
+		vjailbreakv1alpha1.VMMigrationPhaseWaitingForCapacity:       1,
+		vjailbreakv1alpha1.VMMigrationPhaseValidating:               2,
+		vjailbreakv1alpha1.VMMigrationPhaseFailed:                   3,
+		vjailbreakv1alpha1.VMMigrationPhaseAwaitingDataCopyStart:    4,
+		vjailbreakv1alpha1.VMMigrationPhaseCopying:                  5,
+		vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks:     6,
+		vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk:           7,
+		vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime: 8,
+		vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver:     9,
+		vjailbreakv1alpha1.VMMigrationPhaseSucceeded:                10,
+		vjailbreakv1alpha1.VMMigrationPhaseRolledBack:               11,
+		vjailbreakv1alpha1.VMMigrationPhaseInspecting:               12,
+		vjailbreakv1alpha1.VMMigrationPhaseInspected:                13,
+		vjailbreakv1alpha1.VMMigrationPhaseUnknown:                  14,
 	}
 
 	// MigrationJobTTL is the TTL for migration job
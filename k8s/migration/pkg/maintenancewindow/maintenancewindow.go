@@ -0,0 +1,389 @@
+// Package maintenancewindow evaluates the windows of a MaintenanceWindow. A window opens on a cron or RRULE
+// schedule in the time zone of the MaintenanceWindow and stays open for its duration, except on blackout dates.
+// The controller reports the next window in the status of the MaintenanceWindow, and v2v-helper only starts the
+// cutover of a VM inside a window.
+package maintenancewindow
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// Horizon is how far ahead windows are looked for
+const Horizon = 366 * 24 * time.Hour
+
+// blackoutDateFormat is the format of the blackout dates
+const blackoutDateFormat = "2006-01-02"
+
+// Interval is a time range during which cutovers may start
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Calendar holds the parsed windows and blackout dates of a MaintenanceWindow
+type Calendar struct {
+	location  *time.Location
+	windows   []window
+	blackouts []Interval
+}
+
+type window struct {
+	schedule *schedule
+	duration time.Duration
+}
+
+// schedule is a set of minutes, hours, days of the month, months and days of the week a window opens at
+type schedule struct {
+	minutes  uint64
+	hours    uint64
+	days     uint64
+	months   uint64
+	weekdays uint64
+	// anyDay and anyWeekday are set when the days of the month or of the week are not restricted
+	anyDay     bool
+	anyWeekday bool
+	// allDayFields requires both the day of the month and of the week to match when both are restricted.
+	// Cron only requires one of them to match
+	allDayFields bool
+}
+
+// Parse parses the windows and blackout dates of a MaintenanceWindow
+func Parse(spec *vjailbreakv1alpha1.MaintenanceWindowSpec) (*Calendar, error) {
+	location := time.UTC
+	if spec.TimeZone != "" {
+		var err error
+		location, err = time.LoadLocation(spec.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("unknown time zone '%s': %w", spec.TimeZone, err)
+		}
+	}
+	calendar := &Calendar{location: location}
+	if len(spec.Windows) == 0 {
+		return nil, fmt.Errorf("no window is defined")
+	}
+	for i, w := range spec.Windows {
+		sched, err := parseSchedule(w.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %w", i+1, err)
+		}
+		if w.Duration.Duration <= 0 {
+			return nil, fmt.Errorf("window %d: the duration must be positive", i+1)
+		}
+		calendar.windows = append(calendar.windows, window{schedule: sched, duration: w.Duration.Duration})
+	}
+	for i, blackout := range spec.Blackouts {
+		start, err := time.ParseInLocation(blackoutDateFormat, blackout.Start, location)
+		if err != nil {
+			return nil, fmt.Errorf("blackout %d: invalid start date '%s'", i+1, blackout.Start)
+		}
+		end := start
+		if blackout.End != "" {
+			end, err = time.ParseInLocation(blackoutDateFormat, blackout.End, location)
+			if err != nil {
+				return nil, fmt.Errorf("blackout %d: invalid end date '%s'", i+1, blackout.End)
+			}
+		}
+		if end.Before(start) {
+			return nil, fmt.Errorf("blackout %d ends before it starts", i+1)
+		}
+		// The end date is included
+		calendar.blackouts = append(calendar.blackouts, Interval{Start: start, End: end.AddDate(0, 0, 1)})
+	}
+	return calendar, nil
+}
+
+// parseSchedule parses a schedule, either a cron expression with 5 fields (minute, hour, day of the month, month,
+// day of the week) or an RRULE. RRULEs support FREQ=DAILY, WEEKLY or MONTHLY with BYMONTH, BYMONTHDAY, BYDAY,
+// BYHOUR and BYMINUTE, the hour and minute default to 0
+func parseSchedule(expression string) (*schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if strings.HasPrefix(strings.ToUpper(expression), "RRULE:") || strings.Contains(strings.ToUpper(expression), "FREQ=") {
+		return parseRRule(expression)
+	}
+	return parseCron(expression)
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var cronWeekdayNames = map[string]int{"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6}
+
+var rruleWeekdayNames = map[string]int{"SU": 0, "MO": 1, "TU": 2, "WE": 3, "TH": 4, "FR": 5, "SA": 6}
+
+func parseCron(expression string) (*schedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression '%s' must have 5 fields", expression)
+	}
+	sched := &schedule{}
+	var err error
+	if sched.minutes, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if sched.hours, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if sched.days, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("day of the month: %w", err)
+	}
+	if sched.months, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	// Sunday is both 0 and 7
+	if sched.weekdays, err = parseCronField(fields[4], 0, 7, cronWeekdayNames); err != nil {
+		return nil, fmt.Errorf("day of the week: %w", err)
+	}
+	if sched.weekdays&(1<<7) != 0 {
+		sched.weekdays |= 1
+	}
+	sched.anyDay = fields[2] == "*"
+	sched.anyWeekday = fields[4] == "*"
+	return sched, nil
+}
+
+// parseCronField parses a comma separated list of values, ranges and steps
+func parseCronField(field string, minValue, maxValue int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rangePart, stepPart, ok := strings.Cut(part, "/"); ok {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step '%s'", stepPart)
+			}
+			part = rangePart
+		}
+		low, high := minValue, maxValue
+		if part != "*" {
+			lowPart, highPart, isRange := strings.Cut(part, "-")
+			var err error
+			if low, err = parseCronValue(lowPart, minValue, maxValue, names); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseCronValue(highPart, minValue, maxValue, names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// a/n runs from a to the end of the range
+				high = maxValue
+			}
+			if high < low {
+				return 0, fmt.Errorf("invalid range '%s'", part)
+			}
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+	return bits, nil
+}
+
+func parseCronValue(value string, minValue, maxValue int, names map[string]int) (int, error) {
+	if n, ok := names[strings.ToUpper(value)]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minValue || n > maxValue {
+		return 0, fmt.Errorf("invalid value '%s', expected %d to %d", value, minValue, maxValue)
+	}
+	return n, nil
+}
+
+func parseRRule(expression string) (*schedule, error) {
+	expression = strings.TrimPrefix(strings.TrimPrefix(expression, "RRULE:"), "rrule:")
+	sched := &schedule{minutes: 1, hours: 1, months: bitRange(1, 12), anyDay: true, anyWeekday: true, allDayFields: true}
+	freq := ""
+	for _, part := range strings.Split(expression, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part '%s'", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			freq = strings.ToUpper(value)
+		case "INTERVAL":
+			if value != "1" {
+				return nil, fmt.Errorf("RRULE INTERVAL other than 1 is not supported")
+			}
+		case "BYMINUTE":
+			sched.minutes, err = parseRRuleList(value, 0, 59)
+		case "BYHOUR":
+			sched.hours, err = parseRRuleList(value, 0, 23)
+		case "BYMONTHDAY":
+			sched.days, err = parseRRuleList(value, 1, 31)
+			sched.anyDay = false
+		case "BYMONTH":
+			sched.months, err = parseRRuleList(value, 1, 12)
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, ok := rruleWeekdayNames[strings.ToUpper(day)]
+				if !ok {
+					return nil, fmt.Errorf("invalid RRULE BYDAY '%s', only MO to SU are supported", day)
+				}
+				sched.weekdays |= 1 << n
+			}
+			sched.anyWeekday = false
+		default:
+			return nil, fmt.Errorf("RRULE %s is not supported", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("RRULE %s: %w", key, err)
+		}
+	}
+	switch freq {
+	case "DAILY":
+	case "WEEKLY":
+		if sched.anyWeekday {
+			return nil, fmt.Errorf("a WEEKLY RRULE needs BYDAY")
+		}
+	case "MONTHLY":
+		if sched.anyDay && sched.anyWeekday {
+			return nil, fmt.Errorf("a MONTHLY RRULE needs BYMONTHDAY or BYDAY")
+		}
+	default:
+		return nil, fmt.Errorf("RRULE FREQ '%s' is not supported, expected DAILY, WEEKLY or MONTHLY", freq)
+	}
+	return sched, nil
+}
+
+func parseRRuleList(value string, minValue, maxValue int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < minValue || n > maxValue {
+			return 0, fmt.Errorf("invalid value '%s', expected %d to %d", part, minValue, maxValue)
+		}
+		bits |= 1 << n
+	}
+	return bits, nil
+}
+
+func bitRange(low, high int) uint64 {
+	var bits uint64
+	for value := low; value <= high; value++ {
+		bits |= 1 << value
+	}
+	return bits
+}
+
+// matchesDay returns true if the schedule opens windows on the day of t
+func (s *schedule) matchesDay(t time.Time) bool {
+	if s.months&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	day := s.days&(1<<t.Day()) != 0
+	weekday := s.weekdays&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.anyDay && s.anyWeekday:
+		return true
+	case s.anyDay:
+		return weekday
+	case s.anyWeekday:
+		return day
+	case s.allDayFields:
+		return day && weekday
+	default:
+		return day || weekday
+	}
+}
+
+// Current returns the window open at t
+func (c *Calendar) Current(t time.Time) (Interval, bool) {
+	for _, interval := range c.Intervals(t, t.Add(time.Minute)) {
+		if !t.Before(interval.Start) && t.Before(interval.End) {
+			return interval, true
+		}
+	}
+	return Interval{}, false
+}
+
+// Next returns the first window, open at t or opening later within the horizon, that stays open for at least length
+// from the time it is entered. The start of the returned window is t if the window is already open
+func (c *Calendar) Next(t time.Time, length time.Duration) (Interval, bool) {
+	for _, interval := range c.Intervals(t, t.Add(Horizon)) {
+		if interval.Start.Before(t) {
+			interval.Start = t
+		}
+		if interval.End.Sub(interval.Start) >= length {
+			return interval, true
+		}
+	}
+	return Interval{}, false
+}
+
+// Intervals returns the windows overlapping [from, to), merged when they overlap and without the blackout dates
+func (c *Calendar) Intervals(from, to time.Time) []Interval {
+	intervals := []Interval{}
+	for _, w := range c.windows {
+		// Windows opened before from may still be open
+		first := from.Add(-w.duration).In(c.location)
+		day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, c.location)
+		for ; day.Before(to); day = day.AddDate(0, 0, 1) {
+			if !w.schedule.matchesDay(day) {
+				continue
+			}
+			for hour := 0; hour < 24; hour++ {
+				if w.schedule.hours&(1<<hour) == 0 {
+					continue
+				}
+				for minute := 0; minute < 60; minute++ {
+					if w.schedule.minutes&(1<<minute) == 0 {
+						continue
+					}
+					start := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, c.location)
+					end := start.Add(w.duration)
+					if end.After(from) && start.Before(to) {
+						intervals = append(intervals, Interval{Start: start, End: end})
+					}
+				}
+			}
+		}
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].Start.Before(intervals[j].Start)
+	})
+
+	merged := []Interval{}
+	for _, interval := range intervals {
+		if n := len(merged); n > 0 && !interval.Start.After(merged[n-1].End) {
+			if interval.End.After(merged[n-1].End) {
+				merged[n-1].End = interval.End
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	for _, blackout := range c.blackouts {
+		merged = subtract(merged, blackout)
+	}
+	return merged
+}
+
+// subtract removes the blackout from the intervals
+func subtract(intervals []Interval, blackout Interval) []Interval {
+	result := []Interval{}
+	for _, interval := range intervals {
+		if !interval.Start.Before(blackout.End) || !interval.End.After(blackout.Start) {
+			result = append(result, interval)
+			continue
+		}
+		if interval.Start.Before(blackout.Start) {
+			result = append(result, Interval{Start: interval.Start, End: blackout.Start})
+		}
+		if interval.End.After(blackout.End) {
+			result = append(result, Interval{Start: blackout.End, End: interval.End})
+		}
+	}
+	return result
+}
@@ -0,0 +1,157 @@
+package maintenancewindow_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/maintenancewindow"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func windowSpec(timeZone string, duration time.Duration, schedules ...string) *vjailbreakv1alpha1.MaintenanceWindowSpec {
+	spec := &vjailbreakv1alpha1.MaintenanceWindowSpec{TimeZone: timeZone}
+	for _, schedule := range schedules {
+		spec.Windows = append(spec.Windows, vjailbreakv1alpha1.MaintenanceWindowSchedule{
+			Schedule: schedule,
+			Duration: metav1.Duration{Duration: duration},
+		})
+	}
+	return spec
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  *vjailbreakv1alpha1.MaintenanceWindowSpec
+		error string
+	}{
+		{name: "cron", spec: windowSpec("", time.Hour, "*/15 22-23 1,15 JAN-MAR mon-fri")},
+		{name: "rrule", spec: windowSpec("Europe/Paris", time.Hour, "RRULE:FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=2;BYMINUTE=30")},
+		{name: "no window", spec: windowSpec("", time.Hour), error: "no window"},
+		{name: "unknown time zone", spec: windowSpec("Mars/Olympus", time.Hour, "0 22 * * *"), error: "unknown time zone"},
+		{name: "cron with 6 fields", spec: windowSpec("", time.Hour, "0 0 22 * * *"), error: "5 fields"},
+		{name: "cron out of range", spec: windowSpec("", time.Hour, "0 24 * * *"), error: "hour"},
+		{name: "rrule interval", spec: windowSpec("", time.Hour, "FREQ=DAILY;INTERVAL=2"), error: "INTERVAL"},
+		{name: "weekly rrule without days", spec: windowSpec("", time.Hour, "FREQ=WEEKLY;BYHOUR=2"), error: "BYDAY"},
+		{name: "yearly rrule", spec: windowSpec("", time.Hour, "FREQ=YEARLY;BYMONTH=1"), error: "FREQ"},
+		{name: "no duration", spec: windowSpec("", 0, "0 22 * * *"), error: "duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := maintenancewindow.Parse(tt.spec)
+			switch {
+			case tt.error == "" && err != nil:
+				t.Errorf("Parse() failed: %v", err)
+			case tt.error != "" && (err == nil || !strings.Contains(err.Error(), tt.error)):
+				t.Errorf("Parse() error = %v, expected it to mention %s", err, tt.error)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database not available: %v", err)
+	}
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, paris)
+	}
+
+	// Fridays at 22:00 for 6 hours, and every first of the month at 02:00 for 2 hours
+	spec := windowSpec("Europe/Paris", 6*time.Hour, "0 22 * * FRI")
+	spec.Windows = append(spec.Windows, vjailbreakv1alpha1.MaintenanceWindowSchedule{
+		Schedule: "FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=2",
+		Duration: metav1.Duration{Duration: 2 * time.Hour},
+	})
+	spec.Blackouts = []vjailbreakv1alpha1.MaintenanceWindowBlackout{{Start: "2024-06-14"}}
+	calendar, err := maintenancewindow.Parse(spec)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		length        time.Duration
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{
+			name:          "next friday",
+			now:           at(time.June, 4, 12, 0),
+			expectedStart: at(time.June, 7, 22, 0),
+			expectedEnd:   at(time.June, 8, 4, 0),
+		},
+		{
+			name:          "open window starts now",
+			now:           at(time.June, 8, 1, 0),
+			expectedStart: at(time.June, 8, 1, 0),
+			expectedEnd:   at(time.June, 8, 4, 0),
+		},
+		{
+			name:          "too little left of the open window",
+			now:           at(time.June, 8, 1, 0),
+			length:        5 * time.Hour,
+			expectedStart: at(time.June, 21, 22, 0),
+			expectedEnd:   at(time.June, 22, 4, 0),
+		},
+		{
+			name:          "blackout dates are cut out of the windows",
+			now:           at(time.June, 10, 0, 0),
+			expectedStart: at(time.June, 15, 0, 0),
+			expectedEnd:   at(time.June, 15, 4, 0),
+		},
+		{
+			name:          "monthly window",
+			now:           at(time.June, 29, 5, 0),
+			expectedStart: at(time.July, 1, 2, 0),
+			expectedEnd:   at(time.July, 1, 4, 0),
+		},
+		{
+			name:          "windows are in the time zone",
+			now:           at(time.October, 24, 0, 0),
+			expectedStart: at(time.October, 25, 22, 0),
+			expectedEnd:   at(time.October, 26, 4, 0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, ok := calendar.Next(tt.now, tt.length)
+			if !ok {
+				t.Fatalf("Next() found no window")
+			}
+			if !interval.Start.Equal(tt.expectedStart) || !interval.End.Equal(tt.expectedEnd) {
+				t.Errorf("Next() = %v - %v, expected %v - %v", interval.Start, interval.End, tt.expectedStart, tt.expectedEnd)
+			}
+		})
+	}
+
+	if _, open := calendar.Current(at(time.June, 14, 23, 0)); open {
+		t.Errorf("Current() found a window open on a blackout date")
+	}
+	if interval, open := calendar.Current(at(time.June, 7, 23, 0)); !open || !interval.Start.Equal(at(time.June, 7, 22, 0)) {
+		t.Errorf("Current() = %v, %v, expected the window opened at 22:00", interval, open)
+	}
+}
+
+func TestCronDayFields(t *testing.T) {
+	// Cron opens the window when either the day of the month or of the week matches, an RRULE when both match
+	cron, err := maintenancewindow.Parse(windowSpec("", time.Hour, "0 0 13 * FRI"))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	rrule, err := maintenancewindow.Parse(windowSpec("", time.Hour, "FREQ=MONTHLY;BYMONTHDAY=13;BYDAY=FR"))
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	now := time.Date(2024, time.September, 1, 0, 0, 0, 0, time.UTC)
+	if interval, _ := cron.Next(now, 0); !interval.Start.Equal(time.Date(2024, time.September, 6, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("cron Next() = %v, expected friday 6 September", interval.Start)
+	}
+	if interval, _ := rrule.Next(now, 0); !interval.Start.Equal(time.Date(2024, time.September, 13, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("RRULE Next() = %v, expected friday 13 September", interval.Start)
+	}
+}
@@ -0,0 +1,149 @@
+// Package metrics provides the vJailbreak specific Prometheus metrics served by the controller manager.
+// Per-phase object counts are read from the cache on every scrape, while phase transitions and the time
+// spent in each phase are recorded by the reconcilers as they observe phase changes.
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// KindMigration is the kind label value for Migration objects
+	KindMigration = "Migration"
+	// KindMigrationPlan is the kind label value for MigrationPlan objects
+	KindMigrationPlan = "MigrationPlan"
+	// KindRollingMigrationPlan is the kind label value for RollingMigrationPlan objects
+	KindRollingMigrationPlan = "RollingMigrationPlan"
+
+	// collectTimeout bounds the time spent listing objects on a scrape
+	collectTimeout = 10 * time.Second
+)
+
+var (
+	phaseTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vjailbreak_phase_transitions_total",
+		Help: "Number of times a vJailbreak object entered a phase",
+	}, []string{"kind", "phase"})
+
+	phaseDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vjailbreak_phase_duration_seconds",
+		Help:    "Time a vJailbreak object spent in a phase before moving to the next one",
+		Buckets: []float64{10, 30, 60, 300, 900, 1800, 3600, 7200, 14400, 28800, 86400},
+	}, []string{"kind", "phase"})
+
+	objectsDesc = prometheus.NewDesc(
+		"vjailbreak_objects",
+		"Number of vJailbreak objects in each phase",
+		[]string{"kind", "phase"}, nil,
+	)
+
+	tracker = &phaseTracker{observed: map[string]observedPhase{}}
+)
+
+// observedPhase is the phase an object was last seen in and when it was first seen in it
+type observedPhase struct {
+	phase string
+	since time.Time
+}
+
+// phaseTracker remembers the last phase of every object so that phase changes can be measured
+type phaseTracker struct {
+	mu       sync.Mutex
+	observed map[string]observedPhase
+}
+
+// Register adds the vJailbreak metrics to the controller-runtime metrics registry
+func Register(reader client.Reader) {
+	ctrlmetrics.Registry.MustRegister(phaseTransitions, phaseDuration, &objectsCollector{reader: reader})
+}
+
+// RecordPhase records the phase an object was observed in. When the phase differs from the previous
+// observation, the transition is counted and the time spent in the previous phase is observed.
+// The first observation of an object after a restart only starts tracking it, unless the object
+// has no phase yet, so restarts of the controller do not count transitions twice.
+func RecordPhase(kind string, obj client.Object, phase string) {
+	key := trackingKey(kind, obj)
+	now := time.Now()
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	previous, ok := tracker.observed[key]
+	if ok && previous.phase == phase {
+		return
+	}
+	tracker.observed[key] = observedPhase{phase: phase, since: now}
+	if !ok && phase != "" {
+		return
+	}
+	if phase != "" {
+		phaseTransitions.WithLabelValues(kind, phase).Inc()
+	}
+	if ok && previous.phase != "" {
+		phaseDuration.WithLabelValues(kind, previous.phase).Observe(now.Sub(previous.since).Seconds())
+	}
+}
+
+// ForgetObject stops tracking the phase of a deleted object
+func ForgetObject(kind string, obj client.Object) {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	delete(tracker.observed, trackingKey(kind, obj))
+}
+
+func trackingKey(kind string, obj client.Object) string {
+	return kind + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// objectsCollector counts the Migration, MigrationPlan and RollingMigrationPlan objects in each phase
+type objectsCollector struct {
+	reader client.Reader
+}
+
+// Describe implements prometheus.Collector
+func (c *objectsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- objectsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *objectsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	counts := map[string]map[string]int{
+		KindMigration:            {},
+		KindMigrationPlan:        {},
+		KindRollingMigrationPlan: {},
+	}
+
+	migrations := &vjailbreakv1alpha1.MigrationList{}
+	if err := c.reader.List(ctx, migrations); err == nil {
+		for i := range migrations.Items {
+			counts[KindMigration][string(migrations.Items[i].Status.Phase)]++
+		}
+	}
+	migrationPlans := &vjailbreakv1alpha1.MigrationPlanList{}
+	if err := c.reader.List(ctx, migrationPlans); err == nil {
+		for i := range migrationPlans.Items {
+			counts[KindMigrationPlan][string(migrationPlans.Items[i].Status.MigrationStatus)]++
+		}
+	}
+	rollingMigrationPlans := &vjailbreakv1alpha1.RollingMigrationPlanList{}
+	if err := c.reader.List(ctx, rollingMigrationPlans); err == nil {
+		for i := range rollingMigrationPlans.Items {
+			counts[KindRollingMigrationPlan][string(rollingMigrationPlans.Items[i].Status.Phase)]++
+		}
+	}
+
+	for kind, phases := range counts {
+		for phase, count := range phases {
+			ch <- prometheus.MustNewConstMetric(objectsDesc, prometheus.GaugeValue, float64(count), kind, phase)
+		}
+	}
+}
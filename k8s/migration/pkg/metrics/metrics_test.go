@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRecordPhase(t *testing.T) {
+	migration := &vjailbreakv1alpha1.Migration{ObjectMeta: metav1.ObjectMeta{Name: "migration-vm1", Namespace: "migration-system"}}
+	copying := string(vjailbreakv1alpha1.VMMigrationPhaseCopying)
+	converting := string(vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk)
+
+	// A new object without a phase starts being tracked, its first phase is a transition
+	RecordPhase(KindMigration, migration, "")
+	RecordPhase(KindMigration, migration, copying)
+	RecordPhase(KindMigration, migration, copying)
+	if got := testutil.ToFloat64(phaseTransitions.WithLabelValues(KindMigration, copying)); got != 1 {
+		t.Errorf("expected 1 transition to %s, got %v", copying, got)
+	}
+
+	// Leaving a phase observes the time spent in it
+	tracker.observed[trackingKey(KindMigration, migration)] = observedPhase{phase: copying, since: time.Now().Add(-time.Minute)}
+	RecordPhase(KindMigration, migration, converting)
+	if got := testutil.ToFloat64(phaseTransitions.WithLabelValues(KindMigration, converting)); got != 1 {
+		t.Errorf("expected 1 transition to %s, got %v", converting, got)
+	}
+	if got := testutil.CollectAndCount(phaseDuration); got != 1 {
+		t.Errorf("expected durations for 1 phase, got %d", got)
+	}
+
+	// An object first seen in a phase, e.g. after a controller restart, is not counted again
+	plan := &vjailbreakv1alpha1.MigrationPlan{ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "migration-system"}}
+	RecordPhase(KindMigrationPlan, plan, string(corev1.PodRunning))
+	if got := testutil.ToFloat64(phaseTransitions.WithLabelValues(KindMigrationPlan, string(corev1.PodRunning))); got != 0 {
+		t.Errorf("expected no transitions for a plan seen for the first time, got %v", got)
+	}
+
+	ForgetObject(KindMigration, migration)
+	ForgetObject(KindMigrationPlan, plan)
+	if len(tracker.observed) != 0 {
+		t.Errorf("expected no tracked objects, got %v", tracker.observed)
+	}
+}
+
+func TestObjectsCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := vjailbreakv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&vjailbreakv1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{Name: "migration-vm1", Namespace: "migration-system"},
+			Status:     vjailbreakv1alpha1.MigrationStatus{Phase: vjailbreakv1alpha1.VMMigrationPhaseCopying},
+		},
+		&vjailbreakv1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{Name: "migration-vm2", Namespace: "migration-system"},
+			Status:     vjailbreakv1alpha1.MigrationStatus{Phase: vjailbreakv1alpha1.VMMigrationPhaseCopying},
+		},
+		&vjailbreakv1alpha1.Migration{
+			ObjectMeta: metav1.ObjectMeta{Name: "migration-vm3", Namespace: "migration-system"},
+			Status:     vjailbreakv1alpha1.MigrationStatus{Phase: vjailbreakv1alpha1.VMMigrationPhaseFailed},
+		},
+		&vjailbreakv1alpha1.MigrationPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "migration-system"},
+			Status:     vjailbreakv1alpha1.MigrationPlanStatus{MigrationStatus: corev1.PodRunning},
+		},
+	).Build()
+
+	expected := `
+# HELP vjailbreak_objects Number of vJailbreak objects in each phase
+# TYPE vjailbreak_objects gauge
+vjailbreak_objects{kind="Migration",phase="CopyingBlocks"} 2
+vjailbreak_objects{kind="Migration",phase="Failed"} 1
+vjailbreak_objects{kind="MigrationPlan",phase="Running"} 1
+`
+	if err := testutil.CollectAndCompare(&objectsCollector{reader: reader}, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
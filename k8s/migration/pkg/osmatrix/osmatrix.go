@@ -0,0 +1,308 @@
+// Package osmatrix provides the supported guest OS matrix. The matrix is a versioned JSON document kept in the
+// vjailbreak-os-matrix ConfigMap, which the controller creates from the built-in default when it is missing and
+// upgrades when the built-in matrix is newer, unless the ConfigMap was edited. An edited ConfigMap is never
+// replaced, delete it to get the newer built-in matrix and edit that one again.
+// v2v-helper checks the detected guest OS against it before converting the disks, and the UI reads it to flag
+// the VMs that are not supported before a migration is created.
+package osmatrix
+
+import (
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// ConfigMapName is the name of the ConfigMap holding the matrix
+	ConfigMapName = "vjailbreak-os-matrix"
+	// ConfigMapKey is the key of the ConfigMap holding the JSON encoded matrix
+	ConfigMapKey = "matrix.json"
+	// ChecksumAnnotation is the checksum of the matrix the controller wrote to the ConfigMap, the ConfigMap was
+	// edited if its matrix no longer matches it
+	ChecksumAnnotation = "vjailbreak.k8s.pf9.io/os-matrix-checksum"
+
+	// FamilyLinux is the family of Linux guests
+	FamilyLinux = "linux"
+	// FamilyWindows is the family of Windows guests
+	FamilyWindows = "windows"
+
+	// StepNTFSFix fixes the NTFS filesystems of the guest before the conversion
+	StepNTFSFix = "ntfsfix"
+	// StepGuestNetwork rewrites the network configuration of a Linux guest
+	StepGuestNetwork = "guestNetwork"
+	// StepWindowsFirstBoot injects the script that re-applies the network settings of a Windows guest on its first boot
+	StepWindowsFirstBoot = "windowsFirstBoot"
+)
+
+// knownSteps are the conversion steps v2v-helper knows
+var knownSteps = []string{StepNTFSFix, StepGuestNetwork, StepWindowsFirstBoot}
+
+// defaultSteps are the conversion steps of the guests that are not in the matrix, when their migration is forced
+var defaultSteps = map[string][]string{
+	FamilyLinux:   {StepGuestNetwork},
+	FamilyWindows: {StepNTFSFix, StepWindowsFirstBoot},
+}
+
+//go:embed matrix.json
+var defaultMatrix []byte
+
+// releaseVersion is the version in the release files of the guests without an os-release file
+var releaseVersion = regexp.MustCompile(`release\s+(\d+(\.\d+)*)`)
+
+// Matrix is the list of supported guest operating systems
+type Matrix struct {
+	// Version is the version of the matrix, increased whenever the entries change
+	Version int `json:"version"`
+	// Entries are the supported operating systems, the first entry matching a guest applies
+	Entries []Entry `json:"entries"`
+}
+
+// Entry is a supported operating system
+type Entry struct {
+	// Family is the OS family, linux or windows
+	Family string `json:"family"`
+	// Distro is the name of the operating system
+	Distro string `json:"distro"`
+	// Match are lower case strings, a guest whose OS name contains one of them is this operating system
+	Match []string `json:"match"`
+	// MinVersion is the oldest supported version, all versions are supported if empty
+	MinVersion string `json:"minVersion,omitempty"`
+	// MaxVersion is the newest supported version, compared to the precision it is written with
+	// so that 9 includes 9.4. All newer versions are supported if empty
+	MaxVersion string `json:"maxVersion,omitempty"`
+	// ConversionSteps are the steps v2v-helper runs for the operating system besides virt-v2v
+	ConversionSteps []string `json:"conversionSteps,omitempty"`
+	// Caveats are the known issues of the migration of the operating system
+	Caveats []string `json:"caveats,omitempty"`
+}
+
+// Result is the support status of a guest operating system
+type Result struct {
+	// Supported is true if an entry matches the guest and the version of the guest is in its range
+	Supported bool
+	// Entry is the entry matching the guest, nil if none does
+	Entry *Entry
+	// Reason explains why the guest is not supported
+	Reason string
+}
+
+// Default returns the built-in matrix
+func Default() (*Matrix, error) {
+	return Parse(defaultMatrix)
+}
+
+// Parse decodes and validates a JSON encoded matrix
+func Parse(data []byte) (*Matrix, error) {
+	matrix := &Matrix{}
+	if err := json.Unmarshal(data, matrix); err != nil {
+		return nil, errors.Wrap(err, "failed to parse OS matrix")
+	}
+	for idx, entry := range matrix.Entries {
+		if entry.Family != FamilyLinux && entry.Family != FamilyWindows {
+			return nil, errors.Errorf("entry %d (%s) of the OS matrix has unknown family %q", idx, entry.Distro, entry.Family)
+		}
+		if len(entry.Match) == 0 {
+			return nil, errors.Errorf("entry %d (%s) of the OS matrix has no match strings", idx, entry.Distro)
+		}
+		for _, version := range []string{entry.MinVersion, entry.MaxVersion} {
+			if _, err := parseVersion(version); version != "" && err != nil {
+				return nil, errors.Wrapf(err, "entry %d (%s) of the OS matrix has an invalid version", idx, entry.Distro)
+			}
+		}
+		for _, step := range entry.ConversionSteps {
+			if !slices.Contains(knownSteps, step) {
+				return nil, errors.Errorf("entry %d (%s) of the OS matrix has unknown conversion step %q", idx, entry.Distro, step)
+			}
+		}
+	}
+	return matrix, nil
+}
+
+// Load returns the matrix of the ConfigMap in the namespace, or the built-in matrix if the ConfigMap does not exist
+func Load(ctx context.Context, k8sClient client.Client, namespace string) (*Matrix, error) {
+	configMap := &corev1.ConfigMap{}
+	err := k8sClient.Get(ctx, client.ObjectKey{Name: ConfigMapName, Namespace: namespace}, configMap)
+	if apierrors.IsNotFound(err) {
+		return Default()
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get OS matrix configmap")
+	}
+	return Parse([]byte(configMap.Data[ConfigMapKey]))
+}
+
+// EnsureConfigMap creates the ConfigMap of the matrix from the built-in matrix if it does not exist, and replaces
+// its matrix with the built-in one when that is a newer version and the ConfigMap was not edited
+func EnsureConfigMap(ctx context.Context, k8sClient client.Client, namespace string) error {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ConfigMapName,
+			Namespace:   namespace,
+			Annotations: map[string]string{ChecksumAnnotation: checksum(string(defaultMatrix))},
+		},
+		Data: map[string]string{ConfigMapKey: string(defaultMatrix)},
+	}
+	err := k8sClient.Create(ctx, configMap)
+	if err == nil {
+		return nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return errors.Wrap(err, "failed to create OS matrix configmap")
+	}
+
+	if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(configMap), configMap); err != nil {
+		return errors.Wrap(err, "failed to get OS matrix configmap")
+	}
+	builtin, err := Default()
+	if err != nil {
+		return err
+	}
+	current := &Matrix{}
+	// A matrix that does not parse counts as version 0, it is replaced if it was not edited
+	_ = json.Unmarshal([]byte(configMap.Data[ConfigMapKey]), current)
+	if current.Version >= builtin.Version {
+		return nil
+	}
+	if configMap.Annotations[ChecksumAnnotation] != checksum(configMap.Data[ConfigMapKey]) {
+		log.FromContext(ctx).Info("Keeping the edited OS matrix, delete its ConfigMap to get the newer built-in matrix",
+			"configmap", ConfigMapName, "version", current.Version, "builtinVersion", builtin.Version)
+		return nil
+	}
+	if configMap.Annotations == nil {
+		configMap.Annotations = map[string]string{}
+	}
+	configMap.Annotations[ChecksumAnnotation] = checksum(string(defaultMatrix))
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[ConfigMapKey] = string(defaultMatrix)
+	if err := k8sClient.Update(ctx, configMap); err != nil {
+		return errors.Wrap(err, "failed to upgrade OS matrix configmap")
+	}
+	return nil
+}
+
+// checksum returns the checksum of a JSON encoded matrix
+func checksum(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// Check returns the support status of a guest of the family with the OS name and version. name is matched
+// case insensitively, the version is only checked if it is known
+func (m *Matrix) Check(family, name, version string) Result {
+	name = strings.ToLower(name)
+	for idx := range m.Entries {
+		entry := &m.Entries[idx]
+		if entry.Family != family || !slices.ContainsFunc(entry.Match, func(match string) bool {
+			return strings.Contains(name, strings.ToLower(match))
+		}) {
+			continue
+		}
+		if version == "" {
+			return Result{Supported: true, Entry: entry}
+		}
+		if entry.MinVersion != "" && compareVersions(version, entry.MinVersion) < 0 {
+			return Result{Entry: entry, Reason: fmt.Sprintf("%s %s is older than the oldest supported version %s", entry.Distro, version, entry.MinVersion)}
+		}
+		if entry.MaxVersion != "" && compareVersions(version, entry.MaxVersion) > 0 {
+			return Result{Entry: entry, Reason: fmt.Sprintf("%s %s is newer than the newest supported version %s", entry.Distro, version, entry.MaxVersion)}
+		}
+		return Result{Supported: true, Entry: entry}
+	}
+	return Result{Reason: fmt.Sprintf("%q is not in the supported OS matrix", name)}
+}
+
+// ConversionSteps returns the conversion steps of the guest, the steps of the family if no entry matches it
+func (r Result) ConversionSteps(family string) []string {
+	if r.Entry != nil {
+		return r.Entry.ConversionSteps
+	}
+	return defaultSteps[family]
+}
+
+// Family returns the matrix family of an OS type, such as linuxGuest or windowsGuest
+func Family(osType string) string {
+	osType = strings.ToLower(osType)
+	switch {
+	case strings.Contains(osType, FamilyWindows):
+		return FamilyWindows
+	case strings.Contains(osType, FamilyLinux):
+		return FamilyLinux
+	}
+	return ""
+}
+
+// ParseOSRelease returns the OS name and version of a Linux guest from its os-release file, or from
+// its release file, such as /etc/redhat-release, on the guests without an os-release file
+func ParseOSRelease(osRelease string) (string, string) {
+	vars := map[string]string{}
+	for _, line := range strings.Split(osRelease, "\n") {
+		key, value, found := strings.Cut(line, "=")
+		if found {
+			vars[strings.ToUpper(strings.TrimSpace(key))] = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	if vars["NAME"] != "" || vars["ID"] != "" {
+		return strings.TrimSpace(vars["NAME"] + " " + vars["ID"]), vars["VERSION_ID"]
+	}
+	release := strings.TrimSpace(strings.Split(strings.TrimSpace(osRelease), "\n")[0])
+	if match := releaseVersion.FindStringSubmatch(strings.ToLower(release)); match != nil {
+		return release, match[1]
+	}
+	// SuSE-release holds the version on a line of its own
+	return release, vars["VERSION"]
+}
+
+// parseVersion returns the numeric components of a dotted version
+func parseVersion(version string) ([]int, error) {
+	parts := []int{}
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errors.Errorf("invalid version %q", version)
+		}
+		parts = append(parts, number)
+	}
+	return parts, nil
+}
+
+// compareVersions compares a version to a bound, up to the number of components of the bound.
+// Versions that are not numeric, such as rolling releases, are within every bound
+func compareVersions(version, bound string) int {
+	versionParts, err := parseVersion(version)
+	if err != nil {
+		return 0
+	}
+	boundParts, err := parseVersion(bound)
+	if err != nil {
+		return 0
+	}
+	for idx, boundPart := range boundParts {
+		versionPart := 0
+		if idx < len(versionParts) {
+			versionPart = versionParts[idx]
+		}
+		if versionPart != boundPart {
+			if versionPart < boundPart {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,158 @@
+package osmatrix
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestCheckDefaultMatrix(t *testing.T) {
+	matrix, err := Default()
+	if err != nil {
+		t.Fatalf("failed to parse the built-in matrix: %v", err)
+	}
+	tests := []struct {
+		family    string
+		osRelease string
+		name      string
+		distro    string
+		supported bool
+	}{
+		{family: FamilyLinux, osRelease: "name=\"ubuntu\"\nversion_id=\"22.04\"\nid=ubuntu\nid_like=debian", distro: "Ubuntu", supported: true},
+		{family: FamilyLinux, osRelease: "name=\"ubuntu\"\nversion_id=\"14.04\"\nid=ubuntu", distro: "Ubuntu"},
+		{family: FamilyLinux, osRelease: "name=\"rocky linux\"\nid=\"rocky\"\nid_like=\"rhel centos fedora\"\nversion_id=\"9.4\"", distro: "Rocky Linux", supported: true},
+		{family: FamilyLinux, osRelease: "name=\"red hat enterprise linux\"\nid=\"rhel\"\nversion_id=\"10.0\"", distro: "Red Hat Enterprise Linux"},
+		{family: FamilyLinux, osRelease: "red hat enterprise linux server release 6.10 (santiago)\n", distro: "Red Hat Enterprise Linux", supported: true},
+		{family: FamilyLinux, osRelease: "suse linux enterprise server 11 (x86_64)\nversion = 11\npatchlevel = 4\n", distro: "SUSE Linux Enterprise", supported: true},
+		{family: FamilyLinux, osRelease: "name=\"opensuse tumbleweed\"\nid=\"opensuse-tumbleweed\"\nversion_id=\"20240101\"", distro: "openSUSE", supported: true},
+		{family: FamilyLinux, osRelease: "name=\"vmware photon os\"\nid=photon\nversion_id=5.0"},
+		{family: FamilyWindows, name: "Microsoft Windows Server 2019 (64-bit)", distro: "Windows Server 2019", supported: true},
+		{family: FamilyWindows, name: "Microsoft Windows Server 2008 (64-bit)"},
+	}
+	for _, tt := range tests {
+		name, version := tt.name, ""
+		if tt.osRelease != "" {
+			name, version = ParseOSRelease(tt.osRelease)
+		}
+		result := matrix.Check(tt.family, name, version)
+		if result.Supported != tt.supported {
+			t.Errorf("%q %q: expected supported %v, got %v (%s)", name, version, tt.supported, result.Supported, result.Reason)
+		}
+		distro := ""
+		if result.Entry != nil {
+			distro = result.Entry.Distro
+		}
+		if distro != tt.distro {
+			t.Errorf("%q %q: expected entry %q, got %q", name, version, tt.distro, distro)
+		}
+		if !result.Supported && result.Reason == "" {
+			t.Errorf("%q %q: expected a reason", name, version)
+		}
+	}
+}
+
+func TestConversionSteps(t *testing.T) {
+	matrix, err := Default()
+	if err != nil {
+		t.Fatalf("failed to parse the built-in matrix: %v", err)
+	}
+	// Windows Server 2008 R2 has no NetAdapter module for the first boot script
+	steps := matrix.Check(FamilyWindows, "microsoft windows server 2008 r2 (64-bit)", "").ConversionSteps(FamilyWindows)
+	if !reflect.DeepEqual(steps, []string{StepNTFSFix}) {
+		t.Errorf("unexpected steps %v", steps)
+	}
+	// Guests that are not in the matrix get the steps of their family
+	steps = matrix.Check(FamilyWindows, "microsoft windows 7 (64-bit)", "").ConversionSteps(FamilyWindows)
+	if !reflect.DeepEqual(steps, []string{StepNTFSFix, StepWindowsFirstBoot}) {
+		t.Errorf("unexpected steps %v", steps)
+	}
+}
+
+func TestParseRejectsInvalidMatrix(t *testing.T) {
+	for _, data := range []string{
+		`{"version": 1, "entries": [{"family": "bsd", "distro": "FreeBSD", "match": ["freebsd"]}]}`,
+		`{"version": 1, "entries": [{"family": "linux", "distro": "Debian"}]}`,
+		`{"version": 1, "entries": [{"family": "linux", "distro": "Debian", "match": ["debian"], "minVersion": "bookworm"}]}`,
+		`{"version": 1, "entries": [{"family": "linux", "distro": "Debian", "match": ["debian"], "conversionSteps": ["selinuxRelabel"]}]}`,
+		`entries:`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("expected %s to be rejected", data)
+		}
+	}
+}
+
+func TestLoadAndEnsureConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// The built-in matrix applies until the ConfigMap exists
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	matrix, err := Load(ctx, k8sClient, "migration-system")
+	if err != nil || len(matrix.Entries) == 0 {
+		t.Fatalf("expected the built-in matrix, got %v, %v", matrix, err)
+	}
+	if err := EnsureConfigMap(ctx, k8sClient, "migration-system"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An edited ConfigMap is kept
+	edited := `{"version": 2, "entries": [{"family": "linux", "distro": "Debian", "match": ["debian"], "minVersion": "12"}]}`
+	k8sClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "migration-system"},
+		Data:       map[string]string{ConfigMapKey: edited},
+	}).Build()
+	if err := EnsureConfigMap(ctx, k8sClient, "migration-system"); err != nil {
+		t.Fatal(err)
+	}
+	matrix, err = Load(ctx, k8sClient, "migration-system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if matrix.Version != 2 || len(matrix.Entries) != 1 {
+		t.Errorf("expected the edited matrix, got %+v", matrix)
+	}
+	if result := matrix.Check(FamilyLinux, "debian gnu/linux debian", "11"); result.Supported || !strings.Contains(result.Reason, "older") {
+		t.Errorf("expected Debian 11 to be unsupported, got %+v", result)
+	}
+
+	// An older matrix written by the controller is upgraded, an edited one is kept
+	old := `{"version": 0, "entries": []}`
+	for _, test := range []struct {
+		name     string
+		checksum string
+		upgraded bool
+	}{
+		{name: "unedited", checksum: checksum(old), upgraded: true},
+		{name: "edited", checksum: checksum(`{"version": 0, "entries": [{}]}`)},
+		{name: "created before the checksum was recorded"},
+	} {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "migration-system"},
+			Data:       map[string]string{ConfigMapKey: old},
+		}
+		if test.checksum != "" {
+			configMap.Annotations = map[string]string{ChecksumAnnotation: test.checksum}
+		}
+		k8sClient = fake.NewClientBuilder().WithScheme(scheme).WithObjects(configMap).Build()
+		if err := EnsureConfigMap(ctx, k8sClient, "migration-system"); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		matrix, err = Load(ctx, k8sClient, "migration-system")
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if upgraded := len(matrix.Entries) > 0; upgraded != test.upgraded {
+			t.Errorf("%s: expected upgraded to be %v, got matrix %+v", test.name, test.upgraded, matrix)
+		}
+	}
+}
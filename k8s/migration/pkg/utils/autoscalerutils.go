@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"sort"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// AutoscaledNode is a worker node created by an autoscaler
+type AutoscaledNode struct {
+	Name     string
+	Ready    bool
+	Draining bool
+	// Busy is true while migrations are active or placed on the node
+	Busy bool
+	// IdleSince is the time the last active migration of the node ended, nil while migrations are active
+	IdleSince *time.Time
+}
+
+// AutoscaleDecision is what an autoscaler changes in its nodes
+type AutoscaleDecision struct {
+	// Create is the number of nodes to add
+	Create int
+	// Undrain are the draining nodes to place migrations on again instead of adding nodes
+	Undrain []string
+	// Drain are the idle nodes to stop placing migrations on
+	Drain []string
+	// Delete are the drained nodes with no migration left
+	Delete []string
+}
+
+// PlanAutoscale decides how the nodes of an autoscaler change given the number of queued migrations.
+// Nodes are added once queued reaches the scale up threshold, one for every few queued migrations less the nodes
+// still being provisioned, reusing draining nodes first. Nodes idle for the scale down delay are drained while
+// nothing is queued, and deleted once no migration is left on them. MinNodes and MaxNodes are always kept to.
+func PlanAutoscale(spec *vjailbreakv1alpha1.VjailbreakNodeAutoscalerSpec, nodes []AutoscaledNode, queued int,
+	now time.Time) AutoscaleDecision {
+	decision := AutoscaleDecision{}
+	threshold := max(spec.ScaleUpThreshold, 1)
+	delay := constants.DefaultScaleDownDelay
+	if spec.ScaleDownDelay != nil {
+		delay = spec.ScaleDownDelay.Duration
+	}
+
+	active, provisioning := 0, 0
+	draining := []AutoscaledNode{}
+	for _, node := range nodes {
+		switch {
+		case node.Draining:
+			draining = append(draining, node)
+		case !node.Ready:
+			active++
+			provisioning++
+		default:
+			active++
+		}
+	}
+
+	if queued >= threshold {
+		perNode := constants.DefaultMaxConcurrentMigrationsPerNode
+		needed := (queued+perNode-1)/perNode - provisioning
+		for _, node := range draining {
+			if len(decision.Undrain) >= needed {
+				break
+			}
+			decision.Undrain = append(decision.Undrain, node.Name)
+		}
+		draining = draining[len(decision.Undrain):]
+		active += len(decision.Undrain)
+		decision.Create = max(min(needed-len(decision.Undrain), spec.MaxNodes-len(nodes)), 0)
+	}
+	if missing := spec.MinNodes - active - decision.Create; missing > 0 {
+		decision.Create += max(min(missing, spec.MaxNodes-len(nodes)-decision.Create), 0)
+	}
+
+	if queued == 0 {
+		idle := []AutoscaledNode{}
+		for _, node := range nodes {
+			if !node.Draining && node.Ready && !node.Busy && node.IdleSince != nil && now.Sub(*node.IdleSince) >= delay {
+				idle = append(idle, node)
+			}
+		}
+		// The nodes idle for the longest go first
+		sort.SliceStable(idle, func(i, j int) bool {
+			return idle[i].IdleSince.Before(*idle[j].IdleSince)
+		})
+		for _, node := range idle {
+			if active-len(decision.Drain) <= spec.MinNodes {
+				break
+			}
+			decision.Drain = append(decision.Drain, node.Name)
+		}
+	}
+
+	// Nodes with migrations left are never deleted
+	for _, node := range draining {
+		if !node.Busy {
+			decision.Delete = append(decision.Delete, node.Name)
+		}
+	}
+	return decision
+}
+
+// AgentNamesByNode returns the name of the VjailbreakNode of each agent by both the name and the hostname label of
+// its Kubernetes node. Agents are matched to their node by IP, nodes without an agent are left out.
+func AgentNamesByNode(vjNodes []vjailbreakv1alpha1.VjailbreakNode, nodes []corev1.Node) map[string]string {
+	vjNodesByIP := map[string]string{}
+	for i := range vjNodes {
+		if ip := vjNodes[i].Status.VMIP; ip != "" {
+			vjNodesByIP[ip] = vjNodes[i].Name
+		}
+	}
+	names := map[string]string{}
+	for i := range nodes {
+		name, ok := vjNodesByIP[GetNodeInternalIP(&nodes[i])]
+		if !ok {
+			continue
+		}
+		names[nodes[i].Name] = name
+		if hostname := nodes[i].Labels[corev1.LabelHostname]; hostname != "" {
+			names[hostname] = name
+		}
+	}
+	return names
+}
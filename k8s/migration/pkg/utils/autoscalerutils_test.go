@@ -0,0 +1,102 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPlanAutoscale(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	longIdle := now.Add(-time.Hour)
+	idle := now.Add(-20 * time.Minute)
+	recent := now.Add(-time.Minute)
+	spec := &vjailbreakv1alpha1.VjailbreakNodeAutoscalerSpec{MinNodes: 1, MaxNodes: 4, ScaleUpThreshold: 2}
+
+	tests := []struct {
+		name     string
+		nodes    []utils.AutoscaledNode
+		queued   int
+		expected utils.AutoscaleDecision
+	}{
+		{
+			name:     "below the threshold only keeps the minimum",
+			queued:   1,
+			expected: utils.AutoscaleDecision{Create: 1},
+		},
+		{
+			name: "queued migrations add a node for every few of them, less those being provisioned",
+			nodes: []utils.AutoscaledNode{
+				{Name: "agent-a", Ready: true, Busy: true},
+				{Name: "agent-b"},
+			},
+			queued:   12,
+			expected: utils.AutoscaleDecision{Create: 2},
+		},
+		{
+			name: "draining nodes are reused before adding nodes, up to the maximum",
+			nodes: []utils.AutoscaledNode{
+				{Name: "agent-a", Ready: true, Busy: true},
+				{Name: "agent-b", Ready: true, Draining: true, Busy: true},
+				{Name: "agent-c", Ready: true, Busy: true},
+			},
+			queued:   20,
+			expected: utils.AutoscaleDecision{Create: 1, Undrain: []string{"agent-b"}},
+		},
+		{
+			name: "idle nodes are drained oldest first down to the minimum",
+			nodes: []utils.AutoscaledNode{
+				{Name: "agent-a", Ready: true, IdleSince: &idle},
+				{Name: "agent-b", Ready: true, IdleSince: &longIdle},
+				{Name: "agent-c", Ready: true, IdleSince: &longIdle},
+				{Name: "agent-d", Ready: true, IdleSince: &recent},
+			},
+			expected: utils.AutoscaleDecision{Drain: []string{"agent-b", "agent-c", "agent-a"}},
+		},
+		{
+			name: "busy nodes are neither drained nor deleted",
+			nodes: []utils.AutoscaledNode{
+				{Name: "agent-a", Ready: true, Busy: true, IdleSince: &longIdle},
+				{Name: "agent-b", Ready: true, Draining: true, Busy: true},
+				{Name: "agent-c", Ready: true, Draining: true},
+			},
+			expected: utils.AutoscaleDecision{Delete: []string{"agent-c"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if decision := utils.PlanAutoscale(spec, tt.nodes, tt.queued, now); !reflect.DeepEqual(decision, tt.expected) {
+				t.Errorf("PlanAutoscale() = %+v, expected %+v", decision, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAgentNamesByNode(t *testing.T) {
+	vjNodes := []vjailbreakv1alpha1.VjailbreakNode{
+		{ObjectMeta: metav1.ObjectMeta{Name: "vjailbreak-agent-abc"}, Status: vjailbreakv1alpha1.VjailbreakNodeStatus{VMIP: "10.0.0.2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "vjailbreak-agent-provisioning"}},
+	}
+	nodes := []corev1.Node{
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:        "node-2",
+			Labels:      map[string]string{corev1.LabelHostname: "host-2"},
+			Annotations: map[string]string{constants.InternalIPAnnotation: "10.0.0.2"},
+		}},
+		{ObjectMeta: metav1.ObjectMeta{
+			Name:        "master",
+			Annotations: map[string]string{constants.InternalIPAnnotation: "10.0.0.1"},
+		}},
+	}
+
+	expected := map[string]string{"node-2": "vjailbreak-agent-abc", "host-2": "vjailbreak-agent-abc"}
+	if names := utils.AgentNamesByNode(vjNodes, nodes); !reflect.DeepEqual(names, expected) {
+		t.Errorf("AgentNamesByNode() = %v, expected %v", names, expected)
+	}
+}
@@ -283,31 +283,11 @@ func GetOpenstackInfo(ctx context.Context, k3sclient client.Client, openstackcre
 		openstacknetworks = append(openstacknetworks, allNetworks[i].Name)
 	}
 
-	credsInfo, err := GetOpenstackCredentialsFromSecret(ctx, k3sclient, openstackcreds.Spec.SecretRef.Name)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to get openstack credentials for project lookup")
-	}
-
-	identityClient, err := openstack.NewIdentityV3(openstackClients.BlockStorageClient.ProviderClient, gophercloud.EndpointOpts{})
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create identity client")
-	}
-
-	listOpts := projects.ListOpts{Name: credsInfo.TenantName}
-	allPages, err := projects.List(identityClient, listOpts).AllPages()
+	projectID, err := GetOpenstackProjectID(ctx, k3sclient, openstackcreds, openstackClients)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to list projects with name %s", credsInfo.TenantName)
+		return nil, err
 	}
 
-	allProjects, err := projects.ExtractProjects(allPages)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to extract projects")
-	}
-	if len(allProjects) == 0 {
-		return nil, fmt.Errorf("no project found with name %s", credsInfo.TenantName)
-	}
-	projectID := allProjects[0].ID
-
 	allSecGroupPages, err := groups.List(openstackClients.NetworkingClient, groups.ListOpts{
 		TenantID: projectID,
 	}).AllPages()
@@ -343,6 +323,34 @@ func GetOpenstackInfo(ctx context.Context, k3sclient client.Client, openstackcre
 	}, nil
 }
 
+// GetOpenstackProjectID looks up the ID of the project the OpenStack credentials are scoped to
+func GetOpenstackProjectID(ctx context.Context, k3sclient client.Client, openstackcreds *vjailbreakv1alpha1.OpenstackCreds, openstackClients *OpenStackClients) (string, error) {
+	credsInfo, err := GetOpenstackCredentialsFromSecret(ctx, k3sclient, openstackcreds.Spec.SecretRef.Name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get openstack credentials for project lookup")
+	}
+
+	identityClient, err := openstack.NewIdentityV3(openstackClients.BlockStorageClient.ProviderClient, gophercloud.EndpointOpts{})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create identity client")
+	}
+
+	listOpts := projects.ListOpts{Name: credsInfo.TenantName}
+	allPages, err := projects.List(identityClient, listOpts).AllPages()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to list projects with name %s", credsInfo.TenantName)
+	}
+
+	allProjects, err := projects.ExtractProjects(allPages)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to extract projects")
+	}
+	if len(allProjects) == 0 {
+		return "", fmt.Errorf("no project found with name %s", credsInfo.TenantName)
+	}
+	return allProjects[0].ID, nil
+}
+
 // GetOpenStackClients is a function to create openstack clients
 func GetOpenStackClients(ctx context.Context, k3sclient client.Client, openstackcreds *vjailbreakv1alpha1.OpenstackCreds) (*OpenStackClients, error) {
 	if openstackcreds == nil {
@@ -616,6 +624,39 @@ func GetVMwDatastore(ctx context.Context, k3sclient client.Client, vmwcreds *vja
 	return datastores, nil
 }
 
+// GetVMwDiskCapacities gets the capacity in bytes of the disks of a VM that are copied to Cinder volumes,
+// in the same order as GetVMwDatastore. RDM disks are skipped as they are managed into Cinder instead.
+func GetVMwDiskCapacities(ctx context.Context, k3sclient client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds, datacenter, vmname string) ([]int64, error) {
+	_, finder, err := getFinderForVMwareCreds(ctx, k3sclient, vmwcreds, datacenter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get finder: %w", err)
+	}
+
+	vm, err := finder.VirtualMachine(ctx, vmname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find vm: %w", err)
+	}
+
+	var vmProps mo.VirtualMachine
+	err = vm.Properties(ctx, vm.Reference(), []string{"config"}, &vmProps)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VM properties: %w", err)
+	}
+
+	var capacities []int64
+	for _, device := range vmProps.Config.Hardware.Device {
+		disk, ok := device.(*types.VirtualDisk)
+		if !ok {
+			continue
+		}
+		if _, isRDM := disk.Backing.(*types.VirtualDiskRawDiskMappingVer1BackingInfo); isRDM {
+			continue
+		}
+		capacities = append(capacities, disk.CapacityInBytes)
+	}
+	return capacities, nil
+}
+
 // GetAllVMs gets all the VMs in a datacenter.
 func GetAllVMs(ctx context.Context, scope *scope.VMwareCredsScope, datacenter string) ([]vjailbreakv1alpha1.VMInfo, *sync.Map, error) {
 	log := scope.Logger
@@ -727,9 +768,13 @@ func ExtractVirtualNICs(vmProps *mo.VirtualMachine) ([]vjailbreakv1alpha1.NIC, e
 }
 
 // ExtractGuestNetworkInfo retrieves the runtime guest network configuration (guest.net)
-// reported by VMware Tools. Returns MAC, IP, DNS, and origin for each NIC in the guest.
+// reported by VMware Tools. Returns MAC, IP, gateway, DNS, and origin for each NIC in the guest.
 func ExtractGuestNetworkInfo(vmProps *mo.VirtualMachine) ([]vjailbreakv1alpha1.GuestNetwork, error) {
 	guestNetworks := []vjailbreakv1alpha1.GuestNetwork{}
+	if vmProps.Guest == nil {
+		return guestNetworks, nil
+	}
+	gateways := extractDefaultGateways(vmProps.Guest.IpStack)
 
 	for i, guestNet := range vmProps.Guest.Net {
 		if guestNet.IpConfig == nil {
@@ -747,6 +792,7 @@ func ExtractGuestNetworkInfo(vmProps *mo.VirtualMachine) ([]vjailbreakv1alpha1.G
 				IP:           ip.IpAddress,
 				Origin:       ip.Origin,
 				PrefixLength: ip.PrefixLength,
+				Gateway:      gateways[defaultGatewayKey(fmt.Sprintf("%d", i), ip.IpAddress)],
 				DNS:          dnsConfigList,
 				Device:       fmt.Sprintf("%d", i),
 			})
@@ -756,6 +802,35 @@ func ExtractGuestNetworkInfo(vmProps *mo.VirtualMachine) ([]vjailbreakv1alpha1.G
 	return guestNetworks, nil
 }
 
+// extractDefaultGateways returns the default gateways of the guest routing tables, keyed by
+// the guest.net index of the device the route goes through and the IP family of the gateway
+func extractDefaultGateways(ipStacks []types.GuestStackInfo) map[string]string {
+	gateways := map[string]string{}
+	for _, ipStack := range ipStacks {
+		if ipStack.IpRouteConfig == nil {
+			continue
+		}
+		for _, route := range ipStack.IpRouteConfig.IpRoute {
+			if route.PrefixLength != 0 || route.Gateway.IpAddress == "" {
+				continue
+			}
+			key := defaultGatewayKey(route.Gateway.Device, route.Gateway.IpAddress)
+			if _, ok := gateways[key]; !ok {
+				gateways[key] = route.Gateway.IpAddress
+			}
+		}
+	}
+	return gateways
+}
+
+// defaultGatewayKey is the key of the default gateway of a device for the IP family of ip
+func defaultGatewayKey(device, ip string) string {
+	if strings.Contains(ip, ":") {
+		return device + "/ipv6"
+	}
+	return device + "/ipv4"
+}
+
 // processVMDisk processes a single virtual disk device and updates the disk information
 // it returns the datastore reference, RDM disk info, a skip flag, and any error encountered
 // It checks if the disk is backed by a shared SCSI controller and skips the VM.
@@ -1479,6 +1554,7 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 	var datastores []string
 	networks := make([]string, 0, 4) // Pre-allocate with estimated capacity
 	disks := make([]string, 0, 8)    // Pre-allocate with estimated capacity
+	diskSizes := make([]int64, 0, 8)
 	var clusterName string
 	rdmForVM := make([]string, 0)
 	log := scope.Logger
@@ -1574,6 +1650,7 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 
 			datastores = AppendUnique(datastores, ds.Name)
 			disks = append(disks, disk.DeviceInfo.GetDescription().Label)
+			diskSizes = append(diskSizes, disk.CapacityInBytes)
 		}
 	}
 	// Get the host name and parent (cluster) information
@@ -1623,6 +1700,11 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 	vmwvmKey := k8stypes.NamespacedName{Name: vmName, Namespace: scope.Namespace()}
 	var guestNetworks []vjailbreakv1alpha1.GuestNetwork
 	var osFamily string
+	// VMware Tools report the installed OS, the configured guest OS is used when they are not running
+	osName := vmProps.Guest.GuestFullName
+	if osName == "" {
+		osName = vmProps.Config.GuestFullName
+	}
 	err = scope.Client.Get(ctx, vmwvmKey, vmwvm)
 	switch {
 	case apierrors.IsNotFound(err):
@@ -1649,6 +1731,9 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 		} else {
 			osFamily = vmwvm.Spec.VMInfo.OSFamily
 		}
+		if vmProps.Guest.GuestFullName == "" && vmwvm.Spec.VMInfo.OSName != "" {
+			osName = vmwvm.Spec.VMInfo.OSName
+		}
 	}
 
 	if len(guestNetworksFromVmware) > 0 {
@@ -1685,10 +1770,12 @@ func processSingleVM(ctx context.Context, scope *scope.VMwareCredsScope, vm *obj
 		Name:              vmProps.Config.Name,
 		Datastores:        datastores,
 		Disks:             disks,
+		DiskSizes:         diskSizes,
 		Networks:          networks,
 		IPAddress:         vmProps.Guest.IpAddress,
 		VMState:           vmProps.Guest.GuestState,
 		OSFamily:          osFamily,
+		OSName:            osName,
 		CPU:               int(vmProps.Config.Hardware.NumCPU),
 		Memory:            int(vmProps.Config.Hardware.MemoryMB),
 		ESXiName:          host.Name,
@@ -0,0 +1,68 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestExtractGuestNetworkInfo(t *testing.T) {
+	vmProps := &mo.VirtualMachine{
+		Guest: &types.GuestInfo{
+			Net: []types.GuestNicInfo{
+				{
+					MacAddress: "00:50:56:AA:BB:01",
+					IpConfig: &types.NetIpConfigInfo{
+						IpAddress: []types.NetIpConfigInfoIpAddress{
+							{IpAddress: "10.0.0.5", PrefixLength: 24, Origin: "manual"},
+							{IpAddress: "fe80::1", PrefixLength: 64, Origin: "linklayer"},
+						},
+					},
+					DnsConfig: &types.NetDnsConfigInfo{IpAddress: []string{"10.0.0.2"}},
+				},
+				{
+					MacAddress: "00:50:56:aa:bb:02",
+					IpConfig: &types.NetIpConfigInfo{
+						IpAddress: []types.NetIpConfigInfoIpAddress{
+							{IpAddress: "192.168.1.20", PrefixLength: 16, Origin: "dhcp"},
+						},
+					},
+				},
+			},
+			IpStack: []types.GuestStackInfo{
+				{
+					IpRouteConfig: &types.NetIpRouteConfigInfo{
+						IpRoute: []types.NetIpRouteConfigInfoIpRoute{
+							{Network: "10.0.0.0", PrefixLength: 24, Gateway: types.NetIpRouteConfigInfoGateway{Device: "0"}},
+							{Network: "0.0.0.0", PrefixLength: 0, Gateway: types.NetIpRouteConfigInfoGateway{IpAddress: "10.0.0.1", Device: "0"}},
+							{Network: "::", PrefixLength: 0, Gateway: types.NetIpRouteConfigInfoGateway{IpAddress: "fe80::fe", Device: "0"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	guestNetworks, err := utils.ExtractGuestNetworkInfo(vmProps)
+	if err != nil {
+		t.Fatalf("ExtractGuestNetworkInfo() returned error: %v", err)
+	}
+	// The default gateway applies to the addresses of its device and IP family only
+	expected := []vjailbreakv1alpha1.GuestNetwork{
+		{MAC: "00:50:56:aa:bb:01", IP: "10.0.0.5", Origin: "manual", PrefixLength: 24, Gateway: "10.0.0.1", DNS: []string{"10.0.0.2"}, Device: "0"},
+		{MAC: "00:50:56:aa:bb:01", IP: "fe80::1", Origin: "linklayer", PrefixLength: 64, Gateway: "fe80::fe", DNS: []string{"10.0.0.2"}, Device: "0"},
+		{MAC: "00:50:56:aa:bb:02", IP: "192.168.1.20", Origin: "dhcp", PrefixLength: 16, DNS: []string{}, Device: "1"},
+	}
+	if !reflect.DeepEqual(guestNetworks, expected) {
+		t.Errorf("ExtractGuestNetworkInfo() = %+v, expected %+v", guestNetworks, expected)
+	}
+
+	guestNetworks, err = utils.ExtractGuestNetworkInfo(&mo.VirtualMachine{})
+	if err != nil || len(guestNetworks) != 0 {
+		t.Errorf("ExtractGuestNetworkInfo() without guest info = %+v, %v, expected no networks", guestNetworks, err)
+	}
+}
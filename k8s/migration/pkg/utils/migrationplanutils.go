@@ -7,8 +7,10 @@
 package utils
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"regexp"
@@ -18,9 +20,13 @@ import (
 	"github.com/pkg/errors"
 	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	openstackconst "github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	v2vutils "github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
 	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // MigrationNameFromVMName generates a migration name from a VM name
@@ -78,11 +84,11 @@ func ValidateMigrationPlan(migrationplan *vjailbreakv1alpha1.MigrationPlan) erro
 
 	// If advanced options are set, then there should only be 1 VM in the migrationplan
 	if !reflect.DeepEqual(migrationplan.Spec.AdvancedOptions, vjailbreakv1alpha1.AdvancedOptions{}) &&
-		(len(migrationplan.Spec.VirtualMachines) != 1 || len(migrationplan.Spec.VirtualMachines[0]) != 1) {
+		len(GetMigrationPlanVMs(migrationplan)) != 1 {
 		return fmt.Errorf(`advanced options can only be set for a single VM.
 			Please remove advanced options or reduce the number of VMs in the migrationplan`)
 	}
-	return nil
+	return ValidateMigrationWaves(&migrationplan.Spec)
 }
 
 // GetJobNameForVMName generates a unique name for a job resource
@@ -114,3 +120,44 @@ func GenerateSha256Hash(input string) string {
 
 	return hashStr[:len(hashStr)-1] + string(replacement)
 }
+
+// GetCheckpointedVolumes returns the IDs of the volumes recorded in the copy checkpoints of a migration plan.
+// v2v-helper keeps them after a failed migration, so that a retry of the plan resumes the copy
+func GetCheckpointedVolumes(ctx context.Context, k8sClient client.Client,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan) ([]string, error) {
+	configMaps := &corev1.ConfigMapList{}
+	if err := k8sClient.List(ctx, configMaps, client.InNamespace(constants.NamespaceMigrationSystem)); err != nil {
+		return nil, errors.Wrap(err, "failed to list configmaps")
+	}
+	volumeIDs := []string{}
+	for i := range configMaps.Items {
+		if !strings.HasPrefix(configMaps.Items[i].Name, openstackconst.MigrationCheckpointConfigMapPrefix) ||
+			!isOwnedBy(configMaps.Items[i].OwnerReferences, migrationplan) {
+			continue
+		}
+		data := configMaps.Items[i].Data[openstackconst.MigrationCheckpointDataKey]
+		if data == "" {
+			continue
+		}
+		checkpoint := &v2vutils.MigrationCheckpoint{}
+		if err := json.Unmarshal([]byte(data), checkpoint); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal checkpoint '%s'", configMaps.Items[i].Name)
+		}
+		for _, disk := range checkpoint.Disks {
+			if disk.VolumeID != "" {
+				volumeIDs = append(volumeIDs, disk.VolumeID)
+			}
+		}
+	}
+	return volumeIDs, nil
+}
+
+// isOwnedBy returns whether the owner references include the migration plan
+func isOwnedBy(ownerReferences []metav1.OwnerReference, migrationplan *vjailbreakv1alpha1.MigrationPlan) bool {
+	for _, ref := range ownerReferences {
+		if ref.Kind == "MigrationPlan" && ref.UID == migrationplan.UID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package utils_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetCheckpointedVolumes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	migrationplan := &vjailbreakv1alpha1.MigrationPlan{ObjectMeta: metav1.ObjectMeta{Name: "plan", UID: "plan-uid"}}
+	checkpoint := func(name, data string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       constants.NamespaceMigrationSystem,
+				OwnerReferences: []metav1.OwnerReference{{Kind: "MigrationPlan", Name: "plan", UID: "plan-uid"}},
+			},
+			Data: map[string]string{"checkpoint": data},
+		}
+	}
+	other := checkpoint("migration-checkpoint-vm3", `{"vmName":"vm3","disks":[{"name":"disk1","volumeID":"vol-other"}]}`)
+	other.OwnerReferences[0].UID = "other-uid"
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		checkpoint("migration-checkpoint-vm1",
+			`{"vmName":"vm1","disks":[{"name":"disk1","volumeID":"vol-1","phase":"Copied"},{"name":"disk2","volumeID":"vol-2"}]}`),
+		checkpoint("migration-checkpoint-vm2", ""),
+		checkpoint("migration-config-vm1", `{"disks":[{"volumeID":"vol-config"}]}`),
+		other,
+	).Build()
+
+	volumeIDs, err := utils.GetCheckpointedVolumes(context.Background(), k8sClient, migrationplan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(volumeIDs)
+	if !slices.Equal(volumeIDs, []string{"vol-1", "vol-2"}) {
+		t.Errorf("expected the volumes of the checkpoints of the plan, got %v", volumeIDs)
+	}
+}
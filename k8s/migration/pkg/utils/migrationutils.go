@@ -6,12 +6,17 @@
 package utils
 
 import (
+	"encoding/json"
+	"fmt"
 	"slices"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/pkg/errors"
 	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -27,6 +32,9 @@ type MigrationUtils interface {
 	// CreateMigratingCondition creates a migrated condition for the migration.
 	CreateMigratingCondition(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition
 
+	// CreateHealthCheckCondition creates a health check condition for the migration.
+	CreateHealthCheckCondition(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition
+
 	// SetCutoverLabel sets the cutover label based on the initiateCutover flag.
 	SetCutoverLabel(initiateCutover bool, currentLabel string) string
 
@@ -47,6 +55,18 @@ type MigrationUtils interface {
 
 	// SortConditionsByLastTransitionTime sorts conditions by LastTransitionTime.
 	SortConditionsByLastTransitionTime(conditions []corev1.PodCondition)
+
+	// GetMigrationProgress returns the progress record reported by the migration pod.
+	GetMigrationProgress(pod *corev1.Pod) (*vjailbreakv1alpha1.MigrationProgress, error)
+
+	// CurrentMigrationPod returns the pod of a migration that is running it.
+	CurrentMigrationPod(pods []corev1.Pod) *corev1.Pod
+
+	// MigrationJobFailed returns whether the Job of the migration failed.
+	MigrationJobFailed(job *batchv1.Job) bool
+
+	// NextMigrationPhase returns the phase a migration moves to given the phase reported by its pod.
+	NextMigrationPhase(current, reported vjailbreakv1alpha1.VMMigrationPhase, cutoverStarted bool) vjailbreakv1alpha1.VMMigrationPhase
 }
 
 // CreateValidatedCondition creates a validated condition for a migration
@@ -148,6 +168,84 @@ func CreateFailedCondition(migration *vjailbreakv1alpha1.Migration, eventList *c
 	return existingConditions
 }
 
+// CreateHealthCheckCondition creates a health check condition for a migration from the outcome of the health checks
+// of the migrated VM
+func CreateHealthCheckCondition(migration *vjailbreakv1alpha1.Migration, eventList *corev1.EventList) []corev1.PodCondition {
+	existingConditions := migration.Status.Conditions
+	for i := 0; i < len(eventList.Items); i++ {
+		if eventList.Items[i].Reason != constants.MigrationReason {
+			continue
+		}
+		var status corev1.ConditionStatus
+		var reason string
+		switch {
+		case eventList.Items[i].Message == "Health Checks passed":
+			status, reason = corev1.ConditionTrue, constants.HealthCheckReasonPassed
+		case strings.HasPrefix(eventList.Items[i].Message, "Health Check failed:"):
+			status, reason = corev1.ConditionFalse, constants.HealthCheckReasonFailed
+		default:
+			continue
+		}
+
+		idx := GetConditonIndex(existingConditions, constants.MigrationConditionTypeHealthCheck,
+			constants.HealthCheckReasonPassed, constants.HealthCheckReasonFailed)
+		statuscondition := GeneratePodCondition(constants.MigrationConditionTypeHealthCheck,
+			status,
+			reason,
+			eventList.Items[i].Message,
+			eventList.Items[i].LastTimestamp)
+
+		if idx == -1 {
+			existingConditions = append(existingConditions, *statuscondition)
+		} else {
+			existingConditions[idx] = *statuscondition
+		}
+		break
+	}
+	return existingConditions
+}
+
+// IsMigrationHealthy returns true if the health checks of the migrated VM passed
+func IsMigrationHealthy(migration *vjailbreakv1alpha1.Migration) bool {
+	idx := GetConditonIndex(migration.Status.Conditions, constants.MigrationConditionTypeHealthCheck, constants.HealthCheckReasonPassed)
+	return idx != -1 && migration.Status.Conditions[idx].Status == corev1.ConditionTrue
+}
+
+// CreateCutoverReadyCondition sets the CutoverReady condition of a migration from the downtime estimated by its pod.
+// The condition is only set once the pod has an estimate and the migration plan has a threshold.
+func CreateCutoverReadyCondition(migration *vjailbreakv1alpha1.Migration, threshold *metav1.Duration) []corev1.PodCondition {
+	existingConditions := migration.Status.Conditions
+	progress := migration.Status.Progress
+	if threshold == nil || progress == nil || progress.EstimatedDowntimeSeconds == 0 {
+		return existingConditions
+	}
+	estimate := time.Duration(progress.EstimatedDowntimeSeconds) * time.Second
+	status, reason := corev1.ConditionFalse, constants.CutoverReadyReasonAboveThreshold
+	if estimate <= threshold.Duration {
+		status, reason = corev1.ConditionTrue, constants.CutoverReadyReasonWithinThreshold
+	}
+
+	idx := GetConditonIndex(existingConditions, constants.MigrationConditionTypeCutoverReady,
+		constants.CutoverReadyReasonWithinThreshold, constants.CutoverReadyReasonAboveThreshold)
+	// The transition time only changes with the status
+	timestamp := metav1.Now()
+	if idx != -1 && existingConditions[idx].Status == status {
+		timestamp = existingConditions[idx].LastTransitionTime
+	}
+	statuscondition := GeneratePodCondition(constants.MigrationConditionTypeCutoverReady,
+		status,
+		reason,
+		fmt.Sprintf("Estimated downtime of a cutover now is %s, the threshold is %s", estimate, threshold.Duration),
+		timestamp)
+
+	if idx == -1 {
+		existingConditions = append(existingConditions, *statuscondition)
+	} else {
+		existingConditions[idx] = *statuscondition
+	}
+	return existingConditions
+}
+
 // SetCutoverLabel sets the cutover label for a migration
 func SetCutoverLabel(initiateCutover bool, currentLabel string) string {
 	// If initiateCutover is true, return the current label
@@ -207,3 +305,79 @@ func SortConditionsByLastTransitionTime(conditions []corev1.PodCondition) {
 		return conditions[i].LastTransitionTime.Before(&conditions[j].LastTransitionTime)
 	})
 }
+
+// GetMigrationProgress returns the progress record v2v-helper wrote on the migration pod, or nil if there is none yet
+func GetMigrationProgress(pod *corev1.Pod) (*vjailbreakv1alpha1.MigrationProgress, error) {
+	data, ok := pod.Annotations[vjailbreakv1alpha1.MigrationProgressAnnotation]
+	if !ok || data == "" {
+		return nil, nil
+	}
+	progress := &vjailbreakv1alpha1.MigrationProgress{}
+	if err := json.Unmarshal([]byte(data), progress); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal migration progress")
+	}
+	return progress, nil
+}
+
+// CurrentMigrationPod returns the pod of a migration that is running it. A resumed migration has the pods its
+// Job replaced as well, the newest pod that has not terminated is returned, or the newest pod if all terminated
+func CurrentMigrationPod(pods []corev1.Pod) *corev1.Pod {
+	var current *corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if current == nil {
+			current = pod
+			continue
+		}
+		terminated, currentTerminated := isPodTerminated(pod), isPodTerminated(current)
+		if terminated != currentTerminated {
+			if !terminated {
+				current = pod
+			}
+			continue
+		}
+		if current.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			current = pod
+		}
+	}
+	return current
+}
+
+// isPodTerminated returns whether the pod has finished, successfully or not
+func isPodTerminated(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// MigrationJobFailed returns whether the Job of the migration failed. It covers the pods that crashed before
+// reporting their progress, or without reporting the failure in it. The evicted pods the Job replaces do not
+// fail it
+func MigrationJobFailed(job *batchv1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// NextMigrationPhase returns the phase a migration moves to given the phase reported by its pod.
+// Phases only move forward, except that a failure is always taken, a migration awaiting
+// admin cutover follows the pod back to copying once the cutover has been started, and a migration
+// waiting for its cutover start time or maintenance window keeps syncing the changed blocks meanwhile.
+func NextMigrationPhase(current, reported vjailbreakv1alpha1.VMMigrationPhase, cutoverStarted bool) vjailbreakv1alpha1.VMMigrationPhase {
+	switch {
+	case reported == "":
+		return current
+	case reported == vjailbreakv1alpha1.VMMigrationPhaseFailed:
+		return reported
+	case current == vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver && cutoverStarted:
+		return reported
+	case current == vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime &&
+		reported == vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks:
+		return reported
+	case constants.VMMigrationStatesEnum[current] <= constants.VMMigrationStatesEnum[reported]:
+		return reported
+	default:
+		return current
+	}
+}
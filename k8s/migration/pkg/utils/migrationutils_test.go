@@ -0,0 +1,215 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetMigrationProgress(t *testing.T) {
+	pod := &corev1.Pod{}
+	progress, err := utils.GetMigrationProgress(pod)
+	if err != nil || progress != nil {
+		t.Fatalf("expected no progress for a pod without the annotation, got %v, %v", progress, err)
+	}
+
+	pod.ObjectMeta = metav1.ObjectMeta{
+		Annotations: map[string]string{
+			vjailbreakv1alpha1.MigrationProgressAnnotation: `{"phase":"CopyingChangedBlocks","cbtIteration":3,` +
+				`"disks":[{"index":0,"name":"disk1","bytesCopied":512,"totalBytes":1024}],"throughputBytesPerSecond":64,"etaSeconds":8}`,
+		},
+	}
+	progress, err = utils.GetMigrationProgress(pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if progress.Phase != vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks || progress.CBTIteration != 3 ||
+		progress.ThroughputBytesPerSecond != 64 || progress.ETASeconds != 8 {
+		t.Errorf("unexpected progress %+v", progress)
+	}
+	if len(progress.Disks) != 1 || progress.Disks[0].BytesCopied != 512 || progress.Disks[0].TotalBytes != 1024 {
+		t.Errorf("unexpected disk progress %+v", progress.Disks)
+	}
+
+	pod.Annotations[vjailbreakv1alpha1.MigrationProgressAnnotation] = "Failed to"
+	if _, err := utils.GetMigrationProgress(pod); err == nil {
+		t.Error("expected an error for a malformed progress record")
+	}
+}
+
+func TestNextMigrationPhase(t *testing.T) {
+	tests := []struct {
+		name           string
+		current        vjailbreakv1alpha1.VMMigrationPhase
+		reported       vjailbreakv1alpha1.VMMigrationPhase
+		cutoverStarted bool
+		expected       vjailbreakv1alpha1.VMMigrationPhase
+	}{
+		{
+			name:     "nothing reported yet",
+			current:  vjailbreakv1alpha1.VMMigrationPhaseValidating,
+			expected: vjailbreakv1alpha1.VMMigrationPhaseValidating,
+		},
+		{
+			name:     "moves forward",
+			current:  vjailbreakv1alpha1.VMMigrationPhaseCopying,
+			reported: vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+			expected: vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+		},
+		{
+			name:     "does not move back",
+			current:  vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk,
+			reported: vjailbreakv1alpha1.VMMigrationPhaseCopying,
+			expected: vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk,
+		},
+		{
+			name:     "syncs changed blocks while awaiting the cutover start time",
+			current:  vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime,
+			reported: vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+			expected: vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+		},
+		{
+			name:     "awaits the cutover start time between changed block syncs",
+			current:  vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+			reported: vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime,
+			expected: vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime,
+		},
+		{
+			name:     "failure is always taken",
+			current:  vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk,
+			reported: vjailbreakv1alpha1.VMMigrationPhaseFailed,
+			expected: vjailbreakv1alpha1.VMMigrationPhaseFailed,
+		},
+		{
+			name:     "rollback after the VM was created",
+			current:  vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk,
+			reported: vjailbreakv1alpha1.VMMigrationPhaseRolledBack,
+			expected: vjailbreakv1alpha1.VMMigrationPhaseRolledBack,
+		},
+		{
+			name:     "stays awaiting admin cutover until it is started",
+			current:  vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver,
+			reported: vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+			expected: vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver,
+		},
+		{
+			name:           "follows the pod after admin cutover is started",
+			current:        vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver,
+			reported:       vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+			cutoverStarted: true,
+			expected:       vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := utils.NextMigrationPhase(tt.current, tt.reported, tt.cutoverStarted); got != tt.expected {
+				t.Errorf("NextMigrationPhase() = %s, expected %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCurrentMigrationPod(t *testing.T) {
+	now := time.Now()
+	pod := func(name string, age time.Duration, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-age))},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+	// The pod the job started after the first one was evicted, the first one is listed first
+	pods := []corev1.Pod{pod("evicted", time.Hour, corev1.PodFailed), pod("resumed", time.Minute, corev1.PodRunning)}
+	if current := utils.CurrentMigrationPod(pods); current.Name != "resumed" {
+		t.Errorf("expected the running pod, got %s", current.Name)
+	}
+	// A pod that has not started yet replaces a newer pod that terminated
+	pods = []corev1.Pod{pod("pending", time.Hour, corev1.PodPending), pod("deleted", time.Minute, corev1.PodFailed)}
+	if current := utils.CurrentMigrationPod(pods); current.Name != "pending" {
+		t.Errorf("expected the pending pod, got %s", current.Name)
+	}
+	pods = []corev1.Pod{pod("first", time.Hour, corev1.PodFailed), pod("last", time.Minute, corev1.PodFailed)}
+	if current := utils.CurrentMigrationPod(pods); current.Name != "last" {
+		t.Errorf("expected the newest pod once all terminated, got %s", current.Name)
+	}
+}
+
+func TestMigrationJobFailed(t *testing.T) {
+	job := &batchv1.Job{Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{
+		{Type: batchv1.JobSuspended, Status: corev1.ConditionFalse},
+	}}}
+	if utils.MigrationJobFailed(job) {
+		t.Errorf("expected a running job not to have failed")
+	}
+	// A pod that crashed before reporting any progress fails the job
+	job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{Type: batchv1.JobFailed, Status: corev1.ConditionTrue})
+	if !utils.MigrationJobFailed(job) {
+		t.Errorf("expected a failed job to fail the migration")
+	}
+}
+
+func TestCreateCutoverReadyCondition(t *testing.T) {
+	threshold := &metav1.Duration{Duration: 10 * time.Minute}
+	migration := &vjailbreakv1alpha1.Migration{}
+
+	// Without an estimate or a threshold there is no condition
+	if conditions := utils.CreateCutoverReadyCondition(migration, threshold); len(conditions) != 0 {
+		t.Fatalf("expected no condition without an estimate, got %+v", conditions)
+	}
+	migration.Status.Progress = &vjailbreakv1alpha1.MigrationProgress{EstimatedDowntimeSeconds: 900}
+	if conditions := utils.CreateCutoverReadyCondition(migration, nil); len(conditions) != 0 {
+		t.Fatalf("expected no condition without a threshold, got %+v", conditions)
+	}
+
+	migration.Status.Conditions = utils.CreateCutoverReadyCondition(migration, threshold)
+	if len(migration.Status.Conditions) != 1 || migration.Status.Conditions[0].Type != constants.MigrationConditionTypeCutoverReady ||
+		migration.Status.Conditions[0].Status != corev1.ConditionFalse {
+		t.Fatalf("expected a false CutoverReady condition, got %+v", migration.Status.Conditions)
+	}
+
+	// The condition is updated in place once the estimate drops below the threshold
+	migration.Status.Progress.EstimatedDowntimeSeconds = 300
+	migration.Status.Conditions = utils.CreateCutoverReadyCondition(migration, threshold)
+	if len(migration.Status.Conditions) != 1 || migration.Status.Conditions[0].Status != corev1.ConditionTrue ||
+		migration.Status.Conditions[0].Reason != constants.CutoverReadyReasonWithinThreshold {
+		t.Fatalf("expected a true CutoverReady condition, got %+v", migration.Status.Conditions)
+	}
+
+	// The transition time is kept while the status does not change
+	transition := metav1.NewTime(time.Now().Add(-time.Hour))
+	migration.Status.Conditions[0].LastTransitionTime = transition
+	migration.Status.Progress.EstimatedDowntimeSeconds = 200
+	migration.Status.Conditions = utils.CreateCutoverReadyCondition(migration, threshold)
+	if !migration.Status.Conditions[0].LastTransitionTime.Equal(&transition) {
+		t.Errorf("expected the transition time to be kept, got %s", migration.Status.Conditions[0].LastTransitionTime)
+	}
+}
+
+func TestCreateHealthCheckCondition(t *testing.T) {
+	migration := &vjailbreakv1alpha1.Migration{}
+	events := &corev1.EventList{Items: []corev1.Event{
+		{Reason: constants.MigrationReason, Message: "Health Check failed: health checks failed: HTTP Get"},
+		{Reason: constants.MigrationReason, Message: "Performing Health Checks"},
+	}}
+	migration.Status.Conditions = utils.CreateHealthCheckCondition(migration, events)
+	if len(migration.Status.Conditions) != 1 || migration.Status.Conditions[0].Status != corev1.ConditionFalse ||
+		migration.Status.Conditions[0].Reason != constants.HealthCheckReasonFailed {
+		t.Fatalf("expected a false HealthCheck condition, got %+v", migration.Status.Conditions)
+	}
+	if utils.IsMigrationHealthy(migration) {
+		t.Errorf("expected the migration not to be healthy")
+	}
+
+	// The newest event wins
+	events.Items = append([]corev1.Event{{Reason: constants.MigrationReason, Message: "Health Checks passed"}}, events.Items...)
+	migration.Status.Conditions = utils.CreateHealthCheckCondition(migration, events)
+	if len(migration.Status.Conditions) != 1 || !utils.IsMigrationHealthy(migration) {
+		t.Errorf("expected a true HealthCheck condition, got %+v", migration.Status.Conditions)
+	}
+}
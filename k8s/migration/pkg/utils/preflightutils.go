@@ -0,0 +1,465 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumetypes"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/osmatrix"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Names of the pre-flight checks run for every VM of a MigrationPlan
+const (
+	PreflightCheckVMwareMachine = "VMwareMachine"
+	PreflightCheckNetworks      = "Networks"
+	PreflightCheckStorage       = "Storage"
+	PreflightCheckVDDK          = "VDDK"
+	PreflightCheckGuestOS       = "GuestOS"
+	PreflightCheckFlavor        = "Flavor"
+	PreflightCheckIPAddresses   = "IPAddresses"
+	PreflightCheckQuota         = "Quota"
+)
+
+// PreflightParams are the objects the pre-flight checks of a MigrationPlan are run against
+type PreflightParams struct {
+	MigrationPlan     *vjailbreakv1alpha1.MigrationPlan
+	MigrationTemplate *vjailbreakv1alpha1.MigrationTemplate
+	VMwareCreds       *vjailbreakv1alpha1.VMwareCreds
+	OpenstackCreds    *vjailbreakv1alpha1.OpenstackCreds
+	// VDDKDirectory is the directory the VDDK libraries are uploaded to
+	VDDKDirectory string
+}
+
+// openstackInventory is the OpenStack state shared by the checks of all VMs of a plan
+type openstackInventory struct {
+	clients     *OpenStackClients
+	networks    map[string]networks.Network
+	volumeTypes map[string]bool
+	flavors     []flavors.Flavor
+	baseFlavor  *flavors.Flavor
+	capacity    *ProjectCapacity
+}
+
+// RunMigrationPlanPreflight runs the pre-flight checks for every VM of the plan against vCenter and OpenStack.
+// It only reads from both, nothing is created. An error is returned only if OpenStack cannot be queried at all
+// or the supported OS matrix cannot be loaded, problems with individual VMs are reported as failed checks.
+func RunMigrationPlanPreflight(ctx context.Context, k8sClient client.Client, params PreflightParams) (*vjailbreakv1alpha1.PreflightReport, error) {
+	inventory, err := getOpenstackInventory(ctx, k8sClient, params)
+	if err != nil {
+		return nil, err
+	}
+	vddkCheck := checkVDDKDirectory(params.VDDKDirectory)
+	matrix, err := osmatrix.Load(ctx, k8sClient, constants.NamespaceMigrationSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &vjailbreakv1alpha1.PreflightReport{
+		ObservedGeneration: params.MigrationPlan.Generation,
+		Result:             vjailbreakv1alpha1.PreflightResultPass,
+	}
+	for _, vm := range GetMigrationPlanVMs(params.MigrationPlan) {
+		vmReport := vjailbreakv1alpha1.VMPreflightReport{
+			VMName: vm,
+			Result: vjailbreakv1alpha1.PreflightResultPass,
+			Checks: runVMPreflight(ctx, k8sClient, params, inventory, vddkCheck, matrix, vm),
+		}
+		for _, check := range vmReport.Checks {
+			vmReport.Result = WorsePreflightResult(vmReport.Result, check.Result)
+		}
+		report.Result = WorsePreflightResult(report.Result, vmReport.Result)
+		report.VMs = append(report.VMs, vmReport)
+	}
+	report.CompletionTime = metav1.Now()
+	return report, nil
+}
+
+func getOpenstackInventory(ctx context.Context, k8sClient client.Client, params PreflightParams) (*openstackInventory, error) {
+	openstackClients, err := GetOpenStackClients(ctx, k8sClient, params.OpenstackCreds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack clients")
+	}
+	inventory := &openstackInventory{
+		clients:     openstackClients,
+		networks:    map[string]networks.Network{},
+		volumeTypes: map[string]bool{},
+	}
+
+	allNetworkPages, err := networks.List(openstackClients.NetworkingClient, nil).AllPages()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list networks")
+	}
+	allNetworks, err := networks.ExtractNetworks(allNetworkPages)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to extract all networks")
+	}
+	for i := range allNetworks {
+		inventory.networks[allNetworks[i].Name] = allNetworks[i]
+	}
+
+	allVolumeTypePages, err := volumetypes.List(openstackClients.BlockStorageClient, nil).AllPages()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list volume types")
+	}
+	allVolumeTypes, err := volumetypes.ExtractVolumeTypes(allVolumeTypePages)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to extract all volume types")
+	}
+	for i := range allVolumeTypes {
+		inventory.volumeTypes[allVolumeTypes[i].Name] = true
+	}
+
+	if params.MigrationTemplate.Spec.UseFlavorless {
+		// A missing base flavor is reported per VM
+		inventory.baseFlavor, _ = FindHotplugBaseFlavor(openstackClients.ComputeClient)
+	} else {
+		inventory.flavors, err = ListAllFlavors(ctx, k8sClient, params.OpenstackCreds)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list all flavors")
+		}
+	}
+
+	inventory.capacity, err = GetProjectCapacity(ctx, k8sClient, params.OpenstackCreds)
+	if err != nil {
+		return nil, err
+	}
+	return inventory, nil
+}
+
+func runVMPreflight(ctx context.Context, k8sClient client.Client, params PreflightParams,
+	inventory *openstackInventory, vddkCheck vjailbreakv1alpha1.PreflightCheck, matrix *osmatrix.Matrix, vm string) []vjailbreakv1alpha1.PreflightCheck {
+	var checks []vjailbreakv1alpha1.PreflightCheck
+	addCheck := func(check vjailbreakv1alpha1.PreflightCheck) {
+		checks = append(checks, check)
+	}
+
+	vmwcreds := params.VMwareCreds
+	vmMachine, err := getVMwareMachine(ctx, k8sClient, vmwcreds, params.MigrationTemplate.Namespace, vm)
+	if err != nil {
+		addCheck(failedCheck(PreflightCheckVMwareMachine, err.Error()))
+		return checks
+	}
+	addCheck(passedCheck(PreflightCheckVMwareMachine, fmt.Sprintf("VMwareMachine %s found", vmMachine.Name)))
+
+	advancedOptions := params.MigrationPlan.Spec.AdvancedOptions
+	targetNetworks, networkCheck := checkNetworks(ctx, k8sClient, params, inventory, vm, advancedOptions)
+	addCheck(networkCheck)
+	addCheck(checkStorage(ctx, k8sClient, params, inventory, vm, advancedOptions))
+	addCheck(vddkCheck)
+
+	osFamily := vmMachine.Spec.VMInfo.OSFamily
+	if params.MigrationTemplate.Spec.OSFamily != "" {
+		osFamily = params.MigrationTemplate.Spec.OSFamily
+	}
+	addCheck(CheckGuestOSFamily(matrix, osFamily, vmMachine.Spec.VMInfo.OSName, params.MigrationPlan.Spec.AllowUnsupportedOS))
+	addCheck(checkFlavor(params.MigrationTemplate.Spec.UseFlavorless, inventory, vmMachine))
+
+	if len(advancedOptions.GranularPorts) == 0 && networkCheck.Result != vjailbreakv1alpha1.PreflightResultFail {
+		addCheck(checkIPAddresses(inventory, vmMachine, targetNetworks, params.MigrationPlan.Spec.FallbackToDHCP))
+	}
+
+	request, err := GetVMResourceRequest(ctx, k8sClient, vmwcreds, vmMachine)
+	if err != nil {
+		addCheck(failedCheck(PreflightCheckQuota, err.Error()))
+	} else {
+		addCheck(CheckQuota(inventory.capacity, request))
+	}
+	return checks
+}
+
+func getVMwareMachine(ctx context.Context, k8sClient client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds, namespace, vm string) (*vjailbreakv1alpha1.VMwareMachine, error) {
+	vmMachineName, err := GetK8sCompatibleVMWareObjectName(vm, vmwcreds.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get VMwareMachine name")
+	}
+	vmMachine := &vjailbreakv1alpha1.VMwareMachine{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: vmMachineName, Namespace: namespace}, vmMachine); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, errors.Errorf("VMwareMachine %s not found, the VM may not exist in vCenter", vmMachineName)
+		}
+		return nil, errors.Wrapf(err, "failed to get VMwareMachine %s", vmMachineName)
+	}
+	return vmMachine, nil
+}
+
+// checkNetworks maps the networks of the VM the way the migration does and returns the target networks
+func checkNetworks(ctx context.Context, k8sClient client.Client, params PreflightParams, inventory *openstackInventory,
+	vm string, advancedOptions vjailbreakv1alpha1.AdvancedOptions) ([]string, vjailbreakv1alpha1.PreflightCheck) {
+	vmwcreds := params.VMwareCreds
+	vmNetworks, err := GetVMwNetworks(ctx, k8sClient, vmwcreds, vmwcreds.Spec.DataCenter, vm)
+	if err != nil {
+		return nil, failedCheck(PreflightCheckNetworks, fmt.Sprintf("failed to get VM networks: %v", err))
+	}
+	networkmap := &vjailbreakv1alpha1.NetworkMapping{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: params.MigrationTemplate.Spec.NetworkMapping, Namespace: params.MigrationTemplate.Namespace}, networkmap); err != nil {
+		return nil, failedCheck(PreflightCheckNetworks, fmt.Sprintf("failed to retrieve NetworkMapping CR: %v", err))
+	}
+	targetNetworks, err := MapVMNetworks(vmNetworks, networkmap.Spec.Networks)
+	if err != nil {
+		return nil, failedCheck(PreflightCheckNetworks, err.Error())
+	}
+	if len(advancedOptions.GranularNetworks) > 0 {
+		targetNetworks = advancedOptions.GranularNetworks
+	}
+	if len(targetNetworks) != len(vmNetworks) {
+		return nil, failedCheck(PreflightCheckNetworks, fmt.Sprintf("number of mac addresses does not match number of network names mac(%d) network(%d)",
+			len(vmNetworks), len(targetNetworks)))
+	}
+	for _, network := range targetNetworks {
+		if _, ok := inventory.networks[network]; !ok {
+			return nil, failedCheck(PreflightCheckNetworks, fmt.Sprintf("network '%s' not found in OpenStack", network))
+		}
+	}
+	if len(advancedOptions.GranularPorts) > 0 && len(advancedOptions.GranularPorts) != len(targetNetworks) {
+		return nil, failedCheck(PreflightCheckNetworks, fmt.Sprintf("number of network ports does not match number of network names port(%d) network(%d)",
+			len(advancedOptions.GranularPorts), len(targetNetworks)))
+	}
+	return targetNetworks, passedCheck(PreflightCheckNetworks, fmt.Sprintf("NICs are mapped to networks %s", strings.Join(targetNetworks, ", ")))
+}
+
+func checkStorage(ctx context.Context, k8sClient client.Client, params PreflightParams, inventory *openstackInventory,
+	vm string, advancedOptions vjailbreakv1alpha1.AdvancedOptions) vjailbreakv1alpha1.PreflightCheck {
+	vmwcreds := params.VMwareCreds
+	datastores, err := GetVMwDatastore(ctx, k8sClient, vmwcreds, vmwcreds.Spec.DataCenter, vm)
+	if err != nil {
+		return failedCheck(PreflightCheckStorage, fmt.Sprintf("failed to get VM datastores: %v", err))
+	}
+	storagemap := &vjailbreakv1alpha1.StorageMapping{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: params.MigrationTemplate.Spec.StorageMapping, Namespace: params.MigrationTemplate.Namespace}, storagemap); err != nil {
+		return failedCheck(PreflightCheckStorage, fmt.Sprintf("failed to retrieve StorageMapping CR: %v", err))
+	}
+	volumeTypes, err := MapVMDatastores(datastores, storagemap.Spec.Storages)
+	if err != nil {
+		return failedCheck(PreflightCheckStorage, err.Error())
+	}
+	if len(advancedOptions.GranularVolumeTypes) > 0 {
+		volumeTypes = advancedOptions.GranularVolumeTypes
+	}
+	if len(volumeTypes) != len(datastores) {
+		return failedCheck(PreflightCheckStorage, fmt.Sprintf("number of volume types does not match number of disks vm(%d) volume(%d)",
+			len(datastores), len(volumeTypes)))
+	}
+	for _, volumeType := range volumeTypes {
+		if !inventory.volumeTypes[volumeType] {
+			return failedCheck(PreflightCheckStorage, fmt.Sprintf("volume type '%s' not found in OpenStack", volumeType))
+		}
+	}
+	return passedCheck(PreflightCheckStorage, fmt.Sprintf("disks are mapped to volume types %s", strings.Join(volumeTypes, ", ")))
+}
+
+func checkVDDKDirectory(directory string) vjailbreakv1alpha1.PreflightCheck {
+	files, err := os.ReadDir(directory)
+	if err != nil {
+		return failedCheck(PreflightCheckVDDK, "VDDK directory is missing. Please create and upload the required files.")
+	}
+	if len(files) == 0 {
+		return failedCheck(PreflightCheckVDDK, "VDDK directory is empty. Please upload the required files.")
+	}
+	return passedCheck(PreflightCheckVDDK, "VDDK files are present")
+}
+
+// CheckGuestOSFamily checks the guest OS of a VM, as named by VMware, against the supported OS matrix.
+// The version of a Linux guest is only known once its disks are inspected, so it is not checked here.
+// An unsupported OS is only a warning when the plan allows unsupported OSes
+func CheckGuestOSFamily(matrix *osmatrix.Matrix, osFamily, osName string, allowUnsupported bool) vjailbreakv1alpha1.PreflightCheck {
+	if osFamily == "" {
+		return failedCheck(PreflightCheckGuestOS, "OSFamily is not available for the VM, set it explicitly in the VMwareMachine CR")
+	}
+	family := osmatrix.Family(osFamily)
+	if family == "" {
+		return failedCheck(PreflightCheckGuestOS, fmt.Sprintf("unsupported OS family %s", osFamily))
+	}
+	if osName == "" {
+		return warnedCheck(PreflightCheckGuestOS, fmt.Sprintf("OS name is not available for the VM, the %s guest is checked against the supported OS matrix when it is migrated", family))
+	}
+
+	result := matrix.Check(family, osName, "")
+	switch {
+	case result.Supported:
+		return passedCheck(PreflightCheckGuestOS, fmt.Sprintf("OS %s is supported", result.Entry.Distro))
+	case allowUnsupported:
+		return warnedCheck(PreflightCheckGuestOS, fmt.Sprintf("%s, it is migrated anyway because the plan allows unsupported OSes", result.Reason))
+	default:
+		return failedCheck(PreflightCheckGuestOS, result.Reason)
+	}
+}
+
+func checkFlavor(useFlavorless bool, inventory *openstackInventory, vmMachine *vjailbreakv1alpha1.VMwareMachine) vjailbreakv1alpha1.PreflightCheck {
+	if useFlavorless {
+		if inventory.baseFlavor == nil {
+			return failedCheck(PreflightCheckFlavor, "no base flavor for flavorless migration found")
+		}
+		return passedCheck(PreflightCheckFlavor, fmt.Sprintf("flavorless migration uses base flavor %s", inventory.baseFlavor.Name))
+	}
+
+	cpu, memory := vmMachine.Spec.VMInfo.CPU, vmMachine.Spec.VMInfo.Memory
+	if vmMachine.Spec.TargetFlavorID == "" {
+		flavor, err := GetClosestFlavour(cpu, memory, inventory.flavors)
+		if err != nil {
+			return failedCheck(PreflightCheckFlavor, err.Error())
+		}
+		return passedCheck(PreflightCheckFlavor, fmt.Sprintf("closest flavor is %s", flavor.Name))
+	}
+	for i := range inventory.flavors {
+		flavor := inventory.flavors[i]
+		if flavor.ID != vmMachine.Spec.TargetFlavorID {
+			continue
+		}
+		if flavor.VCPUs < cpu || flavor.RAM < memory {
+			return warnedCheck(PreflightCheckFlavor, fmt.Sprintf("target flavor %s has %d vCPUs and %d MB RAM, less than the %d vCPUs and %d MB RAM of the VM",
+				flavor.Name, flavor.VCPUs, flavor.RAM, cpu, memory))
+		}
+		return passedCheck(PreflightCheckFlavor, fmt.Sprintf("target flavor is %s", flavor.Name))
+	}
+	return failedCheck(PreflightCheckFlavor, fmt.Sprintf("target flavor %s not found in OpenStack", vmMachine.Spec.TargetFlavorID))
+}
+
+// checkIPAddresses checks that the IPs the VM keeps on its target networks are not used by other ports
+func checkIPAddresses(inventory *openstackInventory, vmMachine *vjailbreakv1alpha1.VMwareMachine, targetNetworks []string,
+	fallbackToDHCP bool) vjailbreakv1alpha1.PreflightCheck {
+	var conflicts []string
+	for idx, nic := range vmMachine.Spec.VMInfo.NetworkInterfaces {
+		ip := nic.IPAddress
+		if vmMachine.Spec.VMInfo.AssignedIP != "" {
+			ip = vmMachine.Spec.VMInfo.AssignedIP
+		}
+		if ip == "" || idx >= len(targetNetworks) {
+			continue
+		}
+		network := inventory.networks[targetNetworks[idx]]
+		allPages, err := ports.List(inventory.clients.NetworkingClient, ports.ListOpts{
+			NetworkID: network.ID,
+			FixedIPs:  []ports.FixedIPOpts{{IPAddress: ip}},
+		}).AllPages()
+		if err != nil {
+			return failedCheck(PreflightCheckIPAddresses, fmt.Sprintf("failed to list ports: %v", err))
+		}
+		networkPorts, err := ports.ExtractPorts(allPages)
+		if err != nil {
+			return failedCheck(PreflightCheckIPAddresses, fmt.Sprintf("failed to extract ports: %v", err))
+		}
+		if conflict := FindIPConflict(networkPorts, ip, nic.MAC); conflict != "" {
+			conflicts = append(conflicts, fmt.Sprintf("IP %s on network %s is used by port %s", ip, network.Name, conflict))
+		}
+	}
+	if len(conflicts) == 0 {
+		return passedCheck(PreflightCheckIPAddresses, "IP addresses of the VM are available")
+	}
+	if fallbackToDHCP {
+		return warnedCheck(PreflightCheckIPAddresses, strings.Join(conflicts, "; ")+", the VM will get an address from DHCP")
+	}
+	return failedCheck(PreflightCheckIPAddresses, strings.Join(conflicts, "; "))
+}
+
+// FindIPConflict returns the ID of a port holding ip that is not the port of the NIC with mac, which the migration reuses
+func FindIPConflict(networkPorts []ports.Port, ip, mac string) string {
+	for _, port := range networkPorts {
+		if strings.EqualFold(port.MACAddress, mac) {
+			continue
+		}
+		for _, fixedIP := range port.FixedIPs {
+			if fixedIP.IPAddress == ip {
+				return port.ID
+			}
+		}
+	}
+	return ""
+}
+
+// MapVMNetworks maps each network of the VM, one per NIC, to its target network
+func MapVMNetworks(vmNetworks []string, mappings []vjailbreakv1alpha1.Network) ([]string, error) {
+	targetNetworks := []string{}
+	for _, vmNetwork := range vmNetworks {
+		found := false
+		for _, mapping := range mappings {
+			if vmNetwork == mapping.Source {
+				targetNetworks = append(targetNetworks, mapping.Target)
+				found = true
+				break // Use the first matching mapping
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("VMware network %q not found in NetworkMapping", vmNetwork)
+		}
+	}
+	return targetNetworks, nil
+}
+
+// MapVMDatastores maps the datastore of each disk of the VM to its target volume type
+func MapVMDatastores(datastores []string, mappings []vjailbreakv1alpha1.Storage) ([]string, error) {
+	volumeTypes := []string{}
+	for _, datastore := range datastores {
+		for _, mapping := range mappings {
+			if datastore == mapping.Source {
+				volumeTypes = append(volumeTypes, mapping.Target)
+			}
+		}
+	}
+	if len(volumeTypes) != len(datastores) {
+		return nil, errors.Errorf("VMware Datastore(s) not found in StorageMapping vm(%d) openstack(%d)", len(datastores), len(volumeTypes))
+	}
+	return volumeTypes, nil
+}
+
+// CheckQuota reserves the resources of a VM in the capacity left in the project. VMs that do not fit
+// fail the check and do not take any capacity, so the VMs after them are checked against the same capacity.
+func CheckQuota(capacity *ProjectCapacity, request ResourceRequest) vjailbreakv1alpha1.PreflightCheck {
+	if shortfalls := capacity.Reserve(request); len(shortfalls) > 0 {
+		return failedCheck(PreflightCheckQuota, "not enough quota left: "+strings.Join(shortfalls, ", "))
+	}
+	return passedCheck(PreflightCheckQuota, fmt.Sprintf("%d cores, %d MB RAM, %d ports and %d volumes of %d GB in total fit in the quota",
+		request.Cores, request.RAMMB, request.Ports, request.Volumes, request.VolumeGigabytes))
+}
+
+// WorsePreflightResult returns the more severe of two results
+func WorsePreflightResult(a, b vjailbreakv1alpha1.PreflightResult) vjailbreakv1alpha1.PreflightResult {
+	severity := map[vjailbreakv1alpha1.PreflightResult]int{
+		vjailbreakv1alpha1.PreflightResultPass: 0,
+		vjailbreakv1alpha1.PreflightResultWarn: 1,
+		vjailbreakv1alpha1.PreflightResultFail: 2,
+	}
+	if severity[b] > severity[a] {
+		return b
+	}
+	return a
+}
+
+// PreflightReportUpToDate returns true if the plan has a report for its current spec
+func PreflightReportUpToDate(migrationplan *vjailbreakv1alpha1.MigrationPlan) bool {
+	report := migrationplan.Status.PreflightReport
+	return report != nil && report.ObservedGeneration == migrationplan.Generation
+}
+
+// PreflightSummary summarizes a report in a single line for the plan status message
+func PreflightSummary(report *vjailbreakv1alpha1.PreflightReport) string {
+	counts := map[vjailbreakv1alpha1.PreflightResult]int{}
+	for _, vm := range report.VMs {
+		counts[vm.Result]++
+	}
+	return fmt.Sprintf("Dry run completed: %d VM(s) passed, %d with warnings, %d failed",
+		counts[vjailbreakv1alpha1.PreflightResultPass], counts[vjailbreakv1alpha1.PreflightResultWarn], counts[vjailbreakv1alpha1.PreflightResultFail])
+}
+
+func passedCheck(name, message string) vjailbreakv1alpha1.PreflightCheck {
+	return vjailbreakv1alpha1.PreflightCheck{Name: name, Result: vjailbreakv1alpha1.PreflightResultPass, Message: message}
+}
+
+func warnedCheck(name, message string) vjailbreakv1alpha1.PreflightCheck {
+	return vjailbreakv1alpha1.PreflightCheck{Name: name, Result: vjailbreakv1alpha1.PreflightResultWarn, Message: message}
+}
+
+func failedCheck(name, message string) vjailbreakv1alpha1.PreflightCheck {
+	return vjailbreakv1alpha1.PreflightCheck{Name: name, Result: vjailbreakv1alpha1.PreflightResultFail, Message: message}
+}
@@ -0,0 +1,131 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/osmatrix"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+)
+
+func TestMapVMNetworks(t *testing.T) {
+	mappings := []vjailbreakv1alpha1.Network{
+		{Source: "VM Network", Target: "provider"},
+		{Source: "VM Network", Target: "other"},
+		{Source: "backup", Target: "backup-net"},
+	}
+	targets, err := utils.MapVMNetworks([]string{"VM Network", "backup", "VM Network"}, mappings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"provider", "backup-net", "provider"}
+	if len(targets) != len(expected) {
+		t.Fatalf("MapVMNetworks() = %v, expected %v", targets, expected)
+	}
+	for i := range expected {
+		if targets[i] != expected[i] {
+			t.Errorf("MapVMNetworks() = %v, expected %v", targets, expected)
+		}
+	}
+
+	if _, err := utils.MapVMNetworks([]string{"unmapped"}, mappings); err == nil {
+		t.Error("expected an error for an unmapped network")
+	}
+}
+
+func TestMapVMDatastores(t *testing.T) {
+	mappings := []vjailbreakv1alpha1.Storage{
+		{Source: "datastore1", Target: "ceph"},
+		{Source: "datastore2", Target: "lvm"},
+	}
+	volumeTypes, err := utils.MapVMDatastores([]string{"datastore2", "datastore1"}, mappings)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(volumeTypes) != 2 || volumeTypes[0] != "lvm" || volumeTypes[1] != "ceph" {
+		t.Errorf("MapVMDatastores() = %v, expected [lvm ceph]", volumeTypes)
+	}
+
+	if _, err := utils.MapVMDatastores([]string{"datastore3"}, mappings); err == nil {
+		t.Error("expected an error for an unmapped datastore")
+	}
+}
+
+func TestFindIPConflict(t *testing.T) {
+	networkPorts := []ports.Port{
+		{ID: "own", MACAddress: "00:50:56:aa:bb:cc", FixedIPs: []ports.IP{{IPAddress: "10.0.0.5"}}},
+		{ID: "other", MACAddress: "fa:16:3e:00:00:01", FixedIPs: []ports.IP{{IPAddress: "10.0.0.6"}}},
+	}
+	if conflict := utils.FindIPConflict(networkPorts, "10.0.0.5", "00:50:56:AA:BB:CC"); conflict != "" {
+		t.Errorf("the port of the VM itself is reused, expected no conflict, got %s", conflict)
+	}
+	if conflict := utils.FindIPConflict(networkPorts, "10.0.0.6", "00:50:56:aa:bb:cc"); conflict != "other" {
+		t.Errorf("expected a conflict with port other, got %q", conflict)
+	}
+	if conflict := utils.FindIPConflict(networkPorts, "10.0.0.7", "00:50:56:aa:bb:cc"); conflict != "" {
+		t.Errorf("expected no conflict for a free IP, got %s", conflict)
+	}
+}
+
+func TestCheckGuestOSFamily(t *testing.T) {
+	matrix, err := osmatrix.Default()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		osFamily         string
+		osName           string
+		allowUnsupported bool
+		expected         vjailbreakv1alpha1.PreflightResult
+	}{
+		{"linuxGuest", "Red Hat Enterprise Linux 9 (64-bit)", false, vjailbreakv1alpha1.PreflightResultPass},
+		{"windowsGuest", "Microsoft Windows Server 2019 (64-bit)", false, vjailbreakv1alpha1.PreflightResultPass},
+		{"linuxGuest", "", false, vjailbreakv1alpha1.PreflightResultWarn},
+		{"linuxGuest", "FreeBSD 13 (64-bit)", false, vjailbreakv1alpha1.PreflightResultFail},
+		{"linuxGuest", "FreeBSD 13 (64-bit)", true, vjailbreakv1alpha1.PreflightResultWarn},
+		{"windowsGuest", "Microsoft Windows Server 2003 (32-bit)", false, vjailbreakv1alpha1.PreflightResultFail},
+		{"otherGuest", "Solaris 11 (64-bit)", true, vjailbreakv1alpha1.PreflightResultFail},
+		{"", "", false, vjailbreakv1alpha1.PreflightResultFail},
+	}
+	for _, tt := range tests {
+		if got := utils.CheckGuestOSFamily(matrix, tt.osFamily, tt.osName, tt.allowUnsupported).Result; got != tt.expected {
+			t.Errorf("CheckGuestOSFamily(%q, %q, %t) = %s, expected %s", tt.osFamily, tt.osName, tt.allowUnsupported, got, tt.expected)
+		}
+	}
+}
+
+func TestPreflightReportResults(t *testing.T) {
+	if got := utils.WorsePreflightResult(vjailbreakv1alpha1.PreflightResultWarn, vjailbreakv1alpha1.PreflightResultPass); got != vjailbreakv1alpha1.PreflightResultWarn {
+		t.Errorf("expected Warn, got %s", got)
+	}
+	if got := utils.WorsePreflightResult(vjailbreakv1alpha1.PreflightResultWarn, vjailbreakv1alpha1.PreflightResultFail); got != vjailbreakv1alpha1.PreflightResultFail {
+		t.Errorf("expected Fail, got %s", got)
+	}
+
+	report := &vjailbreakv1alpha1.PreflightReport{
+		ObservedGeneration: 2,
+		VMs: []vjailbreakv1alpha1.VMPreflightReport{
+			{VMName: "vm1", Result: vjailbreakv1alpha1.PreflightResultPass},
+			{VMName: "vm2", Result: vjailbreakv1alpha1.PreflightResultFail},
+			{VMName: "vm3", Result: vjailbreakv1alpha1.PreflightResultPass},
+		},
+	}
+	if got := utils.PreflightSummary(report); got != "Dry run completed: 2 VM(s) passed, 0 with warnings, 1 failed" {
+		t.Errorf("unexpected summary %q", got)
+	}
+
+	plan := &vjailbreakv1alpha1.MigrationPlan{}
+	plan.Generation = 2
+	if utils.PreflightReportUpToDate(plan) {
+		t.Error("a plan without a report is not up to date")
+	}
+	plan.Status.PreflightReport = report
+	if !utils.PreflightReportUpToDate(plan) {
+		t.Error("expected the report to be up to date")
+	}
+	plan.Generation = 3
+	if utils.PreflightReportUpToDate(plan) {
+		t.Error("a report of an older generation is not up to date")
+	}
+}
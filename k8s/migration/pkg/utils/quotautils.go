@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+
+	blockstoragequotasets "github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/quotasets"
+	computequotasets "github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	networkingquotas "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// UnlimitedQuota is the amount left of a resource whose quota is not limited
+const UnlimitedQuota = -1
+
+const bytesPerGiB = 1024 * 1024 * 1024
+
+// ResourceRequest is the amount of OpenStack resources a migrated VM takes in the target project
+type ResourceRequest struct {
+	Instances       int
+	Cores           int
+	RAMMB           int
+	Ports           int
+	Volumes         int
+	VolumeGigabytes int
+}
+
+// ComputeOnly returns the part of the request that is only taken once the VM is created in OpenStack.
+// The volumes of a VM are created before its disks are copied, so they already show up in the Cinder usage
+// of a migration in progress.
+func (r ResourceRequest) ComputeOnly() ResourceRequest {
+	return ResourceRequest{Instances: r.Instances, Cores: r.Cores, RAMMB: r.RAMMB, Ports: r.Ports}
+}
+
+// ResourceRequestForVM returns the resources needed by a VM with disks of the given capacities in bytes.
+// RDM disks are not counted, they are managed into Cinder before the migration starts.
+func ResourceRequestForVM(vminfo *vjailbreakv1alpha1.VMInfo, diskSizes []int64) ResourceRequest {
+	request := ResourceRequest{
+		Instances: 1,
+		Cores:     vminfo.CPU,
+		RAMMB:     vminfo.Memory,
+		Ports:     len(vminfo.Networks),
+		Volumes:   len(diskSizes),
+	}
+	if len(vminfo.NetworkInterfaces) > 0 {
+		request.Ports = len(vminfo.NetworkInterfaces)
+	}
+	for _, size := range diskSizes {
+		request.VolumeGigabytes += int((size + bytesPerGiB - 1) / bytesPerGiB)
+	}
+	return request
+}
+
+// GetVMResourceRequest returns the resources the VM of a VMwareMachine needs in the target project.
+// The disk sizes are read from vCenter if the VMwareMachine was last synced before they were recorded.
+func GetVMResourceRequest(ctx context.Context, k8sClient client.Client, vmwcreds *vjailbreakv1alpha1.VMwareCreds,
+	vmMachine *vjailbreakv1alpha1.VMwareMachine) (ResourceRequest, error) {
+	vminfo := &vmMachine.Spec.VMInfo
+	diskSizes := vminfo.DiskSizes
+	if len(diskSizes) != len(vminfo.Disks) {
+		var err error
+		diskSizes, err = GetVMwDiskCapacities(ctx, k8sClient, vmwcreds, vmwcreds.Spec.DataCenter, vminfo.Name)
+		if err != nil {
+			return ResourceRequest{}, errors.Wrapf(err, "failed to get disk sizes of VM %s", vminfo.Name)
+		}
+	}
+	return ResourceRequestForVM(vminfo, diskSizes), nil
+}
+
+// ProjectCapacity tracks the Nova, Cinder and Neutron quota left in a project while VMs are
+// placed in it one after the other. UnlimitedQuota means the resource is not limited.
+type ProjectCapacity struct {
+	Left ResourceRequest
+}
+
+// quotaLeft returns the amount left of a resource, never less than zero
+func quotaLeft(limit, inUse, reserved int) int {
+	if limit < 0 {
+		return UnlimitedQuota
+	}
+	return max(limit-inUse-reserved, 0)
+}
+
+// NewProjectCapacity returns the capacity left from the quota usage of a project
+func NewProjectCapacity(compute computequotasets.QuotaDetailSet, volume blockstoragequotasets.QuotaUsageSet,
+	network networkingquotas.QuotaDetailSet) *ProjectCapacity {
+	return &ProjectCapacity{Left: ResourceRequest{
+		Instances:       quotaLeft(compute.Instances.Limit, compute.Instances.InUse, compute.Instances.Reserved),
+		Cores:           quotaLeft(compute.Cores.Limit, compute.Cores.InUse, compute.Cores.Reserved),
+		RAMMB:           quotaLeft(compute.RAM.Limit, compute.RAM.InUse, compute.RAM.Reserved),
+		Ports:           quotaLeft(network.Port.Limit, network.Port.Used, network.Port.Reserved),
+		Volumes:         quotaLeft(volume.Volumes.Limit, volume.Volumes.InUse, volume.Volumes.Reserved),
+		VolumeGigabytes: quotaLeft(volume.Gigabytes.Limit, volume.Gigabytes.InUse, volume.Gigabytes.Reserved),
+	}}
+}
+
+// GetProjectCapacity queries the quota and usage of the project of the OpenStack credentials
+func GetProjectCapacity(ctx context.Context, k8sClient client.Client, openstackcreds *vjailbreakv1alpha1.OpenstackCreds) (*ProjectCapacity, error) {
+	openstackClients, err := GetOpenStackClients(ctx, k8sClient, openstackcreds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get openstack clients")
+	}
+	projectID, err := GetOpenstackProjectID(ctx, k8sClient, openstackcreds, openstackClients)
+	if err != nil {
+		return nil, err
+	}
+	compute, err := computequotasets.GetDetail(openstackClients.ComputeClient, projectID).Extract()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get compute quota usage")
+	}
+	volume, err := blockstoragequotasets.GetUsage(openstackClients.BlockStorageClient, projectID).Extract()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get volume quota usage")
+	}
+	network, err := networkingquotas.GetDetail(openstackClients.NetworkingClient, projectID).Extract()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get networking quota usage")
+	}
+	return NewProjectCapacity(compute, volume, *network), nil
+}
+
+type quotaResource struct {
+	name string
+	left *int
+	need int
+}
+
+func (c *ProjectCapacity) resources(request ResourceRequest) []quotaResource {
+	return []quotaResource{
+		{name: "instances", left: &c.Left.Instances, need: request.Instances},
+		{name: "cores", left: &c.Left.Cores, need: request.Cores},
+		{name: "RAM (MB)", left: &c.Left.RAMMB, need: request.RAMMB},
+		{name: "ports", left: &c.Left.Ports, need: request.Ports},
+		{name: "volumes", left: &c.Left.Volumes, need: request.Volumes},
+		{name: "volume gigabytes", left: &c.Left.VolumeGigabytes, need: request.VolumeGigabytes},
+	}
+}
+
+// Shortfalls describes each resource of the request that does not fit in the capacity left.
+// An empty result means the request fits.
+func (c *ProjectCapacity) Shortfalls(request ResourceRequest) []string {
+	var shortfalls []string
+	for _, resource := range c.resources(request) {
+		if *resource.left != UnlimitedQuota && resource.need > *resource.left {
+			shortfalls = append(shortfalls, fmt.Sprintf("%s: %d needed, %d left", resource.name, resource.need, *resource.left))
+		}
+	}
+	return shortfalls
+}
+
+// Take takes the request off the capacity left, whether it fits or not
+func (c *ProjectCapacity) Take(request ResourceRequest) {
+	for _, resource := range c.resources(request) {
+		if *resource.left != UnlimitedQuota {
+			*resource.left = max(*resource.left-resource.need, 0)
+		}
+	}
+}
+
+// Reserve takes the request off the capacity left if it fits, otherwise it returns the shortfalls
+// and leaves the capacity unchanged
+func (c *ProjectCapacity) Reserve(request ResourceRequest) []string {
+	shortfalls := c.Shortfalls(request)
+	if len(shortfalls) == 0 {
+		c.Take(request)
+	}
+	return shortfalls
+}
@@ -0,0 +1,98 @@
+package utils_test
+
+import (
+	"testing"
+
+	blockstoragequotasets "github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/quotasets"
+	computequotasets "github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/quotasets"
+	networkingquotas "github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/quotas"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+)
+
+const gib = 1024 * 1024 * 1024
+
+func TestResourceRequestForVM(t *testing.T) {
+	vminfo := &vjailbreakv1alpha1.VMInfo{
+		CPU:      4,
+		Memory:   8192,
+		Networks: []string{"VM Network"},
+		NetworkInterfaces: []vjailbreakv1alpha1.NIC{
+			{Network: "VM Network", MAC: "00:50:56:aa:bb:01"},
+			{Network: "VM Network", MAC: "00:50:56:aa:bb:02"},
+		},
+	}
+	// A partial GiB counts as a full one
+	request := utils.ResourceRequestForVM(vminfo, []int64{20 * gib, 10*gib + 1})
+	expected := utils.ResourceRequest{Instances: 1, Cores: 4, RAMMB: 8192, Ports: 2, Volumes: 2, VolumeGigabytes: 31}
+	if request != expected {
+		t.Errorf("ResourceRequestForVM() = %+v, expected %+v", request, expected)
+	}
+	if compute := request.ComputeOnly(); compute.Volumes != 0 || compute.VolumeGigabytes != 0 || compute.Cores != 4 {
+		t.Errorf("ComputeOnly() = %+v, expected no volumes", compute)
+	}
+}
+
+func newCapacity() *utils.ProjectCapacity {
+	return utils.NewProjectCapacity(
+		computequotasets.QuotaDetailSet{
+			Instances: computequotasets.QuotaDetail{Limit: 10, InUse: 7},
+			Cores:     computequotasets.QuotaDetail{Limit: 40, InUse: 20, Reserved: 4},
+			RAM:       computequotasets.QuotaDetail{Limit: -1, InUse: 100000},
+		},
+		blockstoragequotasets.QuotaUsageSet{
+			Volumes:   blockstoragequotasets.QuotaUsage{Limit: 10, InUse: 6, Reserved: 1},
+			Gigabytes: blockstoragequotasets.QuotaUsage{Limit: 100, InUse: 40},
+		},
+		networkingquotas.QuotaDetailSet{
+			Port: networkingquotas.QuotaDetail{Limit: 50, Used: 60},
+		},
+	)
+}
+
+func TestProjectCapacity(t *testing.T) {
+	capacity := newCapacity()
+	expected := utils.ResourceRequest{Instances: 3, Cores: 16, RAMMB: utils.UnlimitedQuota, Ports: 0, Volumes: 3, VolumeGigabytes: 60}
+	if capacity.Left != expected {
+		t.Fatalf("NewProjectCapacity() left %+v, expected %+v", capacity.Left, expected)
+	}
+
+	// Usage over the limit leaves nothing, a VM without NICs still fits
+	vm := utils.ResourceRequest{Instances: 1, Cores: 8, RAMMB: 16384, Volumes: 2, VolumeGigabytes: 40}
+	if shortfalls := capacity.Reserve(vm); len(shortfalls) != 0 {
+		t.Fatalf("expected the first VM to fit, got %v", shortfalls)
+	}
+	shortfalls := capacity.Reserve(vm)
+	if len(shortfalls) != 2 || shortfalls[0] != "volumes: 2 needed, 1 left" || shortfalls[1] != "volume gigabytes: 40 needed, 20 left" {
+		t.Errorf("unexpected shortfalls %v", shortfalls)
+	}
+	if capacity.Left.Volumes != 1 || capacity.Left.VolumeGigabytes != 20 {
+		t.Errorf("a VM that does not fit must not take capacity, left %+v", capacity.Left)
+	}
+	if shortfalls := capacity.Reserve(utils.ResourceRequest{Instances: 1, Ports: 1}); len(shortfalls) != 1 || shortfalls[0] != "ports: 1 needed, 0 left" {
+		t.Errorf("unexpected shortfalls %v", shortfalls)
+	}
+
+	// Running migrations are taken off even if they no longer fit
+	capacity.Take(utils.ResourceRequest{Instances: 5, Cores: 2})
+	if capacity.Left.Instances != 0 || capacity.Left.Cores != 6 || capacity.Left.RAMMB != utils.UnlimitedQuota {
+		t.Errorf("unexpected capacity left %+v", capacity.Left)
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	capacity := newCapacity()
+	vm := utils.ResourceRequest{Instances: 1, Cores: 8, Volumes: 1, VolumeGigabytes: 30}
+	if check := utils.CheckQuota(capacity, vm); check.Result != vjailbreakv1alpha1.PreflightResultPass {
+		t.Fatalf("expected the first VM to fit, got %+v", check)
+	}
+	if check := utils.CheckQuota(capacity, vm); check.Result != vjailbreakv1alpha1.PreflightResultPass {
+		t.Fatalf("expected the second VM to fit, got %+v", check)
+	}
+	if check := utils.CheckQuota(capacity, vm); check.Result != vjailbreakv1alpha1.PreflightResultFail {
+		t.Errorf("expected the third VM to exceed the cores and gigabytes left, got %+v", check)
+	}
+	if check := utils.CheckQuota(capacity, utils.ResourceRequest{Instances: 1}); check.Result != vjailbreakv1alpha1.PreflightResultPass {
+		t.Errorf("failed VMs must not take quota, expected the last VM to fit, got %+v", check)
+	}
+}
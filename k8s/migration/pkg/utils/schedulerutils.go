@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+)
+
+// MigrationDemand is what the migration of a VM takes on the agent it runs on
+type MigrationDemand struct {
+	// VolumeAttachments is the number of Cinder volumes the migration attaches to the agent
+	VolumeAttachments int
+	// BandwidthMBps is the copy bandwidth limit of the migration, 0 if unlimited
+	BandwidthMBps int
+}
+
+// MigrationDemandForVM returns what the migration of a VM takes on its agent. The disks are only attached to
+// the agent when they are copied to Cinder volumes without going through Ceph RBD directly.
+func MigrationDemandForVM(vminfo *vjailbreakv1alpha1.VMInfo, migrationtemplate *vjailbreakv1alpha1.MigrationTemplate,
+	migrationplan *vjailbreakv1alpha1.MigrationPlan) MigrationDemand {
+	demand := MigrationDemand{BandwidthMBps: peakBandwidthMBps(migrationplan.Spec.BandwidthLimit)}
+	backend := migrationtemplate.Spec.TargetBackend
+	if (backend == "" || backend == vjailbreakv1alpha1.DiskTargetBackendCinderAttach) && migrationtemplate.Spec.CephRBD == nil {
+		demand.VolumeAttachments = len(vminfo.Disks)
+	}
+	return demand
+}
+
+// peakBandwidthMBps returns the highest bandwidth limit, 0 if the copy is unlimited at any time of day
+func peakBandwidthMBps(limit *vjailbreakv1alpha1.BandwidthLimit) int {
+	if limit == nil || limit.MBps == 0 {
+		return 0
+	}
+	peak := limit.MBps
+	for _, window := range limit.Windows {
+		if window.MBps == 0 {
+			return 0
+		}
+		peak = max(peak, window.MBps)
+	}
+	return peak
+}
+
+// Annotations returns the annotations recording the demand on the Job of the migration
+func (d MigrationDemand) Annotations() map[string]string {
+	return map[string]string{
+		constants.VolumeAttachmentsAnnotation: strconv.Itoa(d.VolumeAttachments),
+		constants.BandwidthMBpsAnnotation:     strconv.Itoa(d.BandwidthMBps),
+	}
+}
+
+// MigrationDemandFromAnnotations returns the demand recorded on the Job of a migration.
+// Jobs created before the demand was recorded only count as a migration.
+func MigrationDemandFromAnnotations(annotations map[string]string) MigrationDemand {
+	demand := MigrationDemand{}
+	demand.VolumeAttachments, _ = strconv.Atoi(annotations[constants.VolumeAttachmentsAnnotation])
+	demand.BandwidthMBps, _ = strconv.Atoi(annotations[constants.BandwidthMBpsAnnotation])
+	return demand
+}
+
+// AgentCapacity tracks what an agent can take and what the migrations placed on it already use
+type AgentCapacity struct {
+	// Name is the name of the VjailbreakNode of the agent
+	Name string
+	// Hostname is the name of the Kubernetes node of the agent
+	Hostname string
+
+	MaxMigrations        int
+	MaxVolumeAttachments int
+	// BandwidthMBps is the network bandwidth of the agent, 0 if unlimited
+	BandwidthMBps int
+
+	Migrations        int
+	VolumeAttachments int
+	UsedBandwidthMBps int
+}
+
+// NewAgentCapacity returns the capacity of the agent of a VjailbreakNode running on the Kubernetes node hostname
+func NewAgentCapacity(vjNode *vjailbreakv1alpha1.VjailbreakNode, hostname string) *AgentCapacity {
+	agent := &AgentCapacity{
+		Name:                 vjNode.Name,
+		Hostname:             hostname,
+		MaxMigrations:        vjNode.Spec.MaxConcurrentMigrations,
+		MaxVolumeAttachments: vjNode.Spec.MaxVolumeAttachments,
+		BandwidthMBps:        vjNode.Spec.BandwidthMBps,
+	}
+	if agent.MaxMigrations == 0 {
+		agent.MaxMigrations = constants.DefaultMaxConcurrentMigrationsPerNode
+	}
+	if agent.MaxVolumeAttachments == 0 {
+		agent.MaxVolumeAttachments = constants.DefaultMaxVolumeAttachmentsPerNode
+	}
+	return agent
+}
+
+// bandwidthFor returns the bandwidth a migration takes on the agent. A migration without a limit is counted
+// for an even share of the bandwidth of the agent.
+func (a *AgentCapacity) bandwidthFor(demand MigrationDemand) int {
+	if demand.BandwidthMBps > 0 || a.BandwidthMBps == 0 {
+		return demand.BandwidthMBps
+	}
+	return max(a.BandwidthMBps/a.MaxMigrations, 1)
+}
+
+// Shortfalls returns what the agent is missing to run the migration, nothing if it fits
+func (a *AgentCapacity) Shortfalls(demand MigrationDemand) []string {
+	shortfalls := []string{}
+	if a.Migrations >= a.MaxMigrations {
+		shortfalls = append(shortfalls, fmt.Sprintf("migrations (%d of %d running)", a.Migrations, a.MaxMigrations))
+	}
+	if left := a.MaxVolumeAttachments - a.VolumeAttachments; demand.VolumeAttachments > left {
+		shortfalls = append(shortfalls, fmt.Sprintf("volume attachments (need %d, %d left)", demand.VolumeAttachments, max(left, 0)))
+	}
+	if a.BandwidthMBps > 0 {
+		if left, need := a.BandwidthMBps-a.UsedBandwidthMBps, a.bandwidthFor(demand); need > left {
+			shortfalls = append(shortfalls, fmt.Sprintf("bandwidth (need %d MB/s, %d MB/s left)", need, max(left, 0)))
+		}
+	}
+	return shortfalls
+}
+
+// Unfit returns what the agent is missing to run the migration even with nothing else running on it,
+// nothing if it can run it once enough migrations finish
+func (a *AgentCapacity) Unfit(demand MigrationDemand) []string {
+	idle := &AgentCapacity{
+		MaxMigrations:        a.MaxMigrations,
+		MaxVolumeAttachments: a.MaxVolumeAttachments,
+		BandwidthMBps:        a.BandwidthMBps,
+	}
+	return idle.Shortfalls(demand)
+}
+
+// Take records a migration placed on the agent
+func (a *AgentCapacity) Take(demand MigrationDemand) {
+	a.Migrations++
+	a.VolumeAttachments += demand.VolumeAttachments
+	a.UsedBandwidthMBps += a.bandwidthFor(demand)
+}
+
+// PendingMigration is a migration waiting to be placed on an agent
+type PendingMigration struct {
+	Name     string
+	Priority int
+	Demand   MigrationDemand
+}
+
+// ScheduleResult is the outcome of the placement of pending migrations on the agents
+type ScheduleResult struct {
+	// Placed is the agent of each placed migration by name
+	Placed map[string]*AgentCapacity
+	// Queued are the names of the migrations no agent has room for, in the order they are placed later
+	Queued []string
+	// Unschedulable are the names of the migrations no agent can run even when idle, they are not queued
+	Unschedulable []string
+	// Reasons explains by name why each queued or unschedulable migration could not be placed
+	Reasons map[string]string
+}
+
+// ScheduleMigrations places the pending migrations on the agents, highest priority first and in the given order
+// within a priority. Each migration goes to the agent with the most free migration slots that has room for it.
+// A migration no agent has room for is queued and reserves the agent with the most free slots among those that
+// can run it, so the capacity freed on that agent goes to it before the migrations after it. A migration no agent
+// can run even when idle is unschedulable and holds back nothing.
+func ScheduleMigrations(agents []*AgentCapacity, pending []PendingMigration) ScheduleResult {
+	result := ScheduleResult{Placed: map[string]*AgentCapacity{}, Reasons: map[string]string{}}
+	ordered := make([]PendingMigration, len(pending))
+	copy(ordered, pending)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+	// reserved holds the queued migration each agent is reserved for
+	reserved := map[*AgentCapacity]string{}
+	for _, migration := range ordered {
+		var best, reserve *AgentCapacity
+		reasons := []string{}
+		fits := false
+		for _, agent := range agents {
+			if unfit := agent.Unfit(migration.Demand); len(unfit) > 0 {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", agent.Name, strings.Join(unfit, ", ")))
+				continue
+			}
+			fits = true
+			if holder, ok := reserved[agent]; ok {
+				reasons = append(reasons, fmt.Sprintf("%s: reserved for %s", agent.Name, holder))
+				continue
+			}
+			if reserve == nil || agent.MaxMigrations-agent.Migrations > reserve.MaxMigrations-reserve.Migrations {
+				reserve = agent
+			}
+			if shortfalls := agent.Shortfalls(migration.Demand); len(shortfalls) > 0 {
+				reasons = append(reasons, fmt.Sprintf("%s: %s", agent.Name, strings.Join(shortfalls, ", ")))
+				continue
+			}
+			if best == nil || agent.MaxMigrations-agent.Migrations > best.MaxMigrations-best.Migrations {
+				best = agent
+			}
+		}
+		switch {
+		case best != nil:
+			best.Take(migration.Demand)
+			result.Placed[migration.Name] = best
+		case len(agents) > 0 && !fits:
+			result.Unschedulable = append(result.Unschedulable, migration.Name)
+			result.Reasons[migration.Name] = strings.Join(reasons, "; ")
+		default:
+			result.Queued = append(result.Queued, migration.Name)
+			if len(agents) == 0 {
+				reasons = append(reasons, "no agent is ready")
+			}
+			if reserve != nil {
+				reserved[reserve] = migration.Name
+			}
+			result.Reasons[migration.Name] = strings.Join(reasons, "; ")
+		}
+	}
+	return result
+}
@@ -0,0 +1,160 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+)
+
+func TestMigrationDemandForVM(t *testing.T) {
+	vminfo := &vjailbreakv1alpha1.VMInfo{Disks: []string{"disk-0", "disk-1", "disk-2"}}
+	plan := &vjailbreakv1alpha1.MigrationPlan{}
+	plan.Spec.BandwidthLimit = &vjailbreakv1alpha1.BandwidthLimit{
+		MBps:    50,
+		Windows: []vjailbreakv1alpha1.BandwidthWindow{{Start: "22:00", End: "06:00", MBps: 200}},
+	}
+	template := &vjailbreakv1alpha1.MigrationTemplate{}
+
+	demand := utils.MigrationDemandForVM(vminfo, template, plan)
+	if expected := (utils.MigrationDemand{VolumeAttachments: 3, BandwidthMBps: 200}); demand != expected {
+		t.Errorf("MigrationDemandForVM() = %+v, expected %+v", demand, expected)
+	}
+
+	// Disks staged on the agent or mapped through Ceph RBD are not attached to it, and a window without
+	// a limit makes the copy unlimited
+	template.Spec.TargetBackend = vjailbreakv1alpha1.DiskTargetBackendGlanceImage
+	plan.Spec.BandwidthLimit.Windows[0].MBps = 0
+	if demand := utils.MigrationDemandForVM(vminfo, template, plan); demand != (utils.MigrationDemand{}) {
+		t.Errorf("MigrationDemandForVM() = %+v, expected nothing", demand)
+	}
+	template.Spec.TargetBackend = vjailbreakv1alpha1.DiskTargetBackendCinderAttach
+	template.Spec.CephRBD = &vjailbreakv1alpha1.CephRBDSpec{KeyringSecret: "ceph-keyring"}
+	if demand := utils.MigrationDemandForVM(vminfo, template, plan); demand.VolumeAttachments != 0 {
+		t.Errorf("MigrationDemandForVM() = %+v, expected no volume attachments", demand)
+	}
+}
+
+func TestMigrationDemandAnnotations(t *testing.T) {
+	demand := utils.MigrationDemand{VolumeAttachments: 4, BandwidthMBps: 100}
+	if parsed := utils.MigrationDemandFromAnnotations(demand.Annotations()); parsed != demand {
+		t.Errorf("MigrationDemandFromAnnotations() = %+v, expected %+v", parsed, demand)
+	}
+	if parsed := utils.MigrationDemandFromAnnotations(nil); parsed != (utils.MigrationDemand{}) {
+		t.Errorf("MigrationDemandFromAnnotations(nil) = %+v, expected nothing", parsed)
+	}
+}
+
+func TestNewAgentCapacity(t *testing.T) {
+	vjNode := &vjailbreakv1alpha1.VjailbreakNode{}
+	vjNode.Name = "agent-1"
+	agent := utils.NewAgentCapacity(vjNode, "agent-1-host")
+	if agent.MaxMigrations != 5 || agent.MaxVolumeAttachments != 25 || agent.BandwidthMBps != 0 {
+		t.Errorf("NewAgentCapacity() = %+v, expected the defaults", agent)
+	}
+	vjNode.Spec.MaxConcurrentMigrations = 2
+	vjNode.Spec.MaxVolumeAttachments = 10
+	vjNode.Spec.BandwidthMBps = 400
+	agent = utils.NewAgentCapacity(vjNode, "agent-1-host")
+	if agent.MaxMigrations != 2 || agent.MaxVolumeAttachments != 10 || agent.BandwidthMBps != 400 {
+		t.Errorf("NewAgentCapacity() = %+v, expected the limits of the node", agent)
+	}
+}
+
+func TestAgentCapacityShortfalls(t *testing.T) {
+	agent := &utils.AgentCapacity{Name: "agent-1", MaxMigrations: 4, MaxVolumeAttachments: 6, BandwidthMBps: 400}
+	agent.Take(utils.MigrationDemand{VolumeAttachments: 4, BandwidthMBps: 150})
+	// A migration without a limit takes an even share of the bandwidth
+	agent.Take(utils.MigrationDemand{VolumeAttachments: 1})
+	if agent.Migrations != 2 || agent.VolumeAttachments != 5 || agent.UsedBandwidthMBps != 250 {
+		t.Fatalf("Take() left %+v", agent)
+	}
+	if shortfalls := agent.Shortfalls(utils.MigrationDemand{VolumeAttachments: 1, BandwidthMBps: 150}); len(shortfalls) != 0 {
+		t.Errorf("Shortfalls() = %v, expected the migration to fit", shortfalls)
+	}
+	expected := []string{
+		"volume attachments (need 2, 1 left)",
+		"bandwidth (need 200 MB/s, 150 MB/s left)",
+	}
+	if shortfalls := agent.Shortfalls(utils.MigrationDemand{VolumeAttachments: 2, BandwidthMBps: 200}); !reflect.DeepEqual(shortfalls, expected) {
+		t.Errorf("Shortfalls() = %v, expected %v", shortfalls, expected)
+	}
+	agent.Take(utils.MigrationDemand{})
+	agent.Take(utils.MigrationDemand{})
+	expected = []string{
+		"migrations (4 of 4 running)",
+		"bandwidth (need 100 MB/s, 0 MB/s left)",
+	}
+	if shortfalls := agent.Shortfalls(utils.MigrationDemand{}); !reflect.DeepEqual(shortfalls, expected) {
+		t.Errorf("Shortfalls() = %v, expected %v", shortfalls, expected)
+	}
+}
+
+func TestScheduleMigrations(t *testing.T) {
+	busy := &utils.AgentCapacity{Name: "busy", Hostname: "busy-host", MaxMigrations: 2, MaxVolumeAttachments: 25, Migrations: 1}
+	idle := &utils.AgentCapacity{Name: "idle", Hostname: "idle-host", MaxMigrations: 2, MaxVolumeAttachments: 4}
+	pending := []utils.PendingMigration{
+		{Name: "low", Demand: utils.MigrationDemand{VolumeAttachments: 1}},
+		{Name: "large", Demand: utils.MigrationDemand{VolumeAttachments: 30}},
+		{Name: "urgent", Priority: 10, Demand: utils.MigrationDemand{VolumeAttachments: 2}},
+		{Name: "next", Demand: utils.MigrationDemand{VolumeAttachments: 1}},
+		{Name: "last", Demand: utils.MigrationDemand{VolumeAttachments: 1}},
+	}
+
+	result := utils.ScheduleMigrations([]*utils.AgentCapacity{busy, idle}, pending)
+	// The urgent migration goes first to the agent with the most free slots, the large one can never run
+	// and holds back nothing
+	placed := map[string]string{}
+	for name, agent := range result.Placed {
+		placed[name] = agent.Name
+	}
+	expectedPlaced := map[string]string{"urgent": "idle", "low": "busy", "next": "idle"}
+	if !reflect.DeepEqual(placed, expectedPlaced) {
+		t.Errorf("Placed = %v, expected %v", placed, expectedPlaced)
+	}
+	if expectedQueued := []string{"last"}; !reflect.DeepEqual(result.Queued, expectedQueued) {
+		t.Errorf("Queued = %v, expected %v", result.Queued, expectedQueued)
+	}
+	if expectedUnschedulable := []string{"large"}; !reflect.DeepEqual(result.Unschedulable, expectedUnschedulable) {
+		t.Errorf("Unschedulable = %v, expected %v", result.Unschedulable, expectedUnschedulable)
+	}
+	if reason := result.Reasons["large"]; reason != "busy: volume attachments (need 30, 25 left); idle: volume attachments (need 30, 4 left)" {
+		t.Errorf("Reasons[large] = %q", reason)
+	}
+	if reason := result.Reasons["last"]; reason != "busy: migrations (2 of 2 running); idle: migrations (2 of 2 running)" {
+		t.Errorf("Reasons[last] = %q", reason)
+	}
+
+	result = utils.ScheduleMigrations(nil, pending[:1])
+	if len(result.Placed) != 0 || result.Reasons["low"] != "no agent is ready" {
+		t.Errorf("ScheduleMigrations() without agents = %+v", result)
+	}
+}
+
+func TestScheduleMigrationsReservesForQueued(t *testing.T) {
+	agent := &utils.AgentCapacity{Name: "agent", MaxMigrations: 3, MaxVolumeAttachments: 4, Migrations: 1, VolumeAttachments: 2}
+	pending := []utils.PendingMigration{
+		{Name: "small", Demand: utils.MigrationDemand{VolumeAttachments: 1}},
+		{Name: "big", Priority: 10, Demand: utils.MigrationDemand{VolumeAttachments: 4}},
+	}
+
+	// The small migration fits, but the agent is kept for the big one queued ahead of it
+	result := utils.ScheduleMigrations([]*utils.AgentCapacity{agent}, pending)
+	if len(result.Placed) != 0 {
+		t.Errorf("Placed = %v, expected nothing", result.Placed)
+	}
+	if expectedQueued := []string{"big", "small"}; !reflect.DeepEqual(result.Queued, expectedQueued) {
+		t.Errorf("Queued = %v, expected %v", result.Queued, expectedQueued)
+	}
+	if reason := result.Reasons["small"]; reason != "agent: reserved for big" {
+		t.Errorf("Reasons[small] = %q", reason)
+	}
+
+	// Once the running migration finishes, the big one takes the agent
+	agent.Migrations, agent.VolumeAttachments = 0, 0
+	result = utils.ScheduleMigrations([]*utils.AgentCapacity{agent}, pending)
+	if _, placed := result.Placed["big"]; !placed || !reflect.DeepEqual(result.Queued, []string{"small"}) {
+		t.Errorf("ScheduleMigrations() after the running migration finished = %+v", result)
+	}
+}
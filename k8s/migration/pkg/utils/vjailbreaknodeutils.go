@@ -513,6 +513,8 @@ func GetActiveMigrations(ctx context.Context, nodeName string, k3sclient client.
 	ignorePhases := []vjailbreakv1alpha1.VMMigrationPhase{vjailbreakv1alpha1.VMMigrationPhasePending,
 		vjailbreakv1alpha1.VMMigrationPhaseFailed,
 		vjailbreakv1alpha1.VMMigrationPhaseSucceeded,
+		vjailbreakv1alpha1.VMMigrationPhaseRolledBack,
+		vjailbreakv1alpha1.VMMigrationPhaseInspected,
 		vjailbreakv1alpha1.VMMigrationPhaseUnknown,
 	}
 
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetMigrationPlanVMs returns the VMs of a migration plan, in the order of its waves when it has waves
+func GetMigrationPlanVMs(migrationplan *vjailbreakv1alpha1.MigrationPlan) []string {
+	vms := []string{}
+	if len(migrationplan.Spec.Waves) > 0 {
+		for _, wave := range migrationplan.Spec.Waves {
+			vms = append(vms, wave.VirtualMachines...)
+		}
+		return vms
+	}
+	for _, parallelvms := range migrationplan.Spec.VirtualMachines {
+		vms = append(vms, parallelvms...)
+	}
+	return vms
+}
+
+// ValidateMigrationWaves checks the waves of a migration plan have unique names, depend on known waves without
+// cycles and hold each VM once
+func ValidateMigrationWaves(spec *vjailbreakv1alpha1.MigrationPlanSpec) error {
+	waves := map[string]*vjailbreakv1alpha1.MigrationWave{}
+	vms := map[string]string{}
+	for i := range spec.Waves {
+		wave := &spec.Waves[i]
+		if wave.Name == "" {
+			return fmt.Errorf("wave %d has no name", i+1)
+		}
+		if _, ok := waves[wave.Name]; ok {
+			return fmt.Errorf("wave '%s' is defined more than once", wave.Name)
+		}
+		waves[wave.Name] = wave
+		for _, vm := range wave.VirtualMachines {
+			if other, ok := vms[vm]; ok {
+				return fmt.Errorf("VM '%s' is in both wave '%s' and wave '%s'", vm, other, wave.Name)
+			}
+			vms[vm] = wave.Name
+		}
+		if wave.Gate == vjailbreakv1alpha1.WaveGateHealthy && !spec.MigrationStrategy.PerformHealthChecks {
+			return fmt.Errorf("wave '%s' waits for healthy dependencies but health checks are not performed", wave.Name)
+		}
+	}
+	for _, wave := range spec.Waves {
+		for _, dependency := range wave.DependsOn {
+			if _, ok := waves[dependency]; !ok {
+				return fmt.Errorf("wave '%s' depends on unknown wave '%s'", wave.Name, dependency)
+			}
+		}
+	}
+
+	// Depth first search for a wave reached again while its dependencies are being visited
+	visiting, visited := map[string]bool{}, map[string]bool{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if visiting[name] {
+			return fmt.Errorf("waves depend on each other: %s", strings.Join(append(path, name), " -> "))
+		}
+		if visited[name] {
+			return nil
+		}
+		visiting[name] = true
+		for _, dependency := range waves[name].DependsOn {
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		visited[name] = true
+		return nil
+	}
+	for _, wave := range spec.Waves {
+		if err := visit(wave.Name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EvaluateMigrationWaves returns the state of each wave of a migration plan given the Migrations of its VMs by
+// VM name. A wave runs once all its dependencies passed its gate and, if it requires approval, it is approved.
+// Once running it stays running until all its VMs succeeded or failed, even if one of its Migrations is recreated.
+// The waves must have been validated.
+func EvaluateMigrationWaves(migrationplan *vjailbreakv1alpha1.MigrationPlan,
+	migrations map[string]*vjailbreakv1alpha1.Migration, now metav1.Time) []vjailbreakv1alpha1.MigrationWaveStatus {
+	previous := map[string]*vjailbreakv1alpha1.MigrationWaveStatus{}
+	for i := range migrationplan.Status.Waves {
+		previous[migrationplan.Status.Waves[i].Name] = &migrationplan.Status.Waves[i]
+	}
+	waves := map[string]*vjailbreakv1alpha1.MigrationWave{}
+	for i := range migrationplan.Spec.Waves {
+		waves[migrationplan.Spec.Waves[i].Name] = &migrationplan.Spec.Waves[i]
+	}
+
+	statuses := map[string]*vjailbreakv1alpha1.MigrationWaveStatus{}
+	var evaluate func(name string) *vjailbreakv1alpha1.MigrationWaveStatus
+	evaluate = func(name string) *vjailbreakv1alpha1.MigrationWaveStatus {
+		if status, ok := statuses[name]; ok {
+			return status
+		}
+		wave := waves[name]
+		status := &vjailbreakv1alpha1.MigrationWaveStatus{Name: name, VMs: len(wave.VirtualMachines)}
+		started := false
+		if prev, ok := previous[name]; ok {
+			status.StartTime = prev.StartTime
+			status.CompletionTime = prev.CompletionTime
+			started = prev.StartTime != nil
+		}
+		for _, vm := range wave.VirtualMachines {
+			migration, ok := migrations[vm]
+			if !ok {
+				continue
+			}
+			started = true
+			switch migration.Status.Phase {
+			case vjailbreakv1alpha1.VMMigrationPhaseSucceeded, vjailbreakv1alpha1.VMMigrationPhaseInspected:
+				status.SucceededVMs++
+				if IsMigrationHealthy(migration) {
+					status.HealthyVMs++
+				}
+			case vjailbreakv1alpha1.VMMigrationPhaseFailed, vjailbreakv1alpha1.VMMigrationPhaseRolledBack:
+				status.FailedVMs++
+			}
+		}
+		statuses[name] = status
+
+		switch {
+		case status.SucceededVMs == status.VMs:
+			status.Phase = vjailbreakv1alpha1.WavePhaseSucceeded
+			if status.CompletionTime == nil {
+				status.CompletionTime = &now
+			}
+		case status.FailedVMs > 0 && status.SucceededVMs+status.FailedVMs == status.VMs:
+			// The wave only fails once all its VMs finished, the others keep being migrated meanwhile
+			status.Phase = vjailbreakv1alpha1.WavePhaseFailed
+			status.Message = fmt.Sprintf("%d of %d VM(s) failed", status.FailedVMs, status.VMs)
+		case started:
+			status.Phase = vjailbreakv1alpha1.WavePhaseRunning
+			status.Message = fmt.Sprintf("%d of %d VM(s) migrated", status.SucceededVMs, status.VMs)
+		default:
+			status.Phase, status.Message = waveGatePhase(wave, evaluate)
+			if status.Phase != vjailbreakv1alpha1.WavePhaseRunning {
+				return status
+			}
+			if wave.RequireApproval && !slices.Contains(migrationplan.Spec.ApprovedWaves, name) {
+				status.Phase = vjailbreakv1alpha1.WavePhaseAwaitingApproval
+				status.Message = fmt.Sprintf("Add '%s' to approvedWaves to start the wave", name)
+				return status
+			}
+			status.Message = fmt.Sprintf("0 of %d VM(s) migrated", status.VMs)
+		}
+		if status.StartTime == nil {
+			status.StartTime = &now
+		}
+		return status
+	}
+
+	result := make([]vjailbreakv1alpha1.MigrationWaveStatus, 0, len(migrationplan.Spec.Waves))
+	for _, wave := range migrationplan.Spec.Waves {
+		result = append(result, *evaluate(wave.Name))
+	}
+	return result
+}
+
+// waveGatePhase returns whether the dependencies of a wave that has not started passed its gate: Running if
+// they did, Pending with the dependencies waited for if they may still pass it, and Blocked if they cannot
+func waveGatePhase(wave *vjailbreakv1alpha1.MigrationWave,
+	evaluate func(name string) *vjailbreakv1alpha1.MigrationWaveStatus) (vjailbreakv1alpha1.WavePhase, string) {
+	waiting := []string{}
+	for _, name := range wave.DependsOn {
+		dependency := evaluate(name)
+		switch {
+		case dependency.Phase == vjailbreakv1alpha1.WavePhaseFailed:
+			return vjailbreakv1alpha1.WavePhaseBlocked, fmt.Sprintf("Wave '%s' failed", name)
+		case dependency.Phase == vjailbreakv1alpha1.WavePhaseBlocked:
+			return vjailbreakv1alpha1.WavePhaseBlocked, fmt.Sprintf("Wave '%s' is blocked", name)
+		case dependency.Phase != vjailbreakv1alpha1.WavePhaseSucceeded:
+			waiting = append(waiting, name)
+		case wave.Gate == vjailbreakv1alpha1.WaveGateHealthy && dependency.HealthyVMs < dependency.VMs:
+			return vjailbreakv1alpha1.WavePhaseBlocked,
+				fmt.Sprintf("%d VM(s) of wave '%s' did not pass their health checks", dependency.VMs-dependency.HealthyVMs, name)
+		}
+	}
+	if len(waiting) > 0 {
+		return vjailbreakv1alpha1.WavePhasePending, fmt.Sprintf("Waiting for wave(s) %s", strings.Join(waiting, ", "))
+	}
+	return vjailbreakv1alpha1.WavePhaseRunning, ""
+}
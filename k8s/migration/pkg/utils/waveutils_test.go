@@ -0,0 +1,169 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/constants"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateMigrationWaves(t *testing.T) {
+	tests := []struct {
+		name     string
+		waves    []vjailbreakv1alpha1.MigrationWave
+		expected string
+	}{
+		{
+			name: "valid",
+			waves: []vjailbreakv1alpha1.MigrationWave{
+				{Name: "db", VirtualMachines: []string{"db-1"}},
+				{Name: "app", VirtualMachines: []string{"app-1"}, DependsOn: []string{"db"}},
+			},
+		},
+		{
+			name: "duplicate name",
+			waves: []vjailbreakv1alpha1.MigrationWave{
+				{Name: "db", VirtualMachines: []string{"db-1"}},
+				{Name: "db", VirtualMachines: []string{"db-2"}},
+			},
+			expected: "defined more than once",
+		},
+		{
+			name: "VM in two waves",
+			waves: []vjailbreakv1alpha1.MigrationWave{
+				{Name: "db", VirtualMachines: []string{"db-1"}},
+				{Name: "app", VirtualMachines: []string{"db-1"}},
+			},
+			expected: "is in both wave",
+		},
+		{
+			name: "unknown dependency",
+			waves: []vjailbreakv1alpha1.MigrationWave{
+				{Name: "app", VirtualMachines: []string{"app-1"}, DependsOn: []string{"db"}},
+			},
+			expected: "unknown wave 'db'",
+		},
+		{
+			name: "cycle",
+			waves: []vjailbreakv1alpha1.MigrationWave{
+				{Name: "db", VirtualMachines: []string{"db-1"}, DependsOn: []string{"web"}},
+				{Name: "app", VirtualMachines: []string{"app-1"}, DependsOn: []string{"db"}},
+				{Name: "web", VirtualMachines: []string{"web-1"}, DependsOn: []string{"app"}},
+			},
+			expected: "db -> web -> app -> db",
+		},
+		{
+			name: "healthy gate without health checks",
+			waves: []vjailbreakv1alpha1.MigrationWave{
+				{Name: "db", VirtualMachines: []string{"db-1"}},
+				{Name: "app", VirtualMachines: []string{"app-1"}, DependsOn: []string{"db"}, Gate: vjailbreakv1alpha1.WaveGateHealthy},
+			},
+			expected: "health checks are not performed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateMigrationWaves(&vjailbreakv1alpha1.MigrationPlanSpec{Waves: tt.waves})
+			if tt.expected == "" && err != nil {
+				t.Errorf("ValidateMigrationWaves() = %v, expected no error", err)
+			}
+			if tt.expected != "" && (err == nil || !strings.Contains(err.Error(), tt.expected)) {
+				t.Errorf("ValidateMigrationWaves() = %v, expected an error containing %q", err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEvaluateMigrationWaves(t *testing.T) {
+	now := metav1.NewTime(time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC))
+	plan := &vjailbreakv1alpha1.MigrationPlan{}
+	plan.Spec.MigrationStrategy.PerformHealthChecks = true
+	plan.Spec.Waves = []vjailbreakv1alpha1.MigrationWave{
+		{Name: "db", VirtualMachines: []string{"db-1", "db-2"}},
+		{Name: "app", VirtualMachines: []string{"app-1"}, DependsOn: []string{"db"}, Gate: vjailbreakv1alpha1.WaveGateHealthy},
+		{Name: "web", VirtualMachines: []string{"web-1"}, DependsOn: []string{"app"}, RequireApproval: true},
+	}
+	migration := func(phase vjailbreakv1alpha1.VMMigrationPhase, healthy bool) *vjailbreakv1alpha1.Migration {
+		m := &vjailbreakv1alpha1.Migration{}
+		m.Status.Phase = phase
+		if healthy {
+			m.Status.Conditions = []corev1.PodCondition{{
+				Type:   constants.MigrationConditionTypeHealthCheck,
+				Status: corev1.ConditionTrue,
+				Reason: constants.HealthCheckReasonPassed,
+			}}
+		}
+		return m
+	}
+	phases := func(statuses []vjailbreakv1alpha1.MigrationWaveStatus) string {
+		names := []string{}
+		for _, status := range statuses {
+			names = append(names, string(status.Phase))
+		}
+		return strings.Join(names, ",")
+	}
+
+	// Only the wave without dependencies starts
+	migrations := map[string]*vjailbreakv1alpha1.Migration{}
+	plan.Status.Waves = utils.EvaluateMigrationWaves(plan, migrations, now)
+	if got := phases(plan.Status.Waves); got != "Running,Pending,Pending" {
+		t.Fatalf("phases = %s", got)
+	}
+	if plan.Status.Waves[0].StartTime == nil || plan.Status.Waves[1].Message != "Waiting for wave(s) db" {
+		t.Errorf("unexpected wave states %+v", plan.Status.Waves)
+	}
+
+	// The app wave waits for the VMs of the db wave to pass their health checks
+	migrations["db-1"] = migration(vjailbreakv1alpha1.VMMigrationPhaseSucceeded, true)
+	migrations["db-2"] = migration(vjailbreakv1alpha1.VMMigrationPhaseSucceeded, false)
+	plan.Status.Waves = utils.EvaluateMigrationWaves(plan, migrations, now)
+	if got := phases(plan.Status.Waves); got != "Succeeded,Blocked,Blocked" {
+		t.Fatalf("phases = %s", got)
+	}
+	migrations["db-2"] = migration(vjailbreakv1alpha1.VMMigrationPhaseSucceeded, true)
+	plan.Status.Waves = utils.EvaluateMigrationWaves(plan, migrations, now)
+	if got := phases(plan.Status.Waves); got != "Succeeded,Running,Pending" {
+		t.Fatalf("phases = %s", got)
+	}
+
+	// The web wave waits for approval once the app wave succeeded
+	migrations["app-1"] = migration(vjailbreakv1alpha1.VMMigrationPhaseSucceeded, true)
+	plan.Status.Waves = utils.EvaluateMigrationWaves(plan, migrations, now)
+	if got := phases(plan.Status.Waves); got != "Succeeded,Succeeded,AwaitingApproval" {
+		t.Fatalf("phases = %s", got)
+	}
+	plan.Spec.ApprovedWaves = []string{"web"}
+	plan.Status.Waves = utils.EvaluateMigrationWaves(plan, migrations, now)
+	if got := phases(plan.Status.Waves); got != "Succeeded,Succeeded,Running" {
+		t.Fatalf("phases = %s", got)
+	}
+
+	// A failed VM fails its wave
+	migrations["web-1"] = migration(vjailbreakv1alpha1.VMMigrationPhaseFailed, false)
+	plan.Status.Waves = utils.EvaluateMigrationWaves(plan, migrations, now)
+	if got := phases(plan.Status.Waves); got != "Succeeded,Succeeded,Failed" || plan.Status.Waves[2].FailedVMs != 1 {
+		t.Errorf("phases = %s, wave states %+v", got, plan.Status.Waves)
+	}
+
+	// A wave with a failed VM keeps running until its other VMs finished
+	single := &vjailbreakv1alpha1.MigrationPlan{}
+	single.Spec.Waves = []vjailbreakv1alpha1.MigrationWave{{Name: "db", VirtualMachines: []string{"db-1", "db-2"}}}
+	migrations = map[string]*vjailbreakv1alpha1.Migration{
+		"db-1": migration(vjailbreakv1alpha1.VMMigrationPhaseFailed, false),
+		"db-2": migration(vjailbreakv1alpha1.VMMigrationPhaseCopying, false),
+	}
+	single.Status.Waves = utils.EvaluateMigrationWaves(single, migrations, now)
+	if got := phases(single.Status.Waves); got != "Running" || single.Status.Waves[0].FailedVMs != 1 {
+		t.Errorf("phases = %s, wave states %+v", got, single.Status.Waves)
+	}
+	migrations["db-2"] = migration(vjailbreakv1alpha1.VMMigrationPhaseSucceeded, false)
+	single.Status.Waves = utils.EvaluateMigrationWaves(single, migrations, now)
+	if got := phases(single.Status.Waves); got != "Failed" {
+		t.Errorf("phases = %s, wave states %+v", got, single.Status.Waves)
+	}
+}
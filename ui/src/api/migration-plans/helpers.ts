@@ -15,6 +15,7 @@ export const createMigrationPlanJson = (params) => {
     disconnectSourceNetwork = false,
     securityGroups,
     fallbackToDHCP = false,
+    allowUnsupportedOS = false,
     postMigrationScript,
   } = params || {}
 
@@ -31,6 +32,7 @@ export const createMigrationPlanJson = (params) => {
     },
     virtualMachines: [virtualMachines],
     fallbackToDHCP,
+    allowUnsupportedOS,
   }
 
   // Add firstBootScript if postMigrationScript is provided
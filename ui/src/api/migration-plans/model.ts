@@ -32,15 +32,47 @@ export interface Spec {
   migrationTemplate: string
   retry: boolean
   virtualMachines: Array<string[]>
+  vmPriorities?: Record<string, number>
+  waves?: MigrationWave[]
+  approvedWaves?: string[]
+}
+
+export interface MigrationWave {
+  name: string
+  virtualMachines: string[]
+  dependsOn?: string[]
+  gate?: "Succeeded" | "Healthy"
+  requireApproval?: boolean
 }
 
 export interface MigrationStrategy {
   type: string
+  warmSyncInterval?: string
+  maintenanceWindow?: string
 }
 
 export interface Status {
   migrationMessage: string
   migrationStatus: string
+  waves?: MigrationWaveStatus[]
+}
+
+export interface MigrationWaveStatus {
+  name: string
+  phase:
+    | "Pending"
+    | "AwaitingApproval"
+    | "Running"
+    | "Succeeded"
+    | "Failed"
+    | "Blocked"
+  message?: string
+  vms: number
+  succeededVMs: number
+  healthyVMs: number
+  failedVMs: number
+  startTime?: string
+  completionTime?: string
 }
 
 export interface GetMigrationPlansListMetadata {
@@ -39,6 +39,12 @@ export interface MigrationTemplateSpec {
   storageMapping: string
   targetPCDClusterName?: string
   useFlavorless?: boolean
+  targetBackend?: "CinderAttach" | "GlanceImage" | "CinderImageUpload"
+  cephRBD?: CephRBD
+}
+
+export interface CephRBD {
+  keyringSecret: string
 }
 
 export interface Destination {
@@ -76,6 +82,7 @@ export interface VmData {
   vmWareMachineName?: string
   networkInterfaces?: VmNetworkInterface[]
   osFamily?: string
+  osName?: string
   // RDM-related properties
   rdmDisks?: string[]
   hasSharedRdm?: boolean
@@ -82,6 +82,7 @@ export enum VMName {
 export interface StatusClass {
   conditions: Condition[]
   phase: Phase
+  queuePosition?: number
 }
 
 export interface Condition {
@@ -113,6 +114,7 @@ export enum Type {
 
 export enum Phase {
   Pending = "Pending",
+  WaitingForCapacity = "WaitingForCapacity",
   Validating = "Validating",
   AwaitingDataCopyStart = "AwaitingDataCopyStart",
   CopyingBlocks = "CopyingBlocks",
@@ -121,6 +123,7 @@ export enum Phase {
   AwaitingCutOverStartTime = "AwaitingCutOverStartTime",
   AwaitingAdminCutOver = "AwaitingAdminCutOver",
   Succeeded = "Succeeded",
+  RolledBack = "RolledBack",
   Failed = "Failed",
   Unknown = "Unknown",
 }
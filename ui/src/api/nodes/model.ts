@@ -34,10 +34,15 @@ export interface Spec {
   nodeRole: string
   openstackCreds: OpenstackCredsRef
   openstackFlavorID: string
+  maxConcurrentMigrations?: number
+  maxVolumeAttachments?: number
+  bandwidthMBps?: number
+  draining?: boolean
 }
 
 export interface Status {
   activeMigrations?: string[]
+  idleSince?: string
   openstackUUID: string
   phase: string
   vmIP: string
@@ -0,0 +1,2 @@
+export * from "./osMatrix";
+export * from "./model";
@@ -0,0 +1,33 @@
+export interface OSMatrixEntry {
+  family: "linux" | "windows"
+  distro: string
+  match: string[]
+  minVersion?: string
+  maxVersion?: string
+  conversionSteps?: string[]
+  caveats?: string[]
+}
+
+export interface OSMatrix {
+  version: number
+  entries: OSMatrixEntry[]
+}
+
+export interface OSMatrixConfigMap {
+  apiVersion: string
+  kind: string
+  data: {
+    "matrix.json": string
+  }
+  metadata: {
+    name: string
+    namespace: string
+    resourceVersion: string
+  }
+}
+
+export interface OSSupport {
+  supported: boolean
+  entry?: OSMatrixEntry
+  reason?: string
+}
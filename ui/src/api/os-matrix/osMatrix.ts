@@ -0,0 +1,61 @@
+import { get } from "../axios"
+import { OSMatrix, OSMatrixConfigMap, OSSupport } from "./model"
+
+const OS_MATRIX_CONFIG_MAP_NAME = "vjailbreak-os-matrix"
+const OS_MATRIX_NAMESPACE = "migration-system"
+
+export const getOSMatrix = async (
+  namespace: string = OS_MATRIX_NAMESPACE
+): Promise<OSMatrix> => {
+  const endpoint = `/api/v1/namespaces/${namespace}/configmaps/${OS_MATRIX_CONFIG_MAP_NAME}`
+  const configMap = await get<OSMatrixConfigMap>({
+    endpoint,
+    config: { mock: false }, // Force real API call, not mock
+  })
+  return JSON.parse(configMap.data["matrix.json"]) as OSMatrix
+}
+
+// Compares a version to a bound, up to the number of components of the bound, like v2v-helper does
+const compareVersions = (version: string, bound: string): number => {
+  const versionParts = version.split(".").map(Number)
+  const boundParts = bound.split(".").map(Number)
+  if (versionParts.some(isNaN) || boundParts.some(isNaN)) {
+    return 0
+  }
+  for (let idx = 0; idx < boundParts.length; idx++) {
+    const versionPart = versionParts[idx] ?? 0
+    if (versionPart !== boundParts[idx]) {
+      return versionPart < boundParts[idx] ? -1 : 1
+    }
+  }
+  return 0
+}
+
+// Checks a guest against the matrix. osFamily is the VMware guest family, such as linuxGuest,
+// and osName the guest OS name VMware reports, which usually carries no version for Linux guests
+export const checkOSSupport = (
+  matrix: OSMatrix,
+  osFamily: string,
+  osName: string,
+  version = ""
+): OSSupport => {
+  const family = osFamily.toLowerCase().includes("windows")
+    ? "windows"
+    : osFamily.toLowerCase().includes("linux")
+      ? "linux"
+      : ""
+  const name = osName.toLowerCase()
+  const entry = matrix.entries.find(
+    (e) => e.family === family && e.match.some((match) => name.includes(match.toLowerCase()))
+  )
+  if (!entry) {
+    return { supported: false, reason: `"${osName}" is not in the supported OS matrix` }
+  }
+  if (version && entry.minVersion && compareVersions(version, entry.minVersion) < 0) {
+    return { supported: false, entry, reason: `${entry.distro} ${version} is older than the oldest supported version ${entry.minVersion}` }
+  }
+  if (version && entry.maxVersion && compareVersions(version, entry.maxVersion) > 0) {
+    return { supported: false, entry, reason: `${entry.distro} ${version} is newer than the newest supported version ${entry.maxVersion}` }
+  }
+  return { supported: true, entry }
+}
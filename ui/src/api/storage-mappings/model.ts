@@ -29,6 +29,7 @@ export interface Annotations {
 
 export interface Spec {
   storages: Storage[]
+  zeroedVolumeTypes?: string[]
 }
 
 export interface Storage {
@@ -9,6 +9,7 @@ export interface VMwareVM {
   ipAddress?: string
   assignedIp?: string
   osFamily?: string
+  osName?: string
   networkInterfaces?: VmNetworkInterface[]
   rdmDisks?: string[]
 }
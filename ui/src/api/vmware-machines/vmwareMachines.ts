@@ -76,6 +76,7 @@ export const mapToVmData = (machines: VMwareMachine[]): VmData[] => {
     targetFlavorId: machine.spec.targetFlavorId,
     labels: machine.metadata.labels,
     osFamily: machine.spec.vms.osFamily,
+    osName: machine.spec.vms.osName,
     esxHost:
       machine.metadata?.labels?.[`vjailbreak.k8s.pf9.io/esxi-name`] || "",
     vmWareMachineName: machine.metadata.name,
@@ -382,6 +382,7 @@ export default function Sidenav({
   // checking if there's any active migrations
   const activePhases = new Set<Phase>([
     Phase.Pending,
+    Phase.WaitingForCapacity,
     Phase.Validating,
     Phase.AwaitingDataCopyStart,
     Phase.CopyingBlocks,
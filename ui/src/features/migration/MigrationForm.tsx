@@ -125,6 +125,7 @@ export interface FormValues extends Record<string, unknown> {
   disconnectSourceNetwork?: boolean
   securityGroups?: string[]
   fallbackToDHCP?: boolean
+  allowUnsupportedOS?: boolean
 }
 
 
@@ -485,6 +486,7 @@ export default function MigrationFormDrawer({
       }),
       disconnectSourceNetwork: params.disconnectSourceNetwork || false,
       fallbackToDHCP: params.fallbackToDHCP || false,
+      allowUnsupportedOS: params.allowUnsupportedOS || false,
       ...(selectedMigrationOptions.postMigrationScript &&
         params.postMigrationScript && {
         postMigrationScript: params.postMigrationScript,
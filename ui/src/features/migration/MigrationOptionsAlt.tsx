@@ -314,6 +314,23 @@ export default function MigrationOptionsAlt({
               </Typography>
             </Fields>
 
+            <Fields sx={{ gridGap: "0" }}>
+              <FormControlLabel
+                label="Allow Unsupported OS"
+                control={
+                  <Checkbox
+                    checked={params?.allowUnsupportedOS || false}
+                    onChange={(e) => {
+                      onChange("allowUnsupportedOS")(e.target.checked);
+                    }}
+                  />
+                }
+              />
+              <Typography variant="caption" sx={{ marginLeft: "32px" }}>
+                Attempt to migrate VMs whose OS is not in the supported OS matrix. A warning is recorded on the migration.
+              </Typography>
+            </Fields>
+
             <Fields>
               <FormControlLabel
                 label="Rename VMware VM"
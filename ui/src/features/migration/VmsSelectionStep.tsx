@@ -48,6 +48,8 @@ import { RdmDiskConfigurationPanel } from "src/components/RdmDiskConfigurationPa
 import { useRdmDisksQuery, RDM_DISKS_BASE_KEY } from "src/hooks/api/useRdmDisksQuery"
 import { patchRdmDisk } from "src/api/rdm-disks/rdmDisks";
 import { RdmDisk } from "src/api/rdm-disks/model";
+import { checkOSSupport } from "src/api/os-matrix";
+import { useOSMatrixQuery } from "src/hooks/api/useOSMatrixQuery";
 
 const VmsSelectionStepContainer = styled("div")(({ theme }) => ({
   display: "grid",
@@ -195,6 +197,7 @@ export default function VmsSelectionStep({
   const [vmOSAssignments, setVmOSAssignments] = useState<Record<string, string>>({});
 
   const { data: rdmDisks = [], isLoading: rdmDisksLoading } = useRdmDisksQuery();
+  const { data: osMatrix } = useOSMatrixQuery();
 
   // RDM validation logic
   const rdmValidation = useRdmConfigValidation({
@@ -425,8 +428,15 @@ export default function VmsSelectionStep({
           displayValue = "Unknown";
         }
 
+        // Linux guests are checked against their os-release file during the migration, the VMware
+        // OS name only flags the ones that match an unsupported entry. Windows guests are checked by name
+        const osSupport = osMatrix && currentOsFamily && params.row?.osName ?
+          checkOSSupport(osMatrix, currentOsFamily, params.row.osName) : undefined;
+        const osSupportWarning = osSupport && (osSupport.entry || displayValue === "Windows") ?
+          (!osSupport.supported ? osSupport.reason : osSupport.entry?.caveats?.join(" ")) : undefined;
+
         return (
-          <Tooltip title={powerState === "powered-off" ?
+          <Tooltip title={osSupportWarning ? `${params.row.osName}: ${osSupportWarning}` : powerState === "powered-off" ?
             ((!currentOsFamily || currentOsFamily === "Unknown") ?
               "OS assignment required for powered-off VMs" :
               "Click to change OS selection") :
@@ -438,7 +448,7 @@ export default function VmsSelectionStep({
               gap: 1
             }}>
               {icon}
-              {(!currentOsFamily || currentOsFamily === "Unknown") && (
+              {((!currentOsFamily || currentOsFamily === "Unknown") || osSupportWarning) && (
                 <WarningIcon sx={{ color: 'warning.main', fontSize: 16 }} />
               )}
               <Typography variant="body2" sx={{
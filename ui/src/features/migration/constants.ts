@@ -15,6 +15,7 @@ export enum OS_TYPES {
 export const DATA_COPY_OPTIONS = [
   { value: "cold", label: "Power off live VMs, then copy" },
   { value: "hot", label: "Copy live VMs, then power off" },
+  { value: "warm", label: "Sync live VMs periodically, power off at cutover" },
 ]
 
 export const OS_TYPES_OPTIONS = [
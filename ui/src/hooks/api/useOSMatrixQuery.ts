@@ -0,0 +1,25 @@
+import {
+  useQuery,
+  UseQueryOptions,
+  UseQueryResult,
+} from "@tanstack/react-query"
+import { getOSMatrix } from "src/api/os-matrix"
+import { OSMatrix } from "src/api/os-matrix/model"
+
+export const OS_MATRIX_QUERY_KEY = ["osMatrix"]
+
+type Options = Omit<UseQueryOptions<OSMatrix>, "queryKey" | "queryFn">
+
+export const useOSMatrixQuery = (
+  namespace = undefined,
+  options: Options = {}
+): UseQueryResult<OSMatrix> => {
+  return useQuery<OSMatrix>({
+    queryKey: [...OS_MATRIX_QUERY_KEY, namespace],
+    queryFn: async () => getOSMatrix(namespace),
+    staleTime: 5 * 60 * 1000,
+    refetchOnWindowFocus: false,
+    retry: 3,
+    ...options,
+  })
+}
@@ -124,7 +124,7 @@ export default function MigrationProgressWithPopover({
       Phase.AwaitingCutOverStartTime
     ].includes(phase as Phase)) {
       return <CircularProgress size={20} style={{ marginRight: 3 }} />
-    } else if (phase === Phase.Failed) {
+    } else if (phase === Phase.Failed || phase === Phase.RolledBack) {
       return <ErrorOutlineIcon style={{ color: "red" }} />
     } else {
       return <HourglassBottomIcon style={{ color: "grey" }} />
@@ -26,6 +26,7 @@ const STATUS_ORDER = {
 }
 const PHASE_STEPS = {
     [Phase.Pending]: 1,
+    [Phase.WaitingForCapacity]: 1,
     [Phase.Validating]: 2,
     [Phase.AwaitingDataCopyStart]: 3,
     [Phase.CopyingBlocks]: 4,
@@ -34,6 +35,7 @@ const PHASE_STEPS = {
     [Phase.AwaitingCutOverStartTime]: 7,
     [Phase.AwaitingAdminCutOver]: 8,
     [Phase.Succeeded]: 9,
+    [Phase.RolledBack]: 9,
     [Phase.Failed]: 9,
 }
 
@@ -52,7 +54,7 @@ const getProgressText = (phase: Phase | undefined, conditions: Condition[] | und
 
     const message = latestCondition?.message || phase;
 
-    if (phase === Phase.Failed || phase === Phase.Succeeded) {
+    if (phase === Phase.Failed || phase === Phase.Succeeded || phase === Phase.RolledBack) {
         return `${phase} - ${message}`;
     }
 
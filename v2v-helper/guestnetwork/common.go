@@ -0,0 +1,209 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// udevRulesPath is the udev rules file that pins the interface names to the MAC addresses
+const udevRulesPath = "/etc/udev/rules.d/70-persistent-net.rules"
+
+// assignNames sets the name of each interface. An interface takes the name the guest
+// configuration binds to its MAC address, else the next configured name in guest order,
+// else the first free ethN name
+func assignNames(ifaces []Interface, byMAC map[string]string, ordered []string) []Interface {
+	named := make([]Interface, len(ifaces))
+	copy(named, ifaces)
+	used := map[string]bool{}
+	for idx := range named {
+		if name, ok := byMAC[named[idx].MAC]; ok && !used[name] {
+			named[idx].Name = name
+			used[name] = true
+		}
+	}
+	for idx := range named {
+		if named[idx].Name != "" {
+			continue
+		}
+		for _, name := range ordered {
+			if !used[name] {
+				named[idx].Name = name
+				break
+			}
+		}
+		for n := 0; named[idx].Name == ""; n++ {
+			if name := fmt.Sprintf("eth%d", n); !used[name] {
+				named[idx].Name = name
+			}
+		}
+		used[named[idx].Name] = true
+	}
+	return named
+}
+
+// writeUdevRules pins the name of each interface to its MAC address, so that the
+// virtio NICs get the names the guest configuration refers to
+func writeUdevRules(fs GuestFS, ifaces []Interface) error {
+	var rules strings.Builder
+	rules.WriteString(generatedHeader + "\n")
+	for _, iface := range ifaces {
+		rules.WriteString(fmt.Sprintf("SUBSYSTEM==\"net\", ACTION==\"add\", ATTR{address}==\"%s\", NAME=\"%s\"\n", iface.MAC, iface.Name))
+	}
+	return errors.Wrap(fs.WriteFile(udevRulesPath, []byte(rules.String()), 0644), "failed to write udev rules")
+}
+
+// listDir returns the names of the entries of the directory that match the filter,
+// or nothing if the directory does not exist
+func listDir(fs GuestFS, dir string, match func(name string) bool) ([]string, error) {
+	exists, err := fs.Exists(dir)
+	if err != nil || !exists {
+		return nil, errors.Wrapf(err, "failed to check for %s", dir)
+	}
+	names, err := fs.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list %s", dir)
+	}
+	matched := []string{}
+	for _, name := range names {
+		if match(name) {
+			matched = append(matched, name)
+		}
+	}
+	return matched, nil
+}
+
+// backup renames the file so the network tools of the guest no longer read it
+func backup(fs GuestFS, name string) error {
+	log.Printf("Backing up guest network file %s", name)
+	return errors.Wrapf(fs.Rename(name, name+backupSuffix), "failed to back up %s", name)
+}
+
+// parseShellVars parses KEY=value lines as found in ifcfg files, removing the quotes of the values
+func parseShellVars(content string) map[string]string {
+	vars := map[string]string{}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		vars[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return vars
+}
+
+// setShellVar sets the variable in the content of a KEY=value file, appending it if it is not set
+func setShellVar(content, key, value string) string {
+	line := fmt.Sprintf("%s=%q", key, value)
+	re := regexp.MustCompile(`(?m)^` + regexp.QuoteMeta(key) + `=.*$`)
+	if re.MatchString(content) {
+		return re.ReplaceAllLiteralString(content, line)
+	}
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + line + "\n"
+}
+
+// uniqueDNS returns the DNS servers of the static interfaces, without duplicates
+func uniqueDNS(ifaces []Interface) []string {
+	servers := []string{}
+	for _, iface := range ifaces {
+		if !iface.Static() {
+			continue
+		}
+		for _, server := range iface.DNS {
+			if !slices.Contains(servers, server) {
+				servers = append(servers, server)
+			}
+		}
+	}
+	return servers
+}
+
+// hasExtension returns a filter matching the file names with the extension
+func hasExtension(ext string) func(string) bool {
+	return func(name string) bool {
+		return path.Ext(name) == ext
+	}
+}
+
+// parseVersionID parses the VERSION_ID from /etc/os-release or /etc/redhat-release format.
+// It returns the version ID as a string, or an empty string if not found.
+func parseVersionID(osRelease string) string {
+	osRelease = strings.TrimSpace(osRelease)
+
+	// Key-value style (os-release, SuSE-release, etc.)
+	if strings.Contains(osRelease, "=") {
+		var version, patchlevel string
+		for _, line := range strings.Split(osRelease, "\n") {
+			kv := strings.SplitN(line, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			key := strings.TrimSpace(strings.ToUpper(kv[0]))
+			val := strings.TrimSpace(strings.Trim(kv[1], `"`)) // Remove quotes and spaces
+			switch key {
+			case "VERSION_ID":
+				return val
+			case "VERSION":
+				version = val
+			case "PATCHLEVEL":
+				patchlevel = val
+			}
+		}
+		// If it's SLES style, combine VERSION + PATCHLEVEL if available
+		if version != "" {
+			if patchlevel != "" {
+				return version + "." + patchlevel
+			}
+			return version
+		}
+	} else {
+		// /etc/redhat-release style
+		re := regexp.MustCompile(`release\s+([0-9]+(\.[0-9]+)?)`)
+		matches := re.FindStringSubmatch(strings.ToLower(osRelease))
+		if len(matches) > 1 {
+			return matches[1]
+		}
+	}
+
+	return ""
+}
+
+// isNetplanSupported returns true if the Ubuntu version is 17.10 or later
+func isNetplanSupported(version string) bool {
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		log.Printf("Warning: unexpected VERSION_ID format: %q", version)
+		return true // assume modern if uncertain
+	}
+
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil {
+		log.Printf("Warning: failed to parse VERSION_ID %q: %v %v", version, err1, err2)
+		return true
+	}
+
+	// Compare with 17.10
+	if major > 17 {
+		return true
+	}
+	if major == 17 && minor >= 10 {
+		return true
+	}
+	return false
+}
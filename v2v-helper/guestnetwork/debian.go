@@ -0,0 +1,203 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	interfacesPath = "/etc/network/interfaces"
+	interfacesDir  = "/etc/network/interfaces.d"
+)
+
+// stanzaKeywords start a new stanza of /etc/network/interfaces
+var stanzaKeywords = []string{"iface", "mapping", "auto", "source", "source-directory", "no-auto-down", "no-scripts"}
+
+// DebianConfigurator configures Debian and Ubuntu releases whose interfaces are set up by ifupdown
+type DebianConfigurator struct{}
+
+// Name is the name of the network stack the configurator handles
+func (c *DebianConfigurator) Name() string {
+	return "debian"
+}
+
+// Detect returns true for Debian and Ubuntu guests that have an /etc/network/interfaces file
+func (c *DebianConfigurator) Detect(fs GuestFS, osRelease string) (bool, error) {
+	if !strings.Contains(osRelease, "debian") && !strings.Contains(osRelease, "ubuntu") {
+		return false, nil
+	}
+	exists, err := fs.Exists(interfacesPath)
+	return exists, errors.Wrapf(err, "failed to check for %s", interfacesPath)
+}
+
+// Configure replaces the stanzas of the interfaces in /etc/network/interfaces, keeping
+// the loopback and every other stanza, and pins the interface names with udev rules
+func (c *DebianConfigurator) Configure(fs GuestFS, ifaces []Interface) error {
+	content, err := fs.ReadFile(interfacesPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", interfacesPath)
+	}
+	lines := strings.Split(string(content), "\n")
+	files, sourced, err := c.readSourced(fs)
+	if err != nil {
+		return err
+	}
+
+	byMAC := map[string]string{}
+	ordered := []string{}
+	virtual := map[string]bool{"lo": true}
+	current := ""
+	for _, line := range slices.Concat(append([][]string{lines}, sourced...)...) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case fields[0] == "iface" && len(fields) > 1:
+			current = fields[1]
+			if !slices.Contains(ordered, current) {
+				ordered = append(ordered, current)
+			}
+			// Aliases and VLANs of an interface are not interfaces of their own
+			if strings.ContainsAny(current, ".:") {
+				virtual[current] = true
+			}
+		case isStanzaKeyword(fields[0]):
+			current = ""
+		case current == "":
+			// Options outside of an iface stanza
+		case fields[0] == "hwaddress":
+			byMAC[strings.ToLower(fields[len(fields)-1])] = current
+		case isVirtualOption(fields[0]):
+			virtual[current] = true
+		}
+	}
+	ordered = slices.DeleteFunc(ordered, func(name string) bool { return virtual[name] })
+	ifaces = assignNames(ifaces, byMAC, ordered)
+	names := []string{}
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+
+	kept := []string{}
+	skip := false
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) > 0 && isStanzaKeyword(fields[0]) {
+			skip = false
+			switch {
+			case fields[0] == "iface" && len(fields) > 1 && slices.Contains(names, fields[1]):
+				skip = true
+				continue
+			case fields[0] == "auto" || strings.HasPrefix(fields[0], "allow-"):
+				others := slices.DeleteFunc(fields[1:], func(name string) bool { return slices.Contains(names, name) })
+				if len(others) == 0 {
+					continue
+				}
+				line = fields[0] + " " + strings.Join(others, " ")
+			}
+		}
+		if !skip {
+			kept = append(kept, line)
+		}
+	}
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		kept = kept[:len(kept)-1]
+	}
+
+	var b strings.Builder
+	if len(kept) > 0 {
+		b.WriteString(strings.Join(kept, "\n") + "\n\n")
+	}
+	b.WriteString(generatedHeader + "\n")
+	for _, iface := range ifaces {
+		b.WriteString(ifupdownStanza(iface))
+	}
+	if err := fs.WriteFile(interfacesPath, []byte(b.String()), 0644); err != nil {
+		return errors.Wrapf(err, "failed to write %s", interfacesPath)
+	}
+
+	// The files of interfaces.d that configure one of the interfaces would configure it twice
+	for idx, file := range files {
+		for _, line := range sourced[idx] {
+			fields := strings.Fields(line)
+			if len(fields) > 1 && fields[0] == "iface" && slices.Contains(names, fields[1]) {
+				if err := backup(fs, path.Join(interfacesDir, file)); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return writeUdevRules(fs, ifaces)
+}
+
+// readSourced returns the files of interfaces.d and the lines of each of them
+func (c *DebianConfigurator) readSourced(fs GuestFS) ([]string, [][]string, error) {
+	files, err := listDir(fs, interfacesDir, isSourcedFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	sourced := [][]string{}
+	for _, file := range files {
+		filePath := path.Join(interfacesDir, file)
+		content, err := fs.ReadFile(filePath)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to read %s", filePath)
+		}
+		sourced = append(sourced, strings.Split(string(content), "\n"))
+	}
+	return files, sourced, nil
+}
+
+// isSourcedFile returns true for the files of interfaces.d that ifupdown reads
+func isSourcedFile(name string) bool {
+	return !strings.HasSuffix(name, backupSuffix)
+}
+
+// isVirtualOption returns true for the options of bonds, bridges and VLANs
+func isVirtualOption(option string) bool {
+	for _, prefix := range []string{"bond-", "bond_", "bridge-", "bridge_", "vlan-raw-device", "vlan_raw_device"} {
+		if strings.HasPrefix(option, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isStanzaKeyword(word string) bool {
+	return slices.Contains(stanzaKeywords, word) || strings.HasPrefix(word, "allow-")
+}
+
+// ifupdownStanza returns the stanzas of the interface. Netmasks are written separately
+// from the addresses for the ifupdown releases that do not accept CIDR addresses
+func ifupdownStanza(iface Interface) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\nauto %s\n", iface.Name)
+	if !iface.Static() {
+		fmt.Fprintf(&b, "iface %s inet dhcp\n", iface.Name)
+		return b.String()
+	}
+	for idx, address := range iface.Addresses {
+		fmt.Fprintf(&b, "iface %s inet static\n", iface.Name)
+		fmt.Fprintf(&b, "    address %s\n", address.IP)
+		fmt.Fprintf(&b, "    netmask %s\n", net.IP(net.CIDRMask(address.PrefixLength, 32)).String())
+		if idx > 0 {
+			continue
+		}
+		if iface.Gateway != "" {
+			fmt.Fprintf(&b, "    gateway %s\n", iface.Gateway)
+		}
+		if len(iface.DNS) > 0 {
+			fmt.Fprintf(&b, "    dns-nameservers %s\n", strings.Join(iface.DNS, " "))
+		}
+	}
+	return b.String()
+}
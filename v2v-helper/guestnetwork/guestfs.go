@@ -0,0 +1,315 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// GuestFS is the filesystem of the guest, paths are absolute paths inside the guest
+type GuestFS interface {
+	// Exists returns true if the file or directory exists
+	Exists(name string) (bool, error)
+	// ReadFile returns the content of the file
+	ReadFile(name string) ([]byte, error)
+	// ReadDir returns the sorted names of the entries of the directory
+	ReadDir(name string) ([]string, error)
+	// WriteFile writes the file with the given permissions, creating its parent directories
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	// Rename renames the file
+	Rename(oldname, newname string) error
+}
+
+// DirFS is a GuestFS on a local directory holding the root filesystem of the guest
+type DirFS string
+
+func (dir DirFS) path(name string) string {
+	return filepath.Join(string(dir), filepath.FromSlash(name))
+}
+
+// Exists returns true if the file or directory exists
+func (dir DirFS) Exists(name string) (bool, error) {
+	_, err := os.Stat(dir.path(name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReadFile returns the content of the file
+func (dir DirFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(dir.path(name))
+}
+
+// ReadDir returns the sorted names of the entries of the directory
+func (dir DirFS) ReadDir(name string) ([]string, error) {
+	entries, err := os.ReadDir(dir.path(name))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names, nil
+}
+
+// WriteFile writes the file with the given permissions, creating its parent directories
+func (dir DirFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dir.path(name)), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(dir.path(name), data, perm); err != nil {
+		return err
+	}
+	return os.Chmod(dir.path(name), perm)
+}
+
+// Rename renames the file
+func (dir DirFS) Rename(oldname, newname string) error {
+	return os.Rename(dir.path(oldname), dir.path(newname))
+}
+
+// guestfishRoots are the guest directories holding the network configuration. They are copied
+// out of the guest on the first access, and the files under them are read from the copy
+var guestfishRoots = []string{
+	"/etc/netplan",
+	"/etc/network",
+	"/etc/sysconfig",
+	"/etc/NetworkManager/system-connections",
+	"/etc/systemd/network",
+	"/etc/udev/rules.d",
+}
+
+// GuestfishFS is a GuestFS on the disks of the guest, accessed with guestfish. Every guestfish
+// run boots an appliance, so the network configuration directories are copied out once and read
+// locally, and the writes are queued until Commit applies them in a single run
+type GuestfishFS struct {
+	// Disks are the disks holding the root filesystem of the guest
+	Disks []vm.VMDisk
+
+	// local holds the copy of the guestfishRoots and the files queued for upload
+	local string
+	// pending is the guestfish script applying the queued writes
+	pending strings.Builder
+	uploads int
+	// exec runs a guestfish script, run unless set by the tests
+	exec func(write bool, script string) (string, error)
+}
+
+// NewGuestfishFS returns the GuestFS on the disks of the guest. Commit writes the changes to the
+// guest and Close removes the local copy
+func NewGuestfishFS(disks []vm.VMDisk) *GuestfishFS {
+	return &GuestfishFS{Disks: disks}
+}
+
+// run runs the guestfish script against the disks and returns its output
+func (g *GuestfishFS) run(write bool, script string) (string, error) {
+	if g.exec != nil {
+		return g.exec(write, script)
+	}
+	os.Setenv("LIBGUESTFS_BACKEND", "direct")
+	option := "--ro"
+	if write {
+		option = "--rw"
+	}
+	args := []string{option}
+	for _, disk := range g.Disks {
+		args = append(args, "-a", disk.Path)
+	}
+	args = append(args, "-i")
+	cmd := exec.Command("guestfish", args...)
+	cmd.Stdin = strings.NewReader(script)
+	log.Printf("Executing %s with input: %s", cmd.String(), strings.TrimSpace(script))
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", errors.Errorf("failed to run guestfish (%s): %v: %s", strings.TrimSpace(script), err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", errors.Wrapf(err, "failed to run guestfish (%s)", strings.TrimSpace(script))
+	}
+	return string(out), nil
+}
+
+// copied returns the local copy of the guestfishRoots if name is under one of them, copying them
+// out of the guest on the first call
+func (g *GuestfishFS) copied(name string) (DirFS, bool, error) {
+	name = path.Clean(name)
+	covered := false
+	for _, root := range guestfishRoots {
+		if name == root || strings.HasPrefix(name, root+"/") {
+			covered = true
+			break
+		}
+	}
+	if !covered {
+		return "", false, nil
+	}
+	if err := g.ensureLocal(); err != nil {
+		return "", false, err
+	}
+	root := DirFS(filepath.Join(g.local, "root"))
+	if exists, err := root.Exists("/"); err != nil || exists {
+		return root, true, err
+	}
+	// Missing roots are skipped, the "-" prefix makes guestfish go on when copy-out fails
+	var script strings.Builder
+	for _, guestRoot := range guestfishRoots {
+		parent := root.path(path.Dir(guestRoot))
+		if err := os.MkdirAll(parent, 0755); err != nil {
+			return "", false, errors.Wrap(err, "failed to create local copy of the guest")
+		}
+		fmt.Fprintf(&script, "-copy-out %s %s\n", quote(guestRoot), quote(parent))
+	}
+	if _, err := g.run(false, script.String()); err != nil {
+		os.RemoveAll(string(root))
+		return "", false, err
+	}
+	return root, true, nil
+}
+
+// ensureLocal creates the local directory of the GuestfishFS
+func (g *GuestfishFS) ensureLocal() error {
+	if g.local != "" {
+		return nil
+	}
+	local, err := os.MkdirTemp("", "guestnetwork-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to create local directory")
+	}
+	g.local = local
+	return nil
+}
+
+// Exists returns true if the file or directory exists
+func (g *GuestfishFS) Exists(name string) (bool, error) {
+	if root, ok, err := g.copied(name); ok || err != nil {
+		if err != nil {
+			return false, err
+		}
+		return root.Exists(name)
+	}
+	out, err := g.run(false, fmt.Sprintf("exists %s\n", quote(name)))
+	if err != nil {
+		return false, err
+	}
+	return strings.TrimSpace(out) == "true", nil
+}
+
+// ReadFile returns the content of the file
+func (g *GuestfishFS) ReadFile(name string) ([]byte, error) {
+	if root, ok, err := g.copied(name); ok || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return root.ReadFile(name)
+	}
+	out, err := g.run(false, fmt.Sprintf("cat %s\n", quote(name)))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// ReadDir returns the sorted names of the entries of the directory
+func (g *GuestfishFS) ReadDir(name string) ([]string, error) {
+	if root, ok, err := g.copied(name); ok || err != nil {
+		if err != nil {
+			return nil, err
+		}
+		return root.ReadDir(name)
+	}
+	out, err := g.run(false, fmt.Sprintf("ls %s\n", quote(name)))
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// WriteFile queues the write of the file with the given permissions, creating its parent directories
+func (g *GuestfishFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	root, ok, err := g.copied(name)
+	if err != nil {
+		return err
+	}
+	if ok {
+		// A guest symlink in the copy must not be followed to a local file
+		if err := os.Remove(root.path(name)); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to write local copy")
+		}
+		if err := root.WriteFile(name, data, perm); err != nil {
+			return errors.Wrap(err, "failed to write local copy")
+		}
+	}
+	if err := g.ensureLocal(); err != nil {
+		return err
+	}
+	g.uploads++
+	upload := filepath.Join(g.local, fmt.Sprintf("upload-%d", g.uploads))
+	if err := os.WriteFile(upload, data, 0600); err != nil {
+		return errors.Wrap(err, "failed to write local file")
+	}
+	fmt.Fprintf(&g.pending, "mkdir-p %s\nupload %s %s\nchmod %#o %s\n",
+		quote(path.Dir(name)), quote(upload), quote(name), perm.Perm(), quote(name))
+	return nil
+}
+
+// Rename queues the rename of the file
+func (g *GuestfishFS) Rename(oldname, newname string) error {
+	root, ok, err := g.copied(oldname)
+	if err != nil {
+		return err
+	}
+	if ok {
+		if err := root.Rename(oldname, newname); err != nil {
+			return errors.Wrap(err, "failed to rename in local copy")
+		}
+	}
+	fmt.Fprintf(&g.pending, "mv %s %s\n", quote(oldname), quote(newname))
+	return nil
+}
+
+// Commit applies the queued writes to the guest in a single guestfish run
+func (g *GuestfishFS) Commit() error {
+	if g.pending.Len() == 0 {
+		return nil
+	}
+	if _, err := g.run(true, g.pending.String()); err != nil {
+		return err
+	}
+	g.pending.Reset()
+	return nil
+}
+
+// Close removes the local copy, the writes that were not committed are dropped
+func (g *GuestfishFS) Close() error {
+	if g.local == "" {
+		return nil
+	}
+	err := os.RemoveAll(g.local)
+	g.local = ""
+	return err
+}
+
+// quote quotes a path for a guestfish script
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(name, `\`, `\\`), `"`, `\"`) + `"`
+}
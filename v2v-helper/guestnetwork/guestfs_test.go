@@ -0,0 +1,86 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGuestfish runs the copy-out commands of the guestfish scripts against the guest directory
+// and records the scripts
+type fakeGuestfish struct {
+	guest   string
+	scripts map[bool][]string
+}
+
+func (f *fakeGuestfish) run(write bool, script string) (string, error) {
+	f.scripts[write] = append(f.scripts[write], script)
+	for _, line := range strings.Split(strings.TrimSpace(script), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "-copy-out" {
+			continue
+		}
+		remote, _ := strconv.Unquote(fields[1])
+		local, _ := strconv.Unquote(fields[2])
+		src := filepath.Join(f.guest, remote)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		if err := os.CopyFS(filepath.Join(local, filepath.Base(remote)), os.DirFS(src)); err != nil {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+func TestGuestfishFSBatches(t *testing.T) {
+	guest := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(guest, "etc/netplan"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(guest, "etc/netplan/01-netcfg.yaml"), []byte("network: {}\n"), 0644))
+	fake := &fakeGuestfish{guest: guest, scripts: map[bool][]string{}}
+	fs := &GuestfishFS{exec: fake.run}
+	defer fs.Close()
+
+	exists, err := fs.Exists("/etc/netplan/01-netcfg.yaml")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = fs.Exists("/etc/network/interfaces")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	content, err := fs.ReadFile("/etc/netplan/01-netcfg.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "network: {}\n", string(content))
+
+	assert.NoError(t, fs.Rename("/etc/netplan/01-netcfg.yaml", "/etc/netplan/01-netcfg.yaml"+backupSuffix))
+	assert.NoError(t, fs.WriteFile(netplanPath, []byte("network:\n  version: 2\n"), 0600))
+	names, err := fs.ReadDir(netplanDir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"01-netcfg.yaml" + backupSuffix, "50-vjailbreak.yaml"}, names)
+
+	// The directories are copied out in one run and nothing is written before the commit
+	assert.Len(t, fake.scripts[false], 1)
+	assert.Contains(t, fake.scripts[false][0], `-copy-out "/etc/netplan"`)
+	assert.Empty(t, fake.scripts[true])
+
+	assert.NoError(t, fs.Commit())
+	assert.Len(t, fake.scripts[true], 1)
+	script := fake.scripts[true][0]
+	assert.Contains(t, script, `mv "/etc/netplan/01-netcfg.yaml" "/etc/netplan/01-netcfg.yaml.pre-migration.bak"`)
+	assert.Contains(t, script, `upload "`)
+	assert.Contains(t, script, `chmod 0600 "/etc/netplan/50-vjailbreak.yaml"`)
+	assert.Less(t, strings.Index(script, "mv "), strings.Index(script, "upload "))
+
+	// Nothing is left to write
+	assert.NoError(t, fs.Commit())
+	assert.Len(t, fake.scripts[true], 1)
+
+	local := fs.local
+	assert.NoError(t, fs.Close())
+	assert.NoDirExists(t, local)
+}
@@ -0,0 +1,119 @@
+// Copyright © 2024 The vjailbreak authors
+
+// Package guestnetwork rewrites the network configuration inside a converted guest, so that
+// its interfaces come up on the new virtio NICs with the settings they had in VMware
+package guestnetwork
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+// generatedHeader is the first line of the files written into the guest
+const generatedHeader = "# Generated by vJailbreak from the VMware guest network settings"
+
+// backupSuffix is appended to the guest network files that are replaced. Network tools
+// ignore files with this suffix, and the original configuration can be restored from them
+const backupSuffix = ".pre-migration.bak"
+
+// Address is a static IP address of an interface
+type Address struct {
+	IP           string
+	PrefixLength int
+}
+
+// CIDR returns the address in CIDR notation
+func (a Address) CIDR() string {
+	return fmt.Sprintf("%s/%d", a.IP, a.PrefixLength)
+}
+
+// Interface is the network configuration of a NIC of the migrated VM
+type Interface struct {
+	// MAC is the MAC address of the NIC, the target port keeps it
+	MAC string
+	// Name is the name of the interface inside the guest, set by the configurator
+	Name string
+	// Addresses are the static addresses of the interface, the interface uses DHCP if empty
+	Addresses []Address
+	// Gateway is the default gateway of the interface
+	Gateway string
+	// DNS are the DNS servers of the interface
+	DNS []string
+}
+
+// Static returns true if the interface is configured with static addresses
+func (iface Interface) Static() bool {
+	return len(iface.Addresses) > 0
+}
+
+// GuestNetworkConfigurator rewrites the network configuration of one family of guest operating systems
+type GuestNetworkConfigurator interface {
+	// Name is the name of the network stack the configurator handles
+	Name() string
+	// Detect returns true if the guest uses the network stack of the configurator
+	Detect(fs GuestFS, osRelease string) (bool, error)
+	// Configure writes the configuration of the interfaces into the guest
+	Configure(fs GuestFS, ifaces []Interface) error
+}
+
+// Configurators are the known configurators, in the order they are detected in
+var Configurators = []GuestNetworkConfigurator{
+	&NetplanConfigurator{},
+	&SUSEConfigurator{},
+	&RedHatConfigurator{},
+	&DebianConfigurator{},
+	&NetworkdConfigurator{},
+}
+
+// Detect returns the first configurator that handles the guest, or nil if none does.
+// osRelease is the lower case content of the os-release file of the guest
+func Detect(fs GuestFS, osRelease string) (GuestNetworkConfigurator, error) {
+	for _, configurator := range Configurators {
+		detected, err := configurator.Detect(fs, osRelease)
+		if err != nil {
+			return nil, err
+		}
+		if detected {
+			return configurator, nil
+		}
+	}
+	return nil, nil
+}
+
+// NewInterfaces returns the configuration of each NIC of the VM, in the order of macs.
+// A NIC keeps the static IPv4 addresses the guest reported for it only if the target port
+// got the same address, portIPs holds the address of the port of each NIC. Every other NIC
+// uses DHCP, which hands out the address of its port
+func NewInterfaces(macs []string, guestNetworks []vjailbreakv1alpha1.GuestNetwork, portIPs []string) []Interface {
+	ifaces := make([]Interface, 0, len(macs))
+	for idx, mac := range macs {
+		iface := Interface{MAC: strings.ToLower(mac)}
+		portIP := ""
+		if idx < len(portIPs) {
+			portIP = portIPs[idx]
+		}
+		for _, guestNetwork := range guestNetworks {
+			if !strings.EqualFold(guestNetwork.MAC, mac) || !isStaticIPv4(guestNetwork) || guestNetwork.IP != portIP {
+				continue
+			}
+			iface.Addresses = append(iface.Addresses, Address{IP: guestNetwork.IP, PrefixLength: int(guestNetwork.PrefixLength)})
+			iface.Gateway = guestNetwork.Gateway
+			iface.DNS = guestNetwork.DNS
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces
+}
+
+// isStaticIPv4 returns true if the guest network is a manually configured IPv4 address
+func isStaticIPv4(guestNetwork vjailbreakv1alpha1.GuestNetwork) bool {
+	origin := strings.ToLower(guestNetwork.Origin)
+	if origin != "manual" && origin != "static" {
+		return false
+	}
+	ip := net.ParseIP(guestNetwork.IP)
+	return ip != nil && ip.To4() != nil && guestNetwork.PrefixLength > 0
+}
@@ -0,0 +1,271 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	mac1 = "00:50:56:aa:bb:01"
+	mac2 = "00:50:56:aa:bb:02"
+)
+
+// copyGuest copies the guest filesystem fixture to a temporary directory
+func copyGuest(t *testing.T, fixture string) DirFS {
+	root := t.TempDir()
+	src := filepath.Join("testdata", fixture, "guest")
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(rel)), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(root, rel), data, 0644)
+	})
+	require.NoError(t, err)
+	return DirFS(root)
+}
+
+// assertGuest checks that the guest has exactly the files of the want fixture, besides os-release
+func assertGuest(t *testing.T, guest DirFS, fixture string) {
+	want := filepath.Join("testdata", fixture, "want")
+	wantFiles := map[string]bool{"etc/os-release": true}
+	err := filepath.WalkDir(want, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(want, path)
+		if err != nil {
+			return err
+		}
+		wantFiles[filepath.ToSlash(rel)] = true
+		expected, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		actual, err := guest.ReadFile("/" + filepath.ToSlash(rel))
+		if assert.NoError(t, err, rel) {
+			assert.Equal(t, string(expected), string(actual), rel)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = filepath.WalkDir(string(guest), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(string(guest), path)
+		if err != nil {
+			return err
+		}
+		assert.True(t, wantFiles[filepath.ToSlash(rel)], "unexpected file %s", rel)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		fixture      string
+		configurator string
+		ifaces       []Interface
+		mode         os.FileMode
+		modeFile     string
+	}{
+		{
+			fixture:      "centos7",
+			configurator: "redhat",
+			ifaces: []Interface{
+				{MAC: mac1, Addresses: []Address{{IP: "10.0.0.5", PrefixLength: 24}}, Gateway: "10.0.0.1", DNS: []string{"10.0.0.2", "10.0.0.3"}},
+				{MAC: mac2},
+			},
+		},
+		{
+			fixture:      "rocky9",
+			configurator: "redhat",
+			ifaces: []Interface{
+				{MAC: mac1, Addresses: []Address{{IP: "192.168.10.20", PrefixLength: 24}}, Gateway: "192.168.10.1", DNS: []string{"192.168.10.2", "192.168.10.3"}},
+			},
+			mode:     0600,
+			modeFile: "/etc/NetworkManager/system-connections/ens192.nmconnection",
+		},
+		{
+			fixture:      "sles15",
+			configurator: "suse",
+			ifaces: []Interface{
+				{MAC: mac1, Addresses: []Address{{IP: "172.16.5.10", PrefixLength: 16}}, Gateway: "172.16.0.1", DNS: []string{"172.16.0.2", "172.16.0.3"}},
+				{MAC: mac2},
+			},
+		},
+		{
+			fixture:      "debian11",
+			configurator: "debian",
+			ifaces: []Interface{
+				{MAC: mac1, Addresses: []Address{{IP: "10.20.0.15", PrefixLength: 24}, {IP: "10.20.0.16", PrefixLength: 24}}, Gateway: "10.20.0.1", DNS: []string{"10.20.0.2"}},
+				{MAC: mac2},
+			},
+		},
+		{
+			fixture:      "ubuntu2204",
+			configurator: "netplan",
+			ifaces: []Interface{
+				{MAC: mac1, Addresses: []Address{{IP: "10.30.0.40", PrefixLength: 24}}, Gateway: "10.30.0.1", DNS: []string{"10.30.0.2"}},
+				{MAC: mac2},
+			},
+			mode:     0600,
+			modeFile: "/etc/netplan/50-vjailbreak.yaml",
+		},
+		{
+			fixture:      "photon4",
+			configurator: "systemd-networkd",
+			ifaces: []Interface{
+				{MAC: mac1, Addresses: []Address{{IP: "10.40.0.8", PrefixLength: 24}}, Gateway: "10.40.0.1", DNS: []string{"10.40.0.2", "10.40.0.3"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			guest := copyGuest(t, tt.fixture)
+			osRelease, err := guest.ReadFile("/etc/os-release")
+			require.NoError(t, err)
+
+			configurator, err := Detect(guest, strings.ToLower(string(osRelease)))
+			require.NoError(t, err)
+			require.NotNil(t, configurator)
+			assert.Equal(t, tt.configurator, configurator.Name())
+
+			require.NoError(t, configurator.Configure(guest, tt.ifaces))
+			assertGuest(t, guest, tt.fixture)
+			if tt.modeFile != "" {
+				info, err := os.Stat(guest.path(tt.modeFile))
+				require.NoError(t, err)
+				assert.Equal(t, tt.mode, info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	empty := DirFS(t.TempDir())
+	tests := []struct {
+		osRelease    string
+		configurator string
+	}{
+		{`name="ubuntu"` + "\nversion_id=\"20.04\"", "netplan"},
+		{`name="ubuntu"` + "\nversion_id=\"16.04\"", ""},
+		{`name="red hat enterprise linux server"` + "\nversion_id=\"6.10\"", "redhat"},
+		{"oracle linux server release 7.9", "redhat"},
+		{`name="opensuse leap"` + "\nversion_id=\"15.6\"", "suse"},
+		{`name="debian gnu/linux"` + "\nversion_id=\"12\"", ""},
+		{`name="vmware photon os"` + "\nversion_id=5.0", "systemd-networkd"},
+		{`name="arch linux"`, ""},
+	}
+	for _, tt := range tests {
+		configurator, err := Detect(empty, tt.osRelease)
+		require.NoError(t, err)
+		name := ""
+		if configurator != nil {
+			name = configurator.Name()
+		}
+		assert.Equal(t, tt.configurator, name, tt.osRelease)
+	}
+
+	// Old Ubuntu and Debian releases are detected by their ifupdown configuration
+	require.NoError(t, empty.WriteFile(interfacesPath, []byte("auto lo\niface lo inet loopback\n"), 0644))
+	configurator, err := Detect(empty, `name="ubuntu"`+"\nversion_id=\"16.04\"")
+	require.NoError(t, err)
+	require.NotNil(t, configurator)
+	assert.Equal(t, "debian", configurator.Name())
+}
+
+func TestNewInterfaces(t *testing.T) {
+	guestNetworks := []vjailbreakv1alpha1.GuestNetwork{
+		{MAC: "00:50:56:AA:BB:01", IP: "10.0.0.5", Origin: "manual", PrefixLength: 24, Gateway: "10.0.0.1", DNS: []string{"10.0.0.2"}},
+		{MAC: mac1, IP: "fe80::1", Origin: "manual", PrefixLength: 64},
+		{MAC: mac2, IP: "10.0.1.5", Origin: "dhcp", PrefixLength: 24, Gateway: "10.0.1.1"},
+		{MAC: "00:50:56:aa:bb:03", IP: "10.0.2.5", Origin: "manual", PrefixLength: 24, Gateway: "10.0.2.1"},
+	}
+	ifaces := NewInterfaces([]string{"00:50:56:AA:BB:01", mac2, "00:50:56:aa:bb:03"}, guestNetworks, []string{"10.0.0.5", "10.0.1.5", "10.0.9.9"})
+	assert.Equal(t, []Interface{
+		{MAC: mac1, Addresses: []Address{{IP: "10.0.0.5", PrefixLength: 24}}, Gateway: "10.0.0.1", DNS: []string{"10.0.0.2"}},
+		// DHCP addresses stay on DHCP
+		{MAC: mac2},
+		// The port did not get the static address of the guest, DHCP hands out the one it got
+		{MAC: "00:50:56:aa:bb:03"},
+	}, ifaces)
+}
+
+func TestAssignNames(t *testing.T) {
+	ifaces := assignNames(
+		[]Interface{{MAC: mac1}, {MAC: mac2}, {MAC: "00:50:56:aa:bb:03"}, {MAC: "00:50:56:aa:bb:04"}},
+		map[string]string{mac2: "ens192"},
+		[]string{"ens192", "ens224", "eth0"},
+	)
+	names := []string{}
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	assert.Equal(t, []string{"ens224", "ens192", "eth0", "eth1"}, names)
+}
+
+func TestWindowsConfigure(t *testing.T) {
+	guest := copyGuest(t, "windows2019")
+	configurator := &WindowsConfigurator{RemoveVMwareTools: true}
+	detected, err := configurator.Detect(guest, "")
+	require.NoError(t, err)
+	assert.True(t, detected)
+
+	require.NoError(t, configurator.Configure(guest, []Interface{
+		{MAC: mac1, Addresses: []Address{{IP: "10.50.0.20", PrefixLength: 24}}, Gateway: "10.50.0.1", DNS: []string{"10.50.0.2", "10.50.0.3"}},
+		{MAC: mac2},
+	}))
+	assertGuest(t, guest, "windows2019")
+
+	// Linux guests are not Windows guests
+	detected, err = configurator.Detect(copyGuest(t, "rocky9"), "")
+	require.NoError(t, err)
+	assert.False(t, detected)
+}
+
+func TestParseWindowsResult(t *testing.T) {
+	result, err := ParseWindowsResult("SeaBIOS (version 1.16.0)\r\nBooting from Hard Disk...\r\n")
+	require.NoError(t, err)
+	assert.Nil(t, result)
+
+	result, err = ParseWindowsResult("Booting from Hard Disk...\r\n" + WindowsResultMarker +
+		`{"adapters":[{"mac":"00:50:56:aa:bb:01","status":"Static"},{"mac":"00:50:56:aa:bb:02","status":"Failed","message":"Access is denied"}],` +
+		`"removedGhostAdapters":["vmxnet3 Ethernet Adapter"],"vmwareToolsRemoved":true,"errors":[]}` + "\r\n")
+	require.NoError(t, err)
+	assert.Equal(t, &vjailbreakv1alpha1.WindowsFirstBootResult{
+		Reported: true,
+		Adapters: []vjailbreakv1alpha1.WindowsAdapterResult{
+			{MAC: mac1, Status: vjailbreakv1alpha1.WindowsAdapterStatic},
+			{MAC: mac2, Status: vjailbreakv1alpha1.WindowsAdapterFailed, Message: "Access is denied"},
+		},
+		RemovedGhostAdapters: []string{"vmxnet3 Ethernet Adapter"},
+		VMwareToolsRemoved:   true,
+		Errors:               []string{},
+	}, result)
+
+	_, err = ParseWindowsResult(WindowsResultMarker + "{\"adapters\":[\r\n")
+	assert.Error(t, err)
+}
@@ -0,0 +1,138 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	netplanDir  = "/etc/netplan"
+	netplanPath = "/etc/netplan/50-vjailbreak.yaml"
+)
+
+// netplanConfig is the part of a netplan file that names the ethernet interfaces
+type netplanConfig struct {
+	Network struct {
+		Ethernets map[string]struct {
+			Match struct {
+				MACAddress string `json:"macaddress"`
+			} `json:"match"`
+			SetName string `json:"set-name"`
+		} `json:"ethernets"`
+	} `json:"network"`
+}
+
+// NetplanConfigurator configures the guests whose network is set up by netplan, such as Ubuntu 17.10 and later
+type NetplanConfigurator struct{}
+
+// Name is the name of the network stack the configurator handles
+func (c *NetplanConfigurator) Name() string {
+	return "netplan"
+}
+
+// Detect returns true for guests with netplan files, and for Ubuntu releases that ship netplan
+func (c *NetplanConfigurator) Detect(fs GuestFS, osRelease string) (bool, error) {
+	files, err := listDir(fs, netplanDir, hasExtension(".yaml"))
+	if err != nil {
+		return false, err
+	}
+	if len(files) > 0 {
+		return true, nil
+	}
+	return strings.Contains(osRelease, "ubuntu") && isNetplanSupported(parseVersionID(osRelease)), nil
+}
+
+// Configure writes a netplan file matching each interface by MAC address, and backs up
+// the netplan files that configure the same interfaces
+func (c *NetplanConfigurator) Configure(fs GuestFS, ifaces []Interface) error {
+	files, err := listDir(fs, netplanDir, hasExtension(".yaml"))
+	if err != nil {
+		return err
+	}
+	byMAC := map[string]string{}
+	ordered := []string{}
+	fileNames := map[string][]string{}
+	for _, file := range files {
+		filePath := path.Join(netplanDir, file)
+		content, err := fs.ReadFile(filePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", filePath)
+		}
+		config := netplanConfig{}
+		if err := yaml.Unmarshal(content, &config); err != nil {
+			return errors.Wrapf(err, "failed to parse %s", filePath)
+		}
+		ids := make([]string, 0, len(config.Network.Ethernets))
+		for id := range config.Network.Ethernets {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			ethernet := config.Network.Ethernets[id]
+			name := id
+			if ethernet.SetName != "" {
+				name = ethernet.SetName
+			}
+			if mac := strings.ToLower(ethernet.Match.MACAddress); mac != "" {
+				byMAC[mac] = name
+			}
+			if !slices.Contains(ordered, name) {
+				ordered = append(ordered, name)
+			}
+			fileNames[filePath] = append(fileNames[filePath], name)
+		}
+	}
+	ifaces = assignNames(ifaces, byMAC, ordered)
+
+	for _, file := range files {
+		filePath := path.Join(netplanDir, file)
+		if filePath == netplanPath {
+			continue
+		}
+		for _, iface := range ifaces {
+			if slices.Contains(fileNames[filePath], iface.Name) {
+				if err := backup(fs, filePath); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	// netplan warns about files readable by other users
+	return errors.Wrapf(fs.WriteFile(netplanPath, []byte(netplan(ifaces)), 0600), "failed to write %s", netplanPath)
+}
+
+// netplan returns the netplan file of the interfaces
+func netplan(ifaces []Interface) string {
+	var b strings.Builder
+	b.WriteString(generatedHeader + "\n")
+	b.WriteString("network:\n  version: 2\n  ethernets:\n")
+	for _, iface := range ifaces {
+		fmt.Fprintf(&b, "    %s:\n", iface.Name)
+		fmt.Fprintf(&b, "      match:\n        macaddress: %q\n", iface.MAC)
+		fmt.Fprintf(&b, "      set-name: %s\n", iface.Name)
+		if !iface.Static() {
+			b.WriteString("      dhcp4: true\n")
+			continue
+		}
+		b.WriteString("      addresses:\n")
+		for _, address := range iface.Addresses {
+			fmt.Fprintf(&b, "        - %s\n", address.CIDR())
+		}
+		if iface.Gateway != "" {
+			fmt.Fprintf(&b, "      routes:\n        - to: 0.0.0.0/0\n          via: %s\n", iface.Gateway)
+		}
+		if len(iface.DNS) > 0 {
+			fmt.Fprintf(&b, "      nameservers:\n        addresses: [%s]\n", strings.Join(iface.DNS, ", "))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,64 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const networkdDir = "/etc/systemd/network"
+
+// NetworkdConfigurator configures the guests whose network is set up by systemd-networkd, such as Photon OS
+type NetworkdConfigurator struct{}
+
+// Name is the name of the network stack the configurator handles
+func (c *NetworkdConfigurator) Name() string {
+	return "systemd-networkd"
+}
+
+// Detect returns true for Photon OS, and for guests with systemd-networkd network files
+func (c *NetworkdConfigurator) Detect(fs GuestFS, osRelease string) (bool, error) {
+	if strings.Contains(osRelease, "photon") {
+		return true, nil
+	}
+	files, err := listDir(fs, networkdDir, hasExtension(".network"))
+	return len(files) > 0, err
+}
+
+// Configure writes a network file matching each interface by MAC address. networkd applies
+// the first file in lexical order that matches an interface, so the files sort before the
+// existing ones, which still apply to any other interface
+func (c *NetworkdConfigurator) Configure(fs GuestFS, ifaces []Interface) error {
+	for idx, iface := range ifaces {
+		networkPath := path.Join(networkdDir, fmt.Sprintf("00-vjailbreak-%d.network", idx))
+		if err := fs.WriteFile(networkPath, []byte(networkdNetwork(iface)), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", networkPath)
+		}
+	}
+	return nil
+}
+
+// networkdNetwork returns the systemd-networkd network file of the interface
+func networkdNetwork(iface Interface) string {
+	var b strings.Builder
+	b.WriteString(generatedHeader + "\n")
+	fmt.Fprintf(&b, "[Match]\nMACAddress=%s\n\n[Network]\n", iface.MAC)
+	if !iface.Static() {
+		b.WriteString("DHCP=yes\n")
+		return b.String()
+	}
+	for _, address := range iface.Addresses {
+		fmt.Fprintf(&b, "Address=%s\n", address.CIDR())
+	}
+	if iface.Gateway != "" {
+		fmt.Fprintf(&b, "Gateway=%s\n", iface.Gateway)
+	}
+	for _, server := range iface.DNS {
+		fmt.Fprintf(&b, "DNS=%s\n", server)
+	}
+	return b.String()
+}
@@ -0,0 +1,252 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/platform9/vjailbreak/v2v-helper/virtv2v"
+)
+
+const (
+	ifcfgDir   = "/etc/sysconfig/network-scripts"
+	keyfileDir = "/etc/NetworkManager/system-connections"
+)
+
+// RedHatConfigurator configures RHEL and its derivatives. Releases that keep their
+// connections in ifcfg files get ifcfg files, others get NetworkManager keyfiles
+type RedHatConfigurator struct{}
+
+// Name is the name of the network stack the configurator handles
+func (c *RedHatConfigurator) Name() string {
+	return "redhat"
+}
+
+// Detect returns true for RHEL, CentOS, Rocky, Alma, Oracle Linux, Scientific Linux and Fedora
+func (c *RedHatConfigurator) Detect(fs GuestFS, osRelease string) (bool, error) {
+	return virtv2v.IsRHELFamily(osRelease) ||
+		strings.Contains(osRelease, "fedora") ||
+		strings.Contains(osRelease, "oracle") ||
+		strings.Contains(osRelease, "scientific linux"), nil
+}
+
+// Configure writes a connection per interface and pins the interface names with udev rules
+func (c *RedHatConfigurator) Configure(fs GuestFS, ifaces []Interface) error {
+	ifcfgs, err := listDir(fs, ifcfgDir, isIfcfgFile)
+	if err != nil {
+		return err
+	}
+	keyfiles, err := listDir(fs, keyfileDir, hasExtension(".nmconnection"))
+	if err != nil {
+		return err
+	}
+	if len(ifcfgs) == 0 && len(keyfiles) > 0 {
+		return c.configureKeyfiles(fs, ifaces, keyfiles)
+	}
+	if len(ifcfgs) == 0 {
+		exists, err := fs.Exists(keyfileDir)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check for %s", keyfileDir)
+		}
+		if exists {
+			// Releases without ifcfg support only ship the keyfile directory
+			return c.configureKeyfiles(fs, ifaces, nil)
+		}
+	}
+	return c.configureIfcfgs(fs, ifaces, ifcfgs)
+}
+
+// existingConnection is an ethernet connection found in the guest
+type existingConnection struct {
+	path string
+	name string
+	mac  string
+}
+
+// bindNames assigns the interface names from the existing connections, and backs up the
+// connections of those interfaces that are not at the path the new connection is written to
+func bindNames(fs GuestFS, ifaces []Interface, existing []existingConnection, connectionPath func(name string) string) ([]Interface, error) {
+	byMAC := map[string]string{}
+	ordered := []string{}
+	for _, conn := range existing {
+		if conn.mac != "" {
+			byMAC[conn.mac] = conn.name
+		}
+		ordered = append(ordered, conn.name)
+	}
+	ifaces = assignNames(ifaces, byMAC, ordered)
+	for _, conn := range existing {
+		for _, iface := range ifaces {
+			if (conn.name == iface.Name || conn.mac == iface.MAC) && conn.path != connectionPath(iface.Name) {
+				if err := backup(fs, conn.path); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+	return ifaces, nil
+}
+
+func (c *RedHatConfigurator) configureIfcfgs(fs GuestFS, ifaces []Interface, ifcfgs []string) error {
+	existing := []existingConnection{}
+	for _, file := range ifcfgs {
+		filePath := path.Join(ifcfgDir, file)
+		content, err := fs.ReadFile(filePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", filePath)
+		}
+		vars := parseShellVars(string(content))
+		if connType := vars["TYPE"]; connType != "" && !strings.EqualFold(connType, "ethernet") {
+			continue
+		}
+		name := vars["DEVICE"]
+		if name == "" {
+			name = strings.TrimPrefix(file, "ifcfg-")
+		}
+		existing = append(existing, existingConnection{path: filePath, name: name, mac: strings.ToLower(vars["HWADDR"])})
+	}
+	ifaces, err := bindNames(fs, ifaces, existing, ifcfgPath)
+	if err != nil {
+		return err
+	}
+	for _, iface := range ifaces {
+		if err := fs.WriteFile(ifcfgPath(iface.Name), []byte(ifcfg(iface)), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write ifcfg file of %s", iface.Name)
+		}
+	}
+	return writeUdevRules(fs, ifaces)
+}
+
+func (c *RedHatConfigurator) configureKeyfiles(fs GuestFS, ifaces []Interface, keyfiles []string) error {
+	existing := []existingConnection{}
+	for _, file := range keyfiles {
+		filePath := path.Join(keyfileDir, file)
+		content, err := fs.ReadFile(filePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", filePath)
+		}
+		sections := parseINI(string(content))
+		if connType := sections["connection"]["type"]; connType != "ethernet" && connType != "802-3-ethernet" {
+			continue
+		}
+		name := sections["connection"]["interface-name"]
+		if name == "" {
+			continue
+		}
+		existing = append(existing, existingConnection{path: filePath, name: name, mac: strings.ToLower(sections["ethernet"]["mac-address"])})
+	}
+	ifaces, err := bindNames(fs, ifaces, existing, keyfilePath)
+	if err != nil {
+		return err
+	}
+	for _, iface := range ifaces {
+		// NetworkManager ignores keyfiles readable by other users
+		if err := fs.WriteFile(keyfilePath(iface.Name), []byte(keyfile(iface)), 0600); err != nil {
+			return errors.Wrapf(err, "failed to write keyfile of %s", iface.Name)
+		}
+	}
+	return writeUdevRules(fs, ifaces)
+}
+
+func ifcfgPath(name string) string {
+	return path.Join(ifcfgDir, "ifcfg-"+name)
+}
+
+func keyfilePath(name string) string {
+	return path.Join(keyfileDir, name+".nmconnection")
+}
+
+// isIfcfgFile returns true for the ifcfg files of interfaces other than the loopback,
+// skipping the backup files the network scripts ignore
+func isIfcfgFile(name string) bool {
+	if !strings.HasPrefix(name, "ifcfg-") || name == "ifcfg-lo" {
+		return false
+	}
+	for _, suffix := range []string{".bak", ".orig", ".rpmnew", ".rpmsave", "~"} {
+		if strings.HasSuffix(name, suffix) {
+			return false
+		}
+	}
+	return true
+}
+
+// ifcfg returns the ifcfg file of the interface
+func ifcfg(iface Interface) string {
+	var b strings.Builder
+	b.WriteString(generatedHeader + "\n")
+	fmt.Fprintf(&b, "DEVICE=%s\nNAME=%s\nHWADDR=%s\nTYPE=Ethernet\nONBOOT=yes\n", iface.Name, iface.Name, iface.MAC)
+	if !iface.Static() {
+		b.WriteString("BOOTPROTO=dhcp\n")
+		return b.String()
+	}
+	b.WriteString("BOOTPROTO=none\n")
+	for idx, address := range iface.Addresses {
+		suffix := ""
+		if idx > 0 {
+			suffix = fmt.Sprintf("%d", idx)
+		}
+		fmt.Fprintf(&b, "IPADDR%s=%s\nPREFIX%s=%d\n", suffix, address.IP, suffix, address.PrefixLength)
+	}
+	if iface.Gateway != "" {
+		fmt.Fprintf(&b, "GATEWAY=%s\n", iface.Gateway)
+	}
+	for idx, server := range iface.DNS {
+		fmt.Fprintf(&b, "DNS%d=%s\n", idx+1, server)
+	}
+	return b.String()
+}
+
+// keyfile returns the NetworkManager keyfile of the interface
+func keyfile(iface Interface) string {
+	var b strings.Builder
+	b.WriteString(generatedHeader + "\n")
+	fmt.Fprintf(&b, "[connection]\nid=%s\ntype=ethernet\ninterface-name=%s\nautoconnect=true\n\n", iface.Name, iface.Name)
+	fmt.Fprintf(&b, "[ethernet]\nmac-address=%s\n\n", strings.ToUpper(iface.MAC))
+	if !iface.Static() {
+		b.WriteString("[ipv4]\nmethod=auto\n\n")
+	} else {
+		b.WriteString("[ipv4]\nmethod=manual\n")
+		for idx, address := range iface.Addresses {
+			fmt.Fprintf(&b, "address%d=%s\n", idx+1, address.CIDR())
+		}
+		if iface.Gateway != "" {
+			fmt.Fprintf(&b, "gateway=%s\n", iface.Gateway)
+		}
+		if len(iface.DNS) > 0 {
+			fmt.Fprintf(&b, "dns=%s;\n", strings.Join(iface.DNS, ";"))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("[ipv6]\nmethod=auto\n")
+	return b.String()
+}
+
+// parseINI parses the sections of an INI file, as used by NetworkManager keyfiles
+func parseINI(content string) map[string]map[string]string {
+	sections := map[string]map[string]string{}
+	section := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSuffix(strings.TrimPrefix(line, "["), "]")
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if sections[section] == nil {
+			sections[section] = map[string]string{}
+		}
+		sections[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return sections
+}
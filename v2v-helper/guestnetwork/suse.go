@@ -0,0 +1,130 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	wickedDir        = "/etc/sysconfig/network"
+	wickedConfigPath = "/etc/sysconfig/network/config"
+)
+
+// SUSEConfigurator configures SLES and openSUSE, whose interfaces are set up by wicked
+type SUSEConfigurator struct{}
+
+// Name is the name of the network stack the configurator handles
+func (c *SUSEConfigurator) Name() string {
+	return "suse"
+}
+
+// Detect returns true for SLES, SLED and openSUSE
+func (c *SUSEConfigurator) Detect(fs GuestFS, osRelease string) (bool, error) {
+	return strings.Contains(osRelease, "suse") ||
+		strings.Contains(osRelease, "sles") ||
+		strings.Contains(osRelease, "sled"), nil
+}
+
+// Configure writes the ifcfg and ifroute files of each interface, sets the static DNS
+// servers of netconfig and pins the interface names with udev rules
+func (c *SUSEConfigurator) Configure(fs GuestFS, ifaces []Interface) error {
+	ifcfgs, err := listDir(fs, wickedDir, isIfcfgFile)
+	if err != nil {
+		return err
+	}
+	byMAC := map[string]string{}
+	ordered := []string{}
+	for _, file := range ifcfgs {
+		filePath := path.Join(wickedDir, file)
+		content, err := fs.ReadFile(filePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", filePath)
+		}
+		vars := parseShellVars(string(content))
+		if strings.EqualFold(vars["BONDING_MASTER"], "yes") || strings.EqualFold(vars["BRIDGE"], "yes") || vars["ETHERDEVICE"] != "" {
+			continue
+		}
+		name := strings.TrimPrefix(file, "ifcfg-")
+		if mac := strings.ToLower(vars["LLADDR"]); mac != "" {
+			byMAC[mac] = name
+		}
+		ordered = append(ordered, name)
+	}
+	ifaces = assignNames(ifaces, byMAC, ordered)
+
+	for _, iface := range ifaces {
+		if err := fs.WriteFile(path.Join(wickedDir, "ifcfg-"+iface.Name), []byte(wickedIfcfg(iface)), 0644); err != nil {
+			return errors.Wrapf(err, "failed to write ifcfg file of %s", iface.Name)
+		}
+		routePath := path.Join(wickedDir, "ifroute-"+iface.Name)
+		if iface.Static() && iface.Gateway != "" {
+			route := fmt.Sprintf("%s\ndefault %s - %s\n", generatedHeader, iface.Gateway, iface.Name)
+			if err := fs.WriteFile(routePath, []byte(route), 0644); err != nil {
+				return errors.Wrapf(err, "failed to write ifroute file of %s", iface.Name)
+			}
+			continue
+		}
+		// The routes of the interface would refer to the gateway of the old address
+		exists, err := fs.Exists(routePath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to check for %s", routePath)
+		}
+		if exists {
+			if err := backup(fs, routePath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := setStaticDNS(fs, uniqueDNS(ifaces)); err != nil {
+		return err
+	}
+	return writeUdevRules(fs, ifaces)
+}
+
+// setStaticDNS sets the DNS servers netconfig writes to resolv.conf
+func setStaticDNS(fs GuestFS, servers []string) error {
+	if len(servers) == 0 {
+		return nil
+	}
+	exists, err := fs.Exists(wickedConfigPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to check for %s", wickedConfigPath)
+	}
+	if !exists {
+		log.Printf("%s not found, not setting the DNS servers %v", wickedConfigPath, servers)
+		return nil
+	}
+	content, err := fs.ReadFile(wickedConfigPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read %s", wickedConfigPath)
+	}
+	updated := setShellVar(string(content), "NETCONFIG_DNS_STATIC_SERVERS", strings.Join(servers, " "))
+	return errors.Wrapf(fs.WriteFile(wickedConfigPath, []byte(updated), 0644), "failed to write %s", wickedConfigPath)
+}
+
+// wickedIfcfg returns the wicked ifcfg file of the interface
+func wickedIfcfg(iface Interface) string {
+	var b strings.Builder
+	b.WriteString(generatedHeader + "\n")
+	b.WriteString("STARTMODE='auto'\n")
+	if !iface.Static() {
+		b.WriteString("BOOTPROTO='dhcp'\n")
+		return b.String()
+	}
+	b.WriteString("BOOTPROTO='static'\n")
+	for idx, address := range iface.Addresses {
+		suffix := ""
+		if idx > 0 {
+			suffix = fmt.Sprintf("_%d", idx)
+		}
+		fmt.Fprintf(&b, "IPADDR%s='%s'\n", suffix, address.CIDR())
+	}
+	return b.String()
+}
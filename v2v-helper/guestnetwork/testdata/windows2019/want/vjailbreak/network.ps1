@@ -0,0 +1,112 @@
+# Generated by vJailbreak from the VMware guest network settings
+$ErrorActionPreference = 'Stop'
+$config = @'
+{
+  "adapters": [
+    {
+      "mac": "00:50:56:aa:bb:01",
+      "addresses": [
+        {
+          "ip": "10.50.0.20",
+          "prefixLength": 24
+        }
+      ],
+      "gateway": "10.50.0.1",
+      "dns": [
+        "10.50.0.2",
+        "10.50.0.3"
+      ]
+    },
+    {
+      "mac": "00:50:56:aa:bb:02",
+      "addresses": []
+    }
+  ],
+  "removeVMwareTools": true
+}
+'@ | ConvertFrom-Json
+
+$result = [ordered]@{ adapters = @(); removedGhostAdapters = @(); vmwareToolsRemoved = $false; errors = @() }
+
+# The VMware adapters that are gone still hold their static addresses, which Windows
+# refuses to assign to another adapter, so they are removed first
+try {
+    $ghosts = @(Get-PnpDevice -Class Net | Where-Object { $_.Status -eq 'Unknown' -and $_.FriendlyName -match 'vmxnet|VMware' })
+    foreach ($ghost in $ghosts) {
+        & pnputil.exe /remove-device $ghost.InstanceId | Out-Null
+        if ($LASTEXITCODE -eq 0) {
+            $result.removedGhostAdapters += $ghost.FriendlyName
+        } else {
+            $result.errors += "failed to remove adapter $($ghost.FriendlyName): pnputil exited with $LASTEXITCODE"
+        }
+    }
+} catch {
+    $result.errors += "failed to remove ghost adapters: $($_.Exception.Message)"
+}
+
+foreach ($nic in $config.adapters) {
+    $mac = $nic.mac.Replace(':', '-').ToUpper()
+    # The virtio adapters show up once their driver is loaded
+    $adapter = $null
+    for ($i = 0; $i -lt 60 -and -not $adapter; $i++) {
+        $adapter = Get-NetAdapter -Physical -ErrorAction SilentlyContinue | Where-Object { $_.MacAddress -eq $mac } | Select-Object -First 1
+        if (-not $adapter) { Start-Sleep -Seconds 5 }
+    }
+    if (-not $adapter) {
+        $result.adapters += [ordered]@{ mac = $nic.mac; status = 'NotFound' }
+        continue
+    }
+    try {
+        $index = $adapter.ifIndex
+        Get-NetIPAddress -InterfaceIndex $index -AddressFamily IPv4 -ErrorAction SilentlyContinue | Remove-NetIPAddress -Confirm:$false
+        Get-NetRoute -InterfaceIndex $index -DestinationPrefix '0.0.0.0/0' -ErrorAction SilentlyContinue | Remove-NetRoute -Confirm:$false
+        if (-not $nic.addresses) {
+            Set-NetIPInterface -InterfaceIndex $index -AddressFamily IPv4 -Dhcp Enabled
+            Set-DnsClientServerAddress -InterfaceIndex $index -ResetServerAddresses
+            $result.adapters += [ordered]@{ mac = $nic.mac; status = 'DHCP' }
+            continue
+        }
+        Set-NetIPInterface -InterfaceIndex $index -AddressFamily IPv4 -Dhcp Disabled
+        foreach ($address in $nic.addresses) {
+            New-NetIPAddress -InterfaceIndex $index -AddressFamily IPv4 -IPAddress $address.ip -PrefixLength $address.prefixLength | Out-Null
+        }
+        if ($nic.gateway) {
+            New-NetRoute -InterfaceIndex $index -DestinationPrefix '0.0.0.0/0' -NextHop $nic.gateway | Out-Null
+        }
+        if ($nic.dns) {
+            Set-DnsClientServerAddress -InterfaceIndex $index -ServerAddresses $nic.dns
+        }
+        $result.adapters += [ordered]@{ mac = $nic.mac; status = 'Static' }
+    } catch {
+        $result.adapters += [ordered]@{ mac = $nic.mac; status = 'Failed'; message = $_.Exception.Message }
+    }
+}
+
+if ($config.removeVMwareTools) {
+    try {
+        $tools = Get-ItemProperty 'HKLM:\Software\Microsoft\Windows\CurrentVersion\Uninstall\*' |
+            Where-Object { $_.DisplayName -eq 'VMware Tools' } | Select-Object -First 1
+        if ($tools) {
+            $process = Start-Process msiexec.exe -ArgumentList "/x $($tools.PSChildName) /qn /norestart" -Wait -PassThru
+            # 3010 means the removal completes on the next reboot
+            if ($process.ExitCode -eq 0 -or $process.ExitCode -eq 3010) {
+                $result.vmwareToolsRemoved = $true
+            } else {
+                $result.errors += "failed to uninstall VMware Tools: msiexec exited with $($process.ExitCode)"
+            }
+        }
+    } catch {
+        $result.errors += "failed to uninstall VMware Tools: $($_.Exception.Message)"
+    }
+}
+
+$json = ConvertTo-Json -InputObject $result -Depth 5 -Compress
+Set-Content -Path (Join-Path $PSScriptRoot 'result.json') -Value $json
+try {
+    $port = New-Object System.IO.Ports.SerialPort 'COM1', 115200, 'None', 8, 'One'
+    $port.Open()
+    $port.WriteLine('VJAILBREAK_FIRSTBOOT_RESULT ' + $json)
+    $port.Close()
+} catch {
+    Write-Output "failed to write the result to COM1: $($_.Exception.Message)"
+}
@@ -0,0 +1,203 @@
+// Copyright © 2024 The vjailbreak authors
+
+package guestnetwork
+
+import (
+	"bufio"
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+)
+
+const (
+	// windowsScriptPath is the path of the network script inside the Windows guest, on the system drive
+	windowsScriptPath = "/vjailbreak/network.ps1"
+	// WindowsResultMarker prefixes the line the network script writes its JSON result on, on the serial console
+	WindowsResultMarker = "VJAILBREAK_FIRSTBOOT_RESULT "
+)
+
+// WindowsFirstBootScript is the virt-v2v first boot script that runs the network script. virt-v2v runs
+// the first boot scripts of Windows guests as batch files, once the virtio drivers are installed
+const WindowsFirstBootScript = "@echo off\r\n" +
+	`powershell.exe -NoProfile -NonInteractive -ExecutionPolicy Bypass -File C:\vjailbreak\network.ps1 > C:\vjailbreak\network.log 2>&1` + "\r\n"
+
+// windowsNetworkScript is the logic of the network script, it runs after the $config header written by
+// the configurator. The result is written next to the script and on COM1, which OpenStack keeps as the
+// console log of the server
+const windowsNetworkScript = `$result = [ordered]@{ adapters = @(); removedGhostAdapters = @(); vmwareToolsRemoved = $false; errors = @() }
+
+# The VMware adapters that are gone still hold their static addresses, which Windows
+# refuses to assign to another adapter, so they are removed first
+try {
+    $ghosts = @(Get-PnpDevice -Class Net | Where-Object { $_.Status -eq 'Unknown' -and $_.FriendlyName -match 'vmxnet|VMware' })
+    foreach ($ghost in $ghosts) {
+        & pnputil.exe /remove-device $ghost.InstanceId | Out-Null
+        if ($LASTEXITCODE -eq 0) {
+            $result.removedGhostAdapters += $ghost.FriendlyName
+        } else {
+            $result.errors += "failed to remove adapter $($ghost.FriendlyName): pnputil exited with $LASTEXITCODE"
+        }
+    }
+} catch {
+    $result.errors += "failed to remove ghost adapters: $($_.Exception.Message)"
+}
+
+foreach ($nic in $config.adapters) {
+    $mac = $nic.mac.Replace(':', '-').ToUpper()
+    # The virtio adapters show up once their driver is loaded
+    $adapter = $null
+    for ($i = 0; $i -lt 60 -and -not $adapter; $i++) {
+        $adapter = Get-NetAdapter -Physical -ErrorAction SilentlyContinue | Where-Object { $_.MacAddress -eq $mac } | Select-Object -First 1
+        if (-not $adapter) { Start-Sleep -Seconds 5 }
+    }
+    if (-not $adapter) {
+        $result.adapters += [ordered]@{ mac = $nic.mac; status = 'NotFound' }
+        continue
+    }
+    try {
+        $index = $adapter.ifIndex
+        Get-NetIPAddress -InterfaceIndex $index -AddressFamily IPv4 -ErrorAction SilentlyContinue | Remove-NetIPAddress -Confirm:$false
+        Get-NetRoute -InterfaceIndex $index -DestinationPrefix '0.0.0.0/0' -ErrorAction SilentlyContinue | Remove-NetRoute -Confirm:$false
+        if (-not $nic.addresses) {
+            Set-NetIPInterface -InterfaceIndex $index -AddressFamily IPv4 -Dhcp Enabled
+            Set-DnsClientServerAddress -InterfaceIndex $index -ResetServerAddresses
+            $result.adapters += [ordered]@{ mac = $nic.mac; status = 'DHCP' }
+            continue
+        }
+        Set-NetIPInterface -InterfaceIndex $index -AddressFamily IPv4 -Dhcp Disabled
+        foreach ($address in $nic.addresses) {
+            New-NetIPAddress -InterfaceIndex $index -AddressFamily IPv4 -IPAddress $address.ip -PrefixLength $address.prefixLength | Out-Null
+        }
+        if ($nic.gateway) {
+            New-NetRoute -InterfaceIndex $index -DestinationPrefix '0.0.0.0/0' -NextHop $nic.gateway | Out-Null
+        }
+        if ($nic.dns) {
+            Set-DnsClientServerAddress -InterfaceIndex $index -ServerAddresses $nic.dns
+        }
+        $result.adapters += [ordered]@{ mac = $nic.mac; status = 'Static' }
+    } catch {
+        $result.adapters += [ordered]@{ mac = $nic.mac; status = 'Failed'; message = $_.Exception.Message }
+    }
+}
+
+if ($config.removeVMwareTools) {
+    try {
+        $tools = Get-ItemProperty 'HKLM:\Software\Microsoft\Windows\CurrentVersion\Uninstall\*' |
+            Where-Object { $_.DisplayName -eq 'VMware Tools' } | Select-Object -First 1
+        if ($tools) {
+            $process = Start-Process msiexec.exe -ArgumentList "/x $($tools.PSChildName) /qn /norestart" -Wait -PassThru
+            # 3010 means the removal completes on the next reboot
+            if ($process.ExitCode -eq 0 -or $process.ExitCode -eq 3010) {
+                $result.vmwareToolsRemoved = $true
+            } else {
+                $result.errors += "failed to uninstall VMware Tools: msiexec exited with $($process.ExitCode)"
+            }
+        }
+    } catch {
+        $result.errors += "failed to uninstall VMware Tools: $($_.Exception.Message)"
+    }
+}
+
+$json = ConvertTo-Json -InputObject $result -Depth 5 -Compress
+Set-Content -Path (Join-Path $PSScriptRoot 'result.json') -Value $json
+try {
+    $port = New-Object System.IO.Ports.SerialPort 'COM1', 115200, 'None', 8, 'One'
+    $port.Open()
+    $port.WriteLine('` + WindowsResultMarker + `' + $json)
+    $port.Close()
+} catch {
+    Write-Output "failed to write the result to COM1: $($_.Exception.Message)"
+}
+`
+
+// windowsConfig is the configuration the network script reads
+type windowsConfig struct {
+	Adapters          []windowsAdapter `json:"adapters"`
+	RemoveVMwareTools bool             `json:"removeVMwareTools"`
+}
+
+// windowsAdapter is the configuration of a NIC in the network script
+type windowsAdapter struct {
+	MAC       string           `json:"mac"`
+	Addresses []windowsAddress `json:"addresses"`
+	Gateway   string           `json:"gateway,omitempty"`
+	DNS       []string         `json:"dns,omitempty"`
+}
+
+type windowsAddress struct {
+	IP           string `json:"ip"`
+	PrefixLength int    `json:"prefixLength"`
+}
+
+// WindowsConfigurator configures Windows guests. The network settings can only be applied once the virtio
+// NICs exist, so it writes a PowerShell script that virt-v2v runs on the first boot through WindowsFirstBootScript.
+// The script re-applies the settings to the NIC with the same MAC address, removes the VMware adapters left
+// behind and optionally uninstalls VMware Tools. It is not part of Configurators, the OS type of the VM selects it
+type WindowsConfigurator struct {
+	// RemoveVMwareTools uninstalls VMware Tools
+	RemoveVMwareTools bool
+}
+
+// Name is the name of the network stack the configurator handles
+func (c *WindowsConfigurator) Name() string {
+	return "windows"
+}
+
+// Detect returns true for guests with a Windows system directory
+func (c *WindowsConfigurator) Detect(fs GuestFS, osRelease string) (bool, error) {
+	exists, err := fs.Exists("/Windows/System32")
+	return exists, errors.Wrap(err, "failed to check for /Windows/System32")
+}
+
+// Configure writes the network script of the interfaces into the guest
+func (c *WindowsConfigurator) Configure(fs GuestFS, ifaces []Interface) error {
+	config := windowsConfig{Adapters: []windowsAdapter{}, RemoveVMwareTools: c.RemoveVMwareTools}
+	for _, iface := range ifaces {
+		adapter := windowsAdapter{MAC: iface.MAC, Addresses: []windowsAddress{}, Gateway: iface.Gateway, DNS: iface.DNS}
+		for _, address := range iface.Addresses {
+			adapter.Addresses = append(adapter.Addresses, windowsAddress{IP: address.IP, PrefixLength: address.PrefixLength})
+		}
+		config.Adapters = append(config.Adapters, adapter)
+	}
+	content, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode the network script configuration")
+	}
+
+	var b strings.Builder
+	b.WriteString(generatedHeader + "\n")
+	b.WriteString("$ErrorActionPreference = 'Stop'\n")
+	b.WriteString("$config = @'\n" + string(content) + "\n'@ | ConvertFrom-Json\n\n")
+	b.WriteString(windowsNetworkScript)
+	// The script is ASCII with Windows line endings, which Windows PowerShell reads without a byte order mark
+	script := strings.ReplaceAll(b.String(), "\n", "\r\n")
+	return errors.Wrapf(fs.WriteFile(windowsScriptPath, []byte(script), 0644), "failed to write %s", windowsScriptPath)
+}
+
+// ParseWindowsResult returns the result the network script wrote on the console of the migrated VM,
+// or nil if the console output does not hold it yet
+func ParseWindowsResult(consoleOutput string) (*vjailbreakv1alpha1.WindowsFirstBootResult, error) {
+	var line string
+	scanner := bufio.NewScanner(strings.NewReader(consoleOutput))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if idx := strings.Index(scanner.Text(), WindowsResultMarker); idx >= 0 {
+			line = scanner.Text()[idx+len(WindowsResultMarker):]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to read console output")
+	}
+	if line == "" {
+		return nil, nil
+	}
+	result := &vjailbreakv1alpha1.WindowsFirstBootResult{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line)), result); err != nil {
+		return nil, errors.Wrap(err, "failed to parse the result of the first boot script")
+	}
+	result.Reported = true
+	return result, nil
+}
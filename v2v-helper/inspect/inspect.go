@@ -0,0 +1,266 @@
+// Copyright © 2024 The vjailbreak authors
+
+// Package inspect inspects the disks of a guest with guestfish and summarizes the operating system,
+// boot configuration, storage layout, network configuration and guest agents it finds
+package inspect
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sectionMarker prefixes the line the inspection script echoes before the output of each probe
+const sectionMarker = "@@"
+
+// sysroot is where guestfish mounts the guest filesystems in its appliance
+const sysroot = "/sysroot"
+
+// Runner runs a guestfish script against the disks of the guest, mounted read-only, and returns its output
+type Runner func(script string) (string, error)
+
+// Guestfish returns the Runner that runs guestfish against the drives, which are the paths of the
+// disks or NBD URIs of the form nbd://?socket=<path>
+func Guestfish(drives []string) Runner {
+	return func(script string) (string, error) {
+		os.Setenv("LIBGUESTFS_BACKEND", "direct")
+		args := []string{"--ro", "--format=raw"}
+		for _, drive := range drives {
+			args = append(args, "-a", drive)
+		}
+		args = append(args, "-i")
+		cmd := exec.Command("guestfish", args...)
+		cmd.Stdin = strings.NewReader(script)
+		log.Printf("Executing %s with the inspection script", cmd.String())
+		out, err := cmd.Output()
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				return "", errors.Errorf("failed to run guestfish: %v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+			}
+			return "", errors.Wrap(err, "failed to run guestfish")
+		}
+		return string(out), nil
+	}
+}
+
+// pathProbe looks for the files matching any of the patterns, the first probe with a match applies
+type pathProbe struct {
+	name     string
+	patterns []string
+}
+
+// linuxBootloaders are checked in order, GRUB 2 keeps a grub.cfg even in /boot/grub
+var linuxBootloaders = []pathProbe{
+	{"GRUB2", []string{"/boot/grub2/grub.cfg", "/boot/grub/grub.cfg", "/boot/efi/EFI/*/grub.cfg"}},
+	{"GRUB Legacy", []string{"/boot/grub/menu.lst", "/boot/grub/grub.conf"}},
+	{"systemd-boot", []string{"/boot/loader/entries/*.conf", "/boot/efi/loader/entries/*.conf"}},
+}
+
+// networkConfigPatterns are the network configuration files of the network stacks guestnetwork configures
+var networkConfigPatterns = []string{
+	"/etc/sysconfig/network-scripts/ifcfg-*",
+	"/etc/NetworkManager/system-connections/*",
+	"/etc/netplan/*.yaml",
+	"/etc/network/interfaces",
+	"/etc/network/interfaces.d/*",
+	"/etc/sysconfig/network/ifcfg-*",
+	"/etc/systemd/network/*.network",
+}
+
+// guestTools are checked in order, VMware Tools installed from its tarball also ships a vmtoolsd
+var guestTools = []pathProbe{
+	{string(vjailbreakv1alpha1.GuestToolsVMwareTools), []string{
+		"/etc/vmware-tools/locations",
+		"/usr/lib/vmware-tools/sbin*/vmtoolsd",
+		"/Program Files/VMware/VMware Tools/vmtoolsd.exe",
+	}},
+	{string(vjailbreakv1alpha1.GuestToolsOpenVMTools), []string{"/usr/bin/vmtoolsd"}},
+}
+
+// virtioDrivers are the virtio storage and network drivers, as modules or built into the kernel of
+// Linux guests, and as driver files of Windows guests
+var virtioDrivers = []pathProbe{
+	{"virtio_blk", []string{"/lib/modules/*/kernel/drivers/block/virtio_blk.ko*"}},
+	{"virtio_scsi", []string{"/lib/modules/*/kernel/drivers/scsi/virtio_scsi.ko*"}},
+	{"virtio_net", []string{"/lib/modules/*/kernel/drivers/net/virtio_net.ko*"}},
+	{"viostor", []string{"/Windows/System32/drivers/viostor.sys"}},
+	{"vioscsi", []string{"/Windows/System32/drivers/vioscsi.sys"}},
+	{"netkvm", []string{"/Windows/System32/drivers/netkvm.sys"}},
+}
+
+// Inspect inspects the guest and returns what it found. uefi is the firmware VMware boots the
+// guest with. Probes that fail, such as the Linux ones on a Windows guest, are left out of the result
+func Inspect(run Runner, uefi bool) (*vjailbreakv1alpha1.GuestInspection, error) {
+	out, err := run("inspect-get-roots\n")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find the operating system of the guest")
+	}
+	root := strings.TrimSpace(strings.Split(strings.TrimSpace(out), "\n")[0])
+	if root == "" {
+		return nil, errors.New("no operating system found on the guest disks")
+	}
+
+	out, err = run(script(root))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to inspect the guest")
+	}
+	sections := parseSections(out)
+	first := func(key string) string {
+		if lines := sections[key]; len(lines) > 0 {
+			return lines[0]
+		}
+		return ""
+	}
+
+	inspection := &vjailbreakv1alpha1.GuestInspection{
+		InspectionTime: metav1.Now(),
+		OSType:         first("type"),
+		Distro:         first("distro"),
+		OSName:         first("product"),
+		Firmware:       vjailbreakv1alpha1.GuestFirmwareBIOS,
+		Filesystems:    parseDF(sections["df"]),
+		VMwareTools:    vjailbreakv1alpha1.GuestToolsNone,
+	}
+	// The version is 0 when the inspection could not tell it
+	if major := first("major"); major != "" && major != "0" {
+		inspection.OSVersion = major + "." + first("minor")
+	}
+	if uefi {
+		inspection.Firmware = vjailbreakv1alpha1.GuestFirmwareUEFI
+	}
+	if inspection.OSType == "linux" {
+		inspection.Kernels = sections["kernels"]
+		inspection.Bootloader = firstMatch(sections, "bootloader", linuxBootloaders)
+	} else if inspection.OSType == "windows" {
+		inspection.Bootloader = "Windows Boot Manager"
+	}
+	if len(sections["pvs"])+len(sections["vgs"])+len(sections["lvs"]) > 0 {
+		inspection.LVM = &vjailbreakv1alpha1.GuestLVM{
+			PhysicalVolumes: sections["pvs"],
+			VolumeGroups:    sections["vgs"],
+			LogicalVolumes:  sections["lvs"],
+		}
+	}
+	for idx := range networkConfigPatterns {
+		inspection.NetworkConfigFiles = append(inspection.NetworkConfigFiles, sections[fmt.Sprintf("network:%d", idx)]...)
+	}
+	if tools := firstMatch(sections, "tools", guestTools); tools != "" {
+		inspection.VMwareTools = vjailbreakv1alpha1.GuestTools(tools)
+	}
+	for _, driver := range virtioDrivers {
+		if len(sections["virtio:"+driver.name]) > 0 || slices.ContainsFunc(sections["builtin"], func(line string) bool {
+			return strings.HasSuffix(line, "/"+driver.name+".ko")
+		}) {
+			inspection.VirtioDrivers = append(inspection.VirtioDrivers, driver.name)
+		}
+	}
+	return inspection, nil
+}
+
+// script returns the guestfish script of the probes of the guest with the root device. Every probe
+// is prefixed with - so that guestfish carries on when it fails, and its output follows its marker
+func script(root string) string {
+	var b strings.Builder
+	section := func(key string, commands ...string) {
+		fmt.Fprintf(&b, "echo %s%s\n", sectionMarker, key)
+		for _, command := range commands {
+			b.WriteString("-" + command + "\n")
+		}
+	}
+	section("type", "inspect-get-type "+root)
+	section("distro", "inspect-get-distro "+root)
+	section("product", "inspect-get-product-name "+root)
+	section("major", "inspect-get-major-version "+root)
+	section("minor", "inspect-get-minor-version "+root)
+	section("kernels", "ls /lib/modules")
+	section("df", "df")
+	section("pvs", "pvs")
+	section("vgs", "vgs")
+	section("lvs", "lvs")
+	globs := func(prefix string, probes []pathProbe) {
+		for _, probe := range probes {
+			commands := []string{}
+			for _, pattern := range probe.patterns {
+				commands = append(commands, "glob-expand "+quote(pattern))
+			}
+			section(prefix+probe.name, commands...)
+		}
+	}
+	globs("bootloader:", linuxBootloaders)
+	for idx, pattern := range networkConfigPatterns {
+		section(fmt.Sprintf("network:%d", idx), "glob-expand "+quote(pattern))
+	}
+	globs("tools:", guestTools)
+	globs("virtio:", virtioDrivers)
+	section("builtin", "glob cat /lib/modules/*/modules.builtin")
+	return b.String()
+}
+
+// parseSections splits the output of the inspection script into the non-empty lines of each probe
+func parseSections(out string) map[string][]string {
+	sections := map[string][]string{}
+	key := ""
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, sectionMarker) {
+			key = strings.TrimPrefix(line, sectionMarker)
+			continue
+		}
+		if line != "" && key != "" {
+			sections[key] = append(sections[key], line)
+		}
+	}
+	return sections
+}
+
+// firstMatch returns the name of the first probe with a match in the sections of the prefix
+func firstMatch(sections map[string][]string, prefix string, probes []pathProbe) string {
+	for _, probe := range probes {
+		if len(sections[prefix+":"+probe.name]) > 0 {
+			return probe.name
+		}
+	}
+	return ""
+}
+
+// parseDF returns the guest filesystems in the output of df in the guestfish appliance, which
+// mounts them under /sysroot
+func parseDF(lines []string) []vjailbreakv1alpha1.GuestFilesystem {
+	filesystems := []vjailbreakv1alpha1.GuestFilesystem{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 6 {
+			continue
+		}
+		mountPoint := fields[len(fields)-1]
+		if mountPoint != sysroot && !strings.HasPrefix(mountPoint, sysroot+"/") {
+			continue
+		}
+		size, sizeErr := strconv.ParseInt(fields[1], 10, 64)
+		used, usedErr := strconv.ParseInt(fields[2], 10, 64)
+		if sizeErr != nil || usedErr != nil {
+			continue
+		}
+		filesystems = append(filesystems, vjailbreakv1alpha1.GuestFilesystem{
+			Device:     fields[0],
+			MountPoint: path.Join("/", strings.TrimPrefix(mountPoint, sysroot)),
+			SizeBytes:  size * 1024,
+			UsedBytes:  used * 1024,
+		})
+	}
+	return filesystems
+}
+
+// quote quotes a path for a guestfish script
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(name, `\`, `\\`), `"`, `\"`) + `"`
+}
@@ -0,0 +1,118 @@
+// Copyright © 2024 The vjailbreak authors
+
+package inspect
+
+import (
+	"strings"
+	"testing"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// fakeGuest answers the commands of a guestfish script from canned outputs, commands
+// without an output fail and print nothing, like guestfish does for the ones prefixed with -
+func fakeGuest(outputs map[string]string) Runner {
+	return func(script string) (string, error) {
+		var b strings.Builder
+		for _, line := range strings.Split(strings.TrimSpace(script), "\n") {
+			if strings.HasPrefix(line, "echo ") {
+				b.WriteString(strings.TrimPrefix(line, "echo ") + "\n")
+				continue
+			}
+			b.WriteString(outputs[strings.TrimPrefix(line, "-")])
+		}
+		return b.String(), nil
+	}
+}
+
+func TestInspectLinux(t *testing.T) {
+	guest := fakeGuest(map[string]string{
+		"inspect-get-roots":                        "/dev/rhel/root\n",
+		"inspect-get-type /dev/rhel/root":          "linux\n",
+		"inspect-get-distro /dev/rhel/root":        "rhel\n",
+		"inspect-get-product-name /dev/rhel/root":  "Red Hat Enterprise Linux 8.9 (Ootpa)\n",
+		"inspect-get-major-version /dev/rhel/root": "8\n",
+		"inspect-get-minor-version /dev/rhel/root": "9\n",
+		"ls /lib/modules":                          "4.18.0-513.5.1.el8_9.x86_64\n4.18.0-513.9.1.el8_9.x86_64\n",
+		"df": "Filesystem            1K-blocks    Used Available Use% Mounted on\n" +
+			"/dev/root               4048284 1803244   2016948  48% /\n" +
+			"tmpfs                    250164       0    250164   0% /run\n" +
+			"/dev/mapper/rhel-root  17811456 4123456  13687000  24% /sysroot\n" +
+			"/dev/sda1               1038336  262144    776192  26% /sysroot/boot\n",
+		"pvs":                                "/dev/sda2\n",
+		"vgs":                                "rhel\n",
+		"lvs":                                "/dev/rhel/root\n/dev/rhel/swap\n",
+		`glob-expand "/boot/grub2/grub.cfg"`: "/boot/grub2/grub.cfg\n",
+		`glob-expand "/etc/sysconfig/network-scripts/ifcfg-*"`:             "/etc/sysconfig/network-scripts/ifcfg-ens192\n",
+		`glob-expand "/etc/NetworkManager/system-connections/*"`:           "/etc/NetworkManager/system-connections/ens224.nmconnection\n",
+		`glob-expand "/usr/bin/vmtoolsd"`:                                  "/usr/bin/vmtoolsd\n",
+		`glob-expand "/lib/modules/*/kernel/drivers/block/virtio_blk.ko*"`: "/lib/modules/4.18.0-513.9.1.el8_9.x86_64/kernel/drivers/block/virtio_blk.ko.xz\n",
+		"glob cat /lib/modules/*/modules.builtin":                          "kernel/drivers/virtio/virtio.ko\nkernel/drivers/net/virtio_net.ko\n",
+	})
+
+	inspection, err := Inspect(guest, true)
+	require.NoError(t, err)
+	assert.False(t, inspection.InspectionTime.IsZero())
+	inspection.InspectionTime = metav1.Time{}
+	assert.Equal(t, &vjailbreakv1alpha1.GuestInspection{
+		OSType:     "linux",
+		Distro:     "rhel",
+		OSName:     "Red Hat Enterprise Linux 8.9 (Ootpa)",
+		OSVersion:  "8.9",
+		Kernels:    []string{"4.18.0-513.5.1.el8_9.x86_64", "4.18.0-513.9.1.el8_9.x86_64"},
+		Firmware:   vjailbreakv1alpha1.GuestFirmwareUEFI,
+		Bootloader: "GRUB2",
+		LVM: &vjailbreakv1alpha1.GuestLVM{
+			PhysicalVolumes: []string{"/dev/sda2"},
+			VolumeGroups:    []string{"rhel"},
+			LogicalVolumes:  []string{"/dev/rhel/root", "/dev/rhel/swap"},
+		},
+		Filesystems: []vjailbreakv1alpha1.GuestFilesystem{
+			{Device: "/dev/mapper/rhel-root", MountPoint: "/", SizeBytes: 17811456 * 1024, UsedBytes: 4123456 * 1024},
+			{Device: "/dev/sda1", MountPoint: "/boot", SizeBytes: 1038336 * 1024, UsedBytes: 262144 * 1024},
+		},
+		NetworkConfigFiles: []string{
+			"/etc/sysconfig/network-scripts/ifcfg-ens192",
+			"/etc/NetworkManager/system-connections/ens224.nmconnection",
+		},
+		VMwareTools:   vjailbreakv1alpha1.GuestToolsOpenVMTools,
+		VirtioDrivers: []string{"virtio_blk", "virtio_net"},
+	}, inspection)
+}
+
+func TestInspectWindows(t *testing.T) {
+	guest := fakeGuest(map[string]string{
+		"inspect-get-roots":                   "/dev/sda2\n",
+		"inspect-get-type /dev/sda2":          "windows\n",
+		"inspect-get-distro /dev/sda2":        "windows\n",
+		"inspect-get-product-name /dev/sda2":  "Windows Server 2019 Standard\n",
+		"inspect-get-major-version /dev/sda2": "10\n",
+		"inspect-get-minor-version /dev/sda2": "0\n",
+		"df": "Filesystem     1K-blocks     Used Available Use% Mounted on\n" +
+			"/dev/sda2       41419772 18316420  23103352  45% /sysroot\n",
+		`glob-expand "/Program Files/VMware/VMware Tools/vmtoolsd.exe"`: "/Program Files/VMware/VMware Tools/vmtoolsd.exe\n",
+	})
+
+	inspection, err := Inspect(guest, false)
+	require.NoError(t, err)
+	assert.Equal(t, "Windows Server 2019 Standard", inspection.OSName)
+	assert.Equal(t, "10.0", inspection.OSVersion)
+	assert.Equal(t, vjailbreakv1alpha1.GuestFirmwareBIOS, inspection.Firmware)
+	assert.Equal(t, "Windows Boot Manager", inspection.Bootloader)
+	assert.Nil(t, inspection.LVM)
+	assert.Empty(t, inspection.Kernels)
+	assert.Empty(t, inspection.NetworkConfigFiles)
+	assert.Equal(t, []vjailbreakv1alpha1.GuestFilesystem{
+		{Device: "/dev/sda2", MountPoint: "/", SizeBytes: 41419772 * 1024, UsedBytes: 18316420 * 1024},
+	}, inspection.Filesystems)
+	assert.Equal(t, vjailbreakv1alpha1.GuestToolsVMwareTools, inspection.VMwareTools)
+	assert.Empty(t, inspection.VirtioDrivers)
+}
+
+func TestInspectWithoutOS(t *testing.T) {
+	_, err := Inspect(fakeGuest(map[string]string{}), false)
+	assert.Error(t, err)
+}
@@ -4,16 +4,25 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
+	// Embed the time zone database, the maintenance windows are in IANA time zones
+	_ "time/tzdata"
 
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/osmatrix"
 	"github.com/platform9/vjailbreak/v2v-helper/migrate"
 	"github.com/platform9/vjailbreak/v2v-helper/nbd"
 	"github.com/platform9/vjailbreak/v2v-helper/openstack"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/metrics"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/throttle"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/rbd"
 	"github.com/platform9/vjailbreak/v2v-helper/reporter"
 	"github.com/platform9/vjailbreak/v2v-helper/vcenter"
 	"github.com/platform9/vjailbreak/v2v-helper/vm"
@@ -43,6 +52,7 @@ func main() {
 
 	// Helper function to report and handle errors
 	handleError := func(msg string) {
+		eventReporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseFailed, msg)
 		if reporter.IsRunningInPod() {
 			eventReporterChan <- msg
 			// Wait for the reporter to process the message
@@ -61,6 +71,22 @@ func main() {
 		handleError(fmt.Sprintf("Failed to get migration parameters: %v", err))
 	}
 
+	planName, err := utils.GetMigrationPlanName(ctx, client)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to get migration plan name, metrics will not be labelled with it: %v", err))
+	}
+	migrationMetrics := metrics.NewMetrics(migrationparams.SourceVMName, planName)
+	metricsPort, err := migrationMetrics.Serve(ctx, constants.MetricsPort)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to serve metrics: %v", err))
+	} else {
+		utils.PrintLog(fmt.Sprintf("Serving metrics on port %d", metricsPort))
+		eventReporter.SetMetricsPort(metricsPort)
+	}
+
+	copyThrottle := throttle.New()
+	go copyThrottle.Run(ctx, constants.BandwidthLimitRefreshInterval, throttle.NewLimitsFunc(client, planName))
+
 	utils.WriteToLogFile(fmt.Sprintf("-----	 Migration started at %s for VM %s -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName))
 
 	var (
@@ -79,6 +105,8 @@ func main() {
 	starttime, _ := time.Parse(time.RFC3339, migrationparams.DataCopyStart)
 	cutstart, _ := time.Parse(time.RFC3339, migrationparams.VMcutoverStart)
 	cutend, _ := time.Parse(time.RFC3339, migrationparams.VMcutoverEnd)
+	// An empty interval falls back to the default interval between warm syncs
+	warmSyncInterval, _ := time.ParseDuration(migrationparams.WarmSyncInterval)
 
 	// Validate vCenter connection
 	vcclient, err := vcenter.VCenterClientBuilder(ctx, vCenterUserName, vCenterPassword, vCenterURL, vCenterInsecure)
@@ -102,6 +130,22 @@ func main() {
 	}
 	utils.PrintLog(fmt.Sprintf("VCenter Thumbprint: %s\n", thumbprint))
 
+	osMatrix, err := osmatrix.Load(ctx, client, constants.NamespaceMigrationSystem)
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to load supported OS matrix: %v", err))
+	}
+
+	diskTarget, err := openstack.NewDiskTarget(migrationparams.TargetBackend, openstackclients, filepath.Join(constants.StagingDir, migrationparams.SourceVMName))
+	if err != nil {
+		handleError(fmt.Sprintf("Failed to set up disk target: %v", err))
+	}
+
+	// The disks are copied straight to the RBD images of the volumes when the template configures Ceph RBD
+	var rbdMapper rbd.Mapper
+	if migrationparams.RBDDirectWrite {
+		rbdMapper = &rbd.NBDMapper{Keyring: rbd.KeyringPath}
+	}
+
 	// Retrieve the source VM
 	vmops, err := vm.VMOpsBuilder(ctx, *vcclient, migrationparams.SourceVMName, client)
 	if err != nil {
@@ -144,23 +188,48 @@ func main() {
 		UseFlavorless:          os.Getenv("USE_FLAVORLESS") == "true",
 		TenantName:             openstackProjectName,
 		Reporter:               eventReporter,
+		Metrics:                migrationMetrics,
+		Throttle:               copyThrottle,
 		FallbackToDHCP:         migrationparams.FallbackToDHCP,
+		DataVerification:       vjailbreakv1alpha1.DataVerificationMode(migrationparams.DataVerification),
+		RollbackPolicy:         vjailbreakv1alpha1.RollbackPolicy(migrationparams.RollbackPolicy),
+		RemoveVMwareTools:      migrationparams.RemoveVMwareTools,
+		OSMatrix:               osMatrix,
+		AllowUnsupportedOS:     migrationparams.AllowUnsupportedOS,
+		InspectOnly:            migrationparams.InspectOnly,
+		DiskTarget:             diskTarget,
+		RBDMapper:              rbdMapper,
+		ZeroedVolumeTypes:      utils.RemoveEmptyStrings(strings.Split(migrationparams.ZeroedVolumeTypes, ",")),
+		WarmSyncInterval:       warmSyncInterval,
+		MaintenanceWindow:      migrationparams.MaintenanceWindow,
+		PlanNamespace:          migrationparams.PlanNamespace,
 	}
 
 	if err := migrationobj.MigrateVM(ctx); err != nil {
+		// The source VM is already restored and the phase reported, but the pod must not succeed
+		if errors.Is(err, migrate.ErrRolledBack) {
+			utils.PrintLog(fmt.Sprintf("----- Migration rolled back at %s for VM %s: %v -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName, err))
+			cancel()
+			os.Exit(1)
+		}
+
 		msg := fmt.Sprintf("Failed to migrate VM: %v", err)
 
-		// Try to power on the VM if migration failed
-		powerOnErr := vmops.VMPowerOn()
-		if powerOnErr != nil {
-			msg += fmt.Sprintf("\nAlso Failed to power on VM after migration failure: %v", powerOnErr)
-		} else {
-			msg += fmt.Sprintf("\nVM %s was powered on after migration failure", migrationparams.SourceVMName)
+		// Try to power on the VM if migration failed, an inspection leaves it running
+		if !migrationparams.InspectOnly {
+			powerOnErr := vmops.VMPowerOn()
+			if powerOnErr != nil {
+				msg += fmt.Sprintf("\nAlso Failed to power on VM after migration failure: %v", powerOnErr)
+			} else {
+				msg += fmt.Sprintf("\nVM %s was powered on after migration failure", migrationparams.SourceVMName)
+			}
 		}
 
 		handleError(msg)
 		utils.PrintLog(fmt.Sprintf("----- Migration completed with errors at %s for VM %s -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName))
-		return
+		// The Failed phase is in the progress record, exiting with an error also fails the job
+		cancel()
+		os.Exit(1)
 	}
 
 	utils.PrintLog(fmt.Sprintf("----- Migration completed successfully at %s for VM %s -----", time.Now().Format(time.RFC3339), migrationparams.SourceVMName))
@@ -0,0 +1,134 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// CreateOrAdoptVolumes re-adopts the volumes of the disks whose full copy completed before the
+// previous v2v-helper pod went away, and creates new volumes for the rest of the disks
+func (migobj *Migrate) CreateOrAdoptVolumes(ctx context.Context, vminfo vm.VMInfo) (vm.VMInfo, error) {
+	openstackops := migobj.Openstackclients
+
+	previous, err := utils.GetMigrationCheckpoint(ctx, migobj.K8sClient)
+	if err != nil {
+		return vminfo, errors.Wrap(err, "failed to get migration checkpoint")
+	}
+	if previous != nil && previous.VMName != vminfo.Name {
+		utils.PrintLog(fmt.Sprintf("Ignoring checkpoint saved for VM %s", previous.VMName))
+		previous = nil
+	}
+
+	checkpoint := &utils.MigrationCheckpoint{VMName: vminfo.Name}
+	migobj.logMessage("Creating volumes in OpenStack")
+	for idx, vmdisk := range vminfo.VMDisks {
+		saved := previous.GetDisk(vmdisk.Name)
+		if saved != nil && saved.VolumeID != "" {
+			volume, err := openstackops.GetVolume(saved.VolumeID)
+			switch {
+			case err != nil:
+				utils.PrintLog(fmt.Sprintf("Checkpointed volume %s of disk %s is not usable: %v", saved.VolumeID, vmdisk.Name, err))
+			case saved.Phase == constants.CheckpointPhaseCopied && int64(volume.Size)*1024*1024*1024 >= vmdisk.Size:
+				vminfo.VMDisks[idx].OpenstackVol = volume
+				checkpoint.Disks = append(checkpoint.Disks, *saved)
+				migobj.logMessage(fmt.Sprintf("Resuming disk %s with existing volume %s", vmdisk.Name, volume.ID))
+				continue
+			default:
+				// The full copy relies on the target being zeroed, so a partially copied volume
+				// cannot be reused and is replaced with a new one
+				migobj.logMessage(fmt.Sprintf("Replacing partially copied volume %s of disk %s", volume.ID, vmdisk.Name))
+				vminfo.VMDisks[idx].OpenstackVol = volume
+				if err := migobj.DetachVolume(vminfo.VMDisks[idx]); err != nil {
+					return vminfo, errors.Wrap(err, "failed to detach partially copied volume")
+				}
+				if err := openstackops.DeleteVolume(volume.ID); err != nil {
+					return vminfo, errors.Wrap(err, "failed to delete partially copied volume")
+				}
+			}
+		}
+
+		if err := migobj.createVolume(&vminfo, idx); err != nil {
+			return vminfo, err
+		}
+		checkpoint.Disks = append(checkpoint.Disks, utils.DiskCheckpoint{
+			Name:     vmdisk.Name,
+			VolumeID: vminfo.VMDisks[idx].OpenstackVol.ID,
+			Phase:    constants.CheckpointPhaseVolumeCreated,
+		})
+	}
+	migobj.logMessage("Volumes created successfully")
+
+	migobj.checkpointLock.Lock()
+	defer migobj.checkpointLock.Unlock()
+	migobj.checkpoint = checkpoint
+	migobj.saveCheckpoint(ctx)
+	return vminfo, nil
+}
+
+// updateDiskCheckpoint records that the volume of the disk is in sync with the source up to its current ChangeID
+func (migobj *Migrate) updateDiskCheckpoint(ctx context.Context, disk vm.VMDisk, iteration int) {
+	migobj.checkpointLock.Lock()
+	defer migobj.checkpointLock.Unlock()
+	saved := migobj.checkpoint.GetDisk(disk.Name)
+	if saved == nil {
+		return
+	}
+	saved.ChangeID = disk.ChangeID
+	saved.Iteration = iteration
+	saved.Phase = constants.CheckpointPhaseCopied
+	migobj.saveCheckpoint(ctx)
+}
+
+// restoreCheckpoint points the disks that were already copied back at their checkpointed ChangeID
+// and returns which disks can skip the full copy, along with the iteration to continue from
+func (migobj *Migrate) restoreCheckpoint(vminfo *vm.VMInfo) ([]bool, int) {
+	resumed := make([]bool, len(vminfo.VMDisks))
+	if !migobj.checkpoint.IsResumable() {
+		return resumed, 0
+	}
+	allResumed := true
+	iteration := 0
+	for idx := range vminfo.VMDisks {
+		saved := migobj.checkpoint.GetDisk(vminfo.VMDisks[idx].Name)
+		if saved == nil || saved.Phase != constants.CheckpointPhaseCopied || saved.ChangeID == "" {
+			allResumed = false
+			continue
+		}
+		vminfo.VMDisks[idx].ChangeID = saved.ChangeID
+		resumed[idx] = true
+		iteration = max(iteration, saved.Iteration)
+	}
+	// Waiting for the cutover happens after the full copy, so only skip past it when every disk is copied
+	if !allResumed || iteration == 0 {
+		return resumed, 0
+	}
+	return resumed, iteration + 1
+}
+
+// saveCheckpoint persists the checkpoint, the caller must hold checkpointLock
+func (migobj *Migrate) saveCheckpoint(ctx context.Context) {
+	if migobj.checkpoint == nil || migobj.K8sClient == nil {
+		return
+	}
+	if err := utils.SaveMigrationCheckpoint(ctx, migobj.K8sClient, migobj.checkpoint); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to save migration checkpoint: %v", err))
+	}
+}
+
+// clearCheckpoint drops the checkpoint once the volumes no longer match the source disks, e.g. when conversion starts
+func (migobj *Migrate) clearCheckpoint(ctx context.Context) {
+	migobj.checkpoint = nil
+	if migobj.K8sClient == nil {
+		return
+	}
+	if err := utils.DeleteMigrationCheckpoint(ctx, migobj.K8sClient); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to delete migration checkpoint: %v", err))
+	}
+}
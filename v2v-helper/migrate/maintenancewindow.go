@@ -0,0 +1,102 @@
+// Copyright © 2024 The vjailbreak authors
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/maintenancewindow"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// getMaintenanceWindow returns the windows the cutover has to start in, nil when the migration has no
+// MaintenanceWindow. It is read before each cutover decision, so that changed windows and blackout dates
+// apply to running migrations
+func (migobj *Migrate) getMaintenanceWindow(ctx context.Context) (*maintenancewindow.Calendar, error) {
+	if migobj.MaintenanceWindow == "" {
+		return nil, nil
+	}
+	// The MaintenanceWindow is in the namespace of the migration plan, ConfigMaps of older controllers do not record it
+	namespace := migobj.PlanNamespace
+	if namespace == "" {
+		namespace = constants.NamespaceMigrationSystem
+	}
+	window := &vjailbreakv1alpha1.MaintenanceWindow{}
+	if err := migobj.K8sClient.Get(ctx, types.NamespacedName{
+		Name:      migobj.MaintenanceWindow,
+		Namespace: namespace,
+	}, window); err != nil {
+		return nil, errors.Wrapf(err, "failed to get maintenance window '%s'", migobj.MaintenanceWindow)
+	}
+	calendar, err := maintenancewindow.Parse(&window.Spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid maintenance window '%s'", migobj.MaintenanceWindow)
+	}
+	return calendar, nil
+}
+
+// nextCutoverStart returns when the cutover may start: now, or the start of the next window of the maintenance window
+// with room left for the estimated downtime. It fails once the VM cutover end time has passed, or when no window
+// opens before it
+func (migobj *Migrate) nextCutoverStart(ctx context.Context, now time.Time) (time.Time, error) {
+	cutoverEnd := migobj.MigrationTimes.VMCutoverEnd
+	if !cutoverEnd.IsZero() && cutoverEnd.Before(now) {
+		return time.Time{}, errors.New("VM Cutover End time has already passed")
+	}
+	calendar, err := migobj.getMaintenanceWindow(ctx)
+	if err != nil || calendar == nil {
+		return now, err
+	}
+	downtime := migobj.Reporter.EstimatedDowntime()
+	window, ok := calendar.Next(now, downtime)
+	if !ok {
+		return time.Time{}, fmt.Errorf("no window of maintenance window '%s' fits the estimated downtime of %s within a year",
+			migobj.MaintenanceWindow, downtime)
+	}
+	if !cutoverEnd.IsZero() && window.Start.After(cutoverEnd) {
+		return time.Time{}, fmt.Errorf("the next window of maintenance window '%s' opens at %s, after the VM Cutover End time",
+			migobj.MaintenanceWindow, window.Start.Format(time.RFC3339))
+	}
+	return window.Start, nil
+}
+
+// waitForCutoverWindow returns true when the cutover may start now. Otherwise it waits for the next window of the
+// maintenance window and returns false, so that the changes made to the live VM in the meantime are copied before
+// the cutover is decided again
+func (migobj *Migrate) waitForCutoverWindow(ctx context.Context) (bool, error) {
+	now := time.Now()
+	start, err := migobj.nextCutoverStart(ctx, now)
+	if err != nil {
+		return false, err
+	}
+	if !start.After(now) {
+		return true, nil
+	}
+	message := fmt.Sprintf("Waiting for maintenance window '%s' opening at %s", migobj.MaintenanceWindow, start.Format(time.RFC3339))
+	migobj.logMessage(message)
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime, message)
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-timer.C:
+	}
+	migobj.logMessage(fmt.Sprintf("Maintenance window '%s' opened", migobj.MaintenanceWindow))
+	return false, nil
+}
+
+// waitForCutoverWindowOpen waits until the cutover may start, when no changes are copied while waiting
+func (migobj *Migrate) waitForCutoverWindowOpen(ctx context.Context) error {
+	for {
+		open, err := migobj.waitForCutoverWindow(ctx)
+		if err != nil || open {
+			return err
+		}
+	}
+}
@@ -6,32 +6,40 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
-	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"regexp"
-	"strconv"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/osmatrix"
+	"github.com/platform9/vjailbreak/v2v-helper/guestnetwork"
+	"github.com/platform9/vjailbreak/v2v-helper/inspect"
 	"github.com/platform9/vjailbreak/v2v-helper/nbd"
 	"github.com/platform9/vjailbreak/v2v-helper/openstack"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/metrics"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/throttle"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils/vmutils"
+	"github.com/platform9/vjailbreak/v2v-helper/rbd"
 	"github.com/platform9/vjailbreak/v2v-helper/reporter"
 	"github.com/platform9/vjailbreak/v2v-helper/vcenter"
 	"github.com/platform9/vjailbreak/v2v-helper/virtv2v"
 	"github.com/platform9/vjailbreak/v2v-helper/vm"
+	"github.com/vmware/govmomi/vim25/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	probing "github.com/prometheus-community/pro-bing"
-	"github.com/vmware/govmomi/vim25/types"
 )
 
 type Migrate struct {
@@ -67,7 +75,47 @@ type Migrate struct {
 	UseFlavorless           bool
 	TenantName              string
 	Reporter                *reporter.Reporter
+	Metrics                 *metrics.Metrics
+	Throttle                *throttle.Throttle
 	FallbackToDHCP          bool
+	DataVerification        vjailbreakv1alpha1.DataVerificationMode
+	RollbackPolicy          vjailbreakv1alpha1.RollbackPolicy
+	RemoveVMwareTools       bool
+	OSMatrix                *osmatrix.Matrix
+	AllowUnsupportedOS      bool
+	InspectOnly             bool
+	// DiskTarget is where the disks are copied and converted, the attached volumes when not set
+	DiskTarget openstack.DiskTarget
+	// RBDMapper maps the RBD images of the volumes, so that the disks are copied straight to them instead
+	// of to the attached volumes. Nil attaches the volumes for the copy
+	RBDMapper rbd.Mapper
+	// ZeroedVolumeTypes are the volume types whose new volumes read back as zeroes, the full copy skips the
+	// unallocated areas of the disks on them instead of zero filling them
+	ZeroedVolumeTypes []string
+	// WarmSyncInterval is the interval between the incremental syncs of a warm migration,
+	// constants.WarmSyncInterval when not set
+	WarmSyncInterval time.Duration
+	// MaintenanceWindow is the name of the MaintenanceWindow the cutover has to start in, the cutover starts
+	// at any time when empty
+	MaintenanceWindow string
+	// PlanNamespace is the namespace of the migration plan, the MaintenanceWindow is read from it.
+	// constants.NamespaceMigrationSystem when not set
+	PlanNamespace string
+
+	// targetServerID is the ID of the OpenStack server created for the VM
+	targetServerID string
+	// windowsFirstBootInjected is set once the network script is injected into a Windows guest
+	windowsFirstBootInjected bool
+	// sourceNetworkDisconnected is set once the network interfaces of the source VM are disconnected
+	sourceNetworkDisconnected bool
+	// checkpoint is the persisted copy state used to resume the migration after a pod restart
+	checkpoint *utils.MigrationCheckpoint
+	// checkpointLock serializes checkpoint updates from disks copied in parallel
+	checkpointLock sync.Mutex
+	// volumesAttached is set once the volumes are attached to the agent
+	volumesAttached bool
+	// adminCutoverTriggered is set once the admin triggered the cutover of a warm migration
+	adminCutoverTriggered bool
 }
 
 type MigrationTimes struct {
@@ -90,6 +138,7 @@ func (migobj *Migrate) DisconnectSourceNetworkIfRequested() error {
 		return fmt.Errorf("failed to disconnect network interfaces: %w", err)
 	}
 
+	migobj.sourceNetworkDisconnected = true
 	migobj.logMessage("Successfully disconnected source VM network interfaces")
 	return nil
 }
@@ -101,49 +150,129 @@ func (migobj *Migrate) logMessage(message string) {
 	utils.PrintLog(message)
 }
 
+// diskProgress returns the callback that records the bytes copied of a disk out of total in the progress record
+// and in the copied bytes metric
+func (migobj *Migrate) diskProgress(idx int, disk vm.VMDisk, total int64) nbd.ProgressFunc {
+	var reported int64
+	return func(fraction float64) {
+		copied := int64(fraction * float64(total))
+		migobj.Reporter.SetDiskProgress(idx, disk.Name, copied, total)
+		migobj.Metrics.AddBytesCopied(disk.Name, copied-reported)
+		reported = max(reported, copied)
+	}
+}
+
 // This function creates volumes in OpenStack and attaches them to the helper vm
 func (migobj *Migrate) CreateVolumes(vminfo vm.VMInfo) (vm.VMInfo, error) {
-	openstackops := migobj.Openstackclients
 	migobj.logMessage("Creating volumes in OpenStack")
 
-	for idx, vmdisk := range vminfo.VMDisks {
-		setRDMLabel := false
-		if len(vminfo.RDMDisks) > 0 {
-			setRDMLabel = true
-		}
-		volume, err := openstackops.CreateVolume(vminfo.Name+"-"+vmdisk.Name, vmdisk.Size, vminfo.OSType, vminfo.UEFI, migobj.Volumetypes[idx], setRDMLabel)
-		if err != nil {
-			return vminfo, errors.Wrap(err, "failed to create volume")
-		}
-		vminfo.VMDisks[idx].OpenstackVol = volume
-		if vminfo.VMDisks[idx].Boot {
-			err = openstackops.SetVolumeBootable(volume)
-			if err != nil {
-				return vminfo, errors.Wrap(err, "failed to set volume as bootable")
-			}
+	for idx := range vminfo.VMDisks {
+		if err := migobj.createVolume(&vminfo, idx); err != nil {
+			return vminfo, err
 		}
 	}
 	migobj.logMessage("Volumes created successfully")
 	return vminfo, nil
 }
 
-func (migobj *Migrate) AttachVolume(disk vm.VMDisk) (string, error) {
+func (migobj *Migrate) createVolume(vminfo *vm.VMInfo, idx int) error {
 	openstackops := migobj.Openstackclients
-	migobj.logMessage(fmt.Sprintf("Attaching volumes to VM: %s", disk.Name))
+	vmdisk := vminfo.VMDisks[idx]
+	setRDMLabel := false
+	if len(vminfo.RDMDisks) > 0 {
+		setRDMLabel = true
+	}
+	volume, err := openstackops.CreateVolume(vminfo.Name+"-"+vmdisk.Name, vmdisk.Size, vminfo.OSType, vminfo.UEFI, migobj.Volumetypes[idx], setRDMLabel)
+	if err != nil {
+		return errors.Wrap(err, "failed to create volume")
+	}
+	vminfo.VMDisks[idx].OpenstackVol = volume
+	if vmdisk.Boot {
+		err = openstackops.SetVolumeBootable(volume)
+		if err != nil {
+			return errors.Wrap(err, "failed to set volume as bootable")
+		}
+	}
+	return nil
+}
+
+// target returns the disk target of the migration
+func (migobj *Migrate) target() openstack.DiskTarget {
+	if migobj.DiskTarget == nil {
+		migobj.DiskTarget = &openstack.VolumeTarget{Ops: migobj.Openstackclients}
+	}
+	return migobj.DiskTarget
+}
+
+// stagesDisks tells whether the disks are staged on the agent instead of written to attached volumes
+func (migobj *Migrate) stagesDisks() bool {
+	_, attached := migobj.target().(*openstack.VolumeTarget)
+	return !attached
+}
+
+// writesToRBD tells whether the disks are copied straight to the RBD images of their volumes
+func (migobj *Migrate) writesToRBD() bool {
+	return migobj.RBDMapper != nil && !migobj.stagesDisks()
+}
+
+// copyTarget returns the opener of the destinations of the copies, nil for the attached volumes
+func (migobj *Migrate) copyTarget() nbd.TargetOpener {
+	if !migobj.writesToRBD() {
+		return nil
+	}
+	return &rbd.Opener{Mapper: migobj.RBDMapper}
+}
+
+// copyPath returns the path the disk is copied to, which is the RBD image of its volume when writing to
+// RBD directly, and the attached volume otherwise
+func (migobj *Migrate) copyPath(disk vm.VMDisk) (string, error) {
+	if !migobj.writesToRBD() {
+		return migobj.AttachVolume(disk)
+	}
 	if disk.OpenstackVol == nil {
-		return "", errors.Wrap(fmt.Errorf("OpenStack volume is nil"), "failed to attach volume to VM")
+		return "", errors.Wrap(fmt.Errorf("OpenStack volume is nil"), "failed to get RBD image of volume")
+	}
+	info, err := migobj.Openstackclients.GetVolumeConnection(disk.OpenstackVol.ID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get connection info of volume")
+	}
+	image, err := rbd.FromConnectionInfo(info)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get RBD image of volume")
+	}
+	migobj.logMessage(fmt.Sprintf("Copying disk %s straight to RBD image %s", disk.Name, image.Spec()))
+	return image.Path(), nil
+}
+
+// fullCopy returns the allocated areas of the disk at idx and whether its target reads back as zeroes.
+// When the allocated areas cannot be queried, the whole disk is copied
+func (migobj *Migrate) fullCopy(vmops vm.VMOperations, idx int, disk vm.VMDisk) nbd.FullCopy {
+	full := nbd.FullCopy{
+		TargetIsZero: migobj.stagesDisks() || (idx < len(migobj.Volumetypes) && slices.Contains(migobj.ZeroedVolumeTypes, migobj.Volumetypes[idx])),
+	}
+	snapshot, err := vmops.GetSnapshot(constants.MigrationSnapshotName)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Disk %d: failed to get snapshot, copying the whole disk: %v", idx, err))
+		return full
 	}
-	volumeID := disk.OpenstackVol.ID
-	if err := openstackops.AttachVolumeToVM(volumeID); err != nil {
-		return "", errors.Wrap(err, "failed to attach volume to VM")
+	// ChangeID "*" returns the allocated areas of the disk
+	areas, err := vmops.CustomQueryChangedDiskAreas("*", snapshot, disk.Disk, 0)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Disk %d: failed to get allocated disk areas, copying the whole disk: %v", idx, err))
+		return full
 	}
+	full.Allocated = append([]types.DiskChangeExtent{}, areas.ChangedArea...)
+	return full
+}
 
-	// Get the Path of the attached volume
-	devicePath, err := openstackops.FindDevice(volumeID)
+func (migobj *Migrate) AttachVolume(disk vm.VMDisk) (string, error) {
+	migobj.logMessage(fmt.Sprintf("Attaching volumes to VM: %s", disk.Name))
+	path, err := migobj.target().Open(disk)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to find device")
+		return "", err
 	}
-	return devicePath, nil
+	migobj.volumesAttached = true
+	return path, nil
 }
 
 func (migobj *Migrate) DetachVolume(disk vm.VMDisk) error {
@@ -161,16 +290,17 @@ func (migobj *Migrate) DetachVolume(disk vm.VMDisk) error {
 }
 
 func (migobj *Migrate) DetachAllVolumes(vminfo vm.VMInfo) error {
-	openstackops := migobj.Openstackclients
+	// Volumes written to RBD directly are only attached for the conversion
+	if migobj.stagesDisks() || (migobj.writesToRBD() && !migobj.volumesAttached) {
+		return nil
+	}
 	for _, vmdisk := range vminfo.VMDisks {
-		migobj.logMessage(fmt.Sprintf("Detaching volume %s from VM", vmdisk.Name))
-		if err := openstackops.DetachVolumeFromVM(vmdisk.OpenstackVol.ID); err != nil && !strings.Contains(err.Error(), "is not attached to volume") {
-			return errors.Wrap(err, "failed to detach volume from VM")
+		if vmdisk.OpenstackVol == nil {
+			continue
 		}
-
-		err := openstackops.WaitForVolume(vmdisk.OpenstackVol.ID)
-		if err != nil {
-			return errors.Wrap(err, "failed to wait for volume to become available")
+		migobj.logMessage(fmt.Sprintf("Detaching volume %s from VM", vmdisk.Name))
+		if err := migobj.target().Close(vmdisk); err != nil {
+			return err
 		}
 		migobj.logMessage(fmt.Sprintf("Volume %s detached from VM", vmdisk.Name))
 	}
@@ -179,17 +309,36 @@ func (migobj *Migrate) DetachAllVolumes(vminfo vm.VMInfo) error {
 }
 
 func (migobj *Migrate) DeleteAllVolumes(vminfo vm.VMInfo) error {
-	openstackops := migobj.Openstackclients
 	for _, vmdisk := range vminfo.VMDisks {
-		err := openstackops.DeleteVolume(vmdisk.OpenstackVol.ID)
-		if err != nil {
-			return errors.Wrap(err, "failed to delete volume")
+		if vmdisk.OpenstackVol == nil && !migobj.stagesDisks() {
+			continue
+		}
+		if err := migobj.target().Discard(vmdisk); err != nil {
+			return err
 		}
 		migobj.logMessage(fmt.Sprintf("Volume %s deleted", vmdisk.Name))
 	}
 	return nil
 }
 
+// CommitVolumes turns the converted disks into the volumes of the target instance. The attached volumes
+// are used as they are, the staged disks are uploaded and their volumes created from the images
+func (migobj *Migrate) CommitVolumes(vminfo vm.VMInfo) error {
+	if !migobj.stagesDisks() {
+		return nil
+	}
+	for idx, vmdisk := range vminfo.VMDisks {
+		migobj.logMessage(fmt.Sprintf("Uploading disk %s and creating its volume", vmdisk.Name))
+		volume, err := migobj.target().Commit(vminfo, vmdisk, migobj.Volumetypes[idx])
+		if err != nil {
+			return errors.Wrapf(err, "failed to create volume of disk %s", vmdisk.Name)
+		}
+		vminfo.VMDisks[idx].OpenstackVol = volume
+		migobj.logMessage(fmt.Sprintf("Volume %s created for disk %s", volume.ID, vmdisk.Name))
+	}
+	return nil
+}
+
 // This function enables CBT on the VM if it is not enabled and takes a snapshot for initializing CBT
 func (migobj *Migrate) EnableCBTWrapper() error {
 	vmops := migobj.VMops
@@ -230,6 +379,7 @@ func (migobj *Migrate) WaitforCutover() error {
 	var zerotime time.Time
 	if !migobj.MigrationTimes.VMCutoverStart.Equal(zerotime) && migobj.MigrationTimes.VMCutoverStart.After(time.Now()) {
 		migobj.logMessage("Waiting for VM Cutover start time")
+		migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime, "Waiting for VM Cutover start time")
 		time.Sleep(time.Until(migobj.MigrationTimes.VMCutoverStart))
 		migobj.logMessage("VM Cutover start time reached")
 	} else {
@@ -242,6 +392,7 @@ func (migobj *Migrate) WaitforCutover() error {
 
 func (migobj *Migrate) WaitforAdminCutover() error {
 	migobj.logMessage("Waiting for Admin Cutover conditions to be met")
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver, "Waiting for Admin Cutover conditions to be met")
 	for {
 		label := <-migobj.PodLabelWatcher
 		migobj.logMessage(fmt.Sprintf("Label: %s", label))
@@ -253,7 +404,80 @@ func (migobj *Migrate) WaitforAdminCutover() error {
 	return nil
 }
 
+// waitForNextWarmSync waits for the next incremental sync of a warm migration, due WarmSyncInterval after the
+// previous one started. It returns true instead once the cutover is due: the VM cutover start time has been
+// reached, when the cutover is admin initiated the admin has triggered it, and a maintenance window with room
+// for the estimated downtime is open. The syncs go on while the cutover waits for the window
+func (migobj *Migrate) waitForNextWarmSync(ctx context.Context, lastSync time.Time, adminCutover bool) (bool, error) {
+	interval := migobj.WarmSyncInterval
+	if interval <= 0 {
+		interval = constants.WarmSyncInterval
+	}
+	nextSync := lastSync.Add(interval)
+	cutoverStart := migobj.MigrationTimes.VMCutoverStart
+	phase := vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks
+	if adminCutover {
+		phase = vjailbreakv1alpha1.VMMigrationPhaseAwaitingAdminCutOver
+	} else if !cutoverStart.IsZero() {
+		phase = vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime
+	}
+
+	reported := false
+	for {
+		now := time.Now()
+		var windowStart time.Time
+		if (!adminCutover || migobj.adminCutoverTriggered) && !now.Before(cutoverStart) {
+			start, err := migobj.nextCutoverStart(ctx, now)
+			if err != nil {
+				return false, err
+			}
+			if !start.After(now) {
+				return true, nil
+			}
+			windowStart = start
+		}
+		if !now.Before(nextSync) {
+			return false, nil
+		}
+		if !reported {
+			message := fmt.Sprintf("Next warm sync at %s", nextSync.Format(time.RFC3339))
+			if !windowStart.IsZero() {
+				phase = vjailbreakv1alpha1.VMMigrationPhaseAwaitingCutOverStartTime
+				message += fmt.Sprintf(", the cutover waits for maintenance window '%s' opening at %s",
+					migobj.MaintenanceWindow, windowStart.Format(time.RFC3339))
+			}
+			migobj.logMessage(message)
+			migobj.Reporter.SetPhase(phase, message)
+			reported = true
+		}
+
+		wake := nextSync
+		if cutoverStart.After(now) && cutoverStart.Before(wake) {
+			wake = cutoverStart
+		}
+		if !windowStart.IsZero() && windowStart.Before(wake) {
+			wake = windowStart
+		}
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false, ctx.Err()
+		case label := <-migobj.PodLabelWatcher:
+			timer.Stop()
+			if label == "yes" && !migobj.adminCutoverTriggered {
+				migobj.logMessage("Cutover conditions met")
+				migobj.adminCutoverTriggered = true
+			}
+		case <-timer.C:
+		}
+	}
+}
+
 func (migobj *Migrate) CheckIfAdminCutoverSelected() bool {
+	if migobj.Reporter == nil {
+		return false
+	}
 	value, err := migobj.Reporter.GetCutoverLabel()
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf("Failed to get pod labels: %v", err))
@@ -266,6 +490,39 @@ func (migobj *Migrate) CheckIfAdminCutoverSelected() bool {
 	return false
 }
 
+// copyDisksInParallel runs copyFn for every disk, with at most limit disks being copied at the same time.
+// A failing disk does not stop the others, so every disk that can be copied still reaches its checkpoint.
+func copyDisksInParallel(limit, numDisks int, copyFn func(idx int) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, limit)
+	diskErrors := make([]error, numDisks)
+
+	for idx := 0; idx < numDisks; idx++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			diskErrors[idx] = copyFn(idx)
+		}(idx)
+	}
+	wg.Wait()
+
+	var failed []string
+	for idx, err := range diskErrors {
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("disk %d: %v", idx, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.Errorf("%d of %d disks failed: %s", len(failed), numDisks, strings.Join(failed, "; "))
+	}
+	return nil
+}
+
 func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo) (vm.VMInfo, error) {
 	vmops := migobj.VMops
 	nbdops := migobj.Nbdops
@@ -275,6 +532,10 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	thumbprint := migobj.Thumbprint
 
 	if migobj.MigrationType == "cold" && !migobj.CheckIfAdminCutoverSelected() {
+		// The source VM stays down for the whole copy, which has to start in a maintenance window
+		if err := migobj.waitForCutoverWindowOpen(ctx); err != nil {
+			return vminfo, errors.Wrap(err, "failed to start VM Cutover")
+		}
 		if err := vmops.VMPowerOff(); err != nil {
 			return vminfo, errors.Wrap(err, "failed to power off VM")
 		}
@@ -298,6 +559,9 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 		return vminfo, errors.Wrap(err, "failed to update disk info")
 	}
 
+	// Disks copied by a previous attempt continue from their checkpointed ChangeID
+	resumed, incrementalCopyCount := migobj.restoreCheckpoint(&vminfo)
+
 	for idx, vmdisk := range vminfo.VMDisks {
 		migobj.logMessage(fmt.Sprintf("Copying disk %d, Completed: 0%%", idx))
 		err = nbdops[idx].StartNBDServer(vmops.GetVMObj(), envURL, envUserName, envPassword, thumbprint, vmdisk.Snapname, vmdisk.SnapBackingDisk, migobj.EventReporter)
@@ -310,7 +574,7 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	final := false
 
 	for idx, vmdisk := range vminfo.VMDisks {
-		vminfo.VMDisks[idx].Path, err = migobj.AttachVolume(vmdisk)
+		vminfo.VMDisks[idx].Path, err = migobj.copyPath(vmdisk)
 		if err != nil {
 			return vminfo, errors.Wrap(err, "failed to attach volume")
 		}
@@ -321,38 +585,64 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 		return vminfo, errors.Wrap(err, "failed to get vcenter settings")
 	}
 	utils.PrintLog(fmt.Sprintf("Fetched vjailbreak settings for Changed Blocks Copy Iteration Threshold: %d", vcenterSettings.ChangedBlocksCopyIterationThreshold))
+	utils.PrintLog(fmt.Sprintf("Fetched vjailbreak settings for Disk Copy Concurrency Limit: %d", vcenterSettings.DiskCopyConcurrencyLimit))
 
 	// Check if migration has admin cutover if so don't copy any more changed blocks
 	adminInitiatedCutover := migobj.CheckIfAdminCutoverSelected()
+	// A warm migration keeps syncing the changed blocks of the live VM until the cutover is due
+	warm := migobj.MigrationType == "warm"
+
+	// copyFailures counts the consecutive passes in which the changed blocks of each disk failed to copy.
+	// A failed disk keeps its ChangeID, so its changes are copied again by the next pass.
+	copyFailures := make([]int, len(vminfo.VMDisks))
 
-	incrementalCopyCount := 0
 	for {
 		// If its the first copy, copy the entire disk
 		if incrementalCopyCount == 0 {
-			for idx := range vminfo.VMDisks {
+			migobj.Reporter.StartCopyPass(vjailbreakv1alpha1.VMMigrationPhaseCopying, incrementalCopyCount)
+			err = copyDisksInParallel(vcenterSettings.DiskCopyConcurrencyLimit, len(vminfo.VMDisks), func(idx int) error {
+				if resumed[idx] {
+					migobj.logMessage(fmt.Sprintf("Disk %d was copied by a previous attempt, skipping full disk copy", idx))
+					migobj.Reporter.SetDiskProgress(idx, vminfo.VMDisks[idx].Name, vminfo.VMDisks[idx].Size, vminfo.VMDisks[idx].Size)
+					return nil
+				}
 				startTime := time.Now()
 				migobj.logMessage(fmt.Sprintf("Starting full disk copy of disk %d ", idx))
 
-				err = nbdops[idx].CopyDisk(ctx, vminfo.VMDisks[idx].Path, idx)
+				progress := migobj.diskProgress(idx, vminfo.VMDisks[idx], vminfo.VMDisks[idx].Size)
+				full := migobj.fullCopy(vmops, idx, vminfo.VMDisks[idx])
+				skipped, err := nbdops[idx].CopyDisk(ctx, vminfo.VMDisks[idx].Path, idx, full, progress)
 				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to copy disk")
+					return err
 				}
 				duration := time.Since(startTime)
-				migobj.logMessage(fmt.Sprintf("Disk %d (%s) copied successfully in %s, copying changed blocks now", idx, vminfo.VMDisks[idx].Path, duration))
+				migobj.Metrics.ObserveCopy(vminfo.VMDisks[idx].Name, vminfo.VMDisks[idx].Size, duration)
+				migobj.Reporter.AddFullCopyBytesSkipped(skipped)
+				migobj.logMessage(fmt.Sprintf("Disk %d (%s) copied successfully in %s, %d bytes not read from the source, copying changed blocks now", idx, vminfo.VMDisks[idx].Path, duration, skipped))
+				migobj.updateDiskCheckpoint(ctx, vminfo.VMDisks[idx], incrementalCopyCount)
+				return nil
+			})
+			if err != nil {
+				return vminfo, errors.Wrap(err, "failed to copy disk")
 			}
-			if adminInitiatedCutover {
+			if adminInitiatedCutover && !warm {
 				utils.PrintLog("Admin initiated cutover detected, skipping changed blocks copy")
 				if err := migobj.WaitforAdminCutover(); err != nil {
 					return vminfo, errors.Wrap(err, "failed to start VM Cutover")
 				}
+				if err := migobj.waitForCutoverWindowOpen(ctx); err != nil {
+					return vminfo, errors.Wrap(err, "failed to start VM Cutover")
+				}
 				utils.PrintLog("Shutting down source VM and performing final copy")
 				err = vmops.VMPowerOff()
 				if err != nil {
 					return vminfo, errors.Wrap(err, "failed to power off VM")
 				}
 			}
-			if err := migobj.WaitforCutover(); err != nil {
-				return vminfo, errors.Wrap(err, "failed to start VM Cutover")
+			if !warm {
+				if err := migobj.WaitforCutover(); err != nil {
+					return vminfo, errors.Wrap(err, "failed to start VM Cutover")
+				}
 			}
 		} else {
 			migration_snapshot, err := vmops.GetSnapshot(constants.MigrationSnapshotName)
@@ -360,73 +650,155 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 				return vminfo, errors.Wrap(err, "failed to get snapshot")
 			}
 
-			var changedAreas types.DiskChangeInfo
-			done := true
-
-			for idx := range vminfo.VMDisks {
-				changedAreas, err = vmops.CustomQueryChangedDiskAreas(vminfo.VMDisks[idx].ChangeID, migration_snapshot, vminfo.VMDisks[idx].Disk, 0)
+			migobj.Reporter.StartCopyPass(vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks, incrementalCopyCount)
+			// Disks are queried and copied independently, record which of them had changes
+			changed := make([]bool, len(vminfo.VMDisks))
+			passStart := time.Now()
+			var passBytes atomic.Int64
+			// deltas are the changed blocks copied from each disk, nil for the disks that failed to copy
+			deltas := make([]*vjailbreakv1alpha1.DiskDelta, len(vminfo.VMDisks))
+			// UpdateDiskInfo rewrites vminfo.VMDisks and may refresh the VM reference, the disks update it one at a time
+			var diskInfoMu sync.Mutex
+			err = copyDisksInParallel(vcenterSettings.DiskCopyConcurrencyLimit, len(vminfo.VMDisks), func(idx int) error {
+				changedAreas, err := vmops.CustomQueryChangedDiskAreas(vminfo.VMDisks[idx].ChangeID, migration_snapshot, vminfo.VMDisks[idx].Disk, 0)
 				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to get changed disk areas")
+					return errors.Wrap(err, "failed to get changed disk areas")
 				}
 
 				if len(changedAreas.ChangedArea) == 0 {
 					migobj.logMessage(fmt.Sprintf("Disk %d: No changed blocks found. Skipping copy", idx))
-				} else {
-					migobj.logMessage(fmt.Sprintf("Disk %d: Blocks have Changed.", idx))
+					deltas[idx] = &vjailbreakv1alpha1.DiskDelta{Index: idx, Iteration: incrementalCopyCount}
+					return nil
+				}
+				migobj.logMessage(fmt.Sprintf("Disk %d: Blocks have Changed.", idx))
 
-					utils.PrintLog("Restarting NBD server")
-					err = nbdops[idx].StopNBDServer()
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to stop NBD server")
-					}
+				utils.PrintLog(fmt.Sprintf("Restarting NBD server for disk %d", idx))
+				if err := nbdops[idx].StopNBDServer(); err != nil {
+					return errors.Wrap(err, "failed to stop NBD server")
+				}
 
-					err = nbdops[idx].StartNBDServer(vmops.GetVMObj(), envURL, envUserName, envPassword, thumbprint, vminfo.VMDisks[idx].Snapname, vminfo.VMDisks[idx].SnapBackingDisk, migobj.EventReporter)
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to start NBD server")
-					}
-					// sleep for 2 seconds to allow the NBD server to start
-					time.Sleep(2 * time.Second)
+				diskInfoMu.Lock()
+				vmObj := vmops.GetVMObj()
+				diskInfoMu.Unlock()
+				if err := nbdops[idx].StartNBDServer(vmObj, envURL, envUserName, envPassword, thumbprint, vminfo.VMDisks[idx].Snapname, vminfo.VMDisks[idx].SnapBackingDisk, migobj.EventReporter); err != nil {
+					return errors.Wrap(err, "failed to start NBD server")
+				}
+				// sleep for 2 seconds to allow the NBD server to start
+				time.Sleep(2 * time.Second)
 
-					// 11. Copy Changed Blocks over
-					done = false
-					changedBlockCopySuccess := true
-					migobj.logMessage("Copying changed blocks")
+				// 11. Copy Changed Blocks over
+				changed[idx] = true
+				migobj.logMessage(fmt.Sprintf("Copying changed blocks for disk %d", idx))
 
-					// incremental block copy
+				// incremental block copy
 
-					startTime := time.Now()
-					migobj.logMessage(fmt.Sprintf("Starting incremental block copy for disk %d at %s", idx, startTime))
+				startTime := time.Now()
+				migobj.logMessage(fmt.Sprintf("Starting incremental block copy for disk %d at %s", idx, startTime))
 
-					err = nbdops[idx].CopyChangedBlocks(ctx, changedAreas, vminfo.VMDisks[idx].Path)
-					if err != nil {
-						changedBlockCopySuccess = false
-					}
+				var changedBytes int64
+				for _, extent := range changedAreas.ChangedArea {
+					changedBytes += extent.Length
+				}
+				progress := migobj.diskProgress(idx, vminfo.VMDisks[idx], changedBytes)
+				copyErr := nbdops[idx].CopyChangedBlocks(ctx, changedAreas, vminfo.VMDisks[idx].Path, idx, progress)
+				if copyErr != nil && ctx.Err() != nil {
+					return errors.Wrap(copyErr, "failed to copy changed blocks")
+				}
 
-					duration := time.Since(startTime)
+				duration := time.Since(startTime)
 
-					migobj.logMessage(fmt.Sprintf("Incremental block copy for disk %d completed in %s", idx, duration))
+				migobj.logMessage(fmt.Sprintf("Incremental block copy for disk %d completed in %s", idx, duration))
 
-					err = vmops.UpdateDiskInfo(&vminfo, vminfo.VMDisks[idx], changedBlockCopySuccess)
-					if err != nil {
-						return vminfo, errors.Wrap(err, "failed to update disk info")
+				// The ChangeID is only advanced after a complete copy, a partial copy is retried from the same ChangeID
+				diskInfoMu.Lock()
+				err = vmops.UpdateDiskInfo(&vminfo, vminfo.VMDisks[idx], copyErr == nil)
+				diskInfoMu.Unlock()
+				if err != nil {
+					return errors.Wrap(err, "failed to update disk info")
+				}
+				if copyErr != nil {
+					copyFailures[idx]++
+					if copyFailures[idx] >= constants.ChangedBlocksCopyFailureLimit {
+						return errors.Wrapf(copyErr, "changed blocks of disk %d failed to copy %d times in a row", idx, copyFailures[idx])
 					}
-					if !changedBlockCopySuccess {
-						migobj.logMessage(fmt.Sprintf("Failed to copy changed blocks: %s", err))
-						migobj.logMessage(fmt.Sprintf("Since full copy has completed, Retrying copy of changed blocks for disk: %d", idx))
+					migobj.logMessage(fmt.Sprintf("Failed to copy changed blocks: %s", copyErr))
+					migobj.logMessage(fmt.Sprintf("Since full copy has completed, Retrying copy of changed blocks for disk: %d", idx))
+				} else {
+					copyFailures[idx] = 0
+					migobj.Metrics.ObserveCopy(vminfo.VMDisks[idx].Name, changedBytes, duration)
+					migobj.Metrics.AddChangedBlockBytes(incrementalCopyCount, changedBytes)
+					passBytes.Add(changedBytes)
+					deltas[idx] = &vjailbreakv1alpha1.DiskDelta{
+						Index:          idx,
+						Iteration:      incrementalCopyCount,
+						ChangedBytes:   changedBytes,
+						BytesPerSecond: int64(float64(changedBytes) / max(duration.Seconds(), 0.001)),
 					}
-					migobj.logMessage(fmt.Sprintf("Finished copying and syncing changed blocks for disk %d in %s [Progress: %d/20]", idx, duration, incrementalCopyCount))
+					migobj.updateDiskCheckpoint(ctx, vminfo.VMDisks[idx], incrementalCopyCount)
 				}
+				migobj.logMessage(fmt.Sprintf("Finished copying and syncing changed blocks for disk %d in %s [Progress: %d/20]", idx, duration, incrementalCopyCount))
+				return nil
+			})
+			if err != nil {
+				return vminfo, errors.Wrap(err, "failed to copy changed blocks")
 			}
-			if final {
-				break
+			done := !slices.Contains(changed, true)
+			retry := slices.ContainsFunc(copyFailures, func(failures int) bool { return failures > 0 })
+			if !final {
+				// The downtime of a cutover now is estimated while the source VM is still running
+				var copied []vjailbreakv1alpha1.DiskDelta
+				for _, delta := range deltas {
+					if delta != nil {
+						copied = append(copied, *delta)
+					}
+				}
+				migobj.Reporter.AddDiskDeltas(copied, constants.EstimatedConversionTime+constants.EstimatedBootTime)
 			}
-			if done || incrementalCopyCount > vcenterSettings.ChangedBlocksCopyIterationThreshold {
-				utils.PrintLog("Shutting down source VM and performing final copy")
-				err = vmops.VMPowerOff()
+			if warm {
+				migobj.Reporter.AddWarmSync(vjailbreakv1alpha1.WarmSync{
+					Iteration:            incrementalCopyCount,
+					StartTime:            metav1.NewTime(passStart),
+					DeltaBytes:           passBytes.Load(),
+					DurationMilliseconds: time.Since(passStart).Milliseconds(),
+				})
+				migobj.logMessage(fmt.Sprintf("Warm sync %d copied %d changed bytes in %s", incrementalCopyCount, passBytes.Load(), time.Since(passStart)))
+			}
+			if final {
+				if !retry {
+					break
+				}
+				// The source VM is off, the changes of the failed disks are copied again from the next snapshot
+				migobj.logMessage("Final copy of changed blocks failed for some disks, retrying")
+			} else if warm {
+				cutover, err := migobj.waitForNextWarmSync(ctx, passStart, adminInitiatedCutover)
 				if err != nil {
-					return vminfo, errors.Wrap(err, "failed to power off VM")
+					return vminfo, errors.Wrap(err, "failed to wait for the next warm sync")
+				}
+				if cutover {
+					utils.PrintLog("Shutting down source VM and performing final sync")
+					err = vmops.VMPowerOff()
+					if err != nil {
+						return vminfo, errors.Wrap(err, "failed to power off VM")
+					}
+					final = true
+				}
+			} else if done || incrementalCopyCount > vcenterSettings.ChangedBlocksCopyIterationThreshold {
+				cutover := true
+				// The live VM is only powered off inside a maintenance window, the changes made while waiting
+				// for the window are copied before the cutover is decided again
+				if migobj.MigrationType != "cold" && !adminInitiatedCutover {
+					if cutover, err = migobj.waitForCutoverWindow(ctx); err != nil {
+						return vminfo, errors.Wrap(err, "failed to start VM Cutover")
+					}
+				}
+				if cutover {
+					utils.PrintLog("Shutting down source VM and performing final copy")
+					err = vmops.VMPowerOff()
+					if err != nil {
+						return vminfo, errors.Wrap(err, "failed to power off VM")
+					}
+					final = true
 				}
-				final = true
 			}
 		}
 
@@ -447,6 +819,10 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 
 	}
 
+	if err := migobj.VerifyDisks(ctx, &vminfo, vcenterSettings.DiskCopyConcurrencyLimit); err != nil {
+		return vminfo, errors.Wrap(err, "failed to verify copied data")
+	}
+
 	err = migobj.DetachAllVolumes(vminfo)
 	if err != nil {
 		return vminfo, errors.Wrap(err, "Failed to detach all volumes from VM")
@@ -469,8 +845,91 @@ func (migobj *Migrate) LiveReplicateDisks(ctx context.Context, vminfo vm.VMInfo)
 	return vminfo, nil
 }
 
-func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) error {
+// VerifyDisks compares the copied volumes with the migration snapshot, as selected by DataVerification, and copies
+// the mismatched data again. It runs after the final copy pass, while the volumes are attached and the source VM is off.
+// The result is reported on the progress record of the pod.
+func (migobj *Migrate) VerifyDisks(ctx context.Context, vminfo *vm.VMInfo, concurrency int) error {
+	if migobj.DataVerification != vjailbreakv1alpha1.DataVerificationSampled && migobj.DataVerification != vjailbreakv1alpha1.DataVerificationFull {
+		return nil
+	}
+	vmops := migobj.VMops
+	nbdops := migobj.Nbdops
+	sampled := migobj.DataVerification == vjailbreakv1alpha1.DataVerificationSampled
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks, "Verifying copied data")
+	migobj.logMessage(fmt.Sprintf("Verifying copied data (%s)", migobj.DataVerification))
+
+	// The NBD servers of the disks without changes in the last passes still serve deleted snapshots
+	if err := vmops.UpdateDisksInfo(vminfo); err != nil {
+		return errors.Wrap(err, "failed to update disk info")
+	}
+	snapshot, err := vmops.GetSnapshot(constants.MigrationSnapshotName)
+	if err != nil {
+		return errors.Wrap(err, "failed to get snapshot")
+	}
+
+	result := &vjailbreakv1alpha1.DataVerificationResult{
+		Mode:  migobj.DataVerification,
+		Disks: make([]vjailbreakv1alpha1.DiskVerification, len(vminfo.VMDisks)),
+	}
+	err = copyDisksInParallel(concurrency, len(vminfo.VMDisks), func(idx int) error {
+		disk := vminfo.VMDisks[idx]
+		diskResult := &result.Disks[idx]
+		diskResult.Index = idx
+		diskResult.Name = disk.Name
+
+		// ChangeID "*" returns the allocated areas of the disk
+		areas, err := vmops.CustomQueryChangedDiskAreas("*", snapshot, disk.Disk, 0)
+		if err != nil {
+			return errors.Wrap(err, "failed to get allocated disk areas")
+		}
+		if err := nbdops[idx].StopNBDServer(); err != nil {
+			return errors.Wrap(err, "failed to stop NBD server")
+		}
+		err = nbdops[idx].StartNBDServer(vmops.GetVMObj(), migobj.URL, migobj.UserName, migobj.Password, migobj.Thumbprint, disk.Snapname, disk.SnapBackingDisk, migobj.EventReporter)
+		if err != nil {
+			return errors.Wrap(err, "failed to start NBD server")
+		}
+		// sleep for 2 seconds to allow the NBD server to start
+		time.Sleep(2 * time.Second)
+
+		mismatches, verified, err := nbdops[idx].VerifyBlocks(ctx, areas, disk.Path, idx, sampled)
+		diskResult.BytesVerified = verified
+		if err != nil {
+			return err
+		}
+		if len(mismatches) == 0 {
+			diskResult.Verified = true
+			migobj.logMessage(fmt.Sprintf("Disk %d: %d bytes verified, no mismatch found", idx, verified))
+			return nil
+		}
+		for _, mismatch := range mismatches {
+			diskResult.MismatchedBytes += mismatch.Length
+		}
+		migobj.logMessage(fmt.Sprintf("Disk %d: %d mismatched bytes in %d chunks, copying them again", idx, diskResult.MismatchedBytes, len(mismatches)))
+
+		recopy := types.DiskChangeInfo{ChangedArea: mismatches, Length: diskResult.MismatchedBytes}
+		if err := nbdops[idx].CopyChangedBlocks(ctx, recopy, disk.Path, idx, nil); err != nil {
+			return errors.Wrap(err, "failed to copy mismatched data again")
+		}
+		mismatches, _, err = nbdops[idx].VerifyBlocks(ctx, recopy, disk.Path, idx, false)
+		if err != nil {
+			return err
+		}
+		if len(mismatches) > 0 {
+			return errors.Errorf("disk %d still has %d mismatched chunks after copying them again, first at offset %d", idx, len(mismatches), mismatches[0].Start)
+		}
+		diskResult.Verified = true
+		migobj.logMessage(fmt.Sprintf("Disk %d: mismatched data copied again and verified", idx))
+		return nil
+	})
+	result.CompletionTime = metav1.Now()
+	migobj.Reporter.SetVerification(result)
+	return err
+}
+
+func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo, ipaddresses []string) error {
 	migobj.logMessage("Converting disk")
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseConvertingDisk, "Converting disk")
 
 	var (
 		osRelease                   = ""
@@ -546,43 +1005,8 @@ func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) err
 				return errors.Wrapf(err, "failed to get os release: %s", strings.TrimSpace(osRelease))
 			}
 		}
-		osDetected := strings.ToLower(strings.TrimSpace(osRelease))
-		utils.PrintLog(fmt.Sprintf("OS detected by guestfish: %s", osDetected))
-		// Supported OSes
-		supportedOS := []string{
-			"redhat",
-			"red hat",
-			"rhel",
-			"centos",
-			"scientific linux",
-			"oracle linux",
-			"fedora",
-			"sles",
-			"sled",
-			"opensuse",
-			"alt linux",
-			"debian",
-			"ubuntu",
-			"rocky linux",
-			"suse linux enterprise server",
-			"alma linux",
-		}
-
-		supported := false
-		for _, s := range supportedOS {
-			if strings.Contains(osDetected, s) {
-				supported = true
-				break
-			}
-		}
-
-		if !supported {
-			return errors.Errorf("unsupported OS detected by guestfish: %s", osDetected)
-		}
-		utils.PrintLog("operating system compatibility check passed")
-
+		utils.PrintLog(fmt.Sprintf("OS detected by guestfish: %s", strings.ToLower(strings.TrimSpace(osRelease))))
 	} else if strings.ToLower(vminfo.OSType) == constants.OSFamilyWindows {
-		utils.PrintLog("operating system compatibility check passed")
 		if !useSingleDisk {
 			utils.PrintLog("checking for bootable volume in case of LDM")
 			// check for bootable volume in case of LVM
@@ -599,176 +1023,291 @@ func (migobj *Migrate) ConvertVolumes(ctx context.Context, vminfo vm.VMInfo) err
 		return errors.Errorf("boot volume not found, cannot create target VM")
 	}
 
+	conversionSteps, err := migobj.CheckOSSupport(vminfo, osRelease)
+	if err != nil {
+		return err
+	}
+
+	// The inspection only informs, a guest that cannot be inspected is still converted
+	drives := []string{}
+	for _, disk := range guestDisks(vminfo, useSingleDisk, bootVolumeIndex) {
+		drives = append(drives, disk.Path)
+	}
+	if _, err := migobj.InspectGuest(ctx, inspect.Guestfish(drives), vminfo); err != nil {
+		migobj.logMessage(fmt.Sprintf("Warning: failed to inspect the guest: %s", err))
+	}
+
 	// save the index of bootVolume
 	utils.PrintLog(fmt.Sprintf("Setting up boot volume as: %s", vminfo.VMDisks[bootVolumeIndex].Name))
 	vminfo.VMDisks[bootVolumeIndex].Boot = true
 	if migobj.Convert {
 		firstbootscripts := []string{}
+		isWindows := strings.ToLower(vminfo.OSType) == constants.OSFamilyWindows
 		// Fix NTFS
-		if strings.ToLower(vminfo.OSType) == constants.OSFamilyWindows {
+		if isWindows && slices.Contains(conversionSteps, osmatrix.StepNTFSFix) {
 			err = virtv2v.NTFSFix(vminfo.VMDisks[bootVolumeIndex].Path)
 			if err != nil {
 				return errors.Wrap(err, "failed to run ntfsfix")
 			}
 		}
-
-		if virtv2v.IsRHELFamily(osRelease) {
-			// If RHEL family, we need to inject a script to make interface come up with DHCP,
-			// We preserve the ip because we have a port created with the same IP
-			// If NM is present, we inject a script to force neutron DHCP on first boot.
-			// If NM is not present, we add udev rules to pin the interface names
-			versionID := parseVersionID(osRelease)
-			if versionID == "" {
-				return errors.Errorf("failed to get version ID")
-			}
-			majorVersion, err := strconv.Atoi(strings.Split(versionID, ".")[0])
+		if isWindows && slices.Contains(conversionSteps, osmatrix.StepWindowsFirstBoot) {
+			// The network settings are best effort, the guest still boots with DHCP without them
+			err = configureGuestfish(guestDisks(vminfo, useSingleDisk, bootVolumeIndex), func(fs guestnetwork.GuestFS) error {
+				return migobj.ConfigureWindowsNetwork(fs, vminfo, ipaddresses)
+			})
 			if err != nil {
-				return fmt.Errorf("failed to parse major version: %v", err)
-			}
-
-			if majorVersion >= 7 {
-				firstbootscriptname := "rhel_enable_dhcp"
-				firstbootscript := constants.RhelFirstBootScript
-				firstbootscripts = append(firstbootscripts, firstbootscriptname)
-				err = virtv2v.AddFirstBootScript(firstbootscript, firstbootscriptname)
+				migobj.logMessage(fmt.Sprintf("Warning: failed to configure guest network, the NICs keep their default settings: %v", err))
+			} else {
+				err = virtv2v.AddFirstBootScript(guestnetwork.WindowsFirstBootScript, "windows_network")
 				if err != nil {
 					return errors.Wrap(err, "failed to add first boot script")
 				}
-				utils.PrintLog("First boot script added successfully")
+				firstbootscripts = append(firstbootscripts, "windows_network")
+				migobj.windowsFirstBootInjected = true
 			}
 		}
 
+		convertStart := time.Now()
 		err := virtv2v.ConvertDisk(ctx, constants.XMLFileName, osPath, vminfo.OSType, migobj.Virtiowin, firstbootscripts, useSingleDisk, vminfo.VMDisks[bootVolumeIndex].Path)
 		if err != nil {
 			return errors.Wrap(err, "failed to run virt-v2v")
 		}
+		migobj.Metrics.ObserveVirtV2V(time.Since(convertStart))
+	}
 
-		openstackops := migobj.Openstackclients
-		err = openstackops.SetVolumeBootable(vminfo.VMDisks[bootVolumeIndex].OpenstackVol)
+	if strings.ToLower(vminfo.OSType) == constants.OSFamilyLinux && slices.Contains(conversionSteps, osmatrix.StepGuestNetwork) {
+		// The network settings are best effort, the guest keeps its own configuration without them
+		err = configureGuestfish(guestDisks(vminfo, useSingleDisk, bootVolumeIndex), func(fs guestnetwork.GuestFS) error {
+			return migobj.ConfigureGuestNetwork(fs, osRelease, vminfo, ipaddresses)
+		})
+		if err != nil {
+			migobj.logMessage(fmt.Sprintf("Warning: failed to configure guest network, please check the network configuration post migration: %v", err))
+		}
+	}
+	err = migobj.DetachAllVolumes(vminfo)
+	if err != nil {
+		return errors.Wrap(err, "Failed to detach all volumes from VM")
+	}
+	err = migobj.CommitVolumes(vminfo)
+	if err != nil {
+		return errors.Wrap(err, "failed to create volumes of the converted disks")
+	}
+	if migobj.Convert {
+		err = migobj.Openstackclients.SetVolumeBootable(vminfo.VMDisks[bootVolumeIndex].OpenstackVol)
 		if err != nil {
 			return errors.Wrap(err, "failed to set volume as bootable")
 		}
 	}
+	migobj.logMessage("Successfully converted disk")
+	return nil
+}
 
-	if strings.ToLower(vminfo.OSType) == constants.OSFamilyLinux {
-		if strings.Contains(osRelease, "ubuntu") {
-			// Check if netplan is supported
-			versionID := parseVersionID(osRelease)
-			utils.PrintLog(fmt.Sprintf("Version ID: %s", versionID))
-			if versionID == "" {
-				return errors.Errorf("failed to get version ID")
-			}
-			if isNetplanSupported(versionID) {
-				// Add Wildcard Netplan
-				utils.PrintLog("Adding wildcard netplan")
-				err := virtv2v.AddWildcardNetplan(vminfo.VMDisks, useSingleDisk, vminfo.VMDisks[bootVolumeIndex].Path)
-				if err != nil {
-					return errors.Wrap(err, "failed to add wildcard netplan")
-				}
-				utils.PrintLog("Wildcard netplan added successfully")
-			} else {
-				utils.PrintLog("Ubuntu version does not support netplan, going to use udev rules")
-				// Since netplan is not supported need to get the ip,mac and network interface mapping
-				// To inject udev rules so that after migration the network interfaces names are consistent
-				// and they get the correct ip address.
-				// Get the network interface mapping from /etc/network/interfaces
+// CheckOSSupport checks the guest OS against the supported OS matrix, reports the result and returns the conversion
+// steps of the guest. Linux guests are identified by their os-release file, Windows guests by the OS name VMware
+// reports. A guest that is not supported fails the migration, unless AllowUnsupportedOS is set
+func (migobj *Migrate) CheckOSSupport(vminfo vm.VMInfo, osRelease string) ([]string, error) {
+	matrix := migobj.OSMatrix
+	if matrix == nil {
+		var err error
+		if matrix, err = osmatrix.Default(); err != nil {
+			return nil, errors.Wrap(err, "failed to load supported OS matrix")
+		}
+	}
+	family := osmatrix.Family(vminfo.OSType)
+	name, version := vminfo.OSName, ""
+	if family == osmatrix.FamilyLinux {
+		name, version = osmatrix.ParseOSRelease(osRelease)
+	}
 
-				interfaces, err := virtv2v.GetNetworkInterfaceNames(vminfo.VMDisks[bootVolumeIndex].Path)
-				if err != nil {
-					return errors.Wrap(err, "failed to get network interface names")
-				}
-				if len(interfaces) == 0 {
-					log.Printf("Failed to get network interface names, cannot add udev rules, network might not come up post migration, please check the network configuration post migration")
-				} else {
-					utils.PrintLog("Adding udev rules")
-					utils.PrintLog(fmt.Sprintf("Interfaces: %v", interfaces))
-					macs := []string{}
+	result := matrix.Check(family, name, version)
+	support := &vjailbreakv1alpha1.OSSupportResult{
+		OSName:        name,
+		Version:       version,
+		Supported:     result.Supported,
+		Message:       result.Reason,
+		MatrixVersion: matrix.Version,
+	}
+	if result.Entry != nil {
+		support.Distro = result.Entry.Distro
+		support.Caveats = result.Entry.Caveats
+	}
+	if !result.Supported {
+		if !migobj.AllowUnsupportedOS {
+			migobj.Reporter.SetOSSupport(support)
+			return nil, errors.Errorf("unsupported OS: %s", result.Reason)
+		}
+		support.Forced = true
+		migobj.logMessage(fmt.Sprintf("Warning: %s, migrating anyway because unsupported OSes are allowed", result.Reason))
+	} else {
+		utils.PrintLog(fmt.Sprintf("operating system compatibility check passed: %s %s", support.Distro, version))
+	}
+	for _, caveat := range support.Caveats {
+		migobj.logMessage(fmt.Sprintf("Warning: known issue of %s: %s", support.Distro, caveat))
+	}
+	migobj.Reporter.SetOSSupport(support)
+	return result.ConversionSteps(family), nil
+}
 
-					// By default the network interfaces macs are in the same order as the interfaces
-					for _, nic := range vminfo.NetworkInterfaces {
-						macs = append(macs, nic.MAC)
-					}
-					utils.PrintLog(fmt.Sprintf("MACs: %v", macs))
-					err = virtv2v.AddUdevRules(vminfo.VMDisks, useSingleDisk, vminfo.VMDisks[bootVolumeIndex].Path, interfaces, macs)
-					if err != nil {
-						log.Printf(`Warning Failed to add udev rules: %s, incase of interface name mismatch,
-                        network might not come up post migration, please check the network configuration post migration`, err)
-						log.Println("Continuing with migration")
-						err = nil
-					}
-				}
-			}
-		} else if virtv2v.IsRHELFamily(osRelease) {
-			versionID := parseVersionID(osRelease)
-			majorVersion, err := strconv.Atoi(strings.Split(versionID, ".")[0])
-			if err != nil {
-				return fmt.Errorf("failed to parse major version: %v", err)
-			}
-			if majorVersion < 7 {
-				diskPath := vminfo.VMDisks[bootVolumeIndex].Path
-				// For RHEL family, we need to inject a script to make interface come up with DHCP,
-				// We preserve the ip because we have a port created with the same IP
-				// If NM is present, we inject a script to force DHCP on first boot.
-				// If NM is not present, we add udev rules to pin the interface names
-				err = DetectAndHandleNetwork(diskPath, osRelease, vminfo)
-				if err != nil {
-					utils.PrintLog(fmt.Sprintf(`Warning: Failed to handle network: %v,Continuing with migration, 
-                    network might not come up post migration, please check the network configuration post migration`, err))
-					err = nil
-				}
-			}
+// InspectGuest inspects the disks of the guest with run and records the result in the status of its VMwareMachine
+func (migobj *Migrate) InspectGuest(ctx context.Context, run inspect.Runner, vminfo vm.VMInfo) (*vjailbreakv1alpha1.GuestInspection, error) {
+	inspection, err := inspect.Inspect(run, vminfo.UEFI)
+	if err != nil {
+		return nil, err
+	}
+	lvm := "no LVM"
+	if inspection.LVM != nil {
+		lvm = fmt.Sprintf("LVM volume groups %v", inspection.LVM.VolumeGroups)
+	}
+	migobj.logMessage(fmt.Sprintf("Guest inspection: %s %s, %s firmware, bootloader %q, %s, VMware tools %s, virtio drivers %v, network configuration %v",
+		inspection.OSName, inspection.OSVersion, inspection.Firmware, inspection.Bootloader, lvm, inspection.VMwareTools,
+		inspection.VirtioDrivers, inspection.NetworkConfigFiles))
+	if err := utils.SaveGuestInspection(ctx, migobj.K8sClient, inspection); err != nil {
+		return nil, err
+	}
+	return inspection, nil
+}
+
+// InspectVM inspects the disks of the source VM from a snapshot, served by the NBD servers without copying
+// them, and records the result in the status of its VMwareMachine. Nothing is created in OpenStack and the
+// source VM keeps running
+func (migobj *Migrate) InspectVM(ctx context.Context, vminfo vm.VMInfo) error {
+	vmops := migobj.VMops
+	migobj.logMessage("Inspecting the guest disks from a snapshot of the source VM")
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseInspecting, "Inspecting the guest disks")
+
+	if err := vmops.TakeSnapshot(constants.InspectionSnapshotName); err != nil {
+		return errors.Wrap(err, "failed to take snapshot of source VM")
+	}
+	defer func() {
+		if err := vmops.DeleteSnapshot(constants.InspectionSnapshotName); err != nil {
+			migobj.logMessage(fmt.Sprintf("Warning: failed to delete snapshot %s of source VM, please delete it manually: %s", constants.InspectionSnapshotName, err))
 		}
+	}()
+	if err := vmops.UpdateDisksInfo(&vminfo); err != nil {
+		return errors.Wrap(err, "failed to update disk info")
 	}
-	err = migobj.DetachAllVolumes(vminfo)
+
+	drives := []string{}
+	for idx, vmdisk := range vminfo.VMDisks {
+		err := migobj.Nbdops[idx].StartNBDServer(vmops.GetVMObj(), migobj.URL, migobj.UserName, migobj.Password, migobj.Thumbprint, vmdisk.Snapname, vmdisk.SnapBackingDisk, migobj.EventReporter)
+		if err != nil {
+			return errors.Wrap(err, "failed to start NBD server")
+		}
+		defer func(server nbd.NBDOperations) {
+			if err := server.StopNBDServer(); err != nil {
+				utils.PrintLog(fmt.Sprintf("Failed to stop NBD server: %s", err))
+			}
+		}(migobj.Nbdops[idx])
+		drives = append(drives, migobj.Nbdops[idx].GuestfishURI())
+	}
+	// sleep for 2 seconds to allow the NBD servers to start
+	time.Sleep(2 * time.Second)
+
+	inspection, err := migobj.InspectGuest(ctx, inspect.Guestfish(drives), vminfo)
 	if err != nil {
-		return errors.Wrap(err, "Failed to detach all volumes from VM")
+		return errors.Wrap(err, "failed to inspect guest")
 	}
-	migobj.logMessage("Successfully converted disk")
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseInspected, fmt.Sprintf("Inspected %s %s", inspection.OSName, inspection.OSVersion))
 	return nil
 }
 
-// DetectAndHandleNetwork: Checks if RHEL family, then detects NM presence offline.
-// If NM (nmcli exists), injects first-boot nmcli script for DHCP force.
-// If not, adds udev rules to pin names without forcing DHCP.
-func DetectAndHandleNetwork(diskPath string, osRelease string, vmInfo vm.VMInfo) error {
+// configureGuestfish runs configure on the filesystem of the guest disks and writes its changes
+// to the guest in a single guestfish run
+func configureGuestfish(disks []vm.VMDisk, configure func(fs guestnetwork.GuestFS) error) error {
+	guestfs := guestnetwork.NewGuestfishFS(disks)
+	defer guestfs.Close()
+	if err := configure(guestfs); err != nil {
+		return err
+	}
+	return guestfs.Commit()
+}
 
-	// No NM: Add udev rules to pin names
-	interfaces, err := virtv2v.GetInterfaceNames(diskPath)
+// ConfigureGuestNetwork rewrites the network configuration of the guest, so that each NIC comes up
+// with the static address, gateway and DNS servers it had in VMware if its port kept the address,
+// and with DHCP otherwise. ipaddresses are the addresses of the ports reserved for the NICs
+func (migobj *Migrate) ConfigureGuestNetwork(fs guestnetwork.GuestFS, osRelease string, vminfo vm.VMInfo, ipaddresses []string) error {
+	configurator, err := guestnetwork.Detect(fs, strings.ToLower(osRelease))
 	if err != nil {
-		utils.PrintLog(fmt.Sprintf("Warning: Failed to get interfaces: %v", err))
+		return errors.Wrap(err, "failed to detect guest network configuration")
 	}
-	if len(interfaces) == 0 {
-		utils.PrintLog(`No network interfaces found, cannot add udev rules, network might not
+	if configurator == nil {
+		utils.PrintLog(`Warning: no network configurator found for the guest OS, network might not
             come up post migration, please check the network configuration post migration`)
 		return nil
 	}
-	macs := []string{}
-	// By default the network interfaces macs are in the same order as the interfaces
-	for _, nic := range vmInfo.NetworkInterfaces {
-		macs = append(macs, nic.MAC)
-	}
-	utils.PrintLog(fmt.Sprintf("Interfaces: %v", interfaces))
-	utils.PrintLog(fmt.Sprintf("MACs: %v", macs))
-	if len(interfaces) != len(macs) {
-		utils.PrintLog("Mismatch between number of interfaces and MACs")
-		return fmt.Errorf("mismatch between number of interfaces and MACs")
-	}
-	// Add udev rules to pin names without forcing DHCP
-	utils.PrintLog("Adding udev rules to pin interface names")
-
-	// This will ensure that the network interfaces are named consistently after migration
-	// and they get the correct IP address.
-	// This is important because RHEL family uses NetworkManager by default and it does not
-	// automatically configure the network interfaces to use DHCP after migration.
-	// So we need to add udev rules to pin the names of the network interfaces
-	// to the MAC addresses so that they are consistent after migration.
-	// This will ensure that the network interfaces are named consistently after migration
-	// and they get the correct IP address.
-	err = virtv2v.AddUdevRules([]vm.VMDisk{{Path: diskPath}}, false, diskPath, interfaces, macs)
-	if err != nil {
-		utils.PrintLog(fmt.Sprintf("Warning: Failed to add udev: %v", err))
+	ifaces := guestnetwork.NewInterfaces(vminfo.Mac, vminfo.GuestNetworks, ipaddresses)
+	logInterfaces(ifaces)
+	migobj.logMessage(fmt.Sprintf("Configuring guest network with the %s configurator", configurator.Name()))
+	return configurator.Configure(fs, ifaces)
+}
+
+// ConfigureWindowsNetwork writes the script that re-applies the network settings of the VMware NICs to the
+// virtio NICs of a Windows guest on its first boot, with the same rules as ConfigureGuestNetwork
+func (migobj *Migrate) ConfigureWindowsNetwork(fs guestnetwork.GuestFS, vminfo vm.VMInfo, ipaddresses []string) error {
+	ifaces := guestnetwork.NewInterfaces(vminfo.Mac, vminfo.GuestNetworks, ipaddresses)
+	logInterfaces(ifaces)
+	migobj.logMessage(fmt.Sprintf("Injecting the Windows first boot network script (RemoveVMwareTools=%v)", migobj.RemoveVMwareTools))
+	configurator := &guestnetwork.WindowsConfigurator{RemoveVMwareTools: migobj.RemoveVMwareTools}
+	return configurator.Configure(fs, ifaces)
+}
+
+// WaitForWindowsFirstBoot waits for the first boot script of a Windows guest to write its result on the
+// console of the migrated VM and reports it. A missing result does not fail the migration, it is reported
+// as not received
+func (migobj *Migrate) WaitForWindowsFirstBoot(ctx context.Context) {
+	migobj.logMessage("Waiting for the first boot script of the guest to report its result")
+	deadline := time.Now().Add(constants.WindowsFirstBootTimeout)
+	for {
+		output, err := migobj.Openstackclients.GetConsoleOutput(migobj.targetServerID, constants.WindowsFirstBootConsoleLines)
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to get console output of the migrated VM: %s", err))
+		} else if result, err := guestnetwork.ParseWindowsResult(output); err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to read the result of the first boot script: %s", err))
+		} else if result != nil {
+			result.CompletionTime = metav1.Now()
+			for _, adapter := range result.Adapters {
+				migobj.logMessage(fmt.Sprintf("First boot network configuration of %s: %s %s", adapter.MAC, adapter.Status, adapter.Message))
+			}
+			for _, scriptErr := range result.Errors {
+				migobj.logMessage(fmt.Sprintf("Warning: first boot script: %s", scriptErr))
+			}
+			migobj.Reporter.SetWindowsFirstBoot(result)
+			return
+		}
+		if !time.Now().Add(constants.WindowsFirstBootPollInterval).Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(constants.WindowsFirstBootPollInterval):
+		}
+	}
+	migobj.logMessage(fmt.Sprintf("Warning: the first boot script of the guest did not report its result within %s, check C:\\vjailbreak\\network.log in the guest", constants.WindowsFirstBootTimeout))
+	migobj.Reporter.SetWindowsFirstBoot(&vjailbreakv1alpha1.WindowsFirstBootResult{
+		Reported:       false,
+		CompletionTime: metav1.Now(),
+		Errors:         []string{fmt.Sprintf("no result reported within %s", constants.WindowsFirstBootTimeout)},
+	})
+}
+
+// guestDisks returns the disks the guest filesystem is on
+func guestDisks(vminfo vm.VMInfo, useSingleDisk bool, bootVolumeIndex int) []vm.VMDisk {
+	if useSingleDisk {
+		return []vm.VMDisk{vminfo.VMDisks[bootVolumeIndex]}
+	}
+	return vminfo.VMDisks
+}
+
+// logInterfaces logs the network configuration of each NIC of the VM
+func logInterfaces(ifaces []guestnetwork.Interface) {
+	for _, iface := range ifaces {
+		if iface.Static() {
+			utils.PrintLog(fmt.Sprintf("Interface %s: static addresses %v, gateway %q, DNS %v", iface.MAC, iface.Addresses, iface.Gateway, iface.DNS))
+		} else {
+			utils.PrintLog(fmt.Sprintf("Interface %s: DHCP", iface.MAC))
+		}
 	}
-	return nil
 }
 
 func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo, networkids, portids []string, ipaddresses []string) error {
@@ -835,87 +1374,11 @@ func (migobj *Migrate) CreateTargetInstance(vminfo vm.VMInfo, networkids, portid
 		time.Sleep(time.Duration(vjailbreakSettings.VMActiveWaitIntervalSeconds) * time.Second)
 	}
 
+	migobj.targetServerID = newVM.ID
 	migobj.logMessage(fmt.Sprintf("VM created successfully: ID: %s", newVM.ID))
-
-	if migobj.PerformHealthChecks {
-		err = migobj.HealthCheck(vminfo, ipaddresses)
-		if err != nil {
-			migobj.logMessage(fmt.Sprintf("Health Check failed: %s", err))
-		}
-	} else {
-		migobj.logMessage("Skipping Health Checks")
-	}
-
 	return nil
 }
 
-// parseVersionID parses the VERSION_ID from /etc/os-release or /etc/redhat-release format.
-// It returns the version ID as a string, or an empty string if not found.
-func parseVersionID(osRelease string) string {
-	osRelease = strings.TrimSpace(osRelease)
-
-	// Key-value style (os-release, SuSE-release, etc.)
-	if strings.Contains(osRelease, "=") {
-		var version, patchlevel string
-		for _, line := range strings.Split(osRelease, "\n") {
-			kv := strings.SplitN(line, "=", 2)
-			if len(kv) != 2 {
-				continue
-			}
-			key := strings.TrimSpace(strings.ToUpper(kv[0]))
-			val := strings.TrimSpace(strings.Trim(kv[1], `"`)) // Remove quotes and spaces
-			switch key {
-			case "VERSION_ID":
-				return val
-			case "VERSION":
-				version = val
-			case "PATCHLEVEL":
-				patchlevel = val
-			}
-		}
-		// If it's SLES style, combine VERSION + PATCHLEVEL if available
-		if version != "" {
-			if patchlevel != "" {
-				return version + "." + patchlevel
-			}
-			return version
-		}
-	} else {
-		// /etc/redhat-release style
-		re := regexp.MustCompile(`release\s+([0-9]+(\.[0-9]+)?)`)
-		matches := re.FindStringSubmatch(strings.ToLower(osRelease))
-		if len(matches) > 1 {
-			return matches[1]
-		}
-	}
-
-	return ""
-}
-
-func isNetplanSupported(version string) bool {
-	parts := strings.Split(version, ".")
-	if len(parts) < 2 {
-		log.Printf("Warning: unexpected VERSION_ID format: %q", version)
-		return true // assume modern if uncertain
-	}
-
-	major, err1 := strconv.Atoi(parts[0])
-	minor, err2 := strconv.Atoi(parts[1])
-	if err1 != nil || err2 != nil {
-		log.Printf("Warning: failed to parse VERSION_ID %q: %v %v", version, err1, err2)
-		return true
-	}
-
-	// Compare with 17.10
-	if major > 17 {
-		return true
-	}
-	if major == 17 && minor >= 10 {
-		return true
-	}
-	return false
-}
-
 func (migobj *Migrate) pingVM(ips []string) error {
 	for _, ip := range ips {
 		migobj.logMessage(fmt.Sprintf("Pinging VM: %s", ip))
@@ -1020,24 +1483,97 @@ func (migobj *Migrate) HealthCheck(vminfo vm.VMInfo, ips []string) error {
 		migobj.logMessage("Waiting for 60 seconds before retrying health checks")
 		time.Sleep(60 * time.Second)
 	}
+	var failed []string
 	for key, value := range healthChecks {
 		if !value {
 			migobj.logMessage(fmt.Sprintf("Health Check %s failed", key))
+			failed = append(failed, key)
 		} else {
 			migobj.logMessage(fmt.Sprintf("Health Check %s succeeded", key))
 		}
 	}
+	if len(failed) > 0 {
+		slices.Sort(failed)
+		return errors.Errorf("health checks failed: %s", strings.Join(failed, ", "))
+	}
 	return nil
 }
 
-func (migobj *Migrate) gracefulTerminate(vminfo vm.VMInfo, cancel context.CancelFunc) {
+// ErrRolledBack is returned by MigrateVM when the migrated VM failed its health checks and was rolled back
+var ErrRolledBack = errors.New("migration rolled back")
+
+// Rollback restores the source VM after the migrated VM failed its health checks, as selected by RollbackPolicy.
+// The migrated VM is shut off or deleted, the ports created for it are deleted, and the source VM is reconnected
+// and powered on. Every step is attempted even if an earlier one fails.
+func (migobj *Migrate) Rollback(vminfo vm.VMInfo, portids []string, cause error) error {
+	openstackops := migobj.Openstackclients
+	migobj.logMessage(fmt.Sprintf("Rolling back migration (%s): %s", migobj.RollbackPolicy, cause))
+
+	var failures []string
+	step := func(description string, err error) {
+		if err != nil {
+			migobj.logMessage(fmt.Sprintf("Rollback: failed to %s: %s", description, err))
+			failures = append(failures, fmt.Sprintf("failed to %s: %s", description, err))
+			return
+		}
+		migobj.logMessage(fmt.Sprintf("Rollback: %s done", description))
+	}
+
+	targetDeleted := false
+	if migobj.targetServerID != "" {
+		switch migobj.RollbackPolicy {
+		case vjailbreakv1alpha1.RollbackPolicyShutOffTarget:
+			step(fmt.Sprintf("shut off server %s", migobj.targetServerID), openstackops.StopServer(migobj.targetServerID))
+		case vjailbreakv1alpha1.RollbackPolicyDeleteTarget:
+			err := openstackops.DeleteServer(migobj.targetServerID)
+			targetDeleted = err == nil
+			step(fmt.Sprintf("delete server %s", migobj.targetServerID), err)
+		}
+	}
+	// Ports given in the migration plan are not owned by the migration and are kept
+	if len(migobj.Networkports) == 0 {
+		for _, portid := range portids {
+			step(fmt.Sprintf("delete port %s", portid), openstackops.DeletePort(portid))
+		}
+	}
+	if targetDeleted {
+		for _, vmdisk := range vminfo.VMDisks {
+			if vmdisk.OpenstackVol == nil {
+				continue
+			}
+			err := openstackops.WaitForVolume(vmdisk.OpenstackVol.ID)
+			if err == nil {
+				err = openstackops.DeleteVolume(vmdisk.OpenstackVol.ID)
+			}
+			step(fmt.Sprintf("delete volume %s", vmdisk.OpenstackVol.ID), err)
+		}
+	}
+	if migobj.sourceNetworkDisconnected {
+		step("reconnect source VM network interfaces", migobj.VMops.ConnectNetworkInterfaces())
+	}
+	step("power on source VM", migobj.VMops.VMPowerOn())
+
+	if len(failures) > 0 {
+		return errors.Errorf("rollback after %s is incomplete: %s", cause, strings.Join(failures, "; "))
+	}
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseRolledBack, fmt.Sprintf("Source VM restored after %s", cause))
+	return nil
+}
+
+// gracefulTerminate takes a pointer so that it sees the volumes created after it was started
+func (migobj *Migrate) gracefulTerminate(vminfo *vm.VMInfo, cancel context.CancelFunc) {
 	gracefulShutdown := make(chan os.Signal, 1)
 	// Handle SIGTERM
 	signal.Notify(gracefulShutdown, syscall.SIGTERM, syscall.SIGINT)
 	<-gracefulShutdown
 	migobj.logMessage("Gracefully terminating")
 	cancel()
-	migobj.cleanup(vminfo, "Migration terminated")
+	if migobj.checkpoint.IsResumable() {
+		// The job starts a new pod for this exit code, up to its backoff limit, which resumes from the checkpoint
+		migobj.keepVolumesForResume(*vminfo)
+		os.Exit(constants.ResumeExitCode)
+	}
+	migobj.cleanup(*vminfo, "Migration terminated")
 	os.Exit(0)
 }
 
@@ -1049,6 +1585,7 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 	var zerotime time.Time
 	if !migobj.MigrationTimes.DataCopyStart.Equal(zerotime) && migobj.MigrationTimes.DataCopyStart.After(time.Now()) {
 		migobj.logMessage("Waiting for data copy start time")
+		migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseAwaitingDataCopyStart, "Waiting for data copy start time")
 		time.Sleep(time.Until(migobj.MigrationTimes.DataCopyStart))
 		migobj.logMessage("Data copy start time reached")
 	}
@@ -1060,6 +1597,12 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		cancel()
 		return errors.Wrap(err, "failed to get all info")
 	}
+	if migobj.InspectOnly {
+		for range vminfo.VMDisks {
+			migobj.Nbdops = append(migobj.Nbdops, &nbd.NBDServer{Throttle: migobj.Throttle})
+		}
+		return migobj.InspectVM(ctx, vminfo)
+	}
 	if len(vminfo.VMDisks) != len(migobj.Volumetypes) {
 		return errors.Errorf("number of volume types does not match number of disks vm(%d) volume(%d)", len(vminfo.VMDisks), len(migobj.Volumetypes))
 	}
@@ -1067,7 +1610,7 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		return errors.Errorf("number of mac addresses does not match number of network names mac(%d) network(%d)", len(vminfo.Mac), len(migobj.Networknames))
 	}
 	// Graceful Termination clean-up volumes and snapshots
-	go migobj.gracefulTerminate(vminfo, cancel)
+	go migobj.gracefulTerminate(&vminfo, cancel)
 
 	// Reserve ports for VM
 	networkids, portids, ipaddresses, err := migobj.ReservePortsForVM(&vminfo)
@@ -1075,10 +1618,15 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		return errors.Wrap(err, "failed to reserve ports for VM")
 	}
 
-	// Create and Add Volumes to Host
-	vminfo, err = migobj.CreateVolumes(vminfo)
-	if err != nil {
-		return errors.Wrap(err, "failed to add volumes to host")
+	// Create and Add Volumes to Host, re-adopting the ones copied by a previous attempt. Staged disks
+	// get their volumes once they are converted
+	if migobj.stagesDisks() {
+		migobj.logMessage("Staging disks on the agent, volumes are created after conversion and the migration cannot be resumed")
+	} else {
+		vminfo, err = migobj.CreateOrAdoptVolumes(ctx, vminfo)
+		if err != nil {
+			return errors.Wrap(err, "failed to add volumes to host")
+		}
 	}
 	// Enable CBT
 	err = migobj.EnableCBTWrapper()
@@ -1089,7 +1637,7 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 
 	// Create NBD servers
 	for range vminfo.VMDisks {
-		migobj.Nbdops = append(migobj.Nbdops, &nbd.NBDServer{})
+		migobj.Nbdops = append(migobj.Nbdops, &nbd.NBDServer{Throttle: migobj.Throttle, Target: migobj.copyTarget()})
 	}
 
 	// Live Replicate Disks
@@ -1101,12 +1649,15 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		}
 		return errors.Wrap(err, "failed to live replicate disks")
 	}
+	// Conversion modifies the volumes, so they can no longer be resumed from
+	migobj.clearCheckpoint(ctx)
+
 	vcenterSettings, err := k8sutils.GetVjailbreakSettings(ctx, migobj.K8sClient)
 	if err != nil {
 		return errors.Wrap(err, "failed to get vcenter settings")
 	}
 	// Convert the Boot Disk to raw format
-	err = migobj.ConvertVolumes(ctx, vminfo)
+	err = migobj.ConvertVolumes(ctx, vminfo, ipaddresses)
 	if err != nil {
 		if !vcenterSettings.CleanupVolumesAfterConvertFailure {
 			migobj.logMessage("Cleanup volumes after convert failure is disabled, detaching volumes and cleaning up snapshots")
@@ -1142,11 +1693,37 @@ func (migobj *Migrate) MigrateVM(ctx context.Context) error {
 		migobj.logMessage(fmt.Sprintf("Warning: Failed to disconnect source VM network interfaces: %v", err))
 	}
 
+	// The health checks need the network the first boot script configures
+	if migobj.windowsFirstBootInjected {
+		migobj.WaitForWindowsFirstBoot(ctx)
+	}
+
+	if migobj.PerformHealthChecks {
+		if err := migobj.HealthCheck(vminfo, ipaddresses); err != nil {
+			migobj.logMessage(fmt.Sprintf("Health Check failed: %s", err))
+			if migobj.RollbackPolicy == vjailbreakv1alpha1.RollbackPolicyShutOffTarget || migobj.RollbackPolicy == vjailbreakv1alpha1.RollbackPolicyDeleteTarget {
+				if rollbackErr := migobj.Rollback(vminfo, portids, err); rollbackErr != nil {
+					return rollbackErr
+				}
+				return errors.Wrap(ErrRolledBack, err.Error())
+			}
+		} else {
+			migobj.logMessage("Health Checks passed")
+		}
+	} else {
+		migobj.logMessage("Skipping Health Checks")
+	}
+
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseSucceeded, "Migration completed successfully")
 	return nil
 }
 
 func (migobj *Migrate) cleanup(vminfo vm.VMInfo, message string) error {
 	migobj.logMessage(fmt.Sprintf("%s. Trying to perform cleanup", message))
+	migobj.Reporter.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseFailed, message)
+	if migobj.checkpoint.IsResumable() {
+		return migobj.keepVolumesForResume(vminfo)
+	}
 	err := migobj.DetachAllVolumes(vminfo)
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf("Failed to detach all volumes from VM: %s\n", err))
@@ -1155,6 +1732,24 @@ func (migobj *Migrate) cleanup(vminfo vm.VMInfo, message string) error {
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf("Failed to delete all volumes from host: %s\n", err))
 	}
+	migobj.clearCheckpoint(context.Background())
+	err = migobj.VMops.CleanUpSnapshots(true)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
+		return errors.Wrap(err, fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
+	}
+	return nil
+}
+
+// keepVolumesForResume releases the volumes and snapshots held by this pod but keeps the
+// volumes and the checkpoint, so that the next attempt continues from where this one stopped.
+// The controller deletes the kept volumes along with the migration plan if it is not retried
+func (migobj *Migrate) keepVolumesForResume(vminfo vm.VMInfo) error {
+	migobj.logMessage("Keeping copied volumes, the next attempt will resume from the saved checkpoint")
+	err := migobj.DetachAllVolumes(vminfo)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to detach all volumes from VM: %s\n", err))
+	}
 	err = migobj.VMops.CleanUpSnapshots(true)
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf("Failed to cleanup snapshot of source VM: %s\n", err))
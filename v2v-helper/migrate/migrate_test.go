@@ -3,11 +3,22 @@ package migrate
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/k8s/migration/pkg/osmatrix"
+	"github.com/platform9/vjailbreak/v2v-helper/guestnetwork"
+	"github.com/platform9/vjailbreak/v2v-helper/inspect"
 	"github.com/platform9/vjailbreak/v2v-helper/nbd"
 	"github.com/platform9/vjailbreak/v2v-helper/openstack"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/rbd"
 	"github.com/platform9/vjailbreak/v2v-helper/vm"
 
 	"github.com/golang/mock/gomock"
@@ -16,11 +27,27 @@ import (
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
+func newFakeK8sClient(objs ...client.Object) client.Client {
+	settings := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      constants.VjailbreakSettingsConfigMapName,
+			Namespace: constants.NamespaceMigrationSystem,
+		},
+	}
+	return fake.NewClientBuilder().WithObjects(append(objs, settings)...).Build()
+}
+
 func TestCreateVolumes(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -160,7 +187,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 		mockVMOps.EXPECT().TakeSnapshot("migration-snap").Return(nil).AnyTimes(),
 		mockVMOps.EXPECT().UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
 		mockVMOps.EXPECT().UpdateDisksInfo(gomock.Any()).Return(nil).AnyTimes(),
-		mockVMOps.EXPECT().GetVMInfo("linux").Return(vm.VMInfo{
+		mockVMOps.EXPECT().GetVMInfo("linux", gomock.Any()).Return(vm.VMInfo{
 			Name:   "test-vm",
 			OSType: "linux",
 			UEFI:   false,
@@ -197,17 +224,17 @@ func TestLiveReplicateDisks(t *testing.T) {
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM("id1").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyDisk(context.TODO(), "/dev/sda", 0).Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyDisk(context.TODO(), "/dev/sda", 0, gomock.Any(), gomock.Any()).Return(int64(0), nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any()).Return(nil).AnyTimes(),
 
 		mockOpenStackOps.EXPECT().AttachVolumeToVM("id2").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id2").Return("/dev/sdb", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyDisk(context.TODO(), "/dev/sdb", 1).Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyDisk(context.TODO(), "/dev/sdb", 1, gomock.Any(), gomock.Any()).Return(int64(0), nil).AnyTimes(),
 		// 1. Both Disks Change
 		mockVMOps.EXPECT().
 			UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
-		mockVMOps.EXPECT().GetVMInfo("linux").Return(vm.VMInfo{
+		mockVMOps.EXPECT().GetVMInfo("linux", gomock.Any()).Return(vm.VMInfo{
 			Name:   "test-vm",
 			OSType: "linux",
 			UEFI:   false,
@@ -237,7 +264,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM("id1").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda", 0, gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any()).Return(nil).AnyTimes(),
 		// Incremental Copy Disk 2
@@ -257,11 +284,11 @@ func TestLiveReplicateDisks(t *testing.T) {
 			AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM("id2").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id2").Return("/dev/sdb", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sdb").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sdb", 1, gomock.Any()).Return(nil).AnyTimes(),
 		// 2. Only Disk 1 Changes
 		mockVMOps.EXPECT().
 			UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
-		mockVMOps.EXPECT().GetVMInfo("linux").Return(vm.VMInfo{
+		mockVMOps.EXPECT().GetVMInfo("linux", gomock.Any()).Return(vm.VMInfo{
 			Name:   "test-vm",
 			OSType: "linux",
 			UEFI:   false,
@@ -281,14 +308,14 @@ func TestLiveReplicateDisks(t *testing.T) {
 		mockNBD.EXPECT().StartNBDServer(&object.VirtualMachine{}, envURL, envUserName, envPassword, thumbprint, "migration-snap", "[ds1] test_vm/test_vm.vmdk", dummychan).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().AttachVolumeToVM("id1").Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes(),
-		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda").Return(nil).AnyTimes(),
+		mockNBD.EXPECT().CopyChangedBlocks(context.TODO(), changedAreasexample, "/dev/sda", 0, gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any()).Return(nil).AnyTimes(),
 		mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any()).Return(nil).AnyTimes(),
 		// No copy for Disk 2
 		// 3. No disk changes
 		mockVMOps.EXPECT().
 			UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
-		mockVMOps.EXPECT().GetVMInfo("linux").Return(vm.VMInfo{
+		mockVMOps.EXPECT().GetVMInfo("linux", gomock.Any()).Return(vm.VMInfo{
 			Name:   "test-vm",
 			OSType: "linux",
 			UEFI:   false,
@@ -316,7 +343,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 		mockVMOps.EXPECT().VMPowerOff().Return(nil).AnyTimes(),
 		mockVMOps.EXPECT().
 			UpdateDiskInfo(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes(),
-		mockVMOps.EXPECT().GetVMInfo("linux").Return(vm.VMInfo{
+		mockVMOps.EXPECT().GetVMInfo("linux", gomock.Any()).Return(vm.VMInfo{
 			Name:   "test-vm",
 			OSType: "linux",
 			UEFI:   false,
@@ -356,6 +383,7 @@ func TestLiveReplicateDisks(t *testing.T) {
 		EventReporter:    dummychan,
 		PodLabelWatcher:  dummychan2,
 		MigrationType:    "hot",
+		K8sClient:        newFakeK8sClient(),
 	}
 	go func() {
 		time.Sleep(15 * time.Second)
@@ -435,19 +463,20 @@ func TestCreateTargetInstance(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().GetSecurityGroupIDs(gomock.Any(), gomock.Any()).Return([]string{}, nil).AnyTimes()
 	mockOpenStackOps.EXPECT().GetClosestFlavour(gomock.Any(), gomock.Any()).Return(&flavors.Flavor{
 		VCPUs: 2,
 		RAM:   2048,
 	}, nil).AnyTimes()
 	mockOpenStackOps.EXPECT().GetNetwork(gomock.Any()).Return(&networks.Network{}, nil).AnyTimes()
-	mockOpenStackOps.EXPECT().CreatePort(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&ports.Port{
+	mockOpenStackOps.EXPECT().CreatePort(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&ports.Port{
 		MACAddress: "mac-address",
 		FixedIPs: []ports.IP{
 			{IPAddress: "ip-address"},
 		},
 	}, nil).AnyTimes()
 	mockOpenStackOps.EXPECT().GetNetwork(gomock.Any()).Return(&networks.Network{}, nil).AnyTimes()
-	mockOpenStackOps.EXPECT().CreatePort(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&ports.Port{
+	mockOpenStackOps.EXPECT().CreatePort(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(&ports.Port{
 		MACAddress: "mac-address",
 		FixedIPs: []ports.IP{
 			{IPAddress: "ip-address"},
@@ -477,6 +506,7 @@ func TestCreateTargetInstance(t *testing.T) {
 		Networknames:     []string{"network-name-1", "network-name-2"},
 		InPod:            false,
 		TargetFlavorId:   "flavor-id",
+		K8sClient:        newFakeK8sClient(),
 	}
 	err := migobj.CreateTargetInstance(inputvminfo, []string{"network-id-1", "network-id-2"}, []string{"port-id-1", "port-id-2"}, []string{"ip-address-1", "ip-address-2"})
 	assert.NoError(t, err)
@@ -487,6 +517,7 @@ func TestCreateTargetInstance_AdvancedMapping_Ports(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().GetSecurityGroupIDs(gomock.Any(), gomock.Any()).Return([]string{}, nil).AnyTimes()
 	mockOpenStackOps.EXPECT().GetClosestFlavour(gomock.Any(), gomock.Any()).Return(&flavors.Flavor{
 		VCPUs: 2,
 		RAM:   2048,
@@ -530,6 +561,7 @@ func TestCreateTargetInstance_AdvancedMapping_Ports(t *testing.T) {
 		Networkports:     []string{"port-1", "port-2"},
 		InPod:            false,
 		TargetFlavorId:   "flavor-id",
+		K8sClient:        newFakeK8sClient(),
 	}
 	err := migobj.CreateTargetInstance(inputvminfo, []string{"network-id-1", "network-id-2"}, []string{"port-id-1", "port-id-2"}, []string{"ip-address-1", "ip-address-2"})
 	assert.NoError(t, err)
@@ -540,6 +572,7 @@ func TestCreateTargetInstance_AdvancedMapping_InsufficientPorts(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().GetSecurityGroupIDs(gomock.Any(), gomock.Any()).Return([]string{}, nil).AnyTimes()
 	mockOpenStackOps.EXPECT().GetFlavor(gomock.Any()).Return(&flavors.Flavor{
 		VCPUs: 2,
 		RAM:   2048,
@@ -564,7 +597,817 @@ func TestCreateTargetInstance_AdvancedMapping_InsufficientPorts(t *testing.T) {
 		Networkports:     []string{"port-1"},
 		InPod:            false,
 		TargetFlavorId:   "flavor-id",
+		K8sClient:        newFakeK8sClient(),
 	}
-	err := migobj.CreateTargetInstance(inputvminfo, []string{"network-id-1", "network-id-2"}, []string{"port-id-1", "port-id-2"}, []string{"ip-address-1", "ip-address-2"})
+	_, _, _, err := migobj.ReservePortsForVM(&inputvminfo)
 	assert.Contains(t, err.Error(), "number of network ports does not match number of network names")
 }
+
+func TestCreateOrAdoptVolumes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	t.Setenv("VMWARE_MACHINE_OBJECT_NAME", "test-vm")
+
+	k8sClient := newFakeK8sClient()
+	err := utils.SaveMigrationCheckpoint(context.TODO(), k8sClient, &utils.MigrationCheckpoint{
+		VMName: "test-vm",
+		Disks: []utils.DiskCheckpoint{
+			{Name: "disk1", VolumeID: "id1", ChangeID: "52", Iteration: 3, Phase: constants.CheckpointPhaseCopied},
+			{Name: "disk2", VolumeID: "id2", Phase: constants.CheckpointPhaseVolumeCreated},
+		},
+	})
+	assert.NoError(t, err)
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	// disk1 finished its full copy, so its volume is adopted
+	mockOpenStackOps.EXPECT().GetVolume("id1").Return(&volumes.Volume{ID: "id1", Size: 1}, nil)
+	// disk2 was only partially copied, so its volume is replaced
+	mockOpenStackOps.EXPECT().GetVolume("id2").Return(&volumes.Volume{ID: "id2", Size: 1}, nil)
+	gomock.InOrder(
+		mockOpenStackOps.EXPECT().DetachVolumeFromVM("id2").Return(nil),
+		mockOpenStackOps.EXPECT().WaitForVolume("id2").Return(nil),
+		mockOpenStackOps.EXPECT().DeleteVolume("id2").Return(nil),
+		mockOpenStackOps.EXPECT().CreateVolume("test-vm-disk2", int64(2048), "linux", false, "voltype-2", false).
+			Return(&volumes.Volume{ID: "id3"}, nil),
+	)
+
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		InPod:            false,
+		Volumetypes:      []string{"voltype-1", "voltype-2"},
+		K8sClient:        k8sClient,
+	}
+	inputvminfo := vm.VMInfo{
+		Name:   "test-vm",
+		OSType: "linux",
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Size: int64(1024)},
+			{Name: "disk2", Size: int64(2048)},
+		},
+	}
+
+	outputvminfo, err := migobj.CreateOrAdoptVolumes(context.TODO(), inputvminfo)
+	assert.NoError(t, err)
+	assert.Equal(t, "id1", outputvminfo.VMDisks[0].OpenstackVol.ID)
+	assert.Equal(t, "id3", outputvminfo.VMDisks[1].OpenstackVol.ID)
+
+	checkpoint, err := utils.GetMigrationCheckpoint(context.TODO(), k8sClient)
+	assert.NoError(t, err)
+	assert.Equal(t, []utils.DiskCheckpoint{
+		{Name: "disk1", VolumeID: "id1", ChangeID: "52", Iteration: 3, Phase: constants.CheckpointPhaseCopied},
+		{Name: "disk2", VolumeID: "id3", Phase: constants.CheckpointPhaseVolumeCreated},
+	}, checkpoint.Disks)
+}
+
+func TestLiveReplicateDisksResumesFromCheckpoint(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	t.Setenv("VMWARE_MACHINE_OBJECT_NAME", "test-vm")
+
+	inputvminfo := vm.VMInfo{
+		Name:   "test-vm",
+		OSType: "linux",
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Size: int64(1024), Disk: &types.VirtualDisk{}, OpenstackVol: &volumes.Volume{ID: "id1"}, Snapname: "migration-snap", SnapBackingDisk: "[ds1] test_vm/test_vm.vmdk", ChangeID: "99"},
+			{Name: "disk2", Size: int64(2048), Disk: &types.VirtualDisk{}, OpenstackVol: &volumes.Volume{ID: "id2"}, Snapname: "migration-snap", SnapBackingDisk: "[ds1] test_vm/test_vm_1.vmdk", ChangeID: "99"},
+		},
+	}
+	changedAreas := types.DiskChangeInfo{
+		Length:      int64(1024),
+		ChangedArea: []types.DiskChangeExtent{{Start: int64(0), Length: int64(10)}},
+	}
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockNBD := nbd.NewMockNBDOperations(ctrl)
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+
+	mockVMOps.EXPECT().CleanUpSnapshots(gomock.Any()).Return(nil).AnyTimes()
+	mockVMOps.EXPECT().TakeSnapshot(constants.MigrationSnapshotName).Return(nil).AnyTimes()
+	mockVMOps.EXPECT().UpdateDisksInfo(gomock.Any()).Return(nil)
+	mockVMOps.EXPECT().GetVMObj().Return(&object.VirtualMachine{}).AnyTimes()
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(&types.ManagedObjectReference{}, nil).AnyTimes()
+	mockVMOps.EXPECT().UpdateDiskInfo(gomock.Any(), gomock.Any(), true).
+		DoAndReturn(func(vminfo *vm.VMInfo, disk vm.VMDisk, _ bool) error {
+			for idx := range vminfo.VMDisks {
+				if vminfo.VMDisks[idx].Name == disk.Name {
+					vminfo.VMDisks[idx].ChangeID = "100"
+				}
+			}
+			return nil
+		})
+	mockVMOps.EXPECT().VMPowerOff().Return(nil)
+	mockNBD.EXPECT().StartNBDServer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockNBD.EXPECT().StopNBDServer().Return(nil).AnyTimes()
+	mockOpenStackOps.EXPECT().AttachVolumeToVM(gomock.Any()).Return(nil).AnyTimes()
+	mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes()
+	mockOpenStackOps.EXPECT().FindDevice("id2").Return("/dev/sdb", nil).AnyTimes()
+	mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any()).Return(nil).AnyTimes()
+	mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any()).Return(nil).AnyTimes()
+
+	// Both disks were copied before the restart, so no full copy is done
+	mockNBD.EXPECT().CopyDisk(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	// The changed areas are queried from the checkpointed ChangeIDs, not from the new snapshot.
+	// Disks are copied in parallel, so the order only holds per disk.
+	gomock.InOrder(
+		mockVMOps.EXPECT().CustomQueryChangedDiskAreas("52", gomock.Any(), gomock.Any(), int64(0)).Return(changedAreas, nil),
+		mockVMOps.EXPECT().CustomQueryChangedDiskAreas("100", gomock.Any(), gomock.Any(), int64(0)).Return(types.DiskChangeInfo{}, nil),
+		// Final copy after powering off the source VM
+		mockVMOps.EXPECT().CustomQueryChangedDiskAreas("100", gomock.Any(), gomock.Any(), int64(0)).Return(types.DiskChangeInfo{}, nil),
+	)
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("17", gomock.Any(), gomock.Any(), int64(0)).Return(types.DiskChangeInfo{}, nil).Times(3)
+	mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), changedAreas, "/dev/sda", 0, gomock.Any()).Return(nil)
+
+	k8sClient := newFakeK8sClient()
+	migobj := Migrate{
+		VMops:            mockVMOps,
+		Nbdops:           []nbd.NBDOperations{mockNBD, mockNBD},
+		Openstackclients: mockOpenStackOps,
+		MigrationType:    "hot",
+		K8sClient:        k8sClient,
+		checkpoint: &utils.MigrationCheckpoint{
+			VMName: "test-vm",
+			Disks: []utils.DiskCheckpoint{
+				{Name: "disk1", VolumeID: "id1", ChangeID: "52", Iteration: 3, Phase: constants.CheckpointPhaseCopied},
+				{Name: "disk2", VolumeID: "id2", ChangeID: "17", Iteration: 2, Phase: constants.CheckpointPhaseCopied},
+			},
+		},
+	}
+
+	updatedVMInfo, err := migobj.LiveReplicateDisks(context.TODO(), inputvminfo)
+	assert.NoError(t, err)
+	assert.Equal(t, "100", updatedVMInfo.VMDisks[0].ChangeID)
+	assert.Equal(t, "17", updatedVMInfo.VMDisks[1].ChangeID)
+
+	// The synced ChangeID of disk1 is persisted along with the iteration it was copied in
+	checkpoint, err := utils.GetMigrationCheckpoint(context.TODO(), k8sClient)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.DiskCheckpoint{Name: "disk1", VolumeID: "id1", ChangeID: "100", Iteration: 4, Phase: constants.CheckpointPhaseCopied}, checkpoint.Disks[0])
+}
+
+// newChangedBlocksRetryTest returns a Migrate of a single disk VM whose full copy was checkpointed, so
+// LiveReplicateDisks goes straight to copying changed blocks from ChangeID 52
+func newChangedBlocksRetryTest(t *testing.T, ctrl *gomock.Controller) (*Migrate, vm.VMInfo, *vm.MockVMOperations, *nbd.MockNBDOperations) {
+	t.Setenv("VMWARE_MACHINE_OBJECT_NAME", "test-vm")
+
+	inputvminfo := vm.VMInfo{
+		Name:   "test-vm",
+		OSType: "linux",
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Size: int64(1024), Disk: &types.VirtualDisk{}, OpenstackVol: &volumes.Volume{ID: "id1"}, Snapname: "migration-snap", SnapBackingDisk: "[ds1] test_vm/test_vm.vmdk", ChangeID: "99"},
+		},
+	}
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockNBD := nbd.NewMockNBDOperations(ctrl)
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+
+	mockVMOps.EXPECT().CleanUpSnapshots(gomock.Any()).Return(nil).AnyTimes()
+	mockVMOps.EXPECT().TakeSnapshot(constants.MigrationSnapshotName).Return(nil).AnyTimes()
+	mockVMOps.EXPECT().UpdateDisksInfo(gomock.Any()).Return(nil)
+	mockVMOps.EXPECT().GetVMObj().Return(&object.VirtualMachine{}).AnyTimes()
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(&types.ManagedObjectReference{}, nil).AnyTimes()
+	mockNBD.EXPECT().StartNBDServer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	mockNBD.EXPECT().StopNBDServer().Return(nil).AnyTimes()
+	mockNBD.EXPECT().CopyDisk(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+	mockOpenStackOps.EXPECT().AttachVolumeToVM(gomock.Any()).Return(nil).AnyTimes()
+	mockOpenStackOps.EXPECT().FindDevice("id1").Return("/dev/sda", nil).AnyTimes()
+	mockOpenStackOps.EXPECT().DetachVolumeFromVM(gomock.Any()).Return(nil).AnyTimes()
+	mockOpenStackOps.EXPECT().WaitForVolume(gomock.Any()).Return(nil).AnyTimes()
+
+	checkpoint := &utils.MigrationCheckpoint{
+		VMName: "test-vm",
+		Disks: []utils.DiskCheckpoint{
+			{Name: "disk1", VolumeID: "id1", ChangeID: "52", Iteration: 3, Phase: constants.CheckpointPhaseCopied},
+		},
+	}
+	k8sClient := newFakeK8sClient()
+	assert.NoError(t, utils.SaveMigrationCheckpoint(context.TODO(), k8sClient, checkpoint))
+
+	migobj := &Migrate{
+		VMops:            mockVMOps,
+		Nbdops:           []nbd.NBDOperations{mockNBD},
+		Openstackclients: mockOpenStackOps,
+		MigrationType:    "hot",
+		K8sClient:        k8sClient,
+		checkpoint:       checkpoint,
+	}
+	return migobj, inputvminfo, mockVMOps, mockNBD
+}
+
+func TestLiveReplicateDisksRetriesFailedChangedBlocks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	migobj, inputvminfo, mockVMOps, mockNBD := newChangedBlocksRetryTest(t, ctrl)
+
+	changedAreas := types.DiskChangeInfo{
+		Length:      int64(1024),
+		ChangedArea: []types.DiskChangeExtent{{Start: int64(0), Length: int64(10)}, {Start: int64(512), Length: int64(10)}},
+	}
+	advanceChangeID := func(vminfo *vm.VMInfo, disk vm.VMDisk, _ bool) error {
+		vminfo.VMDisks[0].ChangeID = "100"
+		return nil
+	}
+	gomock.InOrder(
+		// An extent fails, so the ChangeID is kept and the same areas are copied again by the next pass
+		mockVMOps.EXPECT().CustomQueryChangedDiskAreas("52", gomock.Any(), gomock.Any(), int64(0)).Return(changedAreas, nil),
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), changedAreas, "/dev/sda", 0, gomock.Any()).
+			Return(errors.New("failed to copy 1 of 2 changed extents of disk 0: error reading from source at offset 512")),
+		mockVMOps.EXPECT().UpdateDiskInfo(gomock.Any(), gomock.Any(), false).Return(nil),
+		mockVMOps.EXPECT().CustomQueryChangedDiskAreas("52", gomock.Any(), gomock.Any(), int64(0)).Return(changedAreas, nil),
+		mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), changedAreas, "/dev/sda", 0, gomock.Any()).Return(nil),
+		mockVMOps.EXPECT().UpdateDiskInfo(gomock.Any(), gomock.Any(), true).DoAndReturn(advanceChangeID),
+		mockVMOps.EXPECT().CustomQueryChangedDiskAreas("100", gomock.Any(), gomock.Any(), int64(0)).Return(types.DiskChangeInfo{}, nil),
+		mockVMOps.EXPECT().VMPowerOff().Return(nil),
+		// Final copy after powering off the source VM
+		mockVMOps.EXPECT().CustomQueryChangedDiskAreas("100", gomock.Any(), gomock.Any(), int64(0)).Return(types.DiskChangeInfo{}, nil),
+	)
+
+	updatedVMInfo, err := migobj.LiveReplicateDisks(context.TODO(), inputvminfo)
+	assert.NoError(t, err)
+	assert.Equal(t, "100", updatedVMInfo.VMDisks[0].ChangeID)
+
+	// The checkpoint only advanced with the complete copy of the second pass
+	checkpoint, err := utils.GetMigrationCheckpoint(context.TODO(), migobj.K8sClient)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.DiskCheckpoint{Name: "disk1", VolumeID: "id1", ChangeID: "100", Iteration: 5, Phase: constants.CheckpointPhaseCopied}, checkpoint.Disks[0])
+}
+
+func TestLiveReplicateDisksFailsAfterRepeatedChangedBlocksFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	migobj, inputvminfo, mockVMOps, mockNBD := newChangedBlocksRetryTest(t, ctrl)
+
+	changedAreas := types.DiskChangeInfo{
+		Length:      int64(1024),
+		ChangedArea: []types.DiskChangeExtent{{Start: int64(0), Length: int64(10)}},
+	}
+	copyErr := errors.New("failed to copy 1 of 1 changed extents of disk 0: error reading from source at offset 0")
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("52", gomock.Any(), gomock.Any(), int64(0)).Return(changedAreas, nil).
+		Times(constants.ChangedBlocksCopyFailureLimit)
+	mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), changedAreas, "/dev/sda", 0, gomock.Any()).Return(copyErr).
+		Times(constants.ChangedBlocksCopyFailureLimit)
+	// The ChangeID is never advanced
+	mockVMOps.EXPECT().UpdateDiskInfo(gomock.Any(), gomock.Any(), false).Return(nil).
+		Times(constants.ChangedBlocksCopyFailureLimit)
+	mockVMOps.EXPECT().VMPowerOff().Times(0)
+
+	updatedVMInfo, err := migobj.LiveReplicateDisks(context.TODO(), inputvminfo)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "changed blocks of disk 0 failed to copy 3 times in a row")
+	assert.Contains(t, err.Error(), copyErr.Error())
+	assert.Equal(t, "52", updatedVMInfo.VMDisks[0].ChangeID)
+
+	checkpoint, err := utils.GetMigrationCheckpoint(context.TODO(), migobj.K8sClient)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.DiskCheckpoint{Name: "disk1", VolumeID: "id1", ChangeID: "52", Iteration: 3, Phase: constants.CheckpointPhaseCopied}, checkpoint.Disks[0])
+}
+
+func TestVerifyDisksCopiesMismatchedDataAgain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vminfo := vm.VMInfo{
+		Name: "test-vm",
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Path: "/dev/sda", Disk: &types.VirtualDisk{}, Snapname: "snapshot-2", SnapBackingDisk: "[ds1] test_vm/test_vm-000002.vmdk"},
+			{Name: "disk2", Path: "/dev/sdb", Disk: &types.VirtualDisk{}, Snapname: "snapshot-2", SnapBackingDisk: "[ds1] test_vm/test_vm_1-000002.vmdk"},
+		},
+	}
+	allocated := types.DiskChangeInfo{
+		Length:      int64(64 << 20),
+		ChangedArea: []types.DiskChangeExtent{{Start: 0, Length: int64(64 << 20)}},
+	}
+	mismatches := []types.DiskChangeExtent{{Start: int64(8 << 20), Length: int64(4 << 20)}}
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockNBD1 := nbd.NewMockNBDOperations(ctrl)
+	mockNBD2 := nbd.NewMockNBDOperations(ctrl)
+	mockVMOps.EXPECT().UpdateDisksInfo(gomock.Any()).Return(nil)
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(&types.ManagedObjectReference{}, nil)
+	mockVMOps.EXPECT().GetVMObj().Return(&object.VirtualMachine{}).AnyTimes()
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("*", gomock.Any(), gomock.Any(), int64(0)).Return(allocated, nil).Times(2)
+	for _, mockNBD := range []*nbd.MockNBDOperations{mockNBD1, mockNBD2} {
+		mockNBD.EXPECT().StopNBDServer().Return(nil)
+		mockNBD.EXPECT().StartNBDServer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "snapshot-2", gomock.Any(), gomock.Any()).Return(nil)
+	}
+	mockNBD1.EXPECT().VerifyBlocks(gomock.Any(), allocated, "/dev/sda", 0, true).Return(nil, int64(4<<20), nil)
+	// The mismatched chunk of disk2 is copied again, then verified in full
+	recopy := types.DiskChangeInfo{Length: int64(4 << 20), ChangedArea: mismatches}
+	gomock.InOrder(
+		mockNBD2.EXPECT().VerifyBlocks(gomock.Any(), allocated, "/dev/sdb", 1, true).Return(mismatches, int64(4<<20), nil),
+		mockNBD2.EXPECT().CopyChangedBlocks(gomock.Any(), recopy, "/dev/sdb", 1, gomock.Any()).Return(nil),
+		mockNBD2.EXPECT().VerifyBlocks(gomock.Any(), recopy, "/dev/sdb", 1, false).Return(nil, int64(4<<20), nil),
+	)
+
+	migobj := Migrate{
+		VMops:            mockVMOps,
+		Nbdops:           []nbd.NBDOperations{mockNBD1, mockNBD2},
+		DataVerification: vjailbreakv1alpha1.DataVerificationSampled,
+	}
+	err := migobj.VerifyDisks(context.TODO(), &vminfo, 2)
+	assert.NoError(t, err)
+}
+
+func TestVerifyDisksFailsOnPersistentMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	vminfo := vm.VMInfo{
+		Name:    "test-vm",
+		VMDisks: []vm.VMDisk{{Name: "disk1", Path: "/dev/sda", Disk: &types.VirtualDisk{}, Snapname: "snapshot-2"}},
+	}
+	allocated := types.DiskChangeInfo{Length: int64(8 << 20), ChangedArea: []types.DiskChangeExtent{{Start: 0, Length: int64(8 << 20)}}}
+	mismatches := []types.DiskChangeExtent{{Start: int64(4 << 20), Length: int64(4 << 20)}}
+
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockNBD := nbd.NewMockNBDOperations(ctrl)
+	mockVMOps.EXPECT().UpdateDisksInfo(gomock.Any()).Return(nil)
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(&types.ManagedObjectReference{}, nil)
+	mockVMOps.EXPECT().GetVMObj().Return(&object.VirtualMachine{}).AnyTimes()
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("*", gomock.Any(), gomock.Any(), int64(0)).Return(allocated, nil)
+	mockNBD.EXPECT().StopNBDServer().Return(nil)
+	mockNBD.EXPECT().StartNBDServer(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+	mockNBD.EXPECT().VerifyBlocks(gomock.Any(), allocated, "/dev/sda", 0, false).Return(mismatches, int64(8<<20), nil)
+	mockNBD.EXPECT().CopyChangedBlocks(gomock.Any(), gomock.Any(), "/dev/sda", 0, gomock.Any()).Return(nil)
+	mockNBD.EXPECT().VerifyBlocks(gomock.Any(), gomock.Any(), "/dev/sda", 0, false).Return(mismatches, int64(4<<20), nil)
+
+	migobj := Migrate{
+		VMops:            mockVMOps,
+		Nbdops:           []nbd.NBDOperations{mockNBD},
+		DataVerification: vjailbreakv1alpha1.DataVerificationFull,
+	}
+	err := migobj.VerifyDisks(context.TODO(), &vminfo, 1)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "disk 0 still has 1 mismatched chunks after copying them again")
+}
+
+func TestVerifyDisksSkippedByDefault(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No VMOperations or NBDOperations call is expected
+	migobj := Migrate{VMops: vm.NewMockVMOperations(ctrl), Nbdops: []nbd.NBDOperations{nbd.NewMockNBDOperations(ctrl)}}
+	assert.NoError(t, migobj.VerifyDisks(context.TODO(), &vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1"}}}, 1))
+	migobj.DataVerification = vjailbreakv1alpha1.DataVerificationNone
+	assert.NoError(t, migobj.VerifyDisks(context.TODO(), &vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1"}}}, 1))
+}
+
+func TestRollbackDeletesTargetAndRestoresSource(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	gomock.InOrder(
+		mockOpenStackOps.EXPECT().DeleteServer("server-1").Return(nil),
+		mockOpenStackOps.EXPECT().DeletePort("port-id-1").Return(nil),
+		mockOpenStackOps.EXPECT().DeletePort("port-id-2").Return(nil),
+		mockOpenStackOps.EXPECT().WaitForVolume("id1").Return(nil),
+		mockOpenStackOps.EXPECT().DeleteVolume("id1").Return(nil),
+		mockVMOps.EXPECT().ConnectNetworkInterfaces().Return(nil),
+		mockVMOps.EXPECT().VMPowerOn().Return(nil),
+	)
+
+	migobj := Migrate{
+		Openstackclients:          mockOpenStackOps,
+		VMops:                     mockVMOps,
+		RollbackPolicy:            vjailbreakv1alpha1.RollbackPolicyDeleteTarget,
+		targetServerID:            "server-1",
+		sourceNetworkDisconnected: true,
+	}
+	vminfo := vm.VMInfo{
+		Name:    "test-vm",
+		VMDisks: []vm.VMDisk{{Name: "disk1", OpenstackVol: &volumes.Volume{ID: "id1"}}, {Name: "rdm1"}},
+	}
+	err := migobj.Rollback(vminfo, []string{"port-id-1", "port-id-2"}, errors.New("health checks failed: Ping"))
+	assert.NoError(t, err)
+}
+
+func TestRollbackShutsOffTargetAndKeepsPlanPorts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	// The ports of the plan and the volumes of the shut off server are kept
+	mockOpenStackOps.EXPECT().DeletePort(gomock.Any()).Times(0)
+	mockOpenStackOps.EXPECT().DeleteVolume(gomock.Any()).Times(0)
+	mockVMOps.EXPECT().ConnectNetworkInterfaces().Times(0)
+	// A failed step does not stop the source VM from being powered on
+	mockOpenStackOps.EXPECT().StopServer("server-1").Return(errors.New("server is locked"))
+	mockVMOps.EXPECT().VMPowerOn().Return(nil)
+
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		VMops:            mockVMOps,
+		Networkports:     []string{"port-id-1"},
+		RollbackPolicy:   vjailbreakv1alpha1.RollbackPolicyShutOffTarget,
+		targetServerID:   "server-1",
+	}
+	vminfo := vm.VMInfo{Name: "test-vm", VMDisks: []vm.VMDisk{{Name: "disk1", OpenstackVol: &volumes.Volume{ID: "id1"}}}}
+	err := migobj.Rollback(vminfo, []string{"port-id-1"}, errors.New("health checks failed: HTTP Get"))
+	assert.EqualError(t, err, "rollback after health checks failed: HTTP Get is incomplete: failed to shut off server server-1: server is locked")
+}
+
+func TestConfigureGuestNetwork(t *testing.T) {
+	guest := guestnetwork.DirFS(t.TempDir())
+	migobj := Migrate{}
+	vminfo := vm.VMInfo{
+		Mac: []string{"00:50:56:aa:bb:01", "00:50:56:aa:bb:02"},
+		GuestNetworks: []vjailbreakv1alpha1.GuestNetwork{
+			{MAC: "00:50:56:aa:bb:01", IP: "10.0.0.5", Origin: "manual", PrefixLength: 24, Gateway: "10.0.0.1", DNS: []string{"10.0.0.2"}},
+			{MAC: "00:50:56:aa:bb:02", IP: "10.0.1.5", Origin: "manual", PrefixLength: 24, Gateway: "10.0.1.1"},
+		},
+	}
+	// The port of the second NIC got another address, so that NIC uses DHCP
+	err := migobj.ConfigureGuestNetwork(guest, `NAME="Ubuntu"`+"\nVERSION_ID=\"24.04\"", vminfo, []string{"10.0.0.5", "10.0.1.99"})
+	assert.NoError(t, err)
+	netplan, err := guest.ReadFile("/etc/netplan/50-vjailbreak.yaml")
+	assert.NoError(t, err)
+	assert.Contains(t, string(netplan), "      addresses:\n        - 10.0.0.5/24\n")
+	assert.Contains(t, string(netplan), "        macaddress: \"00:50:56:aa:bb:02\"\n      set-name: eth1\n      dhcp4: true\n")
+
+	// Guests without a known network stack are left alone
+	assert.NoError(t, migobj.ConfigureGuestNetwork(guestnetwork.DirFS(t.TempDir()), `NAME="Arch Linux"`, vminfo, nil))
+}
+
+func TestConfigureWindowsNetwork(t *testing.T) {
+	guest := guestnetwork.DirFS(t.TempDir())
+	migobj := Migrate{RemoveVMwareTools: true}
+	vminfo := vm.VMInfo{
+		Mac: []string{"00:50:56:aa:bb:01"},
+		GuestNetworks: []vjailbreakv1alpha1.GuestNetwork{
+			{MAC: "00:50:56:aa:bb:01", IP: "10.0.0.5", Origin: "manual", PrefixLength: 24, Gateway: "10.0.0.1"},
+		},
+	}
+	err := migobj.ConfigureWindowsNetwork(guest, vminfo, []string{"10.0.0.5"})
+	assert.NoError(t, err)
+	script, err := guest.ReadFile("/vjailbreak/network.ps1")
+	assert.NoError(t, err)
+	assert.Contains(t, string(script), "\"ip\": \"10.0.0.5\",\r\n")
+	assert.Contains(t, string(script), "\"removeVMwareTools\": true\r\n")
+}
+
+func TestWaitForWindowsFirstBoot(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().GetConsoleOutput("server-1", gomock.Any()).
+		Return("Booting from Hard Disk...\r\n"+guestnetwork.WindowsResultMarker+`{"adapters":[{"mac":"00:50:56:aa:bb:01","status":"Static"}]}`+"\r\n", nil).
+		Times(1)
+
+	migobj := Migrate{Openstackclients: mockOpenStackOps, targetServerID: "server-1"}
+	migobj.WaitForWindowsFirstBoot(context.Background())
+}
+
+func TestCheckOSSupport(t *testing.T) {
+	linux := vm.VMInfo{OSType: "linuxGuest"}
+	migobj := Migrate{}
+	steps, err := migobj.CheckOSSupport(linux, "name=\"ubuntu\"\nversion_id=\"22.04\"\nid=ubuntu\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{osmatrix.StepGuestNetwork}, steps)
+
+	_, err = migobj.CheckOSSupport(linux, "name=\"ubuntu\"\nversion_id=\"14.04\"\nid=ubuntu\n")
+	assert.EqualError(t, err, "unsupported OS: Ubuntu 14.04 is older than the oldest supported version 16.04")
+
+	// The migration of unsupported guests can be forced
+	migobj.AllowUnsupportedOS = true
+	steps, err = migobj.CheckOSSupport(linux, "name=\"vmware photon os\"\nversion_id=5.0\nid=photon\n")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{osmatrix.StepGuestNetwork}, steps)
+
+	// Windows guests are identified by the OS name VMware reports
+	migobj = Migrate{}
+	steps, err = migobj.CheckOSSupport(vm.VMInfo{OSType: "windowsGuest", OSName: "Microsoft Windows Server 2008 R2 (64-bit)"}, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{osmatrix.StepNTFSFix}, steps)
+}
+
+func TestInspectGuest(t *testing.T) {
+	t.Setenv("VMWARE_MACHINE_OBJECT_NAME", "test-vm")
+	scheme := runtime.NewScheme()
+	assert.NoError(t, vjailbreakv1alpha1.AddToScheme(scheme))
+	vmwareMachine := &vjailbreakv1alpha1.VMwareMachine{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-vm", Namespace: constants.NamespaceMigrationSystem},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(vmwareMachine).WithStatusSubresource(vmwareMachine).Build()
+
+	var run inspect.Runner = func(script string) (string, error) {
+		if script == "inspect-get-roots\n" {
+			return "/dev/sda1\n", nil
+		}
+		return "@@type\nlinux\n@@distro\nubuntu\n@@product\nUbuntu 22.04.4 LTS\n@@major\n22\n@@minor\n4\n", nil
+	}
+	migobj := Migrate{K8sClient: k8sClient}
+	_, err := migobj.InspectGuest(context.TODO(), run, vm.VMInfo{UEFI: true})
+	assert.NoError(t, err)
+
+	assert.NoError(t, k8sClient.Get(context.TODO(), client.ObjectKeyFromObject(vmwareMachine), vmwareMachine))
+	inspection := vmwareMachine.Status.GuestInspection
+	if assert.NotNil(t, inspection) {
+		assert.Equal(t, "Ubuntu 22.04.4 LTS", inspection.OSName)
+		assert.Equal(t, "22.4", inspection.OSVersion)
+		assert.Equal(t, vjailbreakv1alpha1.GuestFirmwareUEFI, inspection.Firmware)
+	}
+}
+
+func TestCopyDisksInParallel(t *testing.T) {
+	var inFlight, maxInFlight int32
+	copied := make([]bool, 6)
+
+	err := copyDisksInParallel(2, len(copied), func(idx int) error {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxInFlight)
+			if current <= seen || atomic.CompareAndSwapInt32(&maxInFlight, seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		copied[idx] = true
+		if idx == 1 || idx == 4 {
+			return errors.New("nbdcopy exited with status 1")
+		}
+		return nil
+	})
+
+	// Failing disks do not stop the rest of the disks from being copied
+	assert.Equal(t, []bool{true, true, true, true, true, true}, copied)
+	assert.LessOrEqual(t, maxInFlight, int32(2))
+	assert.EqualError(t, err, "2 of 6 disks failed: disk 1: nbdcopy exited with status 1; disk 4: nbdcopy exited with status 1")
+
+	// A limit below one copies the disks one at a time
+	maxInFlight = 0
+	err = copyDisksInParallel(0, len(copied), func(idx int) error {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		if current > atomic.LoadInt32(&maxInFlight) {
+			atomic.StoreInt32(&maxInFlight, current)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), maxInFlight)
+}
+
+// fileDiskTarget stages the disks as files and commits them as volumes named after the file
+type fileDiskTarget struct {
+	dir       string
+	committed []string
+}
+
+func (target *fileDiskTarget) Open(disk vm.VMDisk) (string, error) {
+	path := filepath.Join(target.dir, disk.Name)
+	return path, os.WriteFile(path, make([]byte, disk.Size), 0644)
+}
+
+func (target *fileDiskTarget) Close(disk vm.VMDisk) error {
+	return nil
+}
+
+func (target *fileDiskTarget) Commit(vminfo vm.VMInfo, disk vm.VMDisk, volumetype string) (*volumes.Volume, error) {
+	target.committed = append(target.committed, disk.Name+":"+volumetype)
+	return &volumes.Volume{ID: vminfo.Name + "-" + disk.Name, VolumeType: volumetype}, nil
+}
+
+func (target *fileDiskTarget) Discard(disk vm.VMDisk) error {
+	return os.Remove(filepath.Join(target.dir, disk.Name))
+}
+
+func TestStagedDiskTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Staged disks are neither attached nor detached
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	target := &fileDiskTarget{dir: t.TempDir()}
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		Volumetypes:      []string{"ssd", "hdd"},
+		DiskTarget:       target,
+	}
+	vminfo := vm.VMInfo{
+		Name: "test-vm",
+		VMDisks: []vm.VMDisk{
+			{Name: "disk1", Size: 1024},
+			{Name: "disk2", Size: 2048},
+		},
+	}
+
+	for idx, vmdisk := range vminfo.VMDisks {
+		path, err := migobj.AttachVolume(vmdisk)
+		assert.NoError(t, err)
+		assert.FileExists(t, path)
+		vminfo.VMDisks[idx].Path = path
+	}
+	assert.NoError(t, migobj.DetachAllVolumes(vminfo))
+
+	assert.NoError(t, migobj.CommitVolumes(vminfo))
+	assert.Equal(t, []string{"disk1:ssd", "disk2:hdd"}, target.committed)
+	assert.Equal(t, "test-vm-disk1", vminfo.VMDisks[0].OpenstackVol.ID)
+	assert.Equal(t, "test-vm-disk2", vminfo.VMDisks[1].OpenstackVol.ID)
+
+	assert.NoError(t, migobj.DeleteAllVolumes(vminfo))
+	assert.NoFileExists(t, vminfo.VMDisks[0].Path)
+	assert.NoFileExists(t, vminfo.VMDisks[1].Path)
+}
+
+func TestCommitVolumesKeepsAttachedVolumes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	migobj := Migrate{Openstackclients: openstack.NewMockOpenstackOperations(ctrl)}
+	vminfo := vm.VMInfo{VMDisks: []vm.VMDisk{{Name: "disk1", OpenstackVol: &volumes.Volume{ID: "id1"}}}}
+
+	assert.NoError(t, migobj.CommitVolumes(vminfo))
+	assert.Equal(t, "id1", vminfo.VMDisks[0].OpenstackVol.ID)
+}
+
+type fakeRBDMapper struct{}
+
+func (fakeRBDMapper) Map(image rbd.Image) (string, error) {
+	return "/dev/nbd0", nil
+}
+
+func (fakeRBDMapper) Unmap(device string) error {
+	return nil
+}
+
+func TestCopyPathWritesToRBD(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOpenStackOps := openstack.NewMockOpenstackOperations(ctrl)
+	mockOpenStackOps.EXPECT().GetVolumeConnection("id1").Return(map[string]interface{}{
+		"driver_volume_type": "rbd",
+		"data": map[string]interface{}{
+			"name":          "volumes/volume-id1",
+			"auth_username": "cinder",
+			"hosts":         []interface{}{"10.0.0.1"},
+			"ports":         []interface{}{"6789"},
+		},
+	}, nil)
+	migobj := Migrate{
+		Openstackclients: mockOpenStackOps,
+		RBDMapper:        fakeRBDMapper{},
+	}
+	disk := vm.VMDisk{Name: "disk1", OpenstackVol: &volumes.Volume{ID: "id1"}}
+
+	// The volume is not attached for the copy, so there is nothing to detach afterwards
+	path, err := migobj.copyPath(disk)
+	assert.NoError(t, err)
+	image, err := rbd.ParsePath(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "volumes/volume-id1", image.Spec())
+	assert.IsType(t, &rbd.Opener{}, migobj.copyTarget())
+	assert.NoError(t, migobj.DetachAllVolumes(vm.VMInfo{VMDisks: []vm.VMDisk{disk}}))
+
+	// Without a mapper the volumes are attached
+	migobj = Migrate{Openstackclients: mockOpenStackOps}
+	assert.Nil(t, migobj.copyTarget())
+}
+
+func TestFullCopy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	allocated := []types.DiskChangeExtent{{Start: 0, Length: 512}, {Start: 4096, Length: 1024}}
+	snapshot := &types.ManagedObjectReference{Value: "snapshot-1"}
+	mockVMOps := vm.NewMockVMOperations(ctrl)
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(snapshot, nil).Times(2)
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("*", snapshot, gomock.Any(), int64(0)).Return(types.DiskChangeInfo{ChangedArea: allocated}, nil).Times(2)
+
+	migobj := Migrate{
+		Volumetypes:       []string{"ceph", "lvm"},
+		ZeroedVolumeTypes: []string{"ceph"},
+	}
+	disk := vm.VMDisk{Name: "disk1", Disk: &types.VirtualDisk{}}
+
+	// Only the volumes of the zeroed volume types are skipped instead of zero filled
+	assert.Equal(t, nbd.FullCopy{Allocated: allocated, TargetIsZero: true}, migobj.fullCopy(mockVMOps, 0, disk))
+	assert.Equal(t, nbd.FullCopy{Allocated: allocated}, migobj.fullCopy(mockVMOps, 1, disk))
+
+	// Without the allocated areas the whole disk is copied
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(snapshot, nil)
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("*", snapshot, gomock.Any(), int64(0)).Return(types.DiskChangeInfo{}, errors.New("CBT is not enabled"))
+	assert.Equal(t, nbd.FullCopy{TargetIsZero: true}, migobj.fullCopy(mockVMOps, 0, disk))
+
+	// An unallocated disk has no areas to copy, which is not the same as not knowing them
+	mockVMOps.EXPECT().GetSnapshot(constants.MigrationSnapshotName).Return(snapshot, nil)
+	mockVMOps.EXPECT().CustomQueryChangedDiskAreas("*", snapshot, gomock.Any(), int64(0)).Return(types.DiskChangeInfo{}, nil)
+	assert.Equal(t, nbd.FullCopy{Allocated: []types.DiskChangeExtent{}}, migobj.fullCopy(mockVMOps, 1, disk))
+}
+
+func TestWaitForNextWarmSync(t *testing.T) {
+	// The next sync is due once the interval has passed since the previous one started
+	migobj := Migrate{
+		WarmSyncInterval: 20 * time.Millisecond,
+		MigrationTimes:   MigrationTimes{VMCutoverStart: time.Now().Add(time.Hour)},
+	}
+	cutover, err := migobj.waitForNextWarmSync(context.Background(), time.Now(), false)
+	assert.NoError(t, err)
+	assert.False(t, cutover)
+
+	// The cutover is due at the cutover start time, even if the next sync is not
+	migobj = Migrate{
+		WarmSyncInterval: time.Hour,
+		MigrationTimes:   MigrationTimes{VMCutoverStart: time.Now().Add(20 * time.Millisecond)},
+	}
+	cutover, err = migobj.waitForNextWarmSync(context.Background(), time.Now(), false)
+	assert.NoError(t, err)
+	assert.True(t, cutover)
+
+	// An admin initiated cutover is due once the admin triggers it
+	labels := make(chan string, 2)
+	labels <- "no"
+	labels <- "yes"
+	migobj = Migrate{WarmSyncInterval: time.Hour, PodLabelWatcher: labels}
+	cutover, err = migobj.waitForNextWarmSync(context.Background(), time.Now(), true)
+	assert.NoError(t, err)
+	assert.True(t, cutover)
+
+	// A cutover past the end of the cutover window fails
+	migobj = Migrate{
+		WarmSyncInterval: time.Hour,
+		MigrationTimes:   MigrationTimes{VMCutoverEnd: time.Now().Add(-time.Minute)},
+	}
+	_, err = migobj.waitForNextWarmSync(context.Background(), time.Now(), false)
+	assert.Error(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	migobj = Migrate{WarmSyncInterval: time.Hour}
+	_, err = migobj.waitForNextWarmSync(ctx, time.Now(), true)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestWaitForCutoverWindow(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, vjailbreakv1alpha1.AddToScheme(scheme))
+	now := time.Now().UTC()
+	newWindow := func(name, schedule string) *vjailbreakv1alpha1.MaintenanceWindow {
+		return &vjailbreakv1alpha1.MaintenanceWindow{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: constants.NamespaceMigrationSystem},
+			Spec: vjailbreakv1alpha1.MaintenanceWindowSpec{
+				Windows: []vjailbreakv1alpha1.MaintenanceWindowSchedule{
+					{Schedule: schedule, Duration: metav1.Duration{Duration: time.Hour}},
+				},
+			},
+		}
+	}
+	// The closed window opens every day twelve hours from now
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newWindow("open", "* * * * *"),
+		newWindow("closed", fmt.Sprintf("0 %d * * *", (now.Hour()+12)%24)),
+	).Build()
+
+	// Without a maintenance window the cutover may start until the VM cutover end time
+	migobj := Migrate{K8sClient: k8sClient}
+	open, err := migobj.waitForCutoverWindow(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, open)
+
+	migobj = Migrate{K8sClient: k8sClient, MaintenanceWindow: "open"}
+	open, err = migobj.waitForCutoverWindow(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, open)
+
+	// The cutover waits for the window to open
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	migobj = Migrate{K8sClient: k8sClient, MaintenanceWindow: "closed"}
+	_, err = migobj.waitForCutoverWindow(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// A window opening after the VM cutover end time fails the cutover
+	migobj = Migrate{
+		K8sClient:         k8sClient,
+		MaintenanceWindow: "closed",
+		MigrationTimes:    MigrationTimes{VMCutoverEnd: now.Add(6 * time.Hour)},
+	}
+	_, err = migobj.waitForCutoverWindow(context.Background())
+	assert.ErrorContains(t, err, "after the VM Cutover End time")
+
+	migobj = Migrate{K8sClient: k8sClient, MaintenanceWindow: "missing"}
+	_, err = migobj.waitForCutoverWindow(context.Background())
+	assert.Error(t, err)
+
+	// The window is read from the namespace of the migration plan
+	migobj = Migrate{K8sClient: k8sClient, MaintenanceWindow: "open", PlanNamespace: "team-a"}
+	_, err = migobj.waitForCutoverWindow(context.Background())
+	assert.Error(t, err)
+	window := newWindow("open", "* * * * *")
+	window.Namespace = "team-a"
+	assert.NoError(t, k8sClient.Create(context.Background(), window))
+	open, err = migobj.waitForCutoverWindow(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, open)
+
+	// The warm syncs go on while the cutover waits for the window
+	migobj = Migrate{K8sClient: k8sClient, MaintenanceWindow: "closed", WarmSyncInterval: 20 * time.Millisecond}
+	cutover, err := migobj.waitForNextWarmSync(context.Background(), time.Now(), false)
+	assert.NoError(t, err)
+	assert.False(t, cutover)
+}
@@ -4,21 +4,21 @@ package nbd
 
 import (
 	"bufio"
-	"bytes"
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"math"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"sync"
-	"syscall"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/throttle"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
 
-	"golang.org/x/sys/unix"
-
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/vim25/types"
 	"libguestfs.org/libnbd"
@@ -26,17 +26,37 @@ import (
 
 //go:generate mockgen -source=../nbd/nbdops.go -destination=../nbd/nbdops_mock.go -package=nbd
 
+// ProgressFunc is called during a copy with the fraction of the data copied so far, between 0 and 1
+type ProgressFunc func(fraction float64)
+
 type NBDOperations interface {
 	StartNBDServer(vm *object.VirtualMachine, server, username, password, thumbprint, snapref, file string, progchan chan string) error
 	StopNBDServer() error
-	CopyDisk(ctx context.Context, dest string, diskindex int) error
-	CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string) error
+	CopyDisk(ctx context.Context, dest string, diskindex int, full FullCopy, progress ProgressFunc) (int64, error)
+	CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string, diskindex int, progress ProgressFunc) error
+	VerifyBlocks(ctx context.Context, areas types.DiskChangeInfo, path string, diskindex int, sampled bool) ([]types.DiskChangeExtent, int64, error)
+	GuestfishURI() string
 }
 
 type NBDServer struct {
 	cmd          *exec.Cmd
 	tmp_dir      string
 	progresschan chan string
+	// Throttle limits the bandwidth of the copies from this server, nil means unlimited
+	Throttle *throttle.Throttle
+	// Target opens the destinations of the copies. When nil, the destinations are local files or devices
+	// and the full copy is done by nbdcopy
+	Target TargetOpener
+}
+
+// FullCopy is what is known about the source and the destination of a full copy
+type FullCopy struct {
+	// Allocated are the areas of the disk holding data, as returned by QueryChangedDiskAreas with change ID "*".
+	// When nil, the whole disk is read through base:allocation unless nbdcopy does the copy
+	Allocated []types.DiskChangeExtent
+	// TargetIsZero tells that the destination reads back as zeroes, so the unallocated and zero areas of the
+	// disk are skipped instead of zero filled
+	TargetIsZero bool
 }
 
 type BlockStatusData struct {
@@ -47,6 +67,21 @@ type BlockStatusData struct {
 
 const MaxChunkSize = 64 * 1024 * 1024
 
+// MaxExtentCopyAttempts is the number of times a block of a changed extent is copied before the extent is reported as failed
+const MaxExtentCopyAttempts = 4
+
+// ExtentCopyRetryBackoff is the wait before the first retry of a block, it doubles on every retry
+const ExtentCopyRetryBackoff = 2 * time.Second
+
+// ProgressReportInterval limits how often the progress of a copy of areas is logged and sent as an event
+const ProgressReportInterval = 10 * time.Second
+
+// VerifyChunkSize is the size of the chunks whose checksums are compared by VerifyBlocks
+const VerifyChunkSize = 4 << 20
+
+// VerifySampleInterval is the interval between the chunks compared by a sampled verification
+const VerifySampleInterval = 16
+
 // MaxBlockStatusLength limits the maximum block status request size to 2GB
 const MaxBlockStatusLength = (2 << 30)
 
@@ -92,8 +127,7 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 	socket := fmt.Sprintf("%s/nbdkit.sock", tmp_dir)
 	pidFile := fmt.Sprintf("%s/nbdkit.pid", tmp_dir)
 
-	cmd := exec.Command(
-		"nbdkit",
+	args := []string{
 		"--exit-with-parent",
 		"--readonly",
 		"--foreground",
@@ -102,6 +136,16 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 		"--verbose",
 		"-D vddk.datapath=0",
 		"-D nbdkit.backend.datapath=0",
+	}
+	// The rate filter limits the reads of both nbdcopy and libnbd, the Throttle updates its rate file
+	rateFile := nbdserver.Throttle.RateFile(tmp_dir)
+	if rateFile != "" {
+		if err := os.WriteFile(rateFile, []byte("0"), 0644); err != nil {
+			return fmt.Errorf("failed to create rate file: %v", err)
+		}
+		args = append(args, "--filter=rate")
+	}
+	args = append(args,
 		"vddk",
 		"libdir=/home/fedora/vmware-vix-disklib-distrib",
 		fmt.Sprintf("server=%s", server),
@@ -115,6 +159,10 @@ vixDiskLib.nfcAio.Session.BufCount=4`
 		fmt.Sprintf("snapshot=%s", snapref),
 		file,
 	)
+	if rateFile != "" {
+		args = append(args, fmt.Sprintf("rate-file=%s", rateFile))
+	}
+	cmd := exec.Command("nbdkit", args...)
 
 	// Log the command
 	cmdstring := ""
@@ -151,16 +199,28 @@ func (nbdserver *NBDServer) StopNBDServer() error {
 	return nil
 }
 
-func (nbdserver *NBDServer) CopyDisk(ctx context.Context, dest string, diskindex int) error {
+// CopyDisk copies the whole disk to dest and returns the number of bytes it did not read from the source.
+// nbdcopy does the copy when neither the allocated areas nor Target are known, and then nothing is reported
+// as skipped
+func (nbdserver *NBDServer) CopyDisk(ctx context.Context, dest string, diskindex int, full FullCopy, progress ProgressFunc) (int64, error) {
+	if nbdserver.Target != nil || full.Allocated != nil {
+		return nbdserver.copyAllocatedAreas(ctx, dest, diskindex, full, progress)
+	}
+	defer nbdserver.Throttle.Start(nbdserver.Throttle.RateFile(nbdserver.tmp_dir))()
+
 	// Copy the disk from source to destination
 	progressRead, progressWrite, err := os.Pipe()
 	if err != nil {
-		return errors.Wrapf(err, "failed to create pipe")
+		return 0, errors.Wrapf(err, "failed to create pipe")
 	}
 	defer progressRead.Close()
 	defer progressWrite.Close()
 
-	cmd := exec.CommandContext(ctx, "nbdcopy", "--progress=3", "--target-is-zero", generateSockUrl(nbdserver.tmp_dir), dest)
+	args := []string{"--progress=3"}
+	if full.TargetIsZero {
+		args = append(args, "--target-is-zero")
+	}
+	cmd := exec.CommandContext(ctx, "nbdcopy", append(args, generateSockUrl(nbdserver.tmp_dir), dest)...)
 	cmd.ExtraFiles = []*os.File{progressWrite}
 
 	utils.PrintLog(fmt.Sprintf("Executing %s\n", cmd.String()))
@@ -168,11 +228,14 @@ func (nbdserver *NBDServer) CopyDisk(ctx context.Context, dest string, diskindex
 		scanner := bufio.NewScanner(progressRead)
 		lastProgress := 0
 		for scanner.Scan() {
-			progressInt, _, err := utils.ParseFraction(scanner.Text())
+			progressInt, progressTotal, err := utils.ParseFraction(scanner.Text())
 			if err != nil {
 				utils.PrintLog(fmt.Sprintf("Error converting progress percent to int: %v", err))
 				continue
 			}
+			if progress != nil && progressTotal > 0 {
+				progress(float64(progressInt) / float64(progressTotal))
+			}
 			msg := fmt.Sprintf("Copying disk %d, Completed: %d%%", diskindex, progressInt)
 			utils.PrintLog(msg)
 
@@ -190,10 +253,105 @@ func (nbdserver *NBDServer) CopyDisk(ctx context.Context, dest string, diskindex
 		cmd.Stderr = os.Stderr
 		err = cmd.Run()
 		if err != nil {
-			return errors.Wrapf(err, "failed to run nbdcopy")
+			return 0, errors.Wrapf(err, "failed to run nbdcopy")
 		}
 	}
-	return nil
+	return 0, nil
+}
+
+// copyAllocatedAreas copies the allocated areas of the disk, in chunks of MaxChunkSize, and zero fills the
+// rest of the destination unless it is already zero. It returns the number of bytes not read from the source
+func (nbdserver *NBDServer) copyAllocatedAreas(ctx context.Context, dest string, diskindex int, full FullCopy, progress ProgressFunc) (int64, error) {
+	handle, err := nbdserver.connect()
+	if err != nil {
+		return 0, err
+	}
+	defer handle.Close()
+
+	disksize, err := handle.GetSize()
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to get size of disk %d", diskindex)
+	}
+	size := int64(disksize)
+	target, err := nbdserver.openTarget(dest)
+	if err != nil {
+		return 0, err
+	}
+	defer target.Close()
+	defer nbdserver.Throttle.Start(nbdserver.Throttle.RateFile(nbdserver.tmp_dir))()
+
+	allocated := []types.DiskChangeExtent{{Start: 0, Length: size}}
+	if full.Allocated != nil {
+		allocated = clipAreas(full.Allocated, size)
+	}
+	if !full.TargetIsZero {
+		for _, gap := range areaGaps(allocated, size) {
+			if err := target.Zero(gap.Start, gap.Length); err != nil {
+				return 0, errors.Wrapf(err, "failed to zero unallocated range at offset %d of disk %d", gap.Start, diskindex)
+			}
+		}
+	}
+
+	utils.PrintLog(fmt.Sprintf("Copying %d allocated areas of disk %d (%d bytes) to %s", len(allocated), diskindex, size, dest))
+	copied, err := nbdserver.copyAreas(ctx, chunkAreas(allocated), target, handle, diskindex, full.TargetIsZero, progress)
+	if err != nil {
+		return 0, err
+	}
+	return size - copied, nil
+}
+
+// clipAreas sorts the areas and cuts them to a disk of size bytes, merging the ones that overlap
+func clipAreas(areas []types.DiskChangeExtent, size int64) []types.DiskChangeExtent {
+	sorted := append([]types.DiskChangeExtent(nil), areas...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	clipped := []types.DiskChangeExtent{}
+	for _, area := range sorted {
+		start, end := max(area.Start, 0), min(area.Start+area.Length, size)
+		if start >= end {
+			continue
+		}
+		if last := len(clipped) - 1; last >= 0 && start <= clipped[last].Start+clipped[last].Length {
+			clipped[last].Length = max(clipped[last].Length, end-clipped[last].Start)
+			continue
+		}
+		clipped = append(clipped, types.DiskChangeExtent{Start: start, Length: end - start})
+	}
+	return clipped
+}
+
+// areaGaps returns the ranges of a disk of size bytes not covered by the sorted, disjoint areas
+func areaGaps(areas []types.DiskChangeExtent, size int64) []types.DiskChangeExtent {
+	var gaps []types.DiskChangeExtent
+	offset := int64(0)
+	for _, area := range areas {
+		if area.Start > offset {
+			gaps = append(gaps, types.DiskChangeExtent{Start: offset, Length: area.Start - offset})
+		}
+		offset = max(offset, area.Start+area.Length)
+	}
+	if offset < size {
+		gaps = append(gaps, types.DiskChangeExtent{Start: offset, Length: size - offset})
+	}
+	return gaps
+}
+
+// chunkAreas splits the areas into chunks of at most MaxChunkSize bytes
+func chunkAreas(areas []types.DiskChangeExtent) []types.DiskChangeExtent {
+	var chunks []types.DiskChangeExtent
+	for _, area := range areas {
+		for offset := area.Start; offset < area.Start+area.Length; offset += MaxChunkSize {
+			chunks = append(chunks, types.DiskChangeExtent{Start: offset, Length: min(MaxChunkSize, area.Start+area.Length-offset)})
+		}
+	}
+	return chunks
+}
+
+// openTarget opens the destination at path with Target, or as a local file when Target is not set
+func (nbdserver *NBDServer) openTarget(path string) (TargetWriter, error) {
+	if nbdserver.Target == nil {
+		return OpenFileTarget(path)
+	}
+	return nbdserver.Target.Open(path)
 }
 
 func getBlockStatus(handle *libnbd.Libnbd, extent types.DiskChangeExtent) []*BlockStatusData {
@@ -288,152 +446,304 @@ func getBlockStatus(handle *libnbd.Libnbd, extent types.DiskChangeExtent) []*Blo
 	return blocks
 }
 
-// pwrite writes the given byte buffer to the sink at the given offset
-func pwrite(fd *os.File, buffer []byte, offset uint64) (int, error) {
-	blocksize := len(buffer)
-	written, err := syscall.Pwrite(int(fd.Fd()), buffer, int64(offset))
-	if err != nil {
-		return -1, errors.Wrapf(err, "failed to write %d bytes at offset %d", blocksize, offset)
-	}
-	if written < blocksize {
-		utils.PrintLog(fmt.Sprintf("Wrote less than blocksize (%d): %d", blocksize, written))
-	}
-	return written, nil
-}
-
-// zeroRange fills the destination range with zero bytes
-func zeroRange(fd *os.File, offset int64, length int64) error {
-	punch := func(offset int64, length int64) error {
-		utils.PrintLog(fmt.Sprintf("Punching %d-byte hole at offset %d", length, offset))
-		flags := uint32(unix.FALLOC_FL_PUNCH_HOLE | unix.FALLOC_FL_KEEP_SIZE)
-		return syscall.Fallocate(int(fd.Fd()), flags, offset, length)
-	}
-
-	err := punch(offset, length)
-	if err != nil {
-		// Fall back to regular pwrite if punch fails
-		utils.PrintLog(fmt.Sprintf("Failed to punch hole at offset %d, falling back to pwrite: %v", offset, err))
-		utils.PrintLog(fmt.Sprintf("Unable to zero range %d - %d on destination, falling back to pwrite: %v", offset, offset+length, err))
-		count := int64(0)
-		const blocksize = 16 << 20
-		buffer := bytes.Repeat([]byte{0}, blocksize)
-		for count < length {
-			remaining := length - count
-			if remaining < blocksize {
-				buffer = bytes.Repeat([]byte{0}, int(remaining))
-			}
-			written, err := pwrite(fd, buffer, uint64(offset))
-			if err != nil {
-				utils.PrintLog(fmt.Sprintf("Unable to write %d zeroes at offset %d: %v", length, offset, err))
-				break
-			}
-			count += int64(written)
+// copyRange copies a block from the source to the target through buffer and returns the number of bytes read
+// from the source. Zero blocks are not read, they are zero filled on the target unless targetIsZero is set
+func copyRange(target TargetWriter, handle *libnbd.Libnbd, block *BlockStatusData, targetIsZero bool, buffer []byte) (int64, error) {
+	if (block.Flags & libnbd.STATE_ZERO) != 0 {
+		if targetIsZero {
+			return 0, nil
 		}
-	}
-
-	return nil
-}
-
-func copyRange(fd *os.File, handle *libnbd.Libnbd, block *BlockStatusData) error {
-	if (block.Flags & (libnbd.STATE_ZERO | libnbd.STATE_HOLE)) != 0 {
-		err := zeroRange(fd, block.Offset, block.Length)
-		if err != nil {
-			return fmt.Errorf("failed to zero range at offset %d: %v", block.Offset, err)
+		if err := target.Zero(block.Offset, block.Length); err != nil {
+			return 0, fmt.Errorf("failed to zero range at offset %d: %v", block.Offset, err)
 		}
+		return 0, nil
 	}
 
-	buffer := bytes.Repeat([]byte{0}, MaxPreadLength)
 	count := int64(0)
 	for count < block.Length {
-		if block.Length-count < int64(MaxPreadLength) {
-			buffer = bytes.Repeat([]byte{0}, int(block.Length-count))
-		}
-		length := len(buffer)
+		data := buffer[:min(int64(len(buffer)), block.Length-count)]
+		length := len(data)
 
 		offset := block.Offset + count
-		err := handle.Pread(buffer, uint64(offset), nil)
+		err := handle.Pread(data, uint64(offset), nil)
 		if err != nil {
-			return fmt.Errorf("error reading from source at offset %d: %v", offset, err)
+			return count, fmt.Errorf("error reading from source at offset %d: %v", offset, err)
 		}
 
-		_, err = pwrite(fd, buffer, uint64(offset))
+		_, err = target.WriteAt(data, offset)
 		if err != nil {
-			return fmt.Errorf("failed to write data block at offset %d to local file: %v", block.Offset, err)
+			return count, fmt.Errorf("failed to write data block at offset %d to local file: %v", block.Offset, err)
 		}
 		count += int64(length)
 	}
-	return nil
+	return count, nil
 }
 
-func (nbdserver *NBDServer) CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string) error {
-	// Copy the changed blocks from source to destination
+// connect returns a libnbd handle connected to the nbdkit server
+func (nbdserver *NBDServer) connect() (*libnbd.Libnbd, error) {
 	handle, err := libnbd.Create()
 	if err != nil {
-		return fmt.Errorf("failed to create libnbd handle: %v", err)
+		return nil, fmt.Errorf("failed to create libnbd handle: %v", err)
 	}
 	err = handle.AddMetaContext("base:allocation")
 	if err != nil {
-		return fmt.Errorf("failed to add meta context: %v", err)
+		handle.Close()
+		return nil, fmt.Errorf("failed to add meta context: %v", err)
 	}
 	err = handle.ConnectUri(generateSockUrl(nbdserver.tmp_dir))
 	if err != nil {
-		return fmt.Errorf("failed to connect to source: %v", err)
+		handle.Close()
+		return nil, fmt.Errorf("failed to connect to source: %v", err)
+	}
+	return handle, nil
+}
+
+func (nbdserver *NBDServer) CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string, diskindex int, progress ProgressFunc) error {
+	// Copy the changed blocks from source to destination
+	handle, err := nbdserver.connect()
+	if err != nil {
+		return err
 	}
 	defer handle.Close()
 
-	fd, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	target, err := nbdserver.openTarget(path)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %v", err)
+		return err
 	}
+	defer target.Close()
+	defer nbdserver.Throttle.Start(nbdserver.Throttle.RateFile(nbdserver.tmp_dir))()
 
-	defer fd.Close()
+	_, err = nbdserver.copyAreas(ctx, changedAreas.ChangedArea, target, handle, diskindex, false, progress)
+	return err
+}
 
+// copyAreas copies the areas of the disk from the source to the target, up to 16 areas at a time, and returns
+// the number of bytes read from the source. Zero blocks are skipped when targetIsZero is set
+func (nbdserver *NBDServer) copyAreas(ctx context.Context, areas []types.DiskChangeExtent, target TargetWriter, handle *libnbd.Libnbd, diskindex int, targetIsZero bool, progress ProgressFunc) (int64, error) {
 	totalsize := int64(0)
-	for _, extent := range changedAreas.ChangedArea {
+	for _, extent := range areas {
 		totalsize += extent.Length
 	}
 
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, 16)
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed []error
+		copied int64
+	)
+	// Each of the 16 workers reuses its read buffer, allocated on first use, for all the extents it copies
+	buffers := make(chan []byte, 16)
+	for range cap(buffers) {
+		buffers <- nil
+	}
 	incrementalcopyprogress := make(chan int64)
+	progressDone := make(chan struct{})
 
 	// Goroutine for updating progress
 	go func() {
+		defer close(progressDone)
 		copiedsize := int64(0)
-		for progress := range incrementalcopyprogress {
-			copiedsize += progress
-			prog := fmt.Sprintf("Progress: %.2f%%", float64(copiedsize)/float64(totalsize)*100.0)
-			utils.PrintLog(prog)
-			nbdserver.progresschan <- prog
+		var lastReport time.Time
+		for extentsize := range incrementalcopyprogress {
+			copiedsize += extentsize
+			// Only report every ProgressReportInterval and at the end, copies of small extents would flood the events
+			if copiedsize >= totalsize || time.Since(lastReport) >= ProgressReportInterval {
+				lastReport = time.Now()
+				prog := fmt.Sprintf("Disk %d: Progress: %.2f%%", diskindex, float64(copiedsize)/float64(totalsize)*100.0)
+				utils.PrintLog(prog)
+				nbdserver.progresschan <- prog
+			}
+			if progress != nil {
+				progress(float64(copiedsize) / float64(totalsize))
+			}
 		}
 	}()
 
-	for _, extent := range changedAreas.ChangedArea {
+	for _, extent := range areas {
 		wg.Add(1)
 		go func(extent types.DiskChangeExtent) {
-			blocks := getBlockStatus(handle, extent)
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-			for _, block := range blocks {
-				if err := copyRange(fd, handle, block); err != nil {
-					utils.PrintLog(fmt.Sprintf("Failed to copy block: %v", err))
-				}
+			defer wg.Done()
+			buffer := <-buffers
+			if buffer == nil {
+				buffer = make([]byte, MaxPreadLength)
 			}
-			// check if context is cancelled
-			select {
-			case <-ctx.Done():
-				if _, ok := <-incrementalcopyprogress; ok {
-					close(incrementalcopyprogress)
-				}
+			defer func() { buffers <- buffer }()
+			if ctx.Err() != nil {
+				return
+			}
+			read, err := copyExtent(ctx, target, handle, extent, targetIsZero, buffer)
+			mu.Lock()
+			copied += read
+			if err != nil {
+				failed = append(failed, err)
+			}
+			mu.Unlock()
+			if err != nil {
 				return
-			case incrementalcopyprogress <- extent.Length:
-				wg.Done()
 			}
+			incrementalcopyprogress <- extent.Length
 		}(extent)
 	}
 	wg.Wait()
 	close(incrementalcopyprogress)
-	return nil
+	<-progressDone
+
+	if err := ctx.Err(); err != nil {
+		return copied, errors.Wrapf(err, "copy of disk %d was cancelled", diskindex)
+	}
+	return copied, extentsError(diskindex, len(areas), failed)
+}
+
+// copyExtent copies the blocks of a changed extent through buffer, retrying a failed block with an exponential
+// backoff up to MaxExtentCopyAttempts times. It returns the number of bytes read from the source
+func copyExtent(ctx context.Context, target TargetWriter, handle *libnbd.Libnbd, extent types.DiskChangeExtent, targetIsZero bool, buffer []byte) (int64, error) {
+	copied := int64(0)
+	for _, block := range getBlockStatus(handle, extent) {
+		backoff := ExtentCopyRetryBackoff
+		for attempt := 1; ; attempt++ {
+			read, err := copyRange(target, handle, block, targetIsZero, buffer)
+			if err == nil {
+				copied += read
+				break
+			}
+			if attempt >= MaxExtentCopyAttempts {
+				return copied, errors.Wrapf(err, "failed to copy extent at offset %d after %d attempts", extent.Start, attempt)
+			}
+			utils.PrintLog(fmt.Sprintf("Failed to copy block at offset %d (attempt %d/%d), retrying in %s: %v",
+				block.Offset, attempt, MaxExtentCopyAttempts, backoff, err))
+			select {
+			case <-ctx.Done():
+				return copied, errors.Wrapf(ctx.Err(), "failed to copy extent at offset %d", extent.Start)
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+	}
+	return copied, nil
+}
+
+// extentsError aggregates the errors of the extents that could not be copied, nil if all of them were copied.
+// Only the first few errors are listed, a failing disk can have thousands of failed extents.
+func extentsError(diskindex, total int, failed []error) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	const listed = 5
+	var messages []string
+	for _, err := range failed[:min(len(failed), listed)] {
+		messages = append(messages, err.Error())
+	}
+	if len(failed) > listed {
+		messages = append(messages, fmt.Sprintf("and %d more", len(failed)-listed))
+	}
+	return errors.Errorf("failed to copy %d of %d changed extents of disk %d: %s", len(failed), total, diskindex, strings.Join(messages, "; "))
+}
+
+// VerifyBlocks compares the SHA-256 checksums of the areas in the source and in the destination, in chunks of
+// VerifyChunkSize bytes. If sampled is set, only every VerifySampleInterval-th chunk is compared.
+// It returns the chunks whose checksums differ and the number of bytes compared.
+func (nbdserver *NBDServer) VerifyBlocks(ctx context.Context, areas types.DiskChangeInfo, path string, diskindex int, sampled bool) ([]types.DiskChangeExtent, int64, error) {
+	handle, err := nbdserver.connect()
+	if err != nil {
+		return nil, 0, err
+	}
+	defer handle.Close()
+
+	target, err := nbdserver.openTarget(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer target.Close()
+	// Drop the cached pages of the destination, so that its checksums are computed from what is on the volume
+	if cached, ok := target.(interface{ DropCache() error }); ok {
+		if err := cached.DropCache(); err != nil {
+			utils.PrintLog(fmt.Sprintf("Disk %d: failed to drop cached pages of %s before verification: %v", diskindex, path, err))
+		}
+	}
+	defer nbdserver.Throttle.Start(nbdserver.Throttle.RateFile(nbdserver.tmp_dir))()
+
+	chunks := verifyChunks(areas.ChangedArea, sampled)
+	var (
+		wg         sync.WaitGroup
+		mu         sync.Mutex
+		mismatches []types.DiskChangeExtent
+		verified   int64
+		failed     []error
+	)
+	semaphore := make(chan struct{}, 16)
+	for _, chunk := range chunks {
+		wg.Add(1)
+		go func(chunk types.DiskChangeExtent) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if ctx.Err() != nil {
+				return
+			}
+			match, err := compareChunk(target, handle, chunk)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				failed = append(failed, err)
+			case !match:
+				mismatches = append(mismatches, chunk)
+				verified += chunk.Length
+			default:
+				verified += chunk.Length
+			}
+		}(chunk)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, verified, errors.Wrapf(err, "verification of disk %d was cancelled", diskindex)
+	}
+	if len(failed) > 0 {
+		return nil, verified, errors.Wrapf(failed[0], "failed to verify %d of %d chunks of disk %d", len(failed), len(chunks), diskindex)
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Start < mismatches[j].Start })
+	return mismatches, verified, nil
+}
+
+// verifyChunks splits the areas into chunks of at most VerifyChunkSize bytes. If sampled is set, only every
+// VerifySampleInterval-th chunk is kept, always including the first one.
+func verifyChunks(areas []types.DiskChangeExtent, sampled bool) []types.DiskChangeExtent {
+	var chunks []types.DiskChangeExtent
+	index := 0
+	for _, area := range areas {
+		for offset := area.Start; offset < area.Start+area.Length; offset += VerifyChunkSize {
+			if !sampled || index%VerifySampleInterval == 0 {
+				chunks = append(chunks, types.DiskChangeExtent{
+					Start:  offset,
+					Length: min(VerifyChunkSize, area.Start+area.Length-offset),
+				})
+			}
+			index++
+		}
+	}
+	return chunks
+}
+
+// compareChunk reports whether a chunk has the same checksum in the source and in the destination
+func compareChunk(target TargetWriter, handle *libnbd.Libnbd, chunk types.DiskChangeExtent) (bool, error) {
+	source := make([]byte, chunk.Length)
+	for count := int64(0); count < chunk.Length; count += int64(MaxPreadLength) {
+		end := min(count+int64(MaxPreadLength), chunk.Length)
+		if err := handle.Pread(source[count:end], uint64(chunk.Start+count), nil); err != nil {
+			return false, fmt.Errorf("error reading from source at offset %d: %v", chunk.Start+count, err)
+		}
+	}
+	destination := make([]byte, chunk.Length)
+	if _, err := target.ReadAt(destination, chunk.Start); err != nil {
+		return false, fmt.Errorf("error reading from destination at offset %d: %v", chunk.Start, err)
+	}
+	return sha256.Sum256(source) == sha256.Sum256(destination), nil
+}
+
+// GuestfishURI returns the URI guestfish reads the disk of the running server from
+func (nbdserver *NBDServer) GuestfishURI() string {
+	return fmt.Sprintf("nbd://?socket=%s/nbdkit.sock", nbdserver.tmp_dir)
 }
 
 func generateSockUrl(tmp_dir string) string {
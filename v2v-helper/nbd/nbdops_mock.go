@@ -37,31 +37,46 @@ func (m *MockNBDOperations) EXPECT() *MockNBDOperationsMockRecorder {
 }
 
 // CopyChangedBlocks mocks base method.
-func (m *MockNBDOperations) CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string) error {
+func (m *MockNBDOperations) CopyChangedBlocks(ctx context.Context, changedAreas types.DiskChangeInfo, path string, diskindex int, progress ProgressFunc) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CopyChangedBlocks", ctx, changedAreas, path)
+	ret := m.ctrl.Call(m, "CopyChangedBlocks", ctx, changedAreas, path, diskindex, progress)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // CopyChangedBlocks indicates an expected call of CopyChangedBlocks.
-func (mr *MockNBDOperationsMockRecorder) CopyChangedBlocks(ctx, changedAreas, path interface{}) *gomock.Call {
+func (mr *MockNBDOperationsMockRecorder) CopyChangedBlocks(ctx, changedAreas, path, diskindex, progress interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyChangedBlocks", reflect.TypeOf((*MockNBDOperations)(nil).CopyChangedBlocks), ctx, changedAreas, path)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyChangedBlocks", reflect.TypeOf((*MockNBDOperations)(nil).CopyChangedBlocks), ctx, changedAreas, path, diskindex, progress)
 }
 
 // CopyDisk mocks base method.
-func (m *MockNBDOperations) CopyDisk(ctx context.Context, dest string, diskindex int) error {
+func (m *MockNBDOperations) CopyDisk(ctx context.Context, dest string, diskindex int, full FullCopy, progress ProgressFunc) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CopyDisk", ctx, dest, diskindex)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "CopyDisk", ctx, dest, diskindex, full, progress)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // CopyDisk indicates an expected call of CopyDisk.
-func (mr *MockNBDOperationsMockRecorder) CopyDisk(ctx, dest, diskindex interface{}) *gomock.Call {
+func (mr *MockNBDOperationsMockRecorder) CopyDisk(ctx, dest, diskindex, full, progress interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyDisk", reflect.TypeOf((*MockNBDOperations)(nil).CopyDisk), ctx, dest, diskindex)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CopyDisk", reflect.TypeOf((*MockNBDOperations)(nil).CopyDisk), ctx, dest, diskindex, full, progress)
+}
+
+// GuestfishURI mocks base method.
+func (m *MockNBDOperations) GuestfishURI() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GuestfishURI")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GuestfishURI indicates an expected call of GuestfishURI.
+func (mr *MockNBDOperationsMockRecorder) GuestfishURI() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GuestfishURI", reflect.TypeOf((*MockNBDOperations)(nil).GuestfishURI))
 }
 
 // StartNBDServer mocks base method.
@@ -91,3 +106,19 @@ func (mr *MockNBDOperationsMockRecorder) StopNBDServer() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopNBDServer", reflect.TypeOf((*MockNBDOperations)(nil).StopNBDServer))
 }
+
+// VerifyBlocks mocks base method.
+func (m *MockNBDOperations) VerifyBlocks(ctx context.Context, areas types.DiskChangeInfo, path string, diskindex int, sampled bool) ([]types.DiskChangeExtent, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyBlocks", ctx, areas, path, diskindex, sampled)
+	ret0, _ := ret[0].([]types.DiskChangeExtent)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// VerifyBlocks indicates an expected call of VerifyBlocks.
+func (mr *MockNBDOperationsMockRecorder) VerifyBlocks(ctx, areas, path, diskindex, sampled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyBlocks", reflect.TypeOf((*MockNBDOperations)(nil).VerifyBlocks), ctx, areas, path, diskindex, sampled)
+}
@@ -0,0 +1,46 @@
+// Copyright © 2024 The vjailbreak authors
+
+package nbd
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestVerifyChunks(t *testing.T) {
+	areas := []types.DiskChangeExtent{
+		{Start: 0, Length: 10 << 20},
+		{Start: 100 << 20, Length: 1 << 20},
+	}
+	assert.Equal(t, []types.DiskChangeExtent{
+		{Start: 0, Length: 4 << 20},
+		{Start: 4 << 20, Length: 4 << 20},
+		{Start: 8 << 20, Length: 2 << 20},
+		{Start: 100 << 20, Length: 1 << 20},
+	}, verifyChunks(areas, false))
+
+	// Sampling keeps every VerifySampleInterval-th chunk across the areas
+	large := []types.DiskChangeExtent{{Start: 0, Length: 20 * VerifyChunkSize}, {Start: 40 * VerifyChunkSize, Length: 20 * VerifyChunkSize}}
+	assert.Equal(t, []types.DiskChangeExtent{
+		{Start: 0, Length: VerifyChunkSize},
+		{Start: 16 * VerifyChunkSize, Length: VerifyChunkSize},
+		{Start: 52 * VerifyChunkSize, Length: VerifyChunkSize},
+	}, verifyChunks(large, true))
+	assert.Empty(t, verifyChunks(nil, true))
+}
+
+func TestExtentsError(t *testing.T) {
+	assert.NoError(t, extentsError(0, 3, nil))
+
+	var failed []error
+	for i := 0; i < 7; i++ {
+		failed = append(failed, errors.Errorf("error reading from source at offset %d", i))
+	}
+	err := extentsError(1, 10, failed)
+	assert.EqualError(t, err, "failed to copy 7 of 10 changed extents of disk 1: "+
+		"error reading from source at offset 0; error reading from source at offset 1; error reading from source at offset 2; "+
+		"error reading from source at offset 3; error reading from source at offset 4; and 2 more")
+}
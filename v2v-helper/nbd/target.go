@@ -0,0 +1,89 @@
+// Copyright © 2024 The vjailbreak authors
+
+package nbd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"golang.org/x/sys/unix"
+)
+
+// TargetWriter is the destination the data of a disk is copied to. It is read back to verify the copy
+type TargetWriter interface {
+	io.ReaderAt
+	io.WriterAt
+	// Zero fills length bytes at offset with zeroes
+	Zero(offset, length int64) error
+	Close() error
+}
+
+// TargetOpener opens the destination at path, where the disk was attached or mapped
+type TargetOpener interface {
+	Open(path string) (TargetWriter, error)
+}
+
+// FileTarget writes to a local file or block device
+type FileTarget struct {
+	*os.File
+}
+
+// OpenFileTarget opens the file or block device at path for reading and writing
+func OpenFileTarget(path string) (*FileTarget, error) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+	return &FileTarget{File: file}, nil
+}
+
+// WriteAt writes the buffer at offset
+func (target *FileTarget) WriteAt(buffer []byte, offset int64) (int, error) {
+	blocksize := len(buffer)
+	written, err := syscall.Pwrite(int(target.Fd()), buffer, offset)
+	if err != nil {
+		return -1, errors.Wrapf(err, "failed to write %d bytes at offset %d", blocksize, offset)
+	}
+	if written < blocksize {
+		utils.PrintLog(fmt.Sprintf("Wrote less than blocksize (%d): %d", blocksize, written))
+	}
+	return written, nil
+}
+
+// Zero punches a hole in the range, or writes zeroes where holes are not supported
+func (target *FileTarget) Zero(offset int64, length int64) error {
+	utils.PrintLog(fmt.Sprintf("Punching %d-byte hole at offset %d", length, offset))
+	flags := uint32(unix.FALLOC_FL_PUNCH_HOLE | unix.FALLOC_FL_KEEP_SIZE)
+	err := syscall.Fallocate(int(target.Fd()), flags, offset, length)
+	if err == nil {
+		return nil
+	}
+
+	// Fall back to regular pwrite if punch fails
+	utils.PrintLog(fmt.Sprintf("Unable to zero range %d - %d on destination, falling back to pwrite: %v", offset, offset+length, err))
+	count := int64(0)
+	const blocksize = 16 << 20
+	buffer := bytes.Repeat([]byte{0}, blocksize)
+	for count < length {
+		remaining := length - count
+		if remaining < blocksize {
+			buffer = bytes.Repeat([]byte{0}, int(remaining))
+		}
+		written, err := target.WriteAt(buffer, offset+count)
+		if err != nil {
+			return errors.Wrapf(err, "unable to write %d zeroes at offset %d", length, offset)
+		}
+		count += int64(written)
+	}
+	return nil
+}
+
+// DropCache drops the cached pages of the file, so that reads come from the device
+func (target *FileTarget) DropCache() error {
+	return unix.Fadvise(int(target.Fd()), 0, 0, unix.FADV_DONTNEED)
+}
@@ -0,0 +1,75 @@
+// Copyright © 2024 The vjailbreak authors
+
+package nbd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestFileTarget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disk.raw")
+	assert.NoError(t, os.WriteFile(path, []byte("0123456789abcdef"), 0644))
+
+	target, err := OpenFileTarget(path)
+	assert.NoError(t, err)
+	written, err := target.WriteAt([]byte("XYZ"), 4)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, written)
+	assert.NoError(t, target.Zero(10, 4))
+
+	data := make([]byte, 16)
+	_, err = target.ReadAt(data, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("0123XYZ789\x00\x00\x00\x00ef"), data)
+	assert.NoError(t, target.Close())
+
+	_, err = OpenFileTarget(filepath.Join(t.TempDir(), "missing.raw"))
+	assert.Error(t, err)
+}
+
+func TestChunkAreas(t *testing.T) {
+	assert.Empty(t, chunkAreas(nil))
+	assert.Equal(t, []types.DiskChangeExtent{
+		{Start: 0, Length: MaxChunkSize},
+		{Start: MaxChunkSize, Length: MaxChunkSize},
+		{Start: 2 * MaxChunkSize, Length: 512},
+		{Start: 4 * MaxChunkSize, Length: 1024},
+	}, chunkAreas([]types.DiskChangeExtent{
+		{Start: 0, Length: 2*MaxChunkSize + 512},
+		{Start: 4 * MaxChunkSize, Length: 1024},
+	}))
+}
+
+func TestClipAreas(t *testing.T) {
+	assert.Empty(t, clipAreas(nil, 4096))
+	assert.Equal(t, []types.DiskChangeExtent{
+		{Start: 0, Length: 1536},
+		{Start: 2048, Length: 1024},
+		{Start: 3584, Length: 512},
+	}, clipAreas([]types.DiskChangeExtent{
+		{Start: 3584, Length: 1024},
+		{Start: 2048, Length: 512},
+		{Start: 0, Length: 1024},
+		{Start: 512, Length: 1024},
+		{Start: 2560, Length: 512},
+		{Start: 8192, Length: 512},
+	}, 4096))
+}
+
+func TestAreaGaps(t *testing.T) {
+	assert.Equal(t, []types.DiskChangeExtent{{Start: 0, Length: 4096}}, areaGaps(nil, 4096))
+	assert.Empty(t, areaGaps([]types.DiskChangeExtent{{Start: 0, Length: 4096}}, 4096))
+	assert.Equal(t, []types.DiskChangeExtent{
+		{Start: 0, Length: 512},
+		{Start: 1024, Length: 2048},
+		{Start: 3584, Length: 512},
+	}, areaGaps([]types.DiskChangeExtent{
+		{Start: 512, Length: 512},
+		{Start: 3072, Length: 512},
+	}, 4096))
+}
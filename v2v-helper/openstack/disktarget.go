@@ -0,0 +1,219 @@
+// Copyright © 2024 The vjailbreak authors
+
+package openstack
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+)
+
+// DiskTarget is where the disks of the VM are copied to and converted on, before they become the
+// volumes of the target instance
+type DiskTarget interface {
+	// Open returns the path the disk is copied to and converted on
+	Open(disk vm.VMDisk) (string, error)
+	// Close releases the path of the disk, it is opened again for the next step
+	Close(disk vm.VMDisk) error
+	// Commit returns the volume of the converted disk
+	Commit(vminfo vm.VMInfo, disk vm.VMDisk, volumetype string) (*volumes.Volume, error)
+	// Discard deletes everything created for the disk
+	Discard(disk vm.VMDisk) error
+}
+
+// NewDiskTarget returns the disk target of the backend, staging disks in dir when they are not
+// written to attached volumes
+func NewDiskTarget(backend string, ops OpenstackOperations, dir string) (DiskTarget, error) {
+	switch vjailbreakv1alpha1.DiskTargetBackend(backend) {
+	case "", vjailbreakv1alpha1.DiskTargetBackendCinderAttach:
+		return &VolumeTarget{Ops: ops}, nil
+	case vjailbreakv1alpha1.DiskTargetBackendGlanceImage:
+		return &ImageTarget{Ops: ops, Dir: dir, KeepImages: true}, nil
+	case vjailbreakv1alpha1.DiskTargetBackendCinderImageUpload:
+		return &ImageTarget{Ops: ops, Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unknown disk target backend %q", backend)
+	}
+}
+
+// VolumeTarget copies the disks to Cinder volumes created upfront and attached to the agent VM
+type VolumeTarget struct {
+	Ops OpenstackOperations
+}
+
+func (target *VolumeTarget) Open(disk vm.VMDisk) (string, error) {
+	if disk.OpenstackVol == nil {
+		return "", errors.Wrap(fmt.Errorf("OpenStack volume is nil"), "failed to attach volume to VM")
+	}
+	volumeID := disk.OpenstackVol.ID
+	if err := target.Ops.AttachVolumeToVM(volumeID); err != nil {
+		return "", errors.Wrap(err, "failed to attach volume to VM")
+	}
+
+	// Get the Path of the attached volume
+	devicePath, err := target.Ops.FindDevice(volumeID)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to find device")
+	}
+	return devicePath, nil
+}
+
+func (target *VolumeTarget) Close(disk vm.VMDisk) error {
+	if disk.OpenstackVol == nil {
+		return nil
+	}
+	if err := target.Ops.DetachVolumeFromVM(disk.OpenstackVol.ID); err != nil && !strings.Contains(err.Error(), "is not attached to volume") {
+		return errors.Wrap(err, "failed to detach volume from VM")
+	}
+	if err := target.Ops.WaitForVolume(disk.OpenstackVol.ID); err != nil {
+		return errors.Wrap(err, "failed to wait for volume to become available")
+	}
+	return nil
+}
+
+func (target *VolumeTarget) Commit(vminfo vm.VMInfo, disk vm.VMDisk, volumetype string) (*volumes.Volume, error) {
+	return disk.OpenstackVol, nil
+}
+
+func (target *VolumeTarget) Discard(disk vm.VMDisk) error {
+	if disk.OpenstackVol == nil {
+		return nil
+	}
+	if err := target.Ops.DeleteVolume(disk.OpenstackVol.ID); err != nil {
+		return errors.Wrap(err, "failed to delete volume")
+	}
+	return nil
+}
+
+// ImageTarget copies the disks to sparse raw files in Dir, which are uploaded to Glance once converted.
+// The volumes are created from the images, which are deleted afterwards unless KeepImages is set.
+// Dir must have room for the full size of the disks, as virt-v2v converts them in place before the
+// upload. The volumes only exist once the disks are converted, so there is no checkpoint to resume
+// from and an interrupted migration starts over
+type ImageTarget struct {
+	Ops        OpenstackOperations
+	Dir        string
+	KeepImages bool
+
+	// opened holds the size of the disks whose file was created by this process
+	opened sync.Map
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// File returns the path of the staged file of the disk
+func (target *ImageTarget) File(disk vm.VMDisk) string {
+	return filepath.Join(target.Dir, unsafeFileChars.ReplaceAllString(disk.Name, "_")+".raw")
+}
+
+// Open creates the empty sparse file of the disk the first time it is opened, a file left over
+// by a previous attempt is not resumed from. It fails when Dir does not have room for the disk
+// on top of what the other staged disks still need
+func (target *ImageTarget) Open(disk vm.VMDisk) (string, error) {
+	path := target.File(disk)
+	if _, loaded := target.opened.LoadOrStore(disk.Name, disk.Size); loaded {
+		return path, nil
+	}
+	if err := os.MkdirAll(target.Dir, 0755); err != nil {
+		target.opened.Delete(disk.Name)
+		return "", errors.Wrap(err, "failed to create staging directory")
+	}
+	if err := target.checkFreeSpace(disk); err != nil {
+		target.opened.Delete(disk.Name)
+		return "", err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		target.opened.Delete(disk.Name)
+		return "", errors.Wrap(err, "failed to create staged disk")
+	}
+	defer file.Close()
+	if err := file.Truncate(disk.Size); err != nil {
+		target.opened.Delete(disk.Name)
+		return "", errors.Wrap(err, "failed to size staged disk")
+	}
+	return path, nil
+}
+
+// checkFreeSpace makes sure Dir has room for the full disk and for the part of the other staged
+// disks that is not written yet, as the sparse files only take space once copied to
+func (target *ImageTarget) checkFreeSpace(disk vm.VMDisk) error {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(target.Dir, &stat); err != nil {
+		return errors.Wrap(err, "failed to get free space of staging directory")
+	}
+	available := int64(stat.Bavail) * int64(stat.Bsize)
+	needed := disk.Size
+	target.opened.Range(func(key, value any) bool {
+		name := key.(string)
+		if name == disk.Name {
+			return true
+		}
+		pending := value.(int64)
+		if info, err := os.Stat(target.File(vm.VMDisk{Name: name})); err == nil {
+			if sys, ok := info.Sys().(*syscall.Stat_t); ok {
+				pending -= sys.Blocks * 512
+			}
+		}
+		needed += max(pending, 0)
+		return true
+	})
+	if available < needed {
+		return fmt.Errorf("not enough free space in %s to stage disk %s: %d bytes needed, %d bytes available",
+			target.Dir, disk.Name, needed, available)
+	}
+	return nil
+}
+
+func (target *ImageTarget) Close(disk vm.VMDisk) error {
+	return nil
+}
+
+// Commit uploads the staged file of the disk and creates its volume from the image
+func (target *ImageTarget) Commit(vminfo vm.VMInfo, disk vm.VMDisk, volumetype string) (*volumes.Volume, error) {
+	name := vminfo.Name + "-" + disk.Name
+	path := target.File(disk)
+	imageID, err := target.Ops.UploadImage(name, path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to upload image")
+	}
+	volume, err := target.Ops.CreateVolumeFromImage(name, disk.Size, vminfo.OSType, vminfo.UEFI, volumetype, imageID, len(vminfo.RDMDisks) > 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create volume from image")
+	}
+	if !target.KeepImages {
+		if err := target.Ops.DeleteImage(imageID); err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to delete image %s: %v", imageID, err))
+		}
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		utils.PrintLog(fmt.Sprintf("Failed to remove staged disk %s: %v", path, err))
+	}
+	target.opened.Delete(disk.Name)
+	return volume, nil
+}
+
+// Discard removes the staged file of the disk and the volume committed from it
+func (target *ImageTarget) Discard(disk vm.VMDisk) error {
+	if err := os.Remove(target.File(disk)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "failed to remove staged disk")
+	}
+	target.opened.Delete(disk.Name)
+	if disk.OpenstackVol == nil {
+		return nil
+	}
+	if err := target.Ops.DeleteVolume(disk.OpenstackVol.ID); err != nil {
+		return errors.Wrap(err, "failed to delete volume")
+	}
+	return nil
+}
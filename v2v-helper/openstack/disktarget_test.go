@@ -0,0 +1,106 @@
+// Copyright © 2024 The vjailbreak authors
+
+package openstack
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDiskTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ops := NewMockOpenstackOperations(ctrl)
+
+	target, err := NewDiskTarget("", ops, "/staging")
+	assert.NoError(t, err)
+	assert.IsType(t, &VolumeTarget{}, target)
+
+	target, err = NewDiskTarget("GlanceImage", ops, "/staging")
+	assert.NoError(t, err)
+	assert.True(t, target.(*ImageTarget).KeepImages)
+
+	target, err = NewDiskTarget("CinderImageUpload", ops, "/staging")
+	assert.NoError(t, err)
+	assert.False(t, target.(*ImageTarget).KeepImages)
+
+	_, err = NewDiskTarget("Swift", ops, "/staging")
+	assert.EqualError(t, err, `unknown disk target backend "Swift"`)
+}
+
+func TestImageTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ops := NewMockOpenstackOperations(ctrl)
+	target := &ImageTarget{Ops: ops, Dir: t.TempDir()}
+	vminfo := vm.VMInfo{Name: "test-vm", OSType: "linux", UEFI: true}
+	disk := vm.VMDisk{Name: "Hard disk 1", Size: 1 << 30}
+
+	path, err := target.Open(disk)
+	assert.NoError(t, err)
+	assert.Equal(t, target.Dir+"/Hard_disk_1.raw", path)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, disk.Size, info.Size())
+
+	// Opening the disk again keeps what was copied to it
+	assert.NoError(t, os.WriteFile(path, []byte("copied"), 0644))
+	_, err = target.Open(disk)
+	assert.NoError(t, err)
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "copied", string(data))
+	assert.NoError(t, target.Close(disk))
+
+	gomock.InOrder(
+		ops.EXPECT().UploadImage("test-vm-Hard disk 1", path).Return("image1", nil),
+		ops.EXPECT().CreateVolumeFromImage("test-vm-Hard disk 1", disk.Size, "linux", true, "ssd", "image1", false).Return(&volumes.Volume{ID: "vol1"}, nil),
+		ops.EXPECT().DeleteImage("image1").Return(nil),
+	)
+	volume, err := target.Commit(vminfo, disk, "ssd")
+	assert.NoError(t, err)
+	assert.Equal(t, "vol1", volume.ID)
+	assert.NoFileExists(t, path)
+
+	disk.OpenstackVol = volume
+	ops.EXPECT().DeleteVolume("vol1").Return(nil)
+	assert.NoError(t, target.Discard(disk))
+}
+
+func TestImageTargetNotEnoughSpace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	target := &ImageTarget{Ops: NewMockOpenstackOperations(ctrl), Dir: t.TempDir()}
+	disk := vm.VMDisk{Name: "disk1", Size: 1 << 62}
+	_, err := target.Open(disk)
+	assert.ErrorContains(t, err, "not enough free space in "+target.Dir+" to stage disk disk1")
+	assert.NoFileExists(t, target.File(disk))
+
+	// The failed disk is not counted against the next one
+	_, err = target.Open(vm.VMDisk{Name: "disk2", Size: 4096})
+	assert.NoError(t, err)
+}
+
+func TestImageTargetKeepsImages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ops := NewMockOpenstackOperations(ctrl)
+	target := &ImageTarget{Ops: ops, Dir: t.TempDir(), KeepImages: true}
+	disk := vm.VMDisk{Name: "disk1", Size: 4096}
+	_, err := target.Open(disk)
+	assert.NoError(t, err)
+
+	ops.EXPECT().UploadImage(gomock.Any(), gomock.Any()).Return("image1", nil)
+	ops.EXPECT().CreateVolumeFromImage(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), "image1", true).Return(&volumes.Volume{ID: "vol1"}, nil)
+	_, err = target.Commit(vm.VMInfo{Name: "test-vm", RDMDisks: []vjailbreakv1alpha1.RDMDisk{{}}}, disk, "")
+	assert.NoError(t, err)
+}
@@ -27,6 +27,11 @@ import (
 
 type OpenstackOperations interface {
 	CreateVolume(name string, size int64, ostype string, uefi bool, volumetype string, setRDMLabel bool) (*volumes.Volume, error)
+	CreateVolumeFromImage(name string, size int64, ostype string, uefi bool, volumetype, imageID string, setRDMLabel bool) (*volumes.Volume, error)
+	UploadImage(name, path string) (string, error)
+	DeleteImage(imageID string) error
+	GetVolume(volumeID string) (*volumes.Volume, error)
+	GetVolumeConnection(volumeID string) (map[string]interface{}, error)
 	WaitForVolume(volumeID string) error
 	AttachVolumeToVM(volumeID string) error
 	WaitForVolumeAttachment(volumeID string) error
@@ -45,6 +50,10 @@ type OpenstackOperations interface {
 	DeleteVolume(volumeID string) error
 	FindDevice(volumeID string) (string, error)
 	WaitUntilVMActive(vmID string) (bool, error)
+	StopServer(serverID string) error
+	DeleteServer(serverID string) error
+	DeletePort(portID string) error
+	GetConsoleOutput(serverID string, lines int) (string, error)
 }
 
 func validateOpenStack(insecure bool) (*utils.OpenStackClients, error) {
@@ -101,10 +110,16 @@ func validateOpenStack(insecure bool) (*utils.OpenStackClients, error) {
 		return nil, fmt.Errorf("failed to create networking client: %s", err)
 	}
 
+	imageClient, err := openstack.NewImageServiceV2(providerClient, endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image client: %s", err)
+	}
+
 	return &utils.OpenStackClients{
 		BlockStorageClient: blockStorageClient,
 		ComputeClient:      computeClient,
 		NetworkingClient:   networkingClient,
+		ImageClient:        imageClient,
 		K8sClient:          nil,
 		AuthURL:            opts.IdentityEndpoint,
 		Tenant:             opts.TenantName,
@@ -13,7 +13,7 @@ import (
 	servers "github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	networks "github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	ports "github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
-	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	k8sutils "github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
 	vm "github.com/platform9/vjailbreak/v2v-helper/vm"
 )
 
@@ -55,18 +55,18 @@ func (mr *MockOpenstackOperationsMockRecorder) AttachVolumeToVM(volumeID interfa
 }
 
 // CreatePort mocks base method.
-func (m *MockOpenstackOperations) CreatePort(networkid *networks.Network, mac, ip, vmname string, securityGroups []string) (*ports.Port, error) {
+func (m *MockOpenstackOperations) CreatePort(networkid *networks.Network, mac, ip, vmname string, securityGroups []string, fallbackToDHCP bool) (*ports.Port, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreatePort", networkid, mac, ip, vmname, securityGroups)
+	ret := m.ctrl.Call(m, "CreatePort", networkid, mac, ip, vmname, securityGroups, fallbackToDHCP)
 	ret0, _ := ret[0].(*ports.Port)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreatePort indicates an expected call of CreatePort.
-func (mr *MockOpenstackOperationsMockRecorder) CreatePort(networkid, mac, ip, vmname, securityGroups interface{}) *gomock.Call {
+func (mr *MockOpenstackOperationsMockRecorder) CreatePort(networkid, mac, ip, vmname, securityGroups, fallbackToDHCP interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePort", reflect.TypeOf((*MockOpenstackOperations)(nil).CreatePort), networkid, mac, ip, vmname, securityGroups)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePort", reflect.TypeOf((*MockOpenstackOperations)(nil).CreatePort), networkid, mac, ip, vmname, securityGroups, fallbackToDHCP)
 }
 
 // CreateVM mocks base method.
@@ -94,11 +94,68 @@ func (m *MockOpenstackOperations) CreateVolume(name string, size int64, ostype s
 }
 
 // CreateVolume indicates an expected call of CreateVolume.
-func (mr *MockOpenstackOperationsMockRecorder) CreateVolume(name, size, ostype, uefi, volumetype interface{}, setRDMLabel bool) *gomock.Call {
+func (mr *MockOpenstackOperationsMockRecorder) CreateVolume(name, size, ostype, uefi, volumetype, setRDMLabel interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).CreateVolume), name, size, ostype, uefi, volumetype, setRDMLabel)
 }
 
+// CreateVolumeFromImage mocks base method.
+func (m *MockOpenstackOperations) CreateVolumeFromImage(name string, size int64, ostype string, uefi bool, volumetype, imageID string, setRDMLabel bool) (*volumes.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVolumeFromImage", name, size, ostype, uefi, volumetype, imageID, setRDMLabel)
+	ret0, _ := ret[0].(*volumes.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVolumeFromImage indicates an expected call of CreateVolumeFromImage.
+func (mr *MockOpenstackOperationsMockRecorder) CreateVolumeFromImage(name, size, ostype, uefi, volumetype, imageID, setRDMLabel interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVolumeFromImage", reflect.TypeOf((*MockOpenstackOperations)(nil).CreateVolumeFromImage), name, size, ostype, uefi, volumetype, imageID, setRDMLabel)
+}
+
+// DeleteImage mocks base method.
+func (m *MockOpenstackOperations) DeleteImage(imageID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImage", imageID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteImage indicates an expected call of DeleteImage.
+func (mr *MockOpenstackOperationsMockRecorder) DeleteImage(imageID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImage", reflect.TypeOf((*MockOpenstackOperations)(nil).DeleteImage), imageID)
+}
+
+// DeletePort mocks base method.
+func (m *MockOpenstackOperations) DeletePort(portID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeletePort", portID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeletePort indicates an expected call of DeletePort.
+func (mr *MockOpenstackOperationsMockRecorder) DeletePort(portID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeletePort", reflect.TypeOf((*MockOpenstackOperations)(nil).DeletePort), portID)
+}
+
+// DeleteServer mocks base method.
+func (m *MockOpenstackOperations) DeleteServer(serverID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteServer", serverID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteServer indicates an expected call of DeleteServer.
+func (mr *MockOpenstackOperationsMockRecorder) DeleteServer(serverID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteServer", reflect.TypeOf((*MockOpenstackOperations)(nil).DeleteServer), serverID)
+}
+
 // DeleteVolume mocks base method.
 func (m *MockOpenstackOperations) DeleteVolume(volumeID string) error {
 	m.ctrl.T.Helper()
@@ -171,6 +228,21 @@ func (mr *MockOpenstackOperationsMockRecorder) GetClosestFlavour(cpu, memory int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClosestFlavour", reflect.TypeOf((*MockOpenstackOperations)(nil).GetClosestFlavour), cpu, memory)
 }
 
+// GetConsoleOutput mocks base method.
+func (m *MockOpenstackOperations) GetConsoleOutput(serverID string, lines int) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsoleOutput", serverID, lines)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsoleOutput indicates an expected call of GetConsoleOutput.
+func (mr *MockOpenstackOperationsMockRecorder) GetConsoleOutput(serverID, lines interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsoleOutput", reflect.TypeOf((*MockOpenstackOperations)(nil).GetConsoleOutput), serverID, lines)
+}
+
 // GetFlavor mocks base method.
 func (m *MockOpenstackOperations) GetFlavor(flavorId string) (*flavors.Flavor, error) {
 	m.ctrl.T.Helper()
@@ -231,6 +303,36 @@ func (mr *MockOpenstackOperationsMockRecorder) GetSecurityGroupIDs(groupNames, p
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecurityGroupIDs", reflect.TypeOf((*MockOpenstackOperations)(nil).GetSecurityGroupIDs), groupNames, projectName)
 }
 
+// GetVolume mocks base method.
+func (m *MockOpenstackOperations) GetVolume(volumeID string) (*volumes.Volume, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolume", volumeID)
+	ret0, _ := ret[0].(*volumes.Volume)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolume indicates an expected call of GetVolume.
+func (mr *MockOpenstackOperationsMockRecorder) GetVolume(volumeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolume", reflect.TypeOf((*MockOpenstackOperations)(nil).GetVolume), volumeID)
+}
+
+// GetVolumeConnection mocks base method.
+func (m *MockOpenstackOperations) GetVolumeConnection(volumeID string) (map[string]interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVolumeConnection", volumeID)
+	ret0, _ := ret[0].(map[string]interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVolumeConnection indicates an expected call of GetVolumeConnection.
+func (mr *MockOpenstackOperationsMockRecorder) GetVolumeConnection(volumeID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVolumeConnection", reflect.TypeOf((*MockOpenstackOperations)(nil).GetVolumeConnection), volumeID)
+}
+
 // SetVolumeBootable mocks base method.
 func (m *MockOpenstackOperations) SetVolumeBootable(volume *volumes.Volume) error {
 	m.ctrl.T.Helper()
@@ -248,15 +350,15 @@ func (mr *MockOpenstackOperationsMockRecorder) SetVolumeBootable(volume interfac
 // SetVolumeImageMetadata mocks base method.
 func (m *MockOpenstackOperations) SetVolumeImageMetadata(volume *volumes.Volume, setRDMLabel bool) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetVolumeImageMetadata", volume)
+	ret := m.ctrl.Call(m, "SetVolumeImageMetadata", volume, setRDMLabel)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetVolumeImageMetadata indicates an expected call of SetVolumeImageMetadata.
-func (mr *MockOpenstackOperationsMockRecorder) SetVolumeImageMetadata(volume interface{}, setRDMLabel bool) *gomock.Call {
+func (mr *MockOpenstackOperationsMockRecorder) SetVolumeImageMetadata(volume, setRDMLabel interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeImageMetadata", reflect.TypeOf((*MockOpenstackOperations)(nil).SetVolumeImageMetadata), volume)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeImageMetadata", reflect.TypeOf((*MockOpenstackOperations)(nil).SetVolumeImageMetadata), volume, setRDMLabel)
 }
 
 // SetVolumeUEFI mocks base method.
@@ -273,6 +375,35 @@ func (mr *MockOpenstackOperationsMockRecorder) SetVolumeUEFI(volume interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetVolumeUEFI", reflect.TypeOf((*MockOpenstackOperations)(nil).SetVolumeUEFI), volume)
 }
 
+// StopServer mocks base method.
+func (m *MockOpenstackOperations) StopServer(serverID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StopServer", serverID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StopServer indicates an expected call of StopServer.
+func (mr *MockOpenstackOperationsMockRecorder) StopServer(serverID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StopServer", reflect.TypeOf((*MockOpenstackOperations)(nil).StopServer), serverID)
+}
+
+// UploadImage mocks base method.
+func (m *MockOpenstackOperations) UploadImage(name, path string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UploadImage", name, path)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UploadImage indicates an expected call of UploadImage.
+func (mr *MockOpenstackOperationsMockRecorder) UploadImage(name, path interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UploadImage", reflect.TypeOf((*MockOpenstackOperations)(nil).UploadImage), name, path)
+}
+
 // WaitForVolume mocks base method.
 func (m *MockOpenstackOperations) WaitForVolume(volumeID string) error {
 	m.ctrl.T.Helper()
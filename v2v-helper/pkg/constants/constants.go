@@ -7,64 +7,6 @@ const (
 	HotplugMemoryKey    = "HOTPLUG_MEMORY"
 	HotplugCPUMaxKey    = "HOTPLUG_CPU_MAX"
 	HotplugMemoryMaxKey = "HOTPLUG_MEMORY_MAX"
-	RhelFirstBootScript = `#!/bin/bash
-set -e
-LOG_FILE="/var/log/network_fix.log"
-echo "$(date '+%Y-%m-%d %H:%M:%S') - Starting network fix script" >> "$LOG_FILE"
-
-if ! systemctl is-active NetworkManager >/dev/null 2>&1; then
-    echo "$(date '+%Y-%m-%d %H:%M:%S') - NetworkManager not active, attempting to start" >> "$LOG_FILE"
-    systemctl start NetworkManager
-    if ! systemctl is-active NetworkManager >/dev/null 2>&1; then
-        echo "$(date '+%Y-%m-%d %H:%M:%S') - Failed to start NetworkManager, exiting" >> "$LOG_FILE"
-        exit 1
-    fi
-fi
-echo "$(date '+%Y-%m-%d %H:%M:%S') - NetworkManager is active" >> "$LOG_FILE"
-
-nmcli con reload || {
-    echo "$(date '+%Y-%m-%d %H:%M:%S') - Warning: Reload failed, restarting NM" >> "$LOG_FILE"
-    systemctl restart NetworkManager
-    sleep 5
-    nmcli con reload || echo "$(date '+%Y-%m-%d %H:%M:%S') - Error: Reload still failed" >> "$LOG_FILE"
-}
-
-OLD_CONNS=$(nmcli -t -f NAME,TYPE connection show | grep -v ':loopback' | cut -d: -f1)
-if [ -z "$OLD_CONNS" ]; then
-    echo "$(date '+%Y-%m-%d %H:%M:%S') - No existing connections found" >> "$LOG_FILE"
-else
-    echo "$(date '+%Y-%m-%d %H:%M:%S') - Found connections: $OLD_CONNS" >> "$LOG_FILE"
-fi
-
-for conn in $OLD_CONNS; do
-    echo "$(date '+%Y-%m-%d %H:%M:%S') - Processing connection: $conn" >> "$LOG_FILE"
-    nmcli con mod "$conn" ipv4.method auto ipv4.addresses "" ipv4.gateway "" 2>>"$LOG_FILE" || \
-        echo "$(date '+%Y-%m-%d %H:%M:%S') - Error: Failed to modify IPv4 for $conn" >> "$LOG_FILE"
-    nmcli con mod "$conn" ipv6.method auto ipv6.addresses "" ipv6.gateway "" 2>>"$LOG_FILE" || \
-        echo "$(date '+%Y-%m-%d %H:%M:%S') - Error: Failed to modify IPv6 for $conn" >> "$LOG_FILE"
-    nmcli con up "$conn" 2>>"$LOG_FILE" || \
-        echo "$(date '+%Y-%m-%d %H:%M:%S') - Warning: Failed to activate $conn" >> "$LOG_FILE"
-done
-
-NEW_IFACES=$(ip link show | grep -o '^[0-9]\+: [a-zA-Z0-9]\+:' | cut -d ' ' -f2 | cut -d ':' -f1 | grep -v lo)
-if [ -z "$NEW_IFACES" ]; then
-    echo "$(date '+%Y-%m-%d %H:%M:%S') - No new interfaces detected" >> "$LOG_FILE"
-else
-    echo "$(date '+%Y-%m-%d %H:%M:%S') - Detected interfaces: $NEW_IFACES" >> "$LOG_FILE"
-fi
-
-for iface in $NEW_IFACES; do
-    echo "$(date '+%Y-%m-%d %H:%M:%S') - Processing new interface: $iface" >> "$LOG_FILE"
-    conn_name="$iface"
-    if ! nmcli con show "$conn_name" >/dev/null 2>&1; then
-        echo "$(date '+%Y-%m-%d %H:%M:%S') - Creating new connection for $iface" >> "$LOG_FILE"
-        nmcli con add type ethernet con-name "$conn_name" ifname "$iface" ipv4.method auto ipv6.method auto 2>>"$LOG_FILE" || \
-            echo "$(date '+%Y-%m-%d %H:%M:%S') - Error: Failed to create connection for $iface" >> "$LOG_FILE"
-    fi
-    nmcli con up "$conn_name" 2>>"$LOG_FILE" || \
-        echo "$(date '+%Y-%m-%d %H:%M:%S') - Error: Failed to activate $iface" >> "$LOG_FILE"
-done
-echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE"`
 
 	MaxCPU = 9999999
 	MaxRAM = 9999999
@@ -76,6 +18,7 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	LSBootCommand            = "ls /boot"
 	XMLFileName              = "libxml.xml"
 	MigrationSnapshotName    = "migration-snap"
+	InspectionSnapshotName   = "inspection-snap"
 	MaxHTTPRetryCount        = 5
 	MaxVMActiveCheckCount    = 15
 	VMActiveCheckInterval    = 20 * time.Second
@@ -84,6 +27,9 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 
 	LogsDir = "/var/log/pf9"
 
+	// StagingDir is where the disks are staged when they are not converted on attached Cinder volumes
+	StagingDir = "/home/fedora/staging"
+
 	EventMessageConvertingDisk                    = "Converting disk"
 	EventMessageWaitingForCutOverStart            = "Waiting for VM Cutover start time"
 	EventMessageCopyingChangedBlocksWithIteration = "Copying changed blocks"
@@ -107,6 +53,10 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	// ConfigMap default values
 	ChangedBlocksCopyIterationThreshold = 20
 
+	// ChangedBlocksCopyFailureLimit is the number of consecutive passes the changed blocks of a disk may fail to copy
+	// before the migration fails
+	ChangedBlocksCopyFailureLimit = 3
+
 	// VMActiveWaitIntervalSeconds is the interval to wait for vm to become active
 	VMActiveWaitIntervalSeconds = 20
 
@@ -122,6 +72,15 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 	// DefaultMigrationMethod is the default migration method
 	DefaultMigrationMethod = "hot"
 
+	// WarmSyncInterval is the default interval between the incremental syncs of a warm migration
+	WarmSyncInterval = 30 * time.Minute
+
+	// EstimatedConversionTime is the time the conversion of the disks is expected to take in the downtime estimate
+	EstimatedConversionTime = 5 * time.Minute
+
+	// EstimatedBootTime is the time the migrated VM is expected to take to boot in the downtime estimate
+	EstimatedBootTime = 2 * time.Minute
+
 	// VCenterScanConcurrencyLimit is the max number of vcenter scan pods
 	VCenterScanConcurrencyLimit = 100
 
@@ -142,4 +101,41 @@ echo "$(date '+%Y-%m-%d %H:%M:%S') - Network fix script completed" >> "$LOG_FILE
 
 	// VMwareCredsRequeueAfterMinutes is the time to requeue after.
 	VMwareCredsRequeueAfterMinutes = 60
+
+	// DiskCopyConcurrencyLimit is the max number of disks of a VM copied at the same time
+	DiskCopyConcurrencyLimit = 4
+
+	// BandwidthLimitMBps is the default global copy bandwidth limit, 0 means unlimited
+	BandwidthLimitMBps = 0
+
+	// BandwidthLimitRefreshInterval is how often the bandwidth limits are read again
+	BandwidthLimitRefreshInterval = 30 * time.Second
+
+	// MetricsPort is the first port v2v-helper tries to serve its metrics on
+	MetricsPort = 9095
+
+	// MigrationCheckpointConfigMapPrefix is the prefix of the configmap holding the per-disk copy checkpoints
+	MigrationCheckpointConfigMapPrefix = "migration-checkpoint"
+
+	// MigrationCheckpointDataKey is the configmap key under which the checkpoint is stored
+	MigrationCheckpointDataKey = "checkpoint"
+
+	// CheckpointPhaseVolumeCreated means the target volume exists but the full copy has not completed
+	CheckpointPhaseVolumeCreated = "VolumeCreated"
+
+	// CheckpointPhaseCopied means the full copy has completed and the disk is synced up to ChangeID
+	CheckpointPhaseCopied = "Copied"
+
+	// ResumeExitCode is the exit code of a v2v-helper stopped with a resumable checkpoint. The job of the
+	// migration starts a new pod for it, which resumes from the checkpoint
+	ResumeExitCode = 75
+
+	// WindowsFirstBootTimeout is how long the first boot script of a Windows guest has to report its result
+	WindowsFirstBootTimeout = 20 * time.Minute
+
+	// WindowsFirstBootPollInterval is how often the console log of a Windows guest is checked for the result
+	WindowsFirstBootPollInterval = 15 * time.Second
+
+	// WindowsFirstBootConsoleLines is the number of console log lines searched for the result
+	WindowsFirstBootConsoleLines = 200
 )
@@ -113,6 +113,8 @@ func GetVjailbreakSettings(ctx context.Context, k8sClient client.Client) (*Vjail
 			VCenterLoginRetryLimit:              constants.VCenterLoginRetryLimit,
 			OpenstackCredsRequeueAfterMinutes:   constants.OpenstackCredsRequeueAfterMinutes,
 			VMwareCredsRequeueAfterMinutes:      constants.VMwareCredsRequeueAfterMinutes,
+			DiskCopyConcurrencyLimit:            constants.DiskCopyConcurrencyLimit,
+			BandwidthLimitMBps:                  constants.BandwidthLimitMBps,
 		}, nil
 	}
 
@@ -164,6 +166,14 @@ func GetVjailbreakSettings(ctx context.Context, k8sClient client.Client) (*Vjail
 		vjailbreakSettingsCM.Data["VMWARE_CREDS_REQUEUE_AFTER_MINUTES"] = strconv.Itoa(constants.VMwareCredsRequeueAfterMinutes)
 	}
 
+	if vjailbreakSettingsCM.Data["DISK_COPY_CONCURRENCY_LIMIT"] == "" {
+		vjailbreakSettingsCM.Data["DISK_COPY_CONCURRENCY_LIMIT"] = strconv.Itoa(constants.DiskCopyConcurrencyLimit)
+	}
+
+	if vjailbreakSettingsCM.Data["BANDWIDTH_LIMIT_MBPS"] == "" {
+		vjailbreakSettingsCM.Data["BANDWIDTH_LIMIT_MBPS"] = strconv.Itoa(constants.BandwidthLimitMBps)
+	}
+
 	return &VjailbreakSettings{
 		ChangedBlocksCopyIterationThreshold: atoi(vjailbreakSettingsCM.Data["CHANGED_BLOCKS_COPY_ITERATION_THRESHOLD"]),
 		VMActiveWaitIntervalSeconds:         atoi(vjailbreakSettingsCM.Data["VM_ACTIVE_WAIT_INTERVAL_SECONDS"]),
@@ -177,5 +187,9 @@ func GetVjailbreakSettings(ctx context.Context, k8sClient client.Client) (*Vjail
 		VCenterLoginRetryLimit:              atoi(vjailbreakSettingsCM.Data["VCENTER_LOGIN_RETRY_LIMIT"]),
 		OpenstackCredsRequeueAfterMinutes:   atoi(vjailbreakSettingsCM.Data["OPENSTACK_CREDS_REQUEUE_AFTER_MINUTES"]),
 		VMwareCredsRequeueAfterMinutes:      atoi(vjailbreakSettingsCM.Data["VMWARE_CREDS_REQUEUE_AFTER_MINUTES"]),
+		DiskCopyConcurrencyLimit:            atoi(vjailbreakSettingsCM.Data["DISK_COPY_CONCURRENCY_LIMIT"]),
+		BandwidthLimitMBps:                  atoi(vjailbreakSettingsCM.Data["BANDWIDTH_LIMIT_MBPS"]),
+		BandwidthLimitWindows:               vjailbreakSettingsCM.Data["BANDWIDTH_LIMIT_WINDOWS"],
+		BandwidthLimitTimeZone:              vjailbreakSettingsCM.Data["BANDWIDTH_LIMIT_TIMEZONE"],
 	}, nil
 }
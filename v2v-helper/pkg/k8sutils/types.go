@@ -13,4 +13,8 @@ type VjailbreakSettings struct {
 	VCenterLoginRetryLimit              int
 	OpenstackCredsRequeueAfterMinutes   int
 	VMwareCredsRequeueAfterMinutes      int
+	DiskCopyConcurrencyLimit            int
+	BandwidthLimitMBps                  int
+	BandwidthLimitWindows               string
+	BandwidthLimitTimeZone              string
 }
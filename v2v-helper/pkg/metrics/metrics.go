@@ -0,0 +1,160 @@
+// Copyright © 2024 The vjailbreak authors
+
+// Package metrics exposes the copy and conversion metrics of a v2v-helper pod for Prometheus.
+// Every metric is labelled with the VM being migrated and the MigrationPlan it belongs to.
+// A nil *Metrics is valid and records nothing, so callers do not have to check whether metrics are enabled.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// portAttempts is the number of consecutive ports tried when the preferred port is in use.
+	// v2v-helper pods run on the host network, so several of them on a node need different ports.
+	portAttempts = 100
+
+	shutdownTimeout = 5 * time.Second
+)
+
+// Metrics holds the metrics of a single VM migration
+type Metrics struct {
+	registry          *prometheus.Registry
+	bytesCopied       *prometheus.CounterVec
+	changedBlockBytes prometheus.Counter
+	lastChangedBytes  prometheus.Gauge
+	copyThroughput    *prometheus.GaugeVec
+	virtV2VDuration   prometheus.Gauge
+
+	// iterationLock serializes the changed blocks of disks copied in parallel, iteration is the
+	// changed blocks copy iteration lastChangedBytes is counting
+	iterationLock sync.Mutex
+	iteration     int
+}
+
+// NewMetrics creates the metrics of the migration of vmName, which is part of the MigrationPlan planName
+func NewMetrics(vmName, planName string) *Metrics {
+	labels := prometheus.Labels{"vm": vmName, "plan": planName}
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		bytesCopied: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "vjailbreak_v2v_bytes_copied_total",
+			Help:        "Bytes copied from the source disk to the target volume",
+			ConstLabels: labels,
+		}, []string{"disk"}),
+		changedBlockBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "vjailbreak_v2v_changed_block_bytes_total",
+			Help:        "Bytes of changed blocks copied over all changed blocks copy iterations and disks",
+			ConstLabels: labels,
+		}),
+		lastChangedBytes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "vjailbreak_v2v_last_iteration_changed_block_bytes",
+			Help:        "Bytes of changed blocks copied in the latest changed blocks copy iteration, summed over all disks",
+			ConstLabels: labels,
+		}),
+		copyThroughput: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name:        "vjailbreak_v2v_nbdcopy_throughput_bytes_per_second",
+			Help:        "Average throughput of the last completed copy of a disk",
+			ConstLabels: labels,
+		}, []string{"disk"}),
+		virtV2VDuration: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "vjailbreak_v2v_virt_v2v_duration_seconds",
+			Help:        "Time taken by virt-v2v to convert the VM",
+			ConstLabels: labels,
+		}),
+	}
+	m.registry.MustRegister(m.bytesCopied, m.changedBlockBytes, m.lastChangedBytes, m.copyThroughput, m.virtV2VDuration)
+	return m
+}
+
+// AddBytesCopied adds bytes to the number of bytes copied for disk
+func (m *Metrics) AddBytesCopied(disk string, bytes int64) {
+	if m == nil || bytes <= 0 {
+		return
+	}
+	m.bytesCopied.WithLabelValues(disk).Add(float64(bytes))
+}
+
+// AddChangedBlockBytes adds bytes to the changed block volume of a copy iteration. The volume of the
+// latest iteration starts over once a disk reports a newer iteration
+func (m *Metrics) AddChangedBlockBytes(iteration int, bytes int64) {
+	if m == nil {
+		return
+	}
+	m.iterationLock.Lock()
+	defer m.iterationLock.Unlock()
+	if iteration > m.iteration {
+		m.iteration = iteration
+		m.lastChangedBytes.Set(0)
+	}
+	if bytes <= 0 {
+		return
+	}
+	m.changedBlockBytes.Add(float64(bytes))
+	if iteration == m.iteration {
+		m.lastChangedBytes.Add(float64(bytes))
+	}
+}
+
+// ObserveCopy sets the throughput of disk from a completed copy of bytes that took elapsed
+func (m *Metrics) ObserveCopy(disk string, bytes int64, elapsed time.Duration) {
+	if m == nil || elapsed <= 0 {
+		return
+	}
+	m.copyThroughput.WithLabelValues(disk).Set(float64(bytes) / elapsed.Seconds())
+}
+
+// ObserveVirtV2V records the time virt-v2v took to convert the VM
+func (m *Metrics) ObserveVirtV2V(elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.virtV2VDuration.Set(elapsed.Seconds())
+}
+
+// Serve starts serving the metrics on /metrics until ctx is done. It listens on the first free port
+// starting at port and returns the port it listens on.
+func (m *Metrics) Serve(ctx context.Context, port int) (int, error) {
+	if m == nil {
+		return 0, errors.New("metrics are not initialized")
+	}
+	listener, err := listen(port)
+	if err != nil {
+		return 0, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func listen(port int) (net.Listener, error) {
+	var err error
+	for attempt := 0; attempt < portAttempts; attempt++ {
+		var listener net.Listener
+		listener, err = net.Listen("tcp", fmt.Sprintf(":%d", port+attempt))
+		if err == nil {
+			return listener, nil
+		}
+	}
+	return nil, errors.Wrapf(err, "failed to listen on ports %d-%d", port, port+portAttempts-1)
+}
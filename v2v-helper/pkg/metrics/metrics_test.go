@@ -0,0 +1,87 @@
+// Copyright © 2024 The vjailbreak authors
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics("vm1", "plan1")
+	m.AddBytesCopied("disk1", 512)
+	m.AddBytesCopied("disk1", 512)
+	m.AddBytesCopied("disk1", 0)
+	m.AddChangedBlockBytes(1, 100)
+	m.AddChangedBlockBytes(1, 50)
+	m.AddChangedBlockBytes(2, 10)
+	m.ObserveCopy("disk1", 1024, 4*time.Second)
+	m.ObserveVirtV2V(90 * time.Second)
+
+	expected := `
+# HELP vjailbreak_v2v_bytes_copied_total Bytes copied from the source disk to the target volume
+# TYPE vjailbreak_v2v_bytes_copied_total counter
+vjailbreak_v2v_bytes_copied_total{disk="disk1",plan="plan1",vm="vm1"} 1024
+# HELP vjailbreak_v2v_changed_block_bytes_total Bytes of changed blocks copied over all changed blocks copy iterations and disks
+# TYPE vjailbreak_v2v_changed_block_bytes_total counter
+vjailbreak_v2v_changed_block_bytes_total{plan="plan1",vm="vm1"} 160
+# HELP vjailbreak_v2v_last_iteration_changed_block_bytes Bytes of changed blocks copied in the latest changed blocks copy iteration, summed over all disks
+# TYPE vjailbreak_v2v_last_iteration_changed_block_bytes gauge
+vjailbreak_v2v_last_iteration_changed_block_bytes{plan="plan1",vm="vm1"} 10
+# HELP vjailbreak_v2v_nbdcopy_throughput_bytes_per_second Average throughput of the last completed copy of a disk
+# TYPE vjailbreak_v2v_nbdcopy_throughput_bytes_per_second gauge
+vjailbreak_v2v_nbdcopy_throughput_bytes_per_second{disk="disk1",plan="plan1",vm="vm1"} 256
+# HELP vjailbreak_v2v_virt_v2v_duration_seconds Time taken by virt-v2v to convert the VM
+# TYPE vjailbreak_v2v_virt_v2v_duration_seconds gauge
+vjailbreak_v2v_virt_v2v_duration_seconds{plan="plan1",vm="vm1"} 90
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected)))
+
+	// An iteration without changed blocks resets the latest iteration
+	m.AddChangedBlockBytes(3, 0)
+	assert.Equal(t, float64(0), testutil.ToFloat64(m.lastChangedBytes))
+	assert.Equal(t, float64(160), testutil.ToFloat64(m.changedBlockBytes))
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *Metrics
+	assert.NotPanics(t, func() {
+		m.AddBytesCopied("disk1", 1)
+		m.AddChangedBlockBytes(1, 1)
+		m.ObserveCopy("disk1", 1, time.Second)
+		m.ObserveVirtV2V(time.Second)
+	})
+	_, err := m.Serve(context.Background(), 0)
+	assert.Error(t, err)
+}
+
+func TestServeSkipsPortsInUse(t *testing.T) {
+	taken, err := net.Listen("tcp", ":0")
+	require.NoError(t, err)
+	defer taken.Close()
+	takenPort := taken.Addr().(*net.TCPAddr).Port
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewMetrics("vm1", "plan1")
+	m.AddBytesCopied("disk1", 1)
+	port, err := m.Serve(ctx, takenPort)
+	require.NoError(t, err)
+	assert.NotEqual(t, takenPort, port)
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/metrics", port))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `vjailbreak_v2v_bytes_copied_total{disk="disk1",plan="plan1",vm="vm1"} 1`)
+}
@@ -0,0 +1,75 @@
+// Copyright © 2024 The vjailbreak authors
+
+package throttle
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/k8sutils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewLimitsFunc returns a LimitsFunc reading the limit of the MigrationPlan planName, the global limit
+// of the vjailbreak settings and the number of v2v-helper pods copying disks from Kubernetes
+func NewLimitsFunc(k8sClient client.Client, planName string) LimitsFunc {
+	return func(ctx context.Context) (Limits, error) {
+		limits := Limits{}
+		if planName != "" {
+			migrationplan := &vjailbreakv1alpha1.MigrationPlan{}
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: planName, Namespace: constants.NamespaceMigrationSystem}, migrationplan); err != nil {
+				return Limits{}, errors.Wrapf(err, "failed to get migration plan %s", planName)
+			}
+			limits.Plan = migrationplan.Spec.BandwidthLimit
+		}
+
+		settings, err := k8sutils.GetVjailbreakSettings(ctx, k8sClient)
+		if err != nil {
+			return Limits{}, err
+		}
+		windows, err := ParseWindows(settings.BandwidthLimitWindows)
+		if err != nil {
+			return Limits{}, errors.Wrap(err, "failed to parse BANDWIDTH_LIMIT_WINDOWS")
+		}
+		if settings.BandwidthLimitMBps > 0 || len(windows) > 0 {
+			limits.Global = &vjailbreakv1alpha1.BandwidthLimit{
+				MBps:     settings.BandwidthLimitMBps,
+				TimeZone: settings.BandwidthLimitTimeZone,
+				Windows:  windows,
+			}
+			limits.CopyingPods, err = countCopyingPods(ctx, k8sClient)
+			if err != nil {
+				return Limits{}, err
+			}
+		}
+		return limits, nil
+	}
+}
+
+// countCopyingPods returns the number of v2v-helper pods, on any agent, whose reported progress is a copy phase
+func countCopyingPods(ctx context.Context, k8sClient client.Client) (int, error) {
+	pods := &corev1.PodList{}
+	if err := k8sClient.List(ctx, pods, client.InNamespace(constants.NamespaceMigrationSystem)); err != nil {
+		return 0, errors.Wrap(err, "failed to list pods")
+	}
+	copying := 0
+	for i := range pods.Items {
+		data, ok := pods.Items[i].Annotations[vjailbreakv1alpha1.MigrationProgressAnnotation]
+		if !ok || pods.Items[i].Status.Phase != corev1.PodRunning {
+			continue
+		}
+		progress := vjailbreakv1alpha1.MigrationProgress{}
+		if err := json.Unmarshal([]byte(data), &progress); err != nil {
+			continue
+		}
+		if progress.Phase == vjailbreakv1alpha1.VMMigrationPhaseCopying || progress.Phase == vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks {
+			copying++
+		}
+	}
+	return copying, nil
+}
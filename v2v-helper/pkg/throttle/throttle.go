@@ -0,0 +1,242 @@
+// Copyright © 2024 The vjailbreak authors
+
+// Package throttle limits the bandwidth v2v-helper uses to copy disks from VMware.
+//
+// Disks are read through nbdkit, both by nbdcopy for full copies and by libnbd for changed blocks,
+// so the limit is enforced by the nbdkit rate filter of each disk. The rate filter re-reads its rate file
+// every second; the Throttle keeps the rate files of the disks being copied up to date with the share
+// of the bandwidth the pod may use.
+// A nil *Throttle is valid and limits nothing, so callers do not have to check whether throttling is enabled.
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+)
+
+// bytesPerMB is the size of the MB the limits are given in
+const bytesPerMB = 1024 * 1024
+
+// Limits are the bandwidth limits that apply to the copies of a pod
+type Limits struct {
+	// Plan is the limit of the MigrationPlan of the VM, it applies to the pod alone
+	Plan *vjailbreakv1alpha1.BandwidthLimit
+	// Global is the limit shared by all v2v-helper pods copying disks at the same time
+	Global *vjailbreakv1alpha1.BandwidthLimit
+	// CopyingPods is the number of v2v-helper pods copying disks, including this one
+	CopyingPods int
+}
+
+// LimitsFunc reads the current limits
+type LimitsFunc func(ctx context.Context) (Limits, error)
+
+// PodRate returns the bandwidth in bytes per second the pod may use at the given time, 0 means unlimited
+func (l Limits) PodRate(at time.Time) int64 {
+	rate := LimitAt(l.Plan, at)
+	if global := LimitAt(l.Global, at); global > 0 {
+		share := max(global/int64(max(l.CopyingPods, 1)), 1)
+		if rate == 0 || share < rate {
+			rate = share
+		}
+	}
+	return rate
+}
+
+// LimitAt returns the limit in bytes per second that applies at the given time, 0 means unlimited.
+// The windows are compared in the time zone of the limit, UTC if it is not set or unknown
+func LimitAt(limit *vjailbreakv1alpha1.BandwidthLimit, at time.Time) int64 {
+	if limit == nil {
+		return 0
+	}
+	location := time.UTC
+	if limit.TimeZone != "" {
+		if loaded, err := time.LoadLocation(limit.TimeZone); err == nil {
+			location = loaded
+		}
+	}
+	local := at.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	for _, window := range limit.Windows {
+		start, errStart := parseTimeOfDay(window.Start)
+		end, errEnd := parseTimeOfDay(window.End)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+		inWindow := start <= minute && minute < end
+		if end < start {
+			inWindow = minute >= start || minute < end
+		}
+		if inWindow {
+			return int64(window.MBps) * bytesPerMB
+		}
+	}
+	return int64(limit.MBps) * bytesPerMB
+}
+
+// parseTimeOfDay returns the minute of the day of a HH:MM time
+func parseTimeOfDay(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid time of day %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseWindows parses windows written as comma separated HH:MM-HH:MM=MBps entries,
+// e.g. "08:00-18:00=50,18:00-20:00=200"
+func ParseWindows(value string) ([]vjailbreakv1alpha1.BandwidthWindow, error) {
+	var windows []vjailbreakv1alpha1.BandwidthWindow
+	for _, entry := range utils.RemoveEmptyStrings(strings.Split(value, ",")) {
+		times, mbps, found := strings.Cut(entry, "=")
+		start, end, foundRange := strings.Cut(times, "-")
+		if !found || !foundRange {
+			return nil, errors.Errorf("invalid bandwidth window %q, expected HH:MM-HH:MM=MBps", entry)
+		}
+		if _, err := parseTimeOfDay(start); err != nil {
+			return nil, err
+		}
+		if _, err := parseTimeOfDay(end); err != nil {
+			return nil, err
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(mbps))
+		if err != nil || limit < 0 {
+			return nil, errors.Errorf("invalid bandwidth %q in window %q", mbps, entry)
+		}
+		windows = append(windows, vjailbreakv1alpha1.BandwidthWindow{
+			Start: strings.TrimSpace(start),
+			End:   strings.TrimSpace(end),
+			MBps:  limit,
+		})
+	}
+	return windows, nil
+}
+
+// Throttle shares the bandwidth of a pod between the disks it is copying
+type Throttle struct {
+	mu     sync.Mutex
+	limits Limits
+	// copies is the number of copies running through the nbdkit server of each rate file
+	copies map[string]int
+	// rate is the rate last written to the rate files in bytes per second
+	rate int64
+	now  func() time.Time
+}
+
+// New returns a Throttle without limits until they are read by Run
+func New() *Throttle {
+	return &Throttle{copies: map[string]int{}, now: time.Now}
+}
+
+// Run reads the limits every interval, and applies them, until ctx is done.
+// Errors are logged and the previous limits are kept.
+func (t *Throttle) Run(ctx context.Context, interval time.Duration, getLimits LimitsFunc) {
+	if t == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		limits, err := getLimits(ctx)
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to read bandwidth limits, keeping the previous ones: %v", err))
+		} else {
+			t.SetLimits(limits)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SetLimits applies new limits to the running copies
+func (t *Throttle) SetLimits(limits Limits) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limits = limits
+	t.apply()
+}
+
+// RateFile returns the rate file of the nbdkit server using dir as its temporary directory,
+// or an empty string if t is nil and no rate filter is needed
+func (t *Throttle) RateFile(dir string) string {
+	if t == nil {
+		return ""
+	}
+	return filepath.Join(dir, "rate")
+}
+
+// Start marks a copy through the nbdkit server reading rateFile as running. The returned
+// function must be called once the copy is done, the bandwidth is then shared by the other copies.
+func (t *Throttle) Start(rateFile string) (done func()) {
+	if t == nil || rateFile == "" {
+		return func() {}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.copies[rateFile]++
+	t.apply()
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.copies[rateFile]--
+		if t.copies[rateFile] <= 0 {
+			delete(t.copies, rateFile)
+			// The server is idle, a copy started through it later is limited again by apply
+			if err := writeRateFile(rateFile, 0); err != nil {
+				utils.PrintLog(fmt.Sprintf("Failed to reset bandwidth limit in %s: %v", rateFile, err))
+			}
+		}
+		t.apply()
+	}
+}
+
+// apply writes the share of the pod rate of each running copy to the rate files. t.mu must be held.
+func (t *Throttle) apply() {
+	running := 0
+	for _, copies := range t.copies {
+		running += copies
+	}
+	rate := t.limits.PodRate(t.now())
+	if rate != t.rate {
+		if rate == 0 {
+			utils.PrintLog("Disk copy bandwidth is unlimited")
+		} else {
+			utils.PrintLog(fmt.Sprintf("Disk copy bandwidth is limited to %.2f MB/s", float64(rate)/bytesPerMB))
+		}
+		t.rate = rate
+	}
+	for rateFile, copies := range t.copies {
+		share := int64(0)
+		if rate > 0 {
+			share = max(rate*int64(copies)/int64(running), 1)
+		}
+		if err := writeRateFile(rateFile, share); err != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to set bandwidth limit in %s: %v", rateFile, err))
+		}
+	}
+}
+
+// writeRateFile atomically replaces the rate file with a rate in bytes per second.
+// The nbdkit rate filter reads the rate in bits per second, 0 means no limit.
+func writeRateFile(rateFile string, bytesPerSecond int64) error {
+	tmpFile := rateFile + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(strconv.FormatInt(bytesPerSecond*8, 10)), 0644); err != nil {
+		return errors.Wrap(err, "failed to write rate file")
+	}
+	return errors.Wrap(os.Rename(tmpFile, rateFile), "failed to replace rate file")
+}
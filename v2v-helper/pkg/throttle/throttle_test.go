@@ -0,0 +1,120 @@
+// Copyright © 2024 The vjailbreak authors
+package throttle
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2025, 1, 1, hour, minute, 0, 0, time.UTC)
+}
+
+func TestLimitAt(t *testing.T) {
+	limit := &vjailbreakv1alpha1.BandwidthLimit{
+		MBps: 0,
+		Windows: []vjailbreakv1alpha1.BandwidthWindow{
+			{Start: "08:00", End: "18:00", MBps: 50},
+			{Start: "22:00", End: "02:00", MBps: 200},
+		},
+	}
+	assert.Equal(t, int64(0), LimitAt(nil, at(12, 0)))
+	assert.Equal(t, int64(50*bytesPerMB), LimitAt(limit, at(8, 0)))
+	assert.Equal(t, int64(50*bytesPerMB), LimitAt(limit, at(17, 59)))
+	assert.Equal(t, int64(0), LimitAt(limit, at(18, 0)))
+	assert.Equal(t, int64(200*bytesPerMB), LimitAt(limit, at(23, 30)))
+	assert.Equal(t, int64(200*bytesPerMB), LimitAt(limit, at(1, 0)))
+	assert.Equal(t, int64(0), LimitAt(limit, at(3, 0)))
+
+	// Times are compared in UTC
+	newYork := time.FixedZone("EST", -5*60*60)
+	assert.Equal(t, int64(50*bytesPerMB), LimitAt(limit, time.Date(2025, 1, 1, 4, 0, 0, 0, newYork)))
+
+	// unless the limit has a time zone
+	limit.TimeZone = "America/New_York"
+	assert.Equal(t, int64(0), LimitAt(limit, time.Date(2025, 1, 1, 4, 0, 0, 0, newYork)))
+	assert.Equal(t, int64(50*bytesPerMB), LimitAt(limit, at(13, 0)))
+	limit.TimeZone = "Mars/Olympus_Mons"
+	assert.Equal(t, int64(50*bytesPerMB), LimitAt(limit, at(8, 0)))
+}
+
+func TestParseWindows(t *testing.T) {
+	windows, err := ParseWindows("08:00-18:00=50, 22:00-02:00=0")
+	require.NoError(t, err)
+	assert.Equal(t, []vjailbreakv1alpha1.BandwidthWindow{
+		{Start: "08:00", End: "18:00", MBps: 50},
+		{Start: "22:00", End: "02:00", MBps: 0},
+	}, windows)
+
+	windows, err = ParseWindows("")
+	require.NoError(t, err)
+	assert.Empty(t, windows)
+
+	for _, invalid := range []string{"08:00=50", "08:00-18:00", "8am-18:00=50", "08:00-18:00=-1", "08:00-18:00=fast"} {
+		_, err := ParseWindows(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPodRate(t *testing.T) {
+	limits := Limits{
+		Plan:        &vjailbreakv1alpha1.BandwidthLimit{MBps: 100},
+		Global:      &vjailbreakv1alpha1.BandwidthLimit{Windows: []vjailbreakv1alpha1.BandwidthWindow{{Start: "08:00", End: "18:00", MBps: 300}}},
+		CopyingPods: 4,
+	}
+	// The global limit is shared by the copying pods, the lower limit applies
+	assert.Equal(t, int64(75*bytesPerMB), limits.PodRate(at(9, 0)))
+	assert.Equal(t, int64(100*bytesPerMB), limits.PodRate(at(20, 0)))
+
+	limits.CopyingPods = 0
+	assert.Equal(t, int64(100*bytesPerMB), limits.PodRate(at(9, 0)))
+	assert.Equal(t, int64(0), Limits{}.PodRate(at(9, 0)))
+}
+
+func readRate(t *testing.T, rateFile string) string {
+	data, err := os.ReadFile(rateFile)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestThrottleSharesRateBetweenCopies(t *testing.T) {
+	dir := t.TempDir()
+	throttle := New()
+	throttle.now = func() time.Time { return at(12, 0) }
+	throttle.SetLimits(Limits{Plan: &vjailbreakv1alpha1.BandwidthLimit{MBps: 10}})
+
+	disk0 := throttle.RateFile(filepath.Join(dir, "disk0"))
+	disk1 := throttle.RateFile(filepath.Join(dir, "disk1"))
+	require.NoError(t, os.MkdirAll(filepath.Dir(disk0), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Dir(disk1), 0755))
+
+	// Rates are written in bits per second
+	done0 := throttle.Start(disk0)
+	assert.Equal(t, "83886080", readRate(t, disk0))
+	done1 := throttle.Start(disk1)
+	assert.Equal(t, "41943040", readRate(t, disk0))
+	assert.Equal(t, "41943040", readRate(t, disk1))
+
+	done0()
+	assert.Equal(t, "0", readRate(t, disk0))
+	assert.Equal(t, "83886080", readRate(t, disk1))
+
+	throttle.SetLimits(Limits{})
+	assert.Equal(t, "0", readRate(t, disk1))
+	done1()
+}
+
+func TestNilThrottleLimitsNothing(t *testing.T) {
+	var throttle *Throttle
+	assert.Empty(t, throttle.RateFile(t.TempDir()))
+	assert.NotPanics(t, func() {
+		throttle.SetLimits(Limits{CopyingPods: 1})
+		throttle.Start("")()
+	})
+}
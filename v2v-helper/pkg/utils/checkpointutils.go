@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DiskCheckpoint records how far the copy of a single disk has progressed
+type DiskCheckpoint struct {
+	// Name is the name of the source VMware disk
+	Name string `json:"name"`
+	// VolumeID is the ID of the Cinder volume the disk is copied to
+	VolumeID string `json:"volumeID"`
+	// ChangeID is the CBT change ID up to which the volume is in sync with the source disk
+	ChangeID string `json:"changeID,omitempty"`
+	// Iteration is the changed blocks copy iteration in which ChangeID was recorded
+	Iteration int `json:"iteration"`
+	// Phase is one of CheckpointPhaseVolumeCreated or CheckpointPhaseCopied
+	Phase string `json:"phase"`
+}
+
+// MigrationCheckpoint is the copy state of a migration that survives a v2v-helper pod restart
+type MigrationCheckpoint struct {
+	VMName string           `json:"vmName"`
+	Disks  []DiskCheckpoint `json:"disks"`
+}
+
+// GetDisk returns the checkpoint of the named disk, or nil if there is none
+func (c *MigrationCheckpoint) GetDisk(name string) *DiskCheckpoint {
+	if c == nil {
+		return nil
+	}
+	for idx := range c.Disks {
+		if c.Disks[idx].Name == name {
+			return &c.Disks[idx]
+		}
+	}
+	return nil
+}
+
+// IsResumable returns true if at least one disk has completed its full copy
+func (c *MigrationCheckpoint) IsResumable() bool {
+	if c == nil {
+		return false
+	}
+	for _, disk := range c.Disks {
+		if disk.Phase == constants.CheckpointPhaseCopied {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMigrationCheckpointName returns the name of the checkpoint configmap of the current migration
+func GetMigrationCheckpointName() (string, error) {
+	vmK8sName, err := GetVMwareMachineName()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", constants.MigrationCheckpointConfigMapPrefix, vmK8sName), nil
+}
+
+// GetMigrationCheckpoint returns the saved checkpoint of the current migration, or nil if there is none
+func GetMigrationCheckpoint(ctx context.Context, k8sClient client.Client) (*MigrationCheckpoint, error) {
+	configMapName, err := GetMigrationCheckpointName()
+	if err != nil {
+		return nil, err
+	}
+	configMap := &v1.ConfigMap{}
+	err = k8sClient.Get(ctx, types.NamespacedName{
+		Name:      configMapName,
+		Namespace: constants.NamespaceMigrationSystem,
+	}, configMap)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "failed to get checkpoint configmap")
+	}
+	data, ok := configMap.Data[constants.MigrationCheckpointDataKey]
+	if !ok || data == "" {
+		return nil, nil
+	}
+	checkpoint := &MigrationCheckpoint{}
+	if err := json.Unmarshal([]byte(data), checkpoint); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal checkpoint")
+	}
+	return checkpoint, nil
+}
+
+// SaveMigrationCheckpoint creates or updates the checkpoint configmap of the current migration
+func SaveMigrationCheckpoint(ctx context.Context, k8sClient client.Client, checkpoint *MigrationCheckpoint) error {
+	configMapName, err := GetMigrationCheckpointName()
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal checkpoint")
+	}
+
+	configMap := &v1.ConfigMap{}
+	err = k8sClient.Get(ctx, types.NamespacedName{
+		Name:      configMapName,
+		Namespace: constants.NamespaceMigrationSystem,
+	}, configMap)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "failed to get checkpoint configmap")
+		}
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            configMapName,
+				Namespace:       constants.NamespaceMigrationSystem,
+				OwnerReferences: getCheckpointOwnerReferences(ctx, k8sClient),
+			},
+			Data: map[string]string{
+				constants.MigrationCheckpointDataKey: string(data),
+			},
+		}
+		if err := k8sClient.Create(ctx, configMap); err != nil {
+			return errors.Wrap(err, "failed to create checkpoint configmap")
+		}
+		return nil
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[constants.MigrationCheckpointDataKey] = string(data)
+	if err := k8sClient.Update(ctx, configMap); err != nil {
+		return errors.Wrap(err, "failed to update checkpoint configmap")
+	}
+	return nil
+}
+
+// DeleteMigrationCheckpoint deletes the checkpoint configmap of the current migration if it exists
+func DeleteMigrationCheckpoint(ctx context.Context, k8sClient client.Client) error {
+	configMapName, err := GetMigrationCheckpointName()
+	if err != nil {
+		return err
+	}
+	err = k8sClient.Delete(ctx, &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      configMapName,
+			Namespace: constants.NamespaceMigrationSystem,
+		},
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete checkpoint configmap")
+	}
+	return nil
+}
+
+// getCheckpointOwnerReferences makes the MigrationPlan own the checkpoint. The Migration itself is
+// deleted and recreated on retry, so owning the checkpoint by it would throw the progress away.
+func getCheckpointOwnerReferences(ctx context.Context, k8sClient client.Client) []metav1.OwnerReference {
+	migrationName, err := GetMigrationObjectName()
+	if err != nil {
+		return nil
+	}
+	migration := &vjailbreakv1alpha1.Migration{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: migrationName, Namespace: constants.NamespaceMigrationSystem}, migration); err != nil {
+		PrintLog(fmt.Sprintf("Failed to get migration %s, checkpoint will have no owner: %v", migrationName, err))
+		return nil
+	}
+	migrationPlan := &vjailbreakv1alpha1.MigrationPlan{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: migration.Spec.MigrationPlan, Namespace: constants.NamespaceMigrationSystem}, migrationPlan); err != nil {
+		PrintLog(fmt.Sprintf("Failed to get migration plan %s, checkpoint will have no owner: %v", migration.Spec.MigrationPlan, err))
+		return nil
+	}
+	return []metav1.OwnerReference{
+		{
+			APIVersion: vjailbreakv1alpha1.GroupVersion.String(),
+			Kind:       "MigrationPlan",
+			Name:       migrationPlan.Name,
+			UID:        migrationPlan.UID,
+		},
+	}
+}
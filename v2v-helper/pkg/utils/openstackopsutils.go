@@ -26,10 +26,13 @@ import (
 	"github.com/gophercloud/gophercloud/openstack/blockstorage/extensions/volumeactions"
 	"github.com/gophercloud/gophercloud/openstack/blockstorage/v3/volumes"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/bootfromvolume"
+	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/startstop"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/volumeattach"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/flavors"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
 	"github.com/gophercloud/gophercloud/openstack/identity/v3/projects"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/imagedata"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/extensions/security/groups"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/networks"
 	"github.com/gophercloud/gophercloud/openstack/networking/v2/ports"
@@ -39,6 +42,7 @@ type OpenStackClients struct {
 	BlockStorageClient *gophercloud.ServiceClient
 	ComputeClient      *gophercloud.ServiceClient
 	NetworkingClient   *gophercloud.ServiceClient
+	ImageClient        *gophercloud.ServiceClient
 	K8sClient          client.Client
 	AuthURL, Tenant    string
 }
@@ -110,13 +114,24 @@ func GetCurrentInstanceUUID() (string, error) {
 
 // create a new volume
 func (osclient *OpenStackClients) CreateVolume(name string, size int64, ostype string, uefi bool, volumetype string, setRDMLabel bool) (*volumes.Volume, error) {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Creating volume with name %s with size %d, for OS type %s, UEFI %v, volume type %s, authurl %s, tenant %s", name, size, ostype, uefi, volumetype, osclient.AuthURL, osclient.Tenant))
+	return osclient.createVolume(name, size, ostype, uefi, volumetype, "", setRDMLabel)
+}
+
+// CreateVolumeFromImage creates a volume with the content of the image
+func (osclient *OpenStackClients) CreateVolumeFromImage(name string, size int64, ostype string, uefi bool, volumetype, imageID string, setRDMLabel bool) (*volumes.Volume, error) {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Creating volume with name %s with size %d from image %s, for OS type %s, UEFI %v, volume type %s, authurl %s, tenant %s", name, size, imageID, ostype, uefi, volumetype, osclient.AuthURL, osclient.Tenant))
+	return osclient.createVolume(name, size, ostype, uefi, volumetype, imageID, setRDMLabel)
+}
+
+func (osclient *OpenStackClients) createVolume(name string, size int64, ostype string, uefi bool, volumetype, imageID string, setRDMLabel bool) (*volumes.Volume, error) {
 	blockStorageClient := osclient.BlockStorageClient
 
-	PrintLog(fmt.Sprintf("OPENSTACK API: Creating volume with name %s with size %d, for OS type %s, UEFI %v, volume type %s, authurl %s, tenant %s", name, size, ostype, uefi, volumetype, osclient.AuthURL, osclient.Tenant))
 	opts := volumes.CreateOpts{
 		VolumeType: volumetype,
 		Size:       int(math.Ceil(float64(size) / (1024 * 1024 * 1024))),
 		Name:       name,
+		ImageID:    imageID,
 	}
 
 	// Add 1GB to the size to account for the extra space
@@ -159,6 +174,57 @@ func (osclient *OpenStackClients) CreateVolume(name string, size int64, ostype s
 	return volume, nil
 }
 
+// UploadImage uploads the raw disk file to a new image and returns the ID of the image once it is active
+func (osclient *OpenStackClients) UploadImage(name, path string) (string, error) {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Uploading %s to image %s, authurl %s, tenant %s", path, name, osclient.AuthURL, osclient.Tenant))
+	file, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to open disk file")
+	}
+	defer file.Close()
+
+	image, err := images.Create(osclient.ImageClient, images.CreateOpts{
+		Name:            name,
+		DiskFormat:      "raw",
+		ContainerFormat: "bare",
+	}).Extract()
+	if err != nil {
+		return "", fmt.Errorf("failed to create image: %s", err)
+	}
+	if err := imagedata.Upload(osclient.ImageClient, image.ID, file).ExtractErr(); err != nil {
+		return image.ID, fmt.Errorf("failed to upload image data: %s", err)
+	}
+
+	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(context.Background(), osclient.K8sClient)
+	if err != nil {
+		return image.ID, errors.Wrap(err, "failed to get vjailbreak settings")
+	}
+	for i := 0; i < vjailbreakSettings.VolumeAvailableWaitRetryLimit; i++ {
+		current, err := images.Get(osclient.ImageClient, image.ID).Extract()
+		if err != nil {
+			return image.ID, fmt.Errorf("failed to get image: %s", err)
+		}
+		switch current.Status {
+		case images.ImageStatusActive:
+			PrintLog(fmt.Sprintf("Image %s uploaded successfully", image.ID))
+			return image.ID, nil
+		case images.ImageStatusKilled, images.ImageStatusDeleted:
+			return image.ID, fmt.Errorf("image %s is in %s state", image.ID, current.Status)
+		}
+		time.Sleep(time.Duration(vjailbreakSettings.VolumeAvailableWaitIntervalSeconds) * time.Second)
+	}
+	return image.ID, fmt.Errorf("image did not become active within %d seconds", vjailbreakSettings.VolumeAvailableWaitRetryLimit*vjailbreakSettings.VolumeAvailableWaitIntervalSeconds)
+}
+
+func (osclient *OpenStackClients) DeleteImage(imageID string) error {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Deleting image with ID %s, authurl %s, tenant %s", imageID, osclient.AuthURL, osclient.Tenant))
+	err := images.Delete(osclient.ImageClient, imageID).ExtractErr()
+	if err != nil {
+		return fmt.Errorf("failed to delete image: %s", err)
+	}
+	return nil
+}
+
 func (osclient *OpenStackClients) DeleteVolume(volumeID string) error {
 	PrintLog(fmt.Sprintf("OPENSTACK API: Deleting volume with ID %s, authurl %s, tenant %s", volumeID, osclient.AuthURL, osclient.Tenant))
 	err := volumes.Delete(osclient.BlockStorageClient, volumeID, volumes.DeleteOpts{}).ExtractErr()
@@ -168,6 +234,15 @@ func (osclient *OpenStackClients) DeleteVolume(volumeID string) error {
 	return nil
 }
 
+func (osclient *OpenStackClients) GetVolume(volumeID string) (*volumes.Volume, error) {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Getting volume with ID %s, authurl %s, tenant %s", volumeID, osclient.AuthURL, osclient.Tenant))
+	volume, err := volumes.Get(osclient.BlockStorageClient, volumeID).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume: %s", err)
+	}
+	return volume, nil
+}
+
 func (osclient *OpenStackClients) WaitForVolume(volumeID string) error {
 	// Get vjailbreak settings
 	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(context.Background(), osclient.K8sClient)
@@ -364,6 +439,26 @@ func (osclient *OpenStackClients) SetVolumeBootable(volume *volumes.Volume) erro
 	return nil
 }
 
+// GetVolumeConnection returns the connection info of the volume for the agent, which tells how the
+// storage backend of the volume is reached
+func (osclient *OpenStackClients) GetVolumeConnection(volumeID string) (map[string]interface{}, error) {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Getting connection info of volume %s, authurl %s, tenant %s", volumeID, osclient.AuthURL, osclient.Tenant))
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %s", err)
+	}
+	options := volumeactions.InitializeConnectionOpts{
+		Host:     hostname,
+		Platform: "x86_64",
+		OSType:   "linux2",
+	}
+	info, err := volumeactions.InitializeConnection(osclient.BlockStorageClient, volumeID, options).Extract()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection info of volume: %s", err)
+	}
+	return info, nil
+}
+
 func (osclient *OpenStackClients) GetClosestFlavour(cpu int32, memory int32) (*flavors.Flavor, error) {
 	PrintLog(fmt.Sprintf("OPENSTACK API: Getting closest flavor for %d vCPUs and %d MB RAM, authurl %s, tenant %s", cpu, memory, osclient.AuthURL, osclient.Tenant))
 	allPages, err := flavors.ListDetail(osclient.ComputeClient, nil).AllPages()
@@ -636,6 +731,65 @@ func (osclient *OpenStackClients) WaitUntilVMActive(vmID string) (bool, error) {
 	return true, nil
 }
 
+// StopServer shuts off a server and waits until it is stopped
+func (osclient *OpenStackClients) StopServer(serverID string) error {
+	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(context.Background(), osclient.K8sClient)
+	if err != nil {
+		return errors.Wrap(err, "failed to get vjailbreak settings")
+	}
+	PrintLog(fmt.Sprintf("OPENSTACK API: Stopping server %s, authurl %s, tenant %s", serverID, osclient.AuthURL, osclient.Tenant))
+	if err := startstop.Stop(osclient.ComputeClient, serverID).ExtractErr(); err != nil {
+		return fmt.Errorf("failed to stop server: %s", err)
+	}
+	err = servers.WaitForStatus(osclient.ComputeClient, serverID, "SHUTOFF", vjailbreakSettings.VMActiveWaitRetryLimit*vjailbreakSettings.VMActiveWaitIntervalSeconds)
+	if err != nil {
+		return fmt.Errorf("failed to wait for server to stop: %s", err)
+	}
+	return nil
+}
+
+// DeleteServer deletes a server and waits until it is gone. Its volumes are kept.
+func (osclient *OpenStackClients) DeleteServer(serverID string) error {
+	vjailbreakSettings, err := k8sutils.GetVjailbreakSettings(context.Background(), osclient.K8sClient)
+	if err != nil {
+		return errors.Wrap(err, "failed to get vjailbreak settings")
+	}
+	PrintLog(fmt.Sprintf("OPENSTACK API: Deleting server %s, authurl %s, tenant %s", serverID, osclient.AuthURL, osclient.Tenant))
+	if err := servers.Delete(osclient.ComputeClient, serverID).ExtractErr(); err != nil {
+		return fmt.Errorf("failed to delete server: %s", err)
+	}
+	err = gophercloud.WaitFor(vjailbreakSettings.VMActiveWaitRetryLimit*vjailbreakSettings.VMActiveWaitIntervalSeconds, func() (bool, error) {
+		_, err := servers.Get(osclient.ComputeClient, serverID).Extract()
+		if _, ok := err.(gophercloud.ErrDefault404); ok {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for server to be deleted: %s", err)
+	}
+	return nil
+}
+
+// DeletePort deletes a port, detaching it from its server
+func (osclient *OpenStackClients) DeletePort(portID string) error {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Deleting port %s, authurl %s, tenant %s", portID, osclient.AuthURL, osclient.Tenant))
+	if err := ports.Delete(osclient.NetworkingClient, portID).ExtractErr(); err != nil {
+		return fmt.Errorf("failed to delete port: %s", err)
+	}
+	return nil
+}
+
+// GetConsoleOutput returns the last lines of the console log of a server
+func (osclient *OpenStackClients) GetConsoleOutput(serverID string, lines int) (string, error) {
+	PrintLog(fmt.Sprintf("OPENSTACK API: Getting console output of server %s, authurl %s, tenant %s", serverID, osclient.AuthURL, osclient.Tenant))
+	output, err := servers.ShowConsoleOutput(osclient.ComputeClient, serverID, servers.ShowConsoleOutputOpts{Length: lines}).Extract()
+	if err != nil {
+		return "", fmt.Errorf("failed to get console output: %s", err)
+	}
+	return output, nil
+}
+
 func (osclient *OpenStackClients) GetSecurityGroupIDs(groupNames []string, projectName string) ([]string, error) {
 	if len(groupNames) == 0 {
 		return nil, nil
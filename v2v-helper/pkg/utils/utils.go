@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/constants"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
@@ -60,6 +62,37 @@ func GetMigrationObjectName() (string, error) {
 	return fmt.Sprintf("migration-%s", vmK8sName), nil
 }
 
+// GetMigrationPlanName returns the name of the MigrationPlan the migration of this pod belongs to
+func GetMigrationPlanName(ctx context.Context, k8sClient client.Client) (string, error) {
+	migrationName, err := GetMigrationObjectName()
+	if err != nil {
+		return "", err
+	}
+	migration := &vjailbreakv1alpha1.Migration{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: migrationName, Namespace: constants.NamespaceMigrationSystem}, migration); err != nil {
+		return "", errors.Wrapf(err, "failed to get migration %s", migrationName)
+	}
+	return migration.Spec.MigrationPlan, nil
+}
+
+// SaveGuestInspection records the inspection of the guest in the status of the VMwareMachine of this pod
+func SaveGuestInspection(ctx context.Context, k8sClient client.Client, inspection *vjailbreakv1alpha1.GuestInspection) error {
+	vmK8sName, err := GetVMwareMachineName()
+	if err != nil {
+		return err
+	}
+	vmwareMachine := &vjailbreakv1alpha1.VMwareMachine{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{Name: vmK8sName, Namespace: constants.NamespaceMigrationSystem}, vmwareMachine); err != nil {
+		return errors.Wrapf(err, "failed to get vmwaremachine %s", vmK8sName)
+	}
+	patch := client.MergeFrom(vmwareMachine.DeepCopy())
+	vmwareMachine.Status.GuestInspection = inspection
+	if err := k8sClient.Status().Patch(ctx, vmwareMachine, patch); err != nil {
+		return errors.Wrapf(err, "failed to save guest inspection of vmwaremachine %s", vmK8sName)
+	}
+	return nil
+}
+
 // GetMigrationConfigMapName is function that returns the name of the secret
 func GetMigrationConfigMapName() (string, error) {
 	vmK8sName, err := GetVMwareMachineName()
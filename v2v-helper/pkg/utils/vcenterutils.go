@@ -39,6 +39,17 @@ type MigrationParams struct {
 	SecurityGroups          string
 	RDMDisks                string
 	FallbackToDHCP          bool
+	DataVerification        string
+	RollbackPolicy          string
+	RemoveVMwareTools       bool
+	AllowUnsupportedOS      bool
+	InspectOnly             bool
+	TargetBackend           string
+	RBDDirectWrite          bool
+	ZeroedVolumeTypes       string
+	WarmSyncInterval        string
+	MaintenanceWindow       string
+	PlanNamespace           string
 }
 
 // GetMigrationParams is function that returns the migration parameters
@@ -79,5 +90,16 @@ func GetMigrationParams(ctx context.Context, client client.Client) (*MigrationPa
 		SecurityGroups:          string(configMap.Data["SECURITY_GROUPS"]),
 		RDMDisks:                string(configMap.Data["RDM_DISK_NAMES"]),
 		FallbackToDHCP:          string(configMap.Data["FALLBACK_TO_DHCP"]) == constants.TrueString,
+		DataVerification:        string(configMap.Data["DATA_VERIFICATION"]),
+		RollbackPolicy:          string(configMap.Data["ROLLBACK_POLICY"]),
+		RemoveVMwareTools:       string(configMap.Data["REMOVE_VMWARE_TOOLS"]) == constants.TrueString,
+		AllowUnsupportedOS:      string(configMap.Data["ALLOW_UNSUPPORTED_OS"]) == constants.TrueString,
+		InspectOnly:             string(configMap.Data["INSPECT_ONLY"]) == constants.TrueString,
+		TargetBackend:           string(configMap.Data["TARGET_BACKEND"]),
+		RBDDirectWrite:          string(configMap.Data["RBD_DIRECT_WRITE"]) == constants.TrueString,
+		ZeroedVolumeTypes:       string(configMap.Data["ZEROED_VOLUME_TYPES"]),
+		WarmSyncInterval:        string(configMap.Data["WARM_SYNC_INTERVAL"]),
+		MaintenanceWindow:       string(configMap.Data["MAINTENANCE_WINDOW"]),
+		PlanNamespace:           string(configMap.Data["MIGRATION_PLAN_NAMESPACE"]),
 	}, nil
 }
@@ -10,11 +10,7 @@ import (
 )
 
 func GenerateXMLConfig(vminfo vm.VMInfo) error {
-	diskFiles := []string{}
-	for _, vmdisk := range vminfo.VMDisks {
-		diskFiles = append(diskFiles, vmdisk.Path)
-	}
-	if err := xml.GenerateXML(diskFiles, constants.XMLFileName, vminfo.Name); err != nil {
+	if err := xml.GenerateDomainXML(vminfo, constants.XMLFileName); err != nil {
 		return errors.Wrap(err, "Failed to generate XML")
 	}
 	log.Printf("XML file created successfully: %s", constants.XMLFileName)
@@ -4,25 +4,74 @@ import (
 	"encoding/xml"
 	"fmt"
 	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
 )
 
+// UEFILoader is the UEFI firmware of the appliance, referenced by the domains of UEFI guests
+const UEFILoader = "/usr/share/edk2/ovmf/OVMF_CODE.fd"
+
 type Domain struct {
 	XMLName xml.Name `xml:"domain"`
 	Type    string   `xml:"type,attr"`
 	Name    string   `xml:"name"`
+	UUID    string   `xml:"uuid,omitempty"`
+	Memory  *Memory  `xml:"memory,omitempty"`
+	VCPU    int32    `xml:"vcpu,omitempty"`
+	OS      *OS      `xml:"os,omitempty"`
+	CPU     *CPU     `xml:"cpu,omitempty"`
 	Devices Devices  `xml:"devices"`
 }
 
+// Memory is the memory of the domain in Unit
+type Memory struct {
+	Unit  string `xml:"unit,attr"`
+	Value int64  `xml:",chardata"`
+}
+
+// OS is the boot configuration of the domain, Loader is only set for UEFI
+type OS struct {
+	Firmware string  `xml:"firmware,attr,omitempty"`
+	Type     OSType  `xml:"type"`
+	Loader   *Loader `xml:"loader,omitempty"`
+}
+
+type OSType struct {
+	Arch  string `xml:"arch,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type Loader struct {
+	Readonly string `xml:"readonly,attr,omitempty"`
+	Type     string `xml:"type,attr,omitempty"`
+	Path     string `xml:",chardata"`
+}
+
+type CPU struct {
+	Topology *Topology `xml:"topology,omitempty"`
+}
+
+type Topology struct {
+	Sockets int32 `xml:"sockets,attr"`
+	Cores   int32 `xml:"cores,attr"`
+	Threads int32 `xml:"threads,attr"`
+}
+
 type Devices struct {
-	Disks []Disk `xml:"disk"`
+	Disks       []Disk       `xml:"disk"`
+	Controllers []Controller `xml:"controller"`
+	Interfaces  []Interface  `xml:"interface"`
 }
 
 type Disk struct {
-	Type   string `xml:"type,attr"`
-	Device string `xml:"device,attr"`
-	Driver Driver `xml:"driver"`
-	Source Source `xml:"source"`
-	Target Target `xml:"target"`
+	Type    string   `xml:"type,attr"`
+	Device  string   `xml:"device,attr"`
+	Driver  Driver   `xml:"driver"`
+	Source  Source   `xml:"source"`
+	Target  Target   `xml:"target"`
+	Address *Address `xml:"address,omitempty"`
 }
 
 type Driver struct {
@@ -39,6 +88,184 @@ type Target struct {
 	Bus string `xml:"bus,attr"`
 }
 
+// Address is the drive address of a disk on its controller
+type Address struct {
+	Type       string `xml:"type,attr"`
+	Controller int32  `xml:"controller,attr"`
+	Bus        int32  `xml:"bus,attr"`
+	Target     int32  `xml:"target,attr"`
+	Unit       int32  `xml:"unit,attr"`
+}
+
+type Controller struct {
+	Type  string `xml:"type,attr"`
+	Index int32  `xml:"index,attr"`
+	Model string `xml:"model,attr,omitempty"`
+}
+
+type Interface struct {
+	Type   string           `xml:"type,attr"`
+	MAC    MAC              `xml:"mac"`
+	Source *InterfaceSource `xml:"source,omitempty"`
+}
+
+type MAC struct {
+	Address string `xml:"address,attr"`
+}
+
+type InterfaceSource struct {
+	Network string `xml:"network,attr"`
+}
+
+// scsiModels are the libvirt models of the VMware SCSI controllers
+var scsiModels = map[string]string{
+	"pvscsi":     "vmpvscsi",
+	"lsilogic":   "lsilogic",
+	"lsisas1068": "lsisas1068",
+	"buslogic":   "buslogic",
+}
+
+// diskPrefixes are the prefixes of the target device names of the disks on each bus
+var diskPrefixes = map[string]string{
+	vm.DiskControllerSCSI: "sd",
+	vm.DiskControllerSATA: "sd",
+	vm.DiskControllerIDE:  "hd",
+	"virtio":              "vd",
+}
+
+// NewDomain returns the libvirt domain of the VM, with its disks at their Path on the bus of their
+// VMware controller. Disks on NVMe or unknown controllers are put on the virtio bus, since virt-v2v
+// does not read NVMe disks from libvirt XML
+func NewDomain(vminfo vm.VMInfo) *Domain {
+	domain := &Domain{
+		XMLName: xml.Name{Local: "domain"},
+		Type:    "kvm",
+		Name:    vminfo.Name,
+		UUID:    vminfo.UUID,
+		Memory:  &Memory{Unit: "MiB", Value: int64(vminfo.Memory)},
+		VCPU:    vminfo.CPU,
+		OS:      &OS{Type: OSType{Arch: "x86_64", Value: "hvm"}},
+	}
+	if vminfo.UEFI {
+		domain.OS.Firmware = "efi"
+		domain.OS.Loader = &Loader{Readonly: "yes", Type: "pflash", Path: UEFILoader}
+	}
+	if cores := vminfo.CoresPerSocket; cores > 0 && vminfo.CPU%cores == 0 {
+		domain.CPU = &CPU{Topology: &Topology{Sockets: vminfo.CPU / cores, Cores: cores, Threads: 1}}
+	}
+
+	names := map[string]int{}
+	controllers := map[Controller]bool{}
+	for _, vmdisk := range vminfo.VMDisks {
+		bus := vmdisk.Controller.Type
+		if _, ok := diskPrefixes[bus]; !ok {
+			bus = "virtio"
+		}
+		prefix := diskPrefixes[bus]
+		disk := Disk{
+			Type:   "file",
+			Device: "disk",
+			Driver: Driver{Name: "qemu", Type: "raw"},
+			Source: Source{File: vmdisk.Path},
+			Target: Target{Dev: diskName(prefix, names[prefix]), Bus: bus},
+		}
+		names[prefix]++
+		if bus != "virtio" {
+			disk.Address = driveAddress(vmdisk)
+			controller := Controller{Type: bus, Index: disk.Address.Controller, Model: scsiModels[vmdisk.Controller.Model]}
+			if !controllers[controller] {
+				controllers[controller] = true
+				domain.Devices.Controllers = append(domain.Devices.Controllers, controller)
+			}
+		}
+		domain.Devices.Disks = append(domain.Devices.Disks, disk)
+	}
+
+	for _, mac := range vminfo.Mac {
+		nic := Interface{Type: "network", MAC: MAC{Address: mac}}
+		for _, networkInterface := range vminfo.NetworkInterfaces {
+			if strings.EqualFold(networkInterface.MAC, mac) && networkInterface.Network != "" {
+				nic.Source = &InterfaceSource{Network: networkInterface.Network}
+				break
+			}
+		}
+		domain.Devices.Interfaces = append(domain.Devices.Interfaces, nic)
+	}
+	return domain
+}
+
+// driveAddress returns the address of the disk on its controller. SCSI disks are addressed by target,
+// the other buses by unit
+func driveAddress(vmdisk vm.VMDisk) *Address {
+	address := &Address{Type: "drive", Controller: vmdisk.Controller.BusNumber}
+	var unit int32
+	if vmdisk.Disk != nil && vmdisk.Disk.UnitNumber != nil {
+		unit = *vmdisk.Disk.UnitNumber
+	}
+	switch vmdisk.Controller.Type {
+	case vm.DiskControllerSCSI:
+		address.Target = unit
+	case vm.DiskControllerIDE:
+		// The two IDE controllers of VMware are the two buses of the IDE controller of libvirt
+		address.Controller = 0
+		address.Bus = vmdisk.Controller.BusNumber
+		address.Unit = unit
+	default:
+		address.Unit = unit
+	}
+	return address
+}
+
+// diskName returns the name of the disk at the index, like the kernel names them: sda to sdz, then sdaa
+func diskName(prefix string, index int) string {
+	suffix := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		suffix = string(rune('a'+(index-1)%26)) + suffix
+	}
+	return prefix + suffix
+}
+
+// Marshal returns the XML document of the domain
+func (domain *Domain) Marshal() ([]byte, error) {
+	output, err := xml.MarshalIndent(domain, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(xml.Header + string(output) + "\n"), nil
+}
+
+// ParseDomain parses a libvirt domain XML document
+func ParseDomain(data []byte) (*Domain, error) {
+	domain := &Domain{}
+	if err := xml.Unmarshal(data, domain); err != nil {
+		return nil, errors.Wrap(err, "failed to parse domain XML")
+	}
+	return domain, nil
+}
+
+// ReadDomain reads the libvirt domain XML file
+func ReadDomain(file string) (*Domain, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDomain(data)
+}
+
+// WriteDomain writes the domain to the XML file
+func WriteDomain(domain *Domain, outputFile string) error {
+	output, err := domain.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputFile, output, 0644)
+}
+
+// GenerateDomainXML writes the libvirt domain of the VM to the XML file
+func GenerateDomainXML(vminfo vm.VMInfo, outputFile string) error {
+	return WriteDomain(NewDomain(vminfo), outputFile)
+}
+
 func GenerateXML(diskFiles []string, outputFile, vmname string) error {
 	var disks []Disk
 	for i, file := range diskFiles {
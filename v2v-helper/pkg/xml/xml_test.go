@@ -4,9 +4,13 @@ import (
 	"encoding/xml"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/vm"
 	"github.com/stretchr/testify/assert"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 func TestGenerateXML(t *testing.T) {
@@ -135,3 +139,105 @@ func TestGenerateXML(t *testing.T) {
 		})
 	}
 }
+
+// unit returns a pointer to the unit number of a disk
+func unit(number int32) *int32 {
+	return &number
+}
+
+func TestNewDomain(t *testing.T) {
+	tests := []struct {
+		golden string
+		vminfo vm.VMInfo
+	}{
+		{
+			golden: "linux-uefi-pvscsi.xml",
+			vminfo: vm.VMInfo{
+				Name:           "rhel9-db",
+				UUID:           "4223a1a6-5c0e-2b6f-8d8b-1f0e3c9a7d21",
+				CPU:            8,
+				CoresPerSocket: 4,
+				Memory:         16384,
+				UEFI:           true,
+				Mac:            []string{"00:50:56:aa:bb:01", "00:50:56:aa:bb:02"},
+				NetworkInterfaces: []vjailbreakv1alpha1.NIC{
+					{Network: "VM Network", MAC: "00:50:56:AA:BB:01"},
+				},
+				VMDisks: []vm.VMDisk{
+					{
+						Path:       "/dev/vdb",
+						Disk:       &types.VirtualDisk{VirtualDevice: types.VirtualDevice{UnitNumber: unit(0)}},
+						Controller: vm.DiskController{Type: vm.DiskControllerSCSI, Model: "pvscsi"},
+					},
+					{
+						Path:       "/dev/vdc",
+						Disk:       &types.VirtualDisk{VirtualDevice: types.VirtualDevice{UnitNumber: unit(1)}},
+						Controller: vm.DiskController{Type: vm.DiskControllerSCSI, Model: "pvscsi"},
+					},
+					{
+						Path:       "/dev/vdd",
+						Disk:       &types.VirtualDisk{VirtualDevice: types.VirtualDevice{UnitNumber: unit(0)}},
+						Controller: vm.DiskController{Type: vm.DiskControllerSCSI, Model: "lsisas1068", BusNumber: 1},
+					},
+				},
+			},
+		},
+		{
+			golden: "windows-bios-sata-ide.xml",
+			vminfo: vm.VMInfo{
+				Name:   "win2019-app",
+				UUID:   "42230f55-91aa-7c3e-0b3d-6a2f14e8c501",
+				CPU:    3,
+				Memory: 8192,
+				Mac:    []string{"00:50:56:aa:bb:03"},
+				VMDisks: []vm.VMDisk{
+					{
+						Path:       "/dev/vdb",
+						Disk:       &types.VirtualDisk{VirtualDevice: types.VirtualDevice{UnitNumber: unit(0)}},
+						Controller: vm.DiskController{Type: vm.DiskControllerSATA},
+					},
+					{
+						Path:       "/dev/vdc",
+						Disk:       &types.VirtualDisk{VirtualDevice: types.VirtualDevice{UnitNumber: unit(1)}},
+						Controller: vm.DiskController{Type: vm.DiskControllerIDE, BusNumber: 1},
+					},
+					{
+						Path:       "/dev/vdd",
+						Controller: vm.DiskController{Type: vm.DiskControllerNVMe},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			golden := filepath.Join("testdata", tt.golden)
+			domain := NewDomain(tt.vminfo)
+
+			output := filepath.Join(t.TempDir(), "domain.xml")
+			assert.NoError(t, GenerateDomainXML(tt.vminfo, output))
+			actual, err := os.ReadFile(output)
+			assert.NoError(t, err)
+			expected, err := os.ReadFile(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+
+			parsed, err := ReadDomain(golden)
+			assert.NoError(t, err)
+			assert.Equal(t, domain, parsed)
+		})
+	}
+}
+
+func TestDiskName(t *testing.T) {
+	assert.Equal(t, "sda", diskName("sd", 0))
+	assert.Equal(t, "sdz", diskName("sd", 25))
+	assert.Equal(t, "sdaa", diskName("sd", 26))
+	assert.Equal(t, "vdab", diskName("vd", 27))
+}
+
+func TestParseDomain(t *testing.T) {
+	_, err := ParseDomain([]byte("<domain"))
+	assert.Error(t, err)
+}
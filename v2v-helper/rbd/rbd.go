@@ -0,0 +1,171 @@
+// Copyright © 2024 The vjailbreak authors
+
+// Package rbd writes the copied data of the disks straight to the Ceph RBD images of their Cinder volumes.
+// The images are mapped with rbd-nbd, which writes to them with librbd, instead of attaching the volumes
+// to the agent
+package rbd
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/platform9/vjailbreak/v2v-helper/nbd"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+)
+
+// pathPrefix prefixes the paths of the RBD images, which are opened through a Mapper instead of as files
+const pathPrefix = "rbd:"
+
+// KeyringPath is where the Ceph keyring of the Cinder user is mounted in the pod
+const KeyringPath = "/etc/ceph/keyring"
+
+// Image is the RBD image of a Cinder volume
+type Image struct {
+	Pool     string
+	Name     string
+	User     string
+	Monitors []string
+}
+
+// FromConnectionInfo returns the image of a volume from the connection info Cinder returns for it
+func FromConnectionInfo(info map[string]interface{}) (Image, error) {
+	if driver, _ := info["driver_volume_type"].(string); driver != "rbd" {
+		return Image{}, errors.Errorf("volume is not on RBD, its driver volume type is %q", driver)
+	}
+	data, ok := info["data"].(map[string]interface{})
+	if !ok {
+		return Image{}, errors.New("connection info has no data")
+	}
+	name, _ := data["name"].(string)
+	pool, image, found := strings.Cut(name, "/")
+	if !found || pool == "" || image == "" {
+		return Image{}, errors.Errorf("invalid RBD image name %q", name)
+	}
+	user, _ := data["auth_username"].(string)
+	hosts, _ := data["hosts"].([]interface{})
+	ports, _ := data["ports"].([]interface{})
+	if len(hosts) == 0 || len(hosts) != len(ports) {
+		return Image{}, errors.Errorf("connection info has %d monitor hosts and %d ports", len(hosts), len(ports))
+	}
+	monitors := []string{}
+	for i := range hosts {
+		monitors = append(monitors, net.JoinHostPort(fmt.Sprint(hosts[i]), fmt.Sprint(ports[i])))
+	}
+	return Image{Pool: pool, Name: image, User: user, Monitors: monitors}, nil
+}
+
+// Spec returns the pool/image spec of the image
+func (image Image) Spec() string {
+	return image.Pool + "/" + image.Name
+}
+
+// Path returns the path of the image, which the Opener maps to open it
+func (image Image) Path() string {
+	query := url.Values{"mon": image.Monitors}
+	if image.User != "" {
+		query.Set("id", image.User)
+	}
+	return pathPrefix + image.Spec() + "?" + query.Encode()
+}
+
+// IsPath tells whether path is the path of an RBD image
+func IsPath(path string) bool {
+	return strings.HasPrefix(path, pathPrefix)
+}
+
+// ParsePath returns the image at path
+func ParsePath(path string) (Image, error) {
+	if !IsPath(path) {
+		return Image{}, errors.Errorf("%s is not an RBD image", path)
+	}
+	spec, rawQuery, _ := strings.Cut(strings.TrimPrefix(path, pathPrefix), "?")
+	pool, name, found := strings.Cut(spec, "/")
+	if !found || pool == "" || name == "" {
+		return Image{}, errors.Errorf("invalid RBD image spec %q", spec)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Image{}, errors.Wrapf(err, "invalid RBD image path %s", path)
+	}
+	return Image{Pool: pool, Name: name, User: query.Get("id"), Monitors: query["mon"]}, nil
+}
+
+// Mapper maps images to local block devices
+type Mapper interface {
+	Map(image Image) (string, error)
+	Unmap(device string) error
+}
+
+// NBDMapper maps the images with rbd-nbd, authenticating with the keyring at Keyring
+type NBDMapper struct {
+	Keyring string
+}
+
+func (mapper *NBDMapper) Map(image Image) (string, error) {
+	args := []string{"map", "--keyring", mapper.Keyring, "-m", strings.Join(image.Monitors, ",")}
+	if image.User != "" {
+		args = append(args, "--id", image.User)
+	}
+	args = append(args, image.Spec())
+	cmd := exec.Command("rbd-nbd", args...)
+	utils.PrintLog(fmt.Sprintf("Executing %s", cmd.String()))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to map RBD image %s: %s", image.Spec(), strings.TrimSpace(string(out)))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (mapper *NBDMapper) Unmap(device string) error {
+	out, err := exec.Command("rbd-nbd", "unmap", device).CombinedOutput()
+	if err != nil {
+		return errors.Wrapf(err, "failed to unmap %s: %s", device, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Opener opens the RBD images by mapping them with Mapper, and the other paths as local files
+type Opener struct {
+	Mapper Mapper
+}
+
+func (opener *Opener) Open(path string) (nbd.TargetWriter, error) {
+	if !IsPath(path) {
+		return nbd.OpenFileTarget(path)
+	}
+	image, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	device, err := opener.Mapper.Map(image)
+	if err != nil {
+		return nil, err
+	}
+	target, err := nbd.OpenFileTarget(device)
+	if err != nil {
+		if unmapErr := opener.Mapper.Unmap(device); unmapErr != nil {
+			utils.PrintLog(fmt.Sprintf("Failed to unmap %s: %v", device, unmapErr))
+		}
+		return nil, err
+	}
+	return &mappedTarget{FileTarget: target, device: device, mapper: opener.Mapper}, nil
+}
+
+// mappedTarget writes to the device an image is mapped to, and unmaps it when closed
+type mappedTarget struct {
+	*nbd.FileTarget
+	device string
+	mapper Mapper
+}
+
+func (target *mappedTarget) Close() error {
+	closeErr := target.FileTarget.Close()
+	if err := target.mapper.Unmap(target.device); err != nil {
+		return err
+	}
+	return closeErr
+}
@@ -0,0 +1,92 @@
+// Copyright © 2024 The vjailbreak authors
+
+package rbd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromConnectionInfo(t *testing.T) {
+	info := map[string]interface{}{
+		"driver_volume_type": "rbd",
+		"data": map[string]interface{}{
+			"name":          "volumes/volume-1234",
+			"auth_username": "cinder",
+			"hosts":         []interface{}{"10.0.0.1", "fd00::2"},
+			"ports":         []interface{}{"6789", "3300"},
+		},
+	}
+	image, err := FromConnectionInfo(info)
+	assert.NoError(t, err)
+	assert.Equal(t, Image{Pool: "volumes", Name: "volume-1234", User: "cinder", Monitors: []string{"10.0.0.1:6789", "[fd00::2]:3300"}}, image)
+	assert.Equal(t, "volumes/volume-1234", image.Spec())
+
+	parsed, err := ParsePath(image.Path())
+	assert.NoError(t, err)
+	assert.Equal(t, image, parsed)
+
+	_, err = FromConnectionInfo(map[string]interface{}{"driver_volume_type": "iscsi"})
+	assert.EqualError(t, err, `volume is not on RBD, its driver volume type is "iscsi"`)
+
+	info["data"].(map[string]interface{})["name"] = "volume-1234"
+	_, err = FromConnectionInfo(info)
+	assert.EqualError(t, err, `invalid RBD image name "volume-1234"`)
+}
+
+func TestParsePath(t *testing.T) {
+	assert.False(t, IsPath("/dev/vdb"))
+	_, err := ParsePath("/dev/vdb")
+	assert.Error(t, err)
+	_, err = ParsePath("rbd:volumes")
+	assert.EqualError(t, err, `invalid RBD image spec "volumes"`)
+}
+
+// fileMapper maps the images to files in dir, standing in for rbd-nbd
+type fileMapper struct {
+	dir    string
+	mapped map[string]bool
+}
+
+func (mapper *fileMapper) Map(image Image) (string, error) {
+	device := filepath.Join(mapper.dir, image.Name)
+	mapper.mapped[device] = true
+	return device, nil
+}
+
+func (mapper *fileMapper) Unmap(device string) error {
+	delete(mapper.mapped, device)
+	return nil
+}
+
+func TestOpener(t *testing.T) {
+	dir := t.TempDir()
+	device := filepath.Join(dir, "volume-1234")
+	assert.NoError(t, os.WriteFile(device, []byte("0123456789abcdef"), 0644))
+
+	mapper := &fileMapper{dir: dir, mapped: map[string]bool{}}
+	opener := &Opener{Mapper: mapper}
+	image := Image{Pool: "volumes", Name: "volume-1234", Monitors: []string{"10.0.0.1:6789"}}
+
+	target, err := opener.Open(image.Path())
+	assert.NoError(t, err)
+	assert.True(t, mapper.mapped[device])
+	_, err = target.WriteAt([]byte("XY"), 2)
+	assert.NoError(t, err)
+	assert.NoError(t, target.Zero(8, 4))
+	assert.NoError(t, target.Close())
+	assert.Empty(t, mapper.mapped)
+
+	data, err := os.ReadFile(device)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("01XY4567\x00\x00\x00\x00cdef"), data)
+
+	// Other paths are opened as local files
+	target, err = opener.Open(device)
+	assert.NoError(t, err)
+	assert.NoError(t, target.Close())
+	assert.Empty(t, mapper.mapped)
+}
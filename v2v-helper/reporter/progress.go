@@ -0,0 +1,322 @@
+// Copyright © 2024 The vjailbreak authors
+
+package reporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// progressWriteInterval limits how often disk progress alone is written to the pod
+const progressWriteInterval = 10 * time.Second
+
+// warmSyncHistory is the number of warm syncs kept in the progress record
+const warmSyncHistory = 48
+
+// warmForecastSyncs is the number of latest warm syncs the downtime forecast is estimated from
+const warmForecastSyncs = 3
+
+// deltaHistoryLength is the number of changed blocks copies of each disk kept in the progress record
+const deltaHistoryLength = 10
+
+// deltaEstimateIterations is the number of latest changed blocks copies of a disk its next changes are estimated from
+const deltaEstimateIterations = 3
+
+// SetPhase records the phase of the migration and writes the progress record right away
+func (r *Reporter) SetPhase(phase vjailbreakv1alpha1.VMMigrationPhase, message string) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.Phase = phase
+	r.progress.Message = message
+	r.writeProgress(true)
+}
+
+// StartCopyPass resets the disk progress for a new full copy or changed blocks copy pass
+func (r *Reporter) StartCopyPass(phase vjailbreakv1alpha1.VMMigrationPhase, iteration int) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.Phase = phase
+	r.progress.Message = ""
+	r.progress.CBTIteration = iteration
+	r.progress.Disks = nil
+	r.progress.ThroughputBytesPerSecond = 0
+	r.progress.ETASeconds = 0
+	r.passStart = time.Now()
+	r.writeProgress(true)
+}
+
+// SetDiskProgress records the bytes copied of a disk in the current copy pass
+func (r *Reporter) SetDiskProgress(index int, name string, copied, total int64) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.Disks = setDiskProgress(r.progress.Disks, vjailbreakv1alpha1.DiskProgress{
+		Index:       index,
+		Name:        name,
+		BytesCopied: copied,
+		TotalBytes:  total,
+	})
+	r.progress.ThroughputBytesPerSecond, r.progress.ETASeconds = estimateRate(r.progress.Disks, time.Since(r.passStart))
+	r.writeProgress(copied >= total)
+}
+
+// AddFullCopyBytesSkipped adds the bytes of a disk the full copy did not read from the source
+func (r *Reporter) AddFullCopyBytesSkipped(skipped int64) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.FullCopyBytesSkipped += skipped
+	r.writeProgress(true)
+}
+
+// AddWarmSync records an incremental sync of a warm migration and updates the downtime forecast
+func (r *Reporter) AddWarmSync(sync vjailbreakv1alpha1.WarmSync) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.WarmSyncs = append(r.progress.WarmSyncs, sync)
+	if len(r.progress.WarmSyncs) > warmSyncHistory {
+		r.progress.WarmSyncs = r.progress.WarmSyncs[len(r.progress.WarmSyncs)-warmSyncHistory:]
+	}
+	r.progress.ForecastDowntimeSeconds = forecastDowntime(r.progress.WarmSyncs)
+	r.writeProgress(true)
+}
+
+// AddDiskDeltas records the changed blocks copies of the disks in a copy iteration, and updates the estimated downtime
+// of a cutover: the estimated final copy of the changed blocks plus overhead for the conversion and the boot
+func (r *Reporter) AddDiskDeltas(deltas []vjailbreakv1alpha1.DiskDelta, overhead time.Duration) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.DeltaHistory = trimDeltaHistory(append(r.progress.DeltaHistory, deltas...), deltaHistoryLength)
+	if finalCopy, ok := estimateFinalCopy(r.progress.DeltaHistory); ok {
+		r.progress.EstimatedDowntimeSeconds = int64(math.Ceil((finalCopy + overhead).Seconds()))
+	}
+	r.writeProgress(true)
+}
+
+// EstimatedDowntime returns the expected downtime of a cutover started now, or the forecast of the final sync of a
+// warm migration when no estimate is available yet. It is 0 before any changed blocks were copied
+func (r *Reporter) EstimatedDowntime() time.Duration {
+	if r == nil {
+		return 0
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	seconds := r.progress.EstimatedDowntimeSeconds
+	if seconds == 0 {
+		seconds = r.progress.ForecastDowntimeSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// SetVerification records the result of the data verification and writes the progress record right away
+func (r *Reporter) SetVerification(result *vjailbreakv1alpha1.DataVerificationResult) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.Verification = result
+	r.writeProgress(true)
+}
+
+// SetWindowsFirstBoot records the result of the first boot script of a Windows guest and writes the progress record right away
+func (r *Reporter) SetWindowsFirstBoot(result *vjailbreakv1alpha1.WindowsFirstBootResult) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.WindowsFirstBoot = result
+	r.writeProgress(true)
+}
+
+// SetOSSupport records the result of the check of the guest OS against the supported OS matrix and writes the progress record right away
+func (r *Reporter) SetOSSupport(result *vjailbreakv1alpha1.OSSupportResult) {
+	if r == nil {
+		return
+	}
+	r.progressLock.Lock()
+	defer r.progressLock.Unlock()
+	r.progress.OSSupport = result
+	r.writeProgress(true)
+}
+
+// setDiskProgress replaces the progress of the disk, keeping the disks ordered by index
+func setDiskProgress(disks []vjailbreakv1alpha1.DiskProgress, disk vjailbreakv1alpha1.DiskProgress) []vjailbreakv1alpha1.DiskProgress {
+	for idx := range disks {
+		if disks[idx].Index == disk.Index {
+			disks[idx] = disk
+			return disks
+		}
+	}
+	disks = append(disks, disk)
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Index < disks[j].Index })
+	return disks
+}
+
+// estimateRate returns the combined throughput of the disks and the seconds left to copy the rest of their bytes
+func estimateRate(disks []vjailbreakv1alpha1.DiskProgress, elapsed time.Duration) (int64, int64) {
+	var copied, total int64
+	for _, disk := range disks {
+		copied += disk.BytesCopied
+		total += disk.TotalBytes
+	}
+	if elapsed < time.Second || copied == 0 {
+		return 0, 0
+	}
+	throughput := int64(float64(copied) / elapsed.Seconds())
+	if throughput == 0 {
+		return 0, 0
+	}
+	return throughput, max(total-copied, 0) / throughput
+}
+
+// forecastDowntime returns the expected seconds of the final sync of a warm migration, the average duration
+// of the latest warmForecastSyncs syncs rounded up. The final sync copies the changes made since the last
+// sync, which covers no more time than the syncs before it
+func forecastDowntime(syncs []vjailbreakv1alpha1.WarmSync) int64 {
+	latest := syncs[max(len(syncs)-warmForecastSyncs, 0):]
+	if len(latest) == 0 {
+		return 0
+	}
+	var total int64
+	for _, sync := range latest {
+		total += sync.DurationMilliseconds
+	}
+	divisor := int64(len(latest)) * 1000
+	return (total + divisor - 1) / divisor
+}
+
+// trimDeltaHistory keeps the latest perDisk copies of each disk, in their original order
+func trimDeltaHistory(history []vjailbreakv1alpha1.DiskDelta, perDisk int) []vjailbreakv1alpha1.DiskDelta {
+	kept := map[int]int{}
+	trimmed := []vjailbreakv1alpha1.DiskDelta{}
+	for i := len(history) - 1; i >= 0; i-- {
+		if kept[history[i].Index] < perDisk {
+			kept[history[i].Index]++
+			trimmed = append(trimmed, history[i])
+		}
+	}
+	slices.Reverse(trimmed)
+	return trimmed
+}
+
+// estimateFinalCopy returns how long the next copy of the changed blocks is expected to take. The changes of each
+// disk are the average of its latest deltaEstimateIterations copies, copied at the average rate of the disk, or of
+// all disks when the disk has no rate yet. The disks are copied in parallel, so the slowest one sets the duration.
+// It returns false while no copy rate is known.
+func estimateFinalCopy(history []vjailbreakv1alpha1.DiskDelta) (time.Duration, bool) {
+	type diskHistory struct {
+		changes   []int64
+		rateTotal int64
+		rateCount int64
+	}
+	disks := map[int]*diskHistory{}
+	var rateTotal, rateCount int64
+	for _, delta := range history {
+		disk, ok := disks[delta.Index]
+		if !ok {
+			disk = &diskHistory{}
+			disks[delta.Index] = disk
+		}
+		disk.changes = append(disk.changes, delta.ChangedBytes)
+		if delta.BytesPerSecond > 0 {
+			disk.rateTotal += delta.BytesPerSecond
+			disk.rateCount++
+			rateTotal += delta.BytesPerSecond
+			rateCount++
+		}
+	}
+	if rateCount == 0 {
+		return 0, false
+	}
+
+	var slowest time.Duration
+	for _, disk := range disks {
+		latest := disk.changes[max(len(disk.changes)-deltaEstimateIterations, 0):]
+		var changed int64
+		for _, bytes := range latest {
+			changed += bytes
+		}
+		changed /= int64(len(latest))
+		rate := rateTotal / rateCount
+		if disk.rateCount > 0 {
+			rate = disk.rateTotal / disk.rateCount
+		}
+		slowest = max(slowest, time.Duration(float64(changed)/float64(rate)*float64(time.Second)))
+	}
+	return slowest, true
+}
+
+// writeProgress patches the progress record on the pod annotations. Unless force is set, the write is
+// skipped when the previous one happened less than progressWriteInterval ago.
+// The caller must hold progressLock.
+func (r *Reporter) writeProgress(force bool) {
+	if !force && time.Since(r.lastProgressWrite) < progressWriteInterval {
+		return
+	}
+	r.lastProgressWrite = time.Now()
+	r.progress.LastUpdateTime = metav1.Now()
+
+	data, err := json.Marshal(r.progress)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to marshal migration progress: %v", err))
+		return
+	}
+	if err := r.patchPodAnnotations(map[string]string{vjailbreakv1alpha1.MigrationProgressAnnotation: string(data)}); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to write migration progress to pod: %v", err))
+	}
+}
+
+// SetMetricsPort publishes the port the metrics are served on, so that Prometheus can find them
+func (r *Reporter) SetMetricsPort(port int) {
+	if r == nil {
+		return
+	}
+	if err := r.patchPodAnnotations(map[string]string{vjailbreakv1alpha1.MetricsPortAnnotation: strconv.Itoa(port)}); err != nil {
+		utils.PrintLog(fmt.Sprintf("Failed to write metrics port to pod: %v", err))
+	}
+}
+
+// patchPodAnnotations merges annotations into the annotations of the pod
+func (r *Reporter) patchPodAnnotations(annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal annotations patch: %v", err)
+	}
+	if _, err := r.Clientset.CoreV1().Pods(r.PodNamespace).Patch(context.TODO(), r.PodName, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch pod annotations: %v", err)
+	}
+	return nil
+}
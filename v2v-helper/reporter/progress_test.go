@@ -0,0 +1,105 @@
+// Copyright © 2024 The vjailbreak authors
+package reporter
+
+import (
+	"testing"
+	"time"
+
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDiskProgress(t *testing.T) {
+	var disks []vjailbreakv1alpha1.DiskProgress
+	disks = setDiskProgress(disks, vjailbreakv1alpha1.DiskProgress{Index: 2, Name: "disk3", BytesCopied: 10, TotalBytes: 100})
+	disks = setDiskProgress(disks, vjailbreakv1alpha1.DiskProgress{Index: 0, Name: "disk1", BytesCopied: 5, TotalBytes: 50})
+	disks = setDiskProgress(disks, vjailbreakv1alpha1.DiskProgress{Index: 2, Name: "disk3", BytesCopied: 40, TotalBytes: 100})
+
+	assert.Equal(t, []vjailbreakv1alpha1.DiskProgress{
+		{Index: 0, Name: "disk1", BytesCopied: 5, TotalBytes: 50},
+		{Index: 2, Name: "disk3", BytesCopied: 40, TotalBytes: 100},
+	}, disks)
+}
+
+func TestEstimateRate(t *testing.T) {
+	disks := []vjailbreakv1alpha1.DiskProgress{
+		{Index: 0, BytesCopied: 300, TotalBytes: 1000},
+		{Index: 1, BytesCopied: 100, TotalBytes: 200},
+	}
+
+	throughput, eta := estimateRate(disks, 4*time.Second)
+	assert.Equal(t, int64(100), throughput)
+	assert.Equal(t, int64(8), eta)
+
+	// Nothing is estimated before the copy has made progress
+	throughput, eta = estimateRate(disks, 500*time.Millisecond)
+	assert.Zero(t, throughput)
+	assert.Zero(t, eta)
+	throughput, eta = estimateRate([]vjailbreakv1alpha1.DiskProgress{{TotalBytes: 1000}}, 10*time.Second)
+	assert.Zero(t, throughput)
+	assert.Zero(t, eta)
+}
+
+func TestForecastDowntime(t *testing.T) {
+	assert.Zero(t, forecastDowntime(nil))
+
+	syncs := []vjailbreakv1alpha1.WarmSync{
+		{Iteration: 1, DeltaBytes: 8 << 30, DurationMilliseconds: 600000},
+		{Iteration: 2, DeltaBytes: 1 << 30, DurationMilliseconds: 60000},
+	}
+	assert.Equal(t, int64(330), forecastDowntime(syncs))
+
+	// Only the latest syncs are averaged, and partial seconds are rounded up
+	syncs = append(syncs,
+		vjailbreakv1alpha1.WarmSync{Iteration: 3, DeltaBytes: 1 << 30, DurationMilliseconds: 40000},
+		vjailbreakv1alpha1.WarmSync{Iteration: 4, DeltaBytes: 1 << 30, DurationMilliseconds: 20001},
+	)
+	assert.Equal(t, int64(41), forecastDowntime(syncs))
+}
+
+func TestTrimDeltaHistory(t *testing.T) {
+	history := []vjailbreakv1alpha1.DiskDelta{
+		{Index: 0, Iteration: 1}, {Index: 1, Iteration: 1},
+		{Index: 0, Iteration: 2}, {Index: 1, Iteration: 2},
+		{Index: 0, Iteration: 3},
+	}
+	assert.Equal(t, []vjailbreakv1alpha1.DiskDelta{
+		{Index: 1, Iteration: 1},
+		{Index: 0, Iteration: 2}, {Index: 1, Iteration: 2},
+		{Index: 0, Iteration: 3},
+	}, trimDeltaHistory(history, 2))
+}
+
+func TestEstimateFinalCopy(t *testing.T) {
+	// Nothing is estimated before a copy rate is known
+	_, ok := estimateFinalCopy([]vjailbreakv1alpha1.DiskDelta{{Index: 0, Iteration: 1}})
+	assert.False(t, ok)
+
+	history := []vjailbreakv1alpha1.DiskDelta{
+		{Index: 0, Iteration: 1, ChangedBytes: 9000, BytesPerSecond: 100},
+		{Index: 1, Iteration: 1, ChangedBytes: 600, BytesPerSecond: 300},
+		{Index: 0, Iteration: 2, ChangedBytes: 1200, BytesPerSecond: 100},
+		{Index: 1, Iteration: 2},
+		{Index: 0, Iteration: 3, ChangedBytes: 600, BytesPerSecond: 100},
+		{Index: 1, Iteration: 3},
+		{Index: 0, Iteration: 4, ChangedBytes: 1200, BytesPerSecond: 100},
+		// A disk without a rate of its own is copied at the average rate of all disks
+		{Index: 2, Iteration: 4, ChangedBytes: 1600},
+	}
+	// Disk 0 averages 1000 bytes at 100 bytes per second, disk 1 200 bytes at 300, disk 2 1600 bytes at 140
+	estimate, ok := estimateFinalCopy(history)
+	assert.True(t, ok)
+	assert.Equal(t, 11428571428*time.Nanosecond, estimate)
+}
+
+func TestNilReporterIgnoresProgress(t *testing.T) {
+	var r *Reporter
+	assert.NotPanics(t, func() {
+		r.SetPhase(vjailbreakv1alpha1.VMMigrationPhaseCopying, "")
+		r.StartCopyPass(vjailbreakv1alpha1.VMMigrationPhaseCopyingChangedBlocks, 1)
+		r.SetDiskProgress(0, "disk1", 10, 100)
+		r.AddFullCopyBytesSkipped(1024)
+		r.AddWarmSync(vjailbreakv1alpha1.WarmSync{Iteration: 1})
+		r.AddDiskDeltas([]vjailbreakv1alpha1.DiskDelta{{Index: 0, Iteration: 1}}, time.Minute)
+	})
+}
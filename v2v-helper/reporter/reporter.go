@@ -9,8 +9,10 @@ import (
 	"math/big"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
+	vjailbreakv1alpha1 "github.com/platform9/vjailbreak/k8s/migration/api/v1alpha1"
 	"github.com/platform9/vjailbreak/v2v-helper/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -35,6 +37,12 @@ type Reporter struct {
 	PodNamespace string
 	Pod          *corev1.Pod
 	Clientset    *kubernetes.Clientset
+
+	// progress is the typed progress record written to the pod annotations
+	progress          vjailbreakv1alpha1.MigrationProgress
+	progressLock      sync.Mutex
+	passStart         time.Time
+	lastProgressWrite time.Time
 }
 
 func IsRunningInPod() bool {
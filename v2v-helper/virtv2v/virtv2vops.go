@@ -13,7 +13,6 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
-	"regexp"
 	"slices"
 	"strconv"
 	"strings"
@@ -32,11 +31,8 @@ type VirtV2VOperations interface {
 	GetPartitions(disk string) ([]string, error)
 	NTFSFix(path string) error
 	ConvertDisk(ctx context.Context, path, ostype, virtiowindriver string, firstbootscripts []string, useSingleDisk bool, diskPath string) error
-	AddWildcardNetplan(path string) error
 	GetOsRelease(path string) (string, error)
 	AddFirstBootScript(firstbootscript, firstbootscriptname string) error
-	AddUdevRules(disks []vm.VMDisk, useSingleDisk bool, diskPath string, interfaces []string, macs []string) error
-	GetNetworkInterfaceNames(path string) ([]string, error)
 	IsRHELFamily(osRelease string) (bool, error)
 	GetOsReleaseAllVolumes(disks []vm.VMDisk) (string, error)
 }
@@ -256,38 +252,6 @@ func GetOsRelease(path string) (string, error) {
 		strings.Join(releaseFiles, ", "), strings.Join(errs, " | "))
 }
 
-func AddWildcardNetplan(disks []vm.VMDisk, useSingleDisk bool, diskPath string) error {
-	// Add wildcard to netplan
-	var ans string
-	netplan := `[Match]
-Name=en*
-
-[Network]
-DHCP=yes`
-
-	// Create the netplan file
-	err := os.WriteFile("/home/fedora/99-wildcard.network", []byte(netplan), 0644)
-	if err != nil {
-		return fmt.Errorf("failed to create netplan file: %s", err)
-	}
-	log.Println("Created local netplan file")
-	log.Println("Uploading netplan file to disk")
-	// Upload it to the disk
-	os.Setenv("LIBGUESTFS_BACKEND", "direct")
-	if useSingleDisk {
-		command := `upload /home/fedora/99-wildcard.network /etc/systemd/network/99-wildcard.network`
-		ans, err = RunCommandInGuest(diskPath, command, true)
-	} else {
-		command := "upload"
-		ans, err = RunCommandInGuestAllVolumes(disks, command, true, "/home/fedora/99-wildcard.network", "/etc/systemd/network/99-wildcard.network")
-	}
-	if err != nil {
-		fmt.Printf("failed to run command (%s): %v: %s\n", "upload", err, strings.TrimSpace(ans))
-		return err
-	}
-	return nil
-}
-
 func AddFirstBootScript(firstbootscript, firstbootscriptname string) error {
 	// Create the firstboot script
 	firstbootscriptpath := fmt.Sprintf("/home/fedora/%s.sh", firstbootscriptname)
@@ -418,106 +382,6 @@ func GetBootableVolumeIndex(disks []vm.VMDisk) (int, error) {
 	return -1, errors.New("bootable volume not found")
 }
 
-func AddUdevRules(disks []vm.VMDisk, useSingleDisk bool, diskPath string, interfaces []string, macs []string) error {
-
-	if len(interfaces) != len(macs) {
-		return fmt.Errorf("mismatch between number of interfaces and MACs")
-	}
-	var ans string
-
-	// Create the udev rules content
-	var udevRules strings.Builder
-	for i, iface := range interfaces {
-		udevRules.WriteString(fmt.Sprintf("SUBSYSTEM==\"net\", ACTION==\"add\", ATTR{address}==\"%s\", NAME=\"%s\"\n", macs[i], iface))
-		log.Printf("Adding udev rule: %s", udevRules.String())
-	}
-
-	err := os.WriteFile("/home/fedora/70-persistent-net.rules", []byte(udevRules.String()), 0644)
-	if err != nil {
-		return fmt.Errorf("failed to create udev rules file: %s", err)
-	}
-	log.Println("Uploading udev rules file to disk")
-	// Upload it to the disk
-	os.Setenv("LIBGUESTFS_BACKEND", "direct")
-	if useSingleDisk {
-		command := `upload /home/fedora/70-persistent-net.rules /etc/udev/rules.d/70-persistent-net.rules`
-		ans, err = RunCommandInGuest(diskPath, command, true)
-	} else {
-		command := "upload"
-		ans, err = RunCommandInGuestAllVolumes(disks, command, true, "/home/fedora/70-persistent-net.rules", "/etc/udev/rules.d/70-persistent-net.rules")
-	}
-	if err != nil {
-		fmt.Printf("failed to run command (%s): %v: %s\n", "upload", err, strings.TrimSpace(ans))
-		return err
-	}
-	return nil
-}
-
-func GetNetworkInterfaceNames(path string) ([]string, error) {
-	// Get the network interface names
-	command := "cat /etc/network/interfaces"
-	ans, err := RunCommandInGuest(path, command, false)
-	if err != nil {
-		return nil, fmt.Errorf("failed to run command (%s): %v: %s", command, err, strings.TrimSpace(ans))
-	}
-
-	// Parse the output
-	lines := strings.Split(ans, "\n")
-	var interfaces []string
-	for _, line := range lines {
-		if strings.HasPrefix(line, "iface") && !strings.Contains(line, "lo") {
-			interfaces = append(interfaces, strings.Fields(line)[1])
-		}
-	}
-	return interfaces, nil
-
-}
-
-func GetInterfaceNames(path string) ([]string, error) {
-	cmd := "ls /etc/sysconfig/network-scripts | grep '^ifcfg-'"
-	lsOut, err := RunCommandInGuest(path, cmd, false)
-	if err != nil {
-		return nil, err
-	}
-	interfaces := []string{}
-	// Parse the output
-	// Split by newline and trim spaces
-	// Ignore 'ifcfg-lo' as it is the loopback interface
-	files := strings.Split(strings.TrimSpace(lsOut), "\n")
-	for _, file := range files {
-		if file == "ifcfg-lo" {
-			continue
-		}
-		content, err := RunCommandInGuest(path, fmt.Sprintf("cat /etc/sysconfig/network-scripts/%s", file), false)
-		if err != nil {
-			continue
-		}
-		// Extract DEVICE or infer from filename
-		device := extractKeyValue(content, "DEVICE")
-		if device != "" {
-			interfaces = append(interfaces, device)
-		} else {
-			// Fall back to filename if DEVICE not found
-			device = strings.TrimPrefix(file, "ifcfg-")
-			if device != "" {
-				interfaces = append(interfaces, device)
-			}
-		}
-	}
-
-	return interfaces, nil
-}
-
-// Helper: Extract key=value from content, trim quotes/spaces
-func extractKeyValue(content, key string) string {
-	re := regexp.MustCompile(fmt.Sprintf(`(?m)^%s=(.*)$`, key))
-	match := re.FindStringSubmatch(content)
-	if len(match) > 1 {
-		return strings.Trim(strings.Trim(match[1], `"'`), " ")
-	}
-	return ""
-}
-
 func GetOsReleaseAllVolumes(disks []vm.VMDisk) (string, error) {
 	// Attempt /etc/os-release first
 	osRelease, err := RunCommandInGuestAllVolumes(disks, "cat", false, "/etc/os-release")
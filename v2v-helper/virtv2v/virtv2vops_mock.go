@@ -49,34 +49,6 @@ func (mr *MockVirtV2VOperationsMockRecorder) AddFirstBootScript(firstbootscript,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddFirstBootScript", reflect.TypeOf((*MockVirtV2VOperations)(nil).AddFirstBootScript), firstbootscript, firstbootscriptname)
 }
 
-// AddUdevRules mocks base method.
-func (m *MockVirtV2VOperations) AddUdevRules(disks []vm.VMDisk, useSingleDisk bool, diskPath string, interfaces, macs []string) error {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddUdevRules", disks, useSingleDisk, diskPath, interfaces, macs)
-	ret0, _ := ret[0].(error)
-	return ret0
-}
-
-// AddUdevRules indicates an expected call of AddUdevRules.
-func (mr *MockVirtV2VOperationsMockRecorder) AddUdevRules(disks, useSingleDisk, diskPath, interfaces, macs interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUdevRules", reflect.TypeOf((*MockVirtV2VOperations)(nil).AddUdevRules), disks, useSingleDisk, diskPath, interfaces, macs)
-}
-
-// AddWildcardNetplan mocks base method.
-func (m *MockVirtV2VOperations) AddWildcardNetplan(path string) error {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddWildcardNetplan", path)
-	ret0, _ := ret[0].(error)
-	return ret0
-}
-
-// AddWildcardNetplan indicates an expected call of AddWildcardNetplan.
-func (mr *MockVirtV2VOperationsMockRecorder) AddWildcardNetplan(path interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddWildcardNetplan", reflect.TypeOf((*MockVirtV2VOperations)(nil).AddWildcardNetplan), path)
-}
-
 // ConvertDisk mocks base method.
 func (m *MockVirtV2VOperations) ConvertDisk(ctx context.Context, path, ostype, virtiowindriver string, firstbootscripts []string, useSingleDisk bool, diskPath string) error {
 	m.ctrl.T.Helper()
@@ -91,21 +63,6 @@ func (mr *MockVirtV2VOperationsMockRecorder) ConvertDisk(ctx, path, ostype, virt
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertDisk", reflect.TypeOf((*MockVirtV2VOperations)(nil).ConvertDisk), ctx, path, ostype, virtiowindriver, firstbootscripts, useSingleDisk, diskPath)
 }
 
-// GetNetworkInterfaceNames mocks base method.
-func (m *MockVirtV2VOperations) GetNetworkInterfaceNames(path string) ([]string, error) {
-	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetNetworkInterfaceNames", path)
-	ret0, _ := ret[0].([]string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
-}
-
-// GetNetworkInterfaceNames indicates an expected call of GetNetworkInterfaceNames.
-func (mr *MockVirtV2VOperationsMockRecorder) GetNetworkInterfaceNames(path interface{}) *gomock.Call {
-	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetworkInterfaceNames", reflect.TypeOf((*MockVirtV2VOperations)(nil).GetNetworkInterfaceNames), path)
-}
-
 // GetOsRelease mocks base method.
 func (m *MockVirtV2VOperations) GetOsRelease(path string) (string, error) {
 	m.ctrl.T.Helper()
@@ -44,10 +44,12 @@ type VMOperations interface {
 	VMPowerOff() error
 	VMPowerOn() error
 	DisconnectNetworkInterfaces() error
+	ConnectNetworkInterfaces() error
 }
 
 type VMInfo struct {
 	CPU               int32
+	CoresPerSocket    int32
 	Memory            int32
 	State             types.VirtualMachinePowerState
 	Mac               []string
@@ -58,6 +60,7 @@ type VMInfo struct {
 	UEFI              bool
 	Name              string
 	OSType            string
+	OSName            string
 	GuestNetworks     []vjailbreakv1alpha1.GuestNetwork
 	NetworkInterfaces []vjailbreakv1alpha1.NIC
 	RDMDisks          []vjailbreakv1alpha1.RDMDisk
@@ -94,6 +97,25 @@ type VMDisk struct {
 	SnapBackingDisk string
 	ChangeID        string
 	Boot            bool
+	Controller      DiskController
+}
+
+// Types of the controllers a disk is attached to
+const (
+	DiskControllerSCSI = "scsi"
+	DiskControllerSATA = "sata"
+	DiskControllerIDE  = "ide"
+	DiskControllerNVMe = "nvme"
+)
+
+// DiskController is the controller a disk is attached to in VMware
+type DiskController struct {
+	// Type is the type of the controller, empty if it is unknown
+	Type string
+	// Model is the VMware model of a SCSI controller: pvscsi, lsilogic, lsisas1068 or buslogic
+	Model string
+	// BusNumber is the bus number of the controller among the controllers of its type
+	BusNumber int32
 }
 
 type VMOps struct {
@@ -187,15 +209,17 @@ func (vmops *VMOps) GetVMInfo(ostype string, rdmDisks []string) (VMInfo, error)
 	}
 
 	vmdisks := []VMDisk{}
-	for _, device := range o.Config.Hardware.Device {
+	devices := object.VirtualDeviceList(o.Config.Hardware.Device)
+	for _, device := range devices {
 		if disk, ok := device.(*types.VirtualDisk); ok {
 			if _, ok := disk.Backing.(*types.VirtualDiskRawDiskMappingVer1BackingInfo); ok {
 				continue
 			}
 			vmdisks = append(vmdisks, VMDisk{
-				Name: disk.DeviceInfo.GetDescription().Label,
-				Size: disk.CapacityInBytes,
-				Disk: disk,
+				Name:       disk.DeviceInfo.GetDescription().Label,
+				Size:       disk.CapacityInBytes,
+				Disk:       disk,
+				Controller: diskController(devices, disk),
 			})
 		}
 	}
@@ -212,6 +236,10 @@ func (vmops *VMOps) GetVMInfo(ostype string, rdmDisks []string) (VMInfo, error)
 			return VMInfo{}, fmt.Errorf("no OS type provided and unable to determine OS type")
 		}
 	}
+	osName := vmwareMachine.Spec.VMInfo.OSName
+	if osName == "" {
+		osName = o.Config.GuestFullName
+	}
 	rdmDiskSlice := make([]vjailbreakv1alpha1.RDMDisk, 0)
 	// Get RDM disks from vmware machine
 	for _, rdm := range rdmDisks {
@@ -223,6 +251,7 @@ func (vmops *VMOps) GetVMInfo(ostype string, rdmDisks []string) (VMInfo, error)
 	}
 	vminfo := VMInfo{
 		CPU:               o.Config.Hardware.NumCPU,
+		CoresPerSocket:    o.Config.Hardware.NumCoresPerSocket,
 		Memory:            o.Config.Hardware.MemoryMB,
 		State:             o.Runtime.PowerState,
 		Mac:               mac,
@@ -234,12 +263,34 @@ func (vmops *VMOps) GetVMInfo(ostype string, rdmDisks []string) (VMInfo, error)
 		RDMDisks:          rdmDiskSlice,
 		UEFI:              uefi,
 		OSType:            ostype,
+		OSName:            osName,
 		NetworkInterfaces: vmwareMachine.Spec.VMInfo.NetworkInterfaces,
 		GuestNetworks:     vmwareMachine.Spec.VMInfo.GuestNetworks,
 	}
 	return vminfo, nil
 }
 
+// diskController returns the controller the disk is attached to
+func diskController(devices object.VirtualDeviceList, disk *types.VirtualDisk) DiskController {
+	switch controller := devices.FindByKey(disk.ControllerKey).(type) {
+	case *types.ParaVirtualSCSIController:
+		return DiskController{Type: DiskControllerSCSI, Model: "pvscsi", BusNumber: controller.BusNumber}
+	case *types.VirtualLsiLogicController:
+		return DiskController{Type: DiskControllerSCSI, Model: "lsilogic", BusNumber: controller.BusNumber}
+	case *types.VirtualLsiLogicSASController:
+		return DiskController{Type: DiskControllerSCSI, Model: "lsisas1068", BusNumber: controller.BusNumber}
+	case *types.VirtualBusLogicController:
+		return DiskController{Type: DiskControllerSCSI, Model: "buslogic", BusNumber: controller.BusNumber}
+	case *types.VirtualAHCIController:
+		return DiskController{Type: DiskControllerSATA, BusNumber: controller.BusNumber}
+	case *types.VirtualIDEController:
+		return DiskController{Type: DiskControllerIDE, BusNumber: controller.BusNumber}
+	case *types.VirtualNVMEController:
+		return DiskController{Type: DiskControllerNVMe, BusNumber: controller.BusNumber}
+	}
+	return DiskController{}
+}
+
 func parseChangeID(changeId string) (*ChangeID, error) {
 	changeIdParts := strings.Split(changeId, "/")
 	if len(changeIdParts) != 2 {
@@ -387,7 +438,7 @@ func (vmops *VMOps) UpdateDiskInfo(vminfo *VMInfo, disk VMDisk, blockCopySuccess
 				log.Printf("Updated disk info for %s", disk.Name)
 				log.Printf("Snapshot backing disk: %s", snapbackingdisk[idx])
 				log.Printf("Snapshot name: %s", snapname[idx])
-				log.Printf("Change ID: %s", snapid[idx])
+				log.Printf("Change ID: %s", vminfo.VMDisks[idx].ChangeID)
 				break
 			}
 		}
@@ -771,6 +822,16 @@ func (vmops *VMOps) VMPowerOn() error {
 }
 
 func (vmops *VMOps) DisconnectNetworkInterfaces() error {
+	return vmops.setNetworkInterfacesConnected(false)
+}
+
+// ConnectNetworkInterfaces reconnects the network interfaces disconnected by DisconnectNetworkInterfaces
+func (vmops *VMOps) ConnectNetworkInterfaces() error {
+	return vmops.setNetworkInterfacesConnected(true)
+}
+
+// setNetworkInterfacesConnected connects or disconnects all network interfaces of the VM, now and at power on
+func (vmops *VMOps) setNetworkInterfacesConnected(connected bool) error {
 	ctx := vmops.ctx
 
 	vm := vmops.VMObj
@@ -797,11 +858,15 @@ func (vmops *VMOps) DisconnectNetworkInterfaces() error {
 	for _, device := range mvm.Config.Hardware.Device {
 		if nic, ok := device.(types.BaseVirtualEthernetCard); ok {
 			nicName := nic.GetVirtualEthernetCard().DeviceInfo.GetDescription().Label
-			log.Printf("Found NIC to disconnect: %s", nicName)
+			if connected {
+				log.Printf("Found NIC to connect: %s", nicName)
+			} else {
+				log.Printf("Found NIC to disconnect: %s", nicName)
+			}
 			deviceCopy := device
 			connectable := nic.GetVirtualEthernetCard().Connectable
-			connectable.Connected = false
-			connectable.StartConnected = false
+			connectable.Connected = connected
+			connectable.StartConnected = connected
 			spec := &types.VirtualDeviceConfigSpec{
 				Operation: types.VirtualDeviceConfigSpecOperationEdit,
 				Device:    deviceCopy,
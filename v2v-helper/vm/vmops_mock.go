@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: vmops.go
+// Source: ../vm/vmops.go
 
 // Package vm is a generated GoMock package.
 package vm
@@ -49,6 +49,20 @@ func (mr *MockVMOperationsMockRecorder) CleanUpSnapshots(ignoreerror interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CleanUpSnapshots", reflect.TypeOf((*MockVMOperations)(nil).CleanUpSnapshots), ignoreerror)
 }
 
+// ConnectNetworkInterfaces mocks base method.
+func (m *MockVMOperations) ConnectNetworkInterfaces() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConnectNetworkInterfaces")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConnectNetworkInterfaces indicates an expected call of ConnectNetworkInterfaces.
+func (mr *MockVMOperationsMockRecorder) ConnectNetworkInterfaces() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConnectNetworkInterfaces", reflect.TypeOf((*MockVMOperations)(nil).ConnectNetworkInterfaces))
+}
+
 // CustomQueryChangedDiskAreas mocks base method.
 func (m *MockVMOperations) CustomQueryChangedDiskAreas(baseChangeID string, curSnapshot *types.ManagedObjectReference, disk *types.VirtualDisk, offset int64) (types.DiskChangeInfo, error) {
 	m.ctrl.T.Helper()
@@ -150,18 +164,18 @@ func (mr *MockVMOperationsMockRecorder) GetSnapshot(name interface{}) *gomock.Ca
 }
 
 // GetVMInfo mocks base method.
-func (m *MockVMOperations) GetVMInfo(ostype string) (VMInfo, error) {
+func (m *MockVMOperations) GetVMInfo(ostype string, rdmDisks []string) (VMInfo, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetVMInfo", ostype)
+	ret := m.ctrl.Call(m, "GetVMInfo", ostype, rdmDisks)
 	ret0, _ := ret[0].(VMInfo)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetVMInfo indicates an expected call of GetVMInfo.
-func (mr *MockVMOperationsMockRecorder) GetVMInfo(ostype interface{}) *gomock.Call {
+func (mr *MockVMOperationsMockRecorder) GetVMInfo(ostype, rdmDisks interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVMInfo", reflect.TypeOf((*MockVMOperations)(nil).GetVMInfo), ostype)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVMInfo", reflect.TypeOf((*MockVMOperations)(nil).GetVMInfo), ostype, rdmDisks)
 }
 
 // GetVMObj mocks base method.